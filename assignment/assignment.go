@@ -0,0 +1,267 @@
+// Package assignment picks the agent a routed ticket is assigned to. A
+// team's assignment_strategy names a Strategy; the worker runs it when a
+// ticket reaches the team. Only online agents below their max_concurrent
+// open tickets are considered, and each decision records every candidate
+// and why it was or was not chosen.
+package assignment
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	db "tickets/db/sqlc"
+)
+
+// Manual is the strategy of teams whose tickets are assigned by hand.
+const Manual = "manual"
+
+// DefaultClosedStatuses are the ticket statuses that do not count toward an
+// agent's open tickets when ASSIGNMENT_CLOSED_STATUSES is unset.
+const DefaultClosedStatuses = "3,4"
+
+var statusListRe = regexp.MustCompile(`^[0-9]+(,[0-9]+)*$`)
+
+// ClosedStatuses returns ASSIGNMENT_CLOSED_STATUSES, the comma separated
+// ticket statuses that do not count toward an agent's open tickets.
+func ClosedStatuses() string {
+	v := strings.ReplaceAll(os.Getenv("ASSIGNMENT_CLOSED_STATUSES"), " ", "")
+	if v == "" {
+		return DefaultClosedStatuses
+	}
+	if !statusListRe.MatchString(v) {
+		slog.Error("invalid ASSIGNMENT_CLOSED_STATUSES, using default", "value", v, "default", DefaultClosedStatuses)
+		return DefaultClosedStatuses
+	}
+	return v
+}
+
+// Candidate is a team member with their availability and load.
+type Candidate struct {
+	ProfileID     int32
+	Status        db.AgentSettingsStatus
+	MaxConcurrent int32
+	OpenTickets   int64
+	Skills        []string
+}
+
+// Ticket is what strategies pick an agent for.
+type Ticket struct {
+	ID          int64
+	Title       string
+	Description string
+	Priority    string
+	Type        db.TicketsType
+	Segment     string
+}
+
+// Input is what a strategy chooses from.
+type Input struct {
+	Ticket Ticket
+	// Candidates are the available members, ordered by profile id; never empty.
+	Candidates []Candidate
+	// LastAssigned is the member the team's previous ticket went to, 0 if none.
+	LastAssigned int32
+}
+
+// Strategy chooses one of in.Candidates and says why.
+type Strategy interface {
+	Pick(in Input) (Candidate, string)
+}
+
+var strategies = map[string]Strategy{
+	"round_robin": RoundRobin{},
+	"least_open":  LeastOpen{},
+	"skill_match": SkillMatch{},
+}
+
+// Register adds a strategy teams can name. It is meant to be called from
+// init functions and is not safe for concurrent use.
+func Register(name string, s Strategy) {
+	strategies[name] = s
+}
+
+// Lookup returns the named strategy; manual and unknown names have none.
+func Lookup(name string) (Strategy, bool) {
+	s, ok := strategies[name]
+	return s, ok
+}
+
+// Valid reports whether a team may use the named strategy.
+func Valid(name string) bool {
+	_, ok := strategies[name]
+	return ok || name == Manual
+}
+
+// Names lists the strategies teams may use, manual first.
+func Names() []string {
+	names := make([]string, 0, len(strategies))
+	for n := range strategies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return append([]string{Manual}, names...)
+}
+
+// Considered is how one candidate fared, as logged with a decision.
+type Considered struct {
+	ProfileID     int32  `json:"profile_id"`
+	Status        string `json:"status"`
+	OpenTickets   int64  `json:"open_tickets"`
+	MaxConcurrent int32  `json:"max_concurrent"`
+	Skipped       string `json:"skipped,omitempty"`
+}
+
+// Decision is the outcome of Decide. ProfileID is 0 when no one was
+// available.
+type Decision struct {
+	ProfileID  int32
+	Reason     string
+	Candidates []Considered
+}
+
+// Decide drops the candidates who are not online or are at capacity and
+// lets s pick from the rest.
+func Decide(s Strategy, t Ticket, candidates []Candidate, lastAssigned int32) Decision {
+	d := Decision{Candidates: []Considered{}}
+	var available []Candidate
+	for _, c := range candidates {
+		considered := Considered{
+			ProfileID:     c.ProfileID,
+			Status:        string(c.Status),
+			OpenTickets:   c.OpenTickets,
+			MaxConcurrent: c.MaxConcurrent,
+		}
+		switch {
+		case c.Status != db.AgentSettingsStatusOnline:
+			considered.Skipped = string(c.Status)
+		case c.OpenTickets >= int64(c.MaxConcurrent):
+			considered.Skipped = "at capacity"
+		default:
+			available = append(available, c)
+		}
+		d.Candidates = append(d.Candidates, considered)
+	}
+	if len(candidates) == 0 {
+		d.Reason = "team has no agents"
+		return d
+	}
+	if len(available) == 0 {
+		d.Reason = "no agent is online with capacity"
+		return d
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i].ProfileID < available[j].ProfileID })
+	picked, reason := s.Pick(Input{Ticket: t, Candidates: available, LastAssigned: lastAssigned})
+	d.ProfileID = picked.ProfileID
+	d.Reason = reason
+	return d
+}
+
+// RoundRobin takes the members in turn: the first after the one the team's
+// previous ticket went to, by profile id.
+type RoundRobin struct{}
+
+func (RoundRobin) Pick(in Input) (Candidate, string) {
+	for _, c := range in.Candidates {
+		if c.ProfileID > in.LastAssigned {
+			return c, fmt.Sprintf("round robin: next after agent %d", in.LastAssigned)
+		}
+	}
+	return in.Candidates[0], "round robin: back to the first agent"
+}
+
+// LeastOpen takes the member with the fewest open tickets.
+type LeastOpen struct{}
+
+func (LeastOpen) Pick(in Input) (Candidate, string) {
+	c := leastOpen(in.Candidates)
+	return c, fmt.Sprintf("fewest open tickets (%d)", c.OpenTickets)
+}
+
+func leastOpen(candidates []Candidate) Candidate {
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.OpenTickets < best.OpenTickets {
+			best = c
+		}
+	}
+	return best
+}
+
+// SkillMatch takes the member with the most skills that appear in the
+// ticket: in its title or description, or equal to its type, priority or
+// customer segment. Ties, and tickets no one's skills match, go to the
+// member with the fewest open tickets.
+type SkillMatch struct{}
+
+func (SkillMatch) Pick(in Input) (Candidate, string) {
+	bestScore := 0
+	var best []Candidate
+	for _, c := range in.Candidates {
+		score := len(matchSkills(c.Skills, in.Ticket))
+		switch {
+		case score > bestScore:
+			bestScore, best = score, []Candidate{c}
+		case score == bestScore && score > 0:
+			best = append(best, c)
+		}
+	}
+	if bestScore == 0 {
+		c := leastOpen(in.Candidates)
+		return c, fmt.Sprintf("no skill matched; fewest open tickets (%d)", c.OpenTickets)
+	}
+	c := leastOpen(best)
+	matched := matchSkills(c.Skills, in.Ticket)
+	return c, fmt.Sprintf("skills matched: %s; %d open tickets", strings.Join(matched, ", "), c.OpenTickets)
+}
+
+func matchSkills(skills []string, t Ticket) []string {
+	text := strings.ToLower(t.Title + "\n" + t.Description)
+	var matched []string
+	for _, s := range skills {
+		if strings.EqualFold(s, string(t.Type)) || strings.EqualFold(s, t.Priority) ||
+			strings.EqualFold(s, t.Segment) || strings.Contains(text, s) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// ParseSkills validates skills and returns them lowercased, trimmed and
+// without duplicates. Skills cannot contain commas, which separate them in
+// agent_settings.skills.
+func ParseSkills(skills []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(skills))
+	for _, s := range skills {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			return nil, errors.New("skills cannot be empty")
+		}
+		if len(s) > 50 || strings.Contains(s, ",") {
+			return nil, fmt.Errorf("invalid skill %q", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// JoinSkills and SplitSkills convert skills to and from the comma separated
+// form stored in agent_settings.skills.
+func JoinSkills(skills []string) string {
+	return strings.Join(skills, ",")
+}
+
+func SplitSkills(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
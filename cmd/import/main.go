@@ -0,0 +1,66 @@
+// Command import bulk-loads customers or users from a CSV file, as
+// POST /imports/customers and /imports/users do, and prints the report as
+// JSON. It exits 1 when any row failed.
+//
+//	go run ./cmd/import -file customers.csv [-kind users] [-dry-run] [-batch 100]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"tickets/config"
+	db "tickets/db/sqlc"
+	"tickets/importer"
+)
+
+func main() {
+	file := flag.String("file", "", "CSV file to import")
+	kind := flag.String("kind", string(importer.KindCustomers), "what the file holds: customers or users")
+	dryRun := flag.Bool("dry-run", false, "validate and report without keeping any change")
+	batch := flag.Int("batch", importer.DefaultBatchSize, "rows per database transaction")
+	flag.Parse()
+
+	if *file == "" || !importer.Valid(importer.Kind(*kind)) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	conn, err := config.DBConnection()
+	if err != nil {
+		log.Fatal("Failed to connect DB: ", err)
+	}
+	defer conn.Close()
+
+	report, err := importer.Run(context.Background(), conn, db.New(conn), f, importer.Options{
+		Kind:      importer.Kind(*kind),
+		DryRun:    *dryRun,
+		BatchSize: *batch,
+		Progress: func(rows int) {
+			log.Printf("%d rows read", rows)
+		},
+	})
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		if errors.Is(err, importer.ErrBadFile) {
+			log.Fatalf("%s: %v", *file, err)
+		}
+		log.Fatal("import failed: ", err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,52 @@
+// Command ledgercheck verifies the ledger invariants and exits non-zero if
+// any are broken. With -backfill it first posts journal entries for settled
+// transactions that have none.
+//
+//	go run ./cmd/ledgercheck [-backfill]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"tickets/config"
+	db "tickets/db/sqlc"
+	"tickets/ledger"
+)
+
+func main() {
+	backfill := flag.Bool("backfill", false, "post missing journal entries before checking")
+	flag.Parse()
+
+	conn, err := config.DBConnection()
+	if err != nil {
+		log.Fatal("Failed to connect DB: ", err)
+	}
+	defer conn.Close()
+	q := db.New(conn)
+	ctx := context.Background()
+
+	if *backfill {
+		n, err := ledger.Backfill(ctx, conn, q)
+		if err != nil {
+			log.Fatalf("backfill stopped after %d transactions: %v", n, err)
+		}
+		fmt.Printf("posted %d transactions\n", n)
+	}
+
+	problems, err := ledger.Check(ctx, q)
+	if err != nil {
+		log.Fatal("ledger check failed: ", err)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("ledger OK")
+}
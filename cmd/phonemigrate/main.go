@@ -0,0 +1,187 @@
+// Command phonemigrate rewrites profile and customer phones into E.164 form
+// and prints a JSON report of what it changed or would change. Phones that
+// do not parse, and phones that would become the same number as another
+// row's, are reported and left alone: the latter are usually one person
+// entered twice and need merging (POST /customers/:id/merge) before they
+// can be normalized. It exits 1 while any remain.
+//
+// It is a dry run unless -apply is given. Run it with -apply, resolve what
+// it reports and run it again before db/migrations/005_phone_e164.sql.
+//
+//	go run ./cmd/phonemigrate [-apply] [-region KE]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"tickets/config"
+	db "tickets/db/sqlc"
+	"tickets/phone"
+	"tickets/privacy"
+)
+
+// customerPage is how many customers are read per query.
+const customerPage = 1000
+
+type Change struct {
+	ID   int64  `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Invalid struct {
+	ID    int64  `json:"id"`
+	Phone string `json:"phone"`
+}
+
+// Conflict is a number that several rows would share once normalized.
+type Conflict struct {
+	Phone string   `json:"phone"`
+	Rows  []Change `json:"rows"`
+}
+
+type TableReport struct {
+	Checked    int        `json:"checked"`
+	Unchanged  int        `json:"unchanged"`
+	Normalized []Change   `json:"normalized"`
+	Invalid    []Invalid  `json:"invalid"`
+	Conflicts  []Conflict `json:"conflicts"`
+}
+
+type Report struct {
+	Region    string      `json:"region"`
+	Applied   bool        `json:"applied"`
+	Profiles  TableReport `json:"profiles"`
+	Customers TableReport `json:"customers"`
+}
+
+type row struct {
+	id    int64
+	phone string
+}
+
+func main() {
+	apply := flag.Bool("apply", false, "write the normalized phones; without it nothing is changed")
+	region := flag.String("region", phone.Region(), "region numbers without a country code are read in")
+	flag.Parse()
+
+	conn, err := config.DBConnection()
+	if err != nil {
+		log.Fatal("Failed to connect DB: ", err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	q := db.New(conn)
+
+	report := Report{Region: *region, Applied: *apply}
+
+	profiles, err := q.ListProfilePhones(ctx)
+	if err != nil {
+		log.Fatal("list profiles: ", err)
+	}
+	var rows []row
+	for _, p := range profiles {
+		rows = append(rows, row{id: int64(p.ID), phone: p.Phone.String})
+	}
+	report.Profiles = plan(rows, *region)
+
+	rows = nil
+	params := db.ListCustomersAfterParams{Limit: customerPage}
+	for {
+		page, err := q.ListCustomersAfter(ctx, params)
+		if err != nil {
+			log.Fatal("list customers: ", err)
+		}
+		for _, cu := range page {
+			if !privacy.ErasedPhone(cu.PhoneNumber) {
+				rows = append(rows, row{id: cu.ID, phone: cu.PhoneNumber})
+			}
+		}
+		if len(page) < customerPage {
+			break
+		}
+		params.ID = page[len(page)-1].ID
+	}
+	report.Customers = plan(rows, *region)
+
+	if *apply {
+		err := write(ctx, conn, q, report.Profiles.Normalized, func(qtx *db.Queries, c Change) error {
+			return qtx.UpdateProfilePhone(ctx, db.UpdateProfilePhoneParams{
+				Phone: sql.NullString{String: c.To, Valid: true},
+				ID:    int32(c.ID),
+			})
+		})
+		if err != nil {
+			log.Fatal("update profiles: ", err)
+		}
+		err = write(ctx, conn, q, report.Customers.Normalized, func(qtx *db.Queries, c Change) error {
+			return qtx.UpdateCustomerPhone(ctx, db.UpdateCustomerPhoneParams{PhoneNumber: c.To, ID: c.ID})
+		})
+		if err != nil {
+			log.Fatal("update customers: ", err)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	for _, t := range []TableReport{report.Profiles, report.Customers} {
+		if len(t.Invalid) > 0 || len(t.Conflicts) > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// plan works out the new phone of every row. Rows are grouped by the number
+// they normalize to; a group of more than one is a conflict and none of its
+// rows change, so no update can collide with the unique key.
+func plan(rows []row, region string) TableReport {
+	r := TableReport{Normalized: []Change{}, Invalid: []Invalid{}, Conflicts: []Conflict{}}
+	groups := map[string][]Change{}
+	var order []string
+	for _, ro := range rows {
+		r.Checked++
+		p, err := phone.Parse(ro.phone, region)
+		if err != nil {
+			r.Invalid = append(r.Invalid, Invalid{ID: ro.id, Phone: ro.phone})
+			continue
+		}
+		if _, ok := groups[p]; !ok {
+			order = append(order, p)
+		}
+		groups[p] = append(groups[p], Change{ID: ro.id, From: ro.phone, To: p})
+	}
+	for _, p := range order {
+		g := groups[p]
+		switch {
+		case len(g) > 1:
+			r.Conflicts = append(r.Conflicts, Conflict{Phone: p, Rows: g})
+		case g[0].From == g[0].To:
+			r.Unchanged++
+		default:
+			r.Normalized = append(r.Normalized, g[0])
+		}
+	}
+	return r
+}
+
+// write applies changes in one DB transaction.
+func write(ctx context.Context, conn *sql.DB, q *db.Queries, changes []Change, update func(*db.Queries, Change) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	for _, c := range changes {
+		if err := update(qtx, c); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,111 @@
+// Command reconcile matches a provider statement against the transactions
+// table and prints every finding that is not a clean match, followed by a
+// summary. With -save the run is stored and shows up under /reconciliations.
+// It exits 1 when anything did not match.
+//
+//	go run ./cmd/reconcile -file statement.csv [-format mpesa] [-window 10m] [-currency KES] [-save]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"tickets/config"
+	db "tickets/db/sqlc"
+	"tickets/reconcile"
+)
+
+func main() {
+	file := flag.String("file", "", "statement to reconcile")
+	format := flag.String("format", reconcile.FormatCSV, "statement format: csv or mpesa")
+	window := flag.Duration("window", reconcile.DefaultWindow, "how far apart a line and transaction may be when matching by amount")
+	currency := flag.String("currency", "KES", "currency for CSV statements without a currency column")
+	save := flag.Bool("save", false, "store the run")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	lines, err := reconcile.Parse(*format, f, *currency)
+	if err != nil {
+		log.Fatalf("%s: %v", *file, err)
+	}
+	if len(lines) == 0 {
+		log.Fatalf("%s: statement has no lines", *file)
+	}
+
+	conn, err := config.DBConnection()
+	if err != nil {
+		log.Fatal("Failed to connect DB: ", err)
+	}
+	defer conn.Close()
+	q := db.New(conn)
+	ctx := context.Background()
+
+	start, end := reconcile.Period(lines)
+	txns, err := reconcile.Load(ctx, q, start, end, *window)
+	if err != nil {
+		log.Fatal("loading transactions: ", err)
+	}
+	report := reconcile.Match(lines, txns, *window)
+
+	for _, it := range report.Items {
+		if it.Status == reconcile.StatusMatched {
+			continue
+		}
+		fmt.Println(describe(it))
+	}
+
+	counts := report.Counts()
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, string(s))
+	}
+	sort.Strings(statuses)
+	fmt.Printf("%d lines, %d transactions, %s to %s\n", len(lines), len(txns),
+		report.PeriodStart.Format("2006-01-02 15:04:05"), report.PeriodEnd.Format("2006-01-02 15:04:05"))
+	for _, s := range statuses {
+		fmt.Printf("  %-20s %d\n", s, counts[reconcile.Status(s)])
+	}
+
+	if *save {
+		id, err := reconcile.Save(ctx, conn, q, reconcile.Run{
+			Format:    *format,
+			Filename:  filepath.Base(*file),
+			LineCount: len(lines),
+		}, report)
+		if err != nil {
+			log.Fatal("saving run: ", err)
+		}
+		fmt.Printf("saved as reconciliation %d\n", id)
+	}
+
+	if counts[reconcile.StatusMatched] != len(report.Items) {
+		os.Exit(1)
+	}
+}
+
+func describe(it reconcile.Item) string {
+	s := fmt.Sprintf("%-20s", it.Status)
+	if l := it.Line; l != nil {
+		s += fmt.Sprintf(" line %d %s %s %s", l.Number, l.Reference, l.Amount.Currency, l.Amount.String())
+	}
+	if t := it.Transaction; t != nil {
+		s += fmt.Sprintf(" transaction %d (%s) %s %s", t.ID, t.TransactionID, t.Amount.Currency, t.Amount.String())
+	}
+	if it.Note != "" {
+		s += ": " + it.Note
+	}
+	return s
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tickets/assignment"
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AgentController manages the availability and skills automatic assignment
+// goes by. An agent without settings is offline.
+type AgentController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+type AgentResponse struct {
+	ID            int32    `json:"id"`
+	FullName      string   `json:"full_name,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	Role          string   `json:"role"`
+	Status        string   `json:"status"`
+	MaxConcurrent *int32   `json:"max_concurrent,omitempty"`
+	Skills        []string `json:"skills"`
+	OpenTickets   int64    `json:"open_tickets"`
+}
+
+func newAgentResponse(a db.ListAgentsRow) AgentResponse {
+	r := AgentResponse{
+		ID:          a.ID,
+		FullName:    a.FullName.String,
+		Email:       a.Email.String,
+		Role:        string(a.Role),
+		Status:      string(db.AgentSettingsStatusOffline),
+		Skills:      assignment.SplitSkills(a.Skills.String),
+		OpenTickets: a.OpenTickets,
+	}
+	if a.Status.Valid {
+		r.Status = string(a.Status.AgentSettingsStatus)
+	}
+	if a.MaxConcurrent.Valid {
+		r.MaxConcurrent = &a.MaxConcurrent.Int32
+	}
+	if r.Skills == nil {
+		r.Skills = []string{}
+	}
+	return r
+}
+
+type AgentStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=online away offline"`
+}
+
+// AgentSettingsRequest replaces every setting of an agent.
+type AgentSettingsRequest struct {
+	Status        string   `json:"status" binding:"required,oneof=online away offline"`
+	MaxConcurrent int32    `json:"max_concurrent" binding:"required,min=1,max=1000"`
+	Skills        []string `json:"skills" binding:"max=50"`
+}
+
+// ListAgents lists agents and admins with their availability, skills and
+// open tickets.
+func (ac *AgentController) ListAgents(c *gin.Context) {
+	agents, err := ac.Queries.ListAgents(c, assignment.ClosedStatuses())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list agents"})
+		slog.Error("Failed to list agents", "error", err)
+		return
+	}
+	resp := make([]AgentResponse, 0, len(agents))
+	for _, a := range agents {
+		resp = append(resp, newAgentResponse(a))
+	}
+	c.JSON(http.StatusOK, gin.H{"agents": resp})
+}
+
+// UpdateMyStatus sets the caller's own availability: online agents are
+// assigned new tickets, away and offline ones are not.
+func (ac *AgentController) UpdateMyStatus(c *gin.Context) {
+	var req AgentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	profileID := int32(middleware.ProfileID(c))
+	err := ac.Queries.UpdateAgentStatus(c, db.UpdateAgentStatusParams{
+		ProfileID: profileID,
+		Status:    db.AgentSettingsStatus(req.Status),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
+		slog.Error("Failed to update agent status", "profile_id", profileID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": req.Status})
+	slog.Info("Agent status updated", "profile_id", profileID, "status", req.Status)
+}
+
+// UpdateAgent replaces an agent's status, capacity and skills.
+func (ac *AgentController) UpdateAgent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req AgentSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	skills, err := assignment.ParseSkills(req.Skills)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	joined := assignment.JoinSkills(skills)
+	if len(joined) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skills are too long"})
+		return
+	}
+
+	profile, err := ac.Queries.GetProfileByID(c, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update agent"})
+		slog.Error("Failed to get profile", "id", id, "error", err)
+		return
+	}
+	if profile.Role != db.ProfilesRoleAgent && profile.Role != db.ProfilesRoleAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
+	}
+
+	err = ac.Queries.UpsertAgentSettings(c, db.UpsertAgentSettingsParams{
+		ProfileID:     profile.ID,
+		Status:        db.AgentSettingsStatus(req.Status),
+		MaxConcurrent: req.MaxConcurrent,
+		Skills:        joined,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update agent"})
+		slog.Error("Failed to update agent settings", "profile_id", profile.ID, "error", err)
+		return
+	}
+	settings, err := ac.Queries.GetAgentSettings(c, profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update agent"})
+		slog.Error("Failed to load agent settings", "profile_id", profile.ID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":             profile.ID,
+		"status":         settings.Status,
+		"max_concurrent": settings.MaxConcurrent,
+		"skills":         skills,
+		"updated_at":     settings.UpdatedAt,
+	})
+	slog.Info("Agent settings updated", "profile_id", profile.ID, "status", settings.Status,
+		"max_concurrent", settings.MaxConcurrent, "updated_by", middleware.ProfileID(c))
+}
+
+// TicketAssignmentResponse is one automatic assignment decision.
+// Candidates lists every team member considered, with their status and
+// open tickets and why each that was passed over was skipped.
+type TicketAssignmentResponse struct {
+	ID         int64                   `json:"id"`
+	TeamID     *int64                  `json:"team_id,omitempty"`
+	Strategy   string                  `json:"strategy"`
+	AssignedTo *int32                  `json:"assigned_to,omitempty"`
+	Reason     string                  `json:"reason"`
+	Candidates []assignment.Considered `json:"candidates"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+func newTicketAssignmentResponse(a db.TicketAssignment) TicketAssignmentResponse {
+	r := TicketAssignmentResponse{
+		ID:         a.ID,
+		Strategy:   a.Strategy,
+		Reason:     a.Reason,
+		Candidates: []assignment.Considered{},
+		CreatedAt:  a.CreatedAt,
+	}
+	if a.TeamID.Valid {
+		r.TeamID = &a.TeamID.Int64
+	}
+	if a.AssignedTo.Valid {
+		r.AssignedTo = &a.AssignedTo.Int32
+	}
+	if err := json.Unmarshal([]byte(a.Candidates), &r.Candidates); err != nil {
+		slog.Error("Failed to decode assignment candidates", "id", a.ID, "error", err)
+	}
+	return r
+}
+
+// ListTicketAssignments is the log of a ticket's automatic assignment
+// decisions, oldest first.
+func (ac *AgentController) ListTicketAssignments(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, err := ac.Queries.GetTicket(c, id); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+		slog.Error("Failed to get ticket", "id", id, "error", err)
+		return
+	}
+	rows, err := ac.Queries.ListTicketAssignments(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+		slog.Error("Failed to list ticket assignments", "id", id, "error", err)
+		return
+	}
+	resp := make([]TicketAssignmentResponse, 0, len(rows))
+	for _, r := range rows {
+		resp = append(resp, newTicketAssignmentResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"assignments": resp})
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyScopes lists the scopes a key can be granted.
+var APIKeyScopes = map[string]bool{
+	"tickets:read":       true,
+	"tickets:write":      true,
+	"transactions:read":  true,
+	"transactions:write": true,
+	"customers:read":     true,
+	"customers:write":    true,
+	"webhooks:read":      true,
+	"webhooks:write":     true,
+}
+
+type APIKeyController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=730"`
+}
+
+// CreateAPIKey issues a key. The plain key is only ever returned here.
+func (ac *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scopes := map[string]bool{}
+	for _, s := range req.Scopes {
+		if !APIKeyScopes[s] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope " + s})
+			return
+		}
+		scopes[s] = true
+	}
+	scopeList := make([]string, 0, len(scopes))
+	for s := range scopes {
+		scopeList = append(scopeList, s)
+	}
+	sort.Strings(scopeList)
+
+	// keys expire after 90 days unless told otherwise
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = 90
+	}
+	expires := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate key"})
+		slog.Error("Failed to generate api key", "error", err)
+		return
+	}
+
+	result, err := ac.Queries.CreateAPIKey(c, db.CreateAPIKeyParams{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashAPIKey(key),
+		Scopes:    strings.Join(scopeList, ","),
+		CreatedBy: int32(middleware.ProfileID(c)),
+		ExpiresAt: sql.NullTime{Time: expires, Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		slog.Error("Failed to create api key", "error", err)
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         id,
+		"name":       req.Name,
+		"prefix":     prefix,
+		"scopes":     scopeList,
+		"expires_at": expires,
+		"key":        key,
+		"message":    "store this key now, it will not be shown again",
+	})
+	slog.Info("API key created", "id", id, "prefix", prefix, "created_by", middleware.ProfileID(c))
+}
+
+// ListAPIKeys returns key metadata, never the key or its hash.
+func (ac *APIKeyController) ListAPIKeys(c *gin.Context) {
+	keys, err := ac.Queries.ListAPIKeys(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list api keys"})
+		slog.Error("Failed to list api keys", "error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (ac *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
+		return
+	}
+
+	n, err := ac.Queries.RevokeAPIKey(c, db.RevokeAPIKeyParams{
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:        id,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		slog.Error("Failed to revoke api key", "id", id, "error", err)
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found or already revoked"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+	slog.Info("API key revoked", "id", id, "revoked_by", middleware.ProfileID(c))
+}
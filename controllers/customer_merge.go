@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/dedupe"
+	"tickets/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DuplicatePair is two customers that are likely the same person.
+type DuplicatePair struct {
+	ID             int64            `json:"id"`
+	Customer       CustomerResponse `json:"customer"`
+	Duplicate      CustomerResponse `json:"duplicate"`
+	Reasons        []string         `json:"reasons"`
+	NameSimilarity float64          `json:"name_similarity,omitempty"`
+	FoundAt        time.Time        `json:"found_at"`
+}
+
+// CustomerMergeResponse is the JSON shape of a customer_merges row.
+type CustomerMergeResponse struct {
+	ID             int64            `json:"id"`
+	SurvivorID     int64            `json:"survivor_id"`
+	Merged         CustomerResponse `json:"merged"`
+	TicketIDs      []int64          `json:"ticket_ids"`
+	TransactionIDs []int64          `json:"transaction_ids"`
+	MergedBy       int32            `json:"merged_by"`
+	Note           string           `json:"note,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+func newCustomerMergeResponse(m db.CustomerMerge) CustomerMergeResponse {
+	return CustomerMergeResponse{
+		ID:         m.ID,
+		SurvivorID: m.SurvivorID,
+		Merged: CustomerResponse{
+			ID:          m.MergedID,
+			FullName:    m.MergedFullName,
+			Email:       m.MergedEmail,
+			PhoneNumber: m.MergedPhoneNumber,
+		},
+		TicketIDs:      splitIDs(m.TicketIds),
+		TransactionIDs: splitIDs(m.TransactionIds),
+		MergedBy:       m.MergedBy,
+		Note:           m.Note,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+// ListDuplicates lists pairs of customers that are likely duplicates: the
+// same phone number however it was written, the same email in any case, or
+// names at least ?min_similarity= alike (the worker's scan threshold,
+// 0.85, to 1). The pairs come from the worker's periodic scan, so customers
+// added since are not in them yet. Pages are ?limit= long (default 50, at
+// most 200); pass next_after_id as ?after_id= for the next one.
+func (controller *CustomerController) ListDuplicates(c *gin.Context) {
+	params := db.ListCustomerDuplicatesParams{MinSimilarity: dedupe.DefaultNameSimilarity, Limit: 50}
+	if v := c.Query("min_similarity"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < dedupe.DefaultNameSimilarity || f > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_similarity must be between 0.85 and 1"})
+			return
+		}
+		params.MinSimilarity = f
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		params.Limit = int32(min(l, 200))
+	}
+	if a, err := strconv.ParseInt(c.Query("after_id"), 10, 64); err == nil && a > 0 {
+		params.AfterID = a
+	}
+
+	rows, err := controller.Queries.ListCustomerDuplicates(c, params)
+	if err != nil {
+		slog.Error("Failed to list customer duplicates", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	resp := make([]DuplicatePair, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, DuplicatePair{
+			ID:             row.ID,
+			Customer:       newCustomerResponse(row.Customer),
+			Duplicate:      newCustomerResponse(row.Customer_2),
+			Reasons:        strings.Split(row.Reasons, ","),
+			NameSimilarity: row.NameSimilarity,
+			FoundAt:        row.FoundAt,
+		})
+	}
+	out := gin.H{"pairs": resp, "limit": params.Limit}
+	if len(rows) == int(params.Limit) {
+		out["next_after_id"] = rows[len(rows)-1].ID
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+type MergeCustomerRequest struct {
+	DuplicateID int64  `json:"duplicate_id" binding:"required"`
+	Note        string `json:"note" binding:"max=255"`
+}
+
+// MergeCustomer merges the customer duplicate_id into :id, which survives.
+// In one DB transaction the duplicate's tickets are moved to the survivor
+// (with them, the transactions they link), an audit row recording the
+// duplicate's details and what was moved is written, and the duplicate is
+// deleted.
+func (controller *CustomerController) MergeCustomer(c *gin.Context) {
+	survivorID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req MergeCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DuplicateID == survivorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a customer cannot be merged into itself"})
+		return
+	}
+
+	tx, err := controller.DB.BeginTx(c, nil)
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+	defer tx.Rollback()
+	qtx := controller.Queries.WithTx(tx)
+
+	// lock in id order so two opposite merges cannot deadlock
+	locked := map[int64]db.Customer{}
+	for _, id := range []int64{min(survivorID, req.DuplicateID), max(survivorID, req.DuplicateID)} {
+		cu, err := qtx.LockCustomer(c, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "customer " + strconv.FormatInt(id, 10) + " not found"})
+				return
+			}
+			slog.Error("Failed to lock customer", "id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+			return
+		}
+		locked[id] = cu
+	}
+	survivor, duplicate := locked[survivorID], locked[req.DuplicateID]
+
+	from := sql.NullInt64{Int64: duplicate.ID, Valid: true}
+	links, err := qtx.ListCustomerTicketLinks(c, from)
+	if err != nil {
+		slog.Error("Failed to list customer tickets", "id", duplicate.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+	var ticketIDs, transactionIDs []string
+	for _, l := range links {
+		ticketIDs = append(ticketIDs, strconv.FormatInt(l.ID, 10))
+		if l.TransactionID.Valid {
+			transactionIDs = append(transactionIDs, strconv.Itoa(int(l.TransactionID.Int32)))
+		}
+	}
+	if _, err := qtx.MoveCustomerTickets(c, db.MoveCustomerTicketsParams{
+		ToID:   sql.NullInt64{Int64: survivor.ID, Valid: true},
+		FromID: from,
+	}); err != nil {
+		slog.Error("Failed to move customer tickets", "from", duplicate.ID, "to", survivor.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+
+	result, err := qtx.CreateCustomerMerge(c, db.CreateCustomerMergeParams{
+		SurvivorID:        survivor.ID,
+		MergedID:          duplicate.ID,
+		MergedFullName:    duplicate.FullName,
+		MergedEmail:       duplicate.Email,
+		MergedPhoneNumber: duplicate.PhoneNumber,
+		TicketIds:         strings.Join(ticketIDs, ","),
+		TransactionIds:    strings.Join(transactionIDs, ","),
+		MergedBy:          int32(middleware.ProfileID(c)),
+		Note:              req.Note,
+	})
+	if err != nil {
+		slog.Error("Failed to record customer merge", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+	mergeID, err := result.LastInsertId()
+	if err != nil {
+		slog.Error("Failed to retrieve insert ID", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+
+	if _, err := qtx.DeleteCustomer(c, duplicate.ID); err != nil {
+		slog.Error("Failed to delete merged customer", "id", duplicate.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+	merge, err := qtx.GetCustomerMerge(c, mergeID)
+	if err != nil {
+		slog.Error("Failed to load customer merge", "id", mergeID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Failed to commit customer merge", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customer": newCustomerResponse(survivor),
+		"merge":    newCustomerMergeResponse(merge),
+	})
+	slog.Info("Customers merged", "survivor_id", survivor.ID, "merged_id", duplicate.ID,
+		"tickets", len(ticketIDs), "merged_by", middleware.ProfileID(c))
+}
+
+// ListCustomerMerges is the merge audit trail of a customer: merges into it
+// and, for a customer that no longer exists, the merge that removed it.
+func (controller *CustomerController) ListCustomerMerges(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	merges, err := controller.Queries.ListCustomerMerges(c, db.ListCustomerMergesParams{CustomerID: id})
+	if err != nil {
+		slog.Error("Failed to list customer merges", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	resp := make([]CustomerMergeResponse, 0, len(merges))
+	for _, m := range merges {
+		resp = append(resp, newCustomerMergeResponse(m))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func splitIDs(s string) []int64 {
+	ids := []int64{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
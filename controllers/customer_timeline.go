@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeline item types, as accepted by ?type= on the customer timeline.
+const (
+	timelineCustomer     = "customer"
+	timelineMerge        = "merge"
+	timelineTicket       = "ticket"
+	timelineTransaction  = "transaction"
+	timelineStatusChange = "status_change"
+)
+
+var timelineTypes = []string{timelineCustomer, timelineMerge, timelineTicket, timelineTransaction, timelineStatusChange}
+
+// StatusChangeResponse is a recorded ticket or transaction event other than
+// its creation; the timeline item's id is the event's. Payload is the event
+// as it was published.
+type StatusChangeResponse struct {
+	Type      string          `json:"type"`
+	SubjectID int64           `json:"subject_id"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// TimelineItem is one entry of a customer's timeline. Exactly one of the
+// detail fields, the one named by Type, is set.
+type TimelineItem struct {
+	Type         string                 `json:"type"`
+	ID           int64                  `json:"id"`
+	OccurredAt   *time.Time             `json:"occurred_at"`
+	Customer     *CustomerResponse      `json:"customer,omitempty"`
+	Merge        *CustomerMergeResponse `json:"merge,omitempty"`
+	Ticket       *TicketResponse        `json:"ticket,omitempty"`
+	Transaction  *TransactionResponse   `json:"transaction,omitempty"`
+	StatusChange *StatusChangeResponse  `json:"status_change,omitempty"`
+}
+
+// GetCustomerTimeline is everything that happened to a customer, newest
+// first: the customer being created, merges into it, its tickets, the
+// transactions those tickets link, and the status changes of both. ?type=
+// keeps only the given item types (comma separated); limit and offset page
+// through the result as on the other lists. Tickets have no comments and
+// SMS are only sent to profiles, not customers, so neither is an item type.
+//
+// The page, its total and the records it refers to are read in one
+// read-only transaction, so they agree even while records are changed or
+// deleted, and the records are loaded with one query per item type.
+func (controller *CustomerController) GetCustomerTimeline(c *gin.Context) {
+	customer, ok := controller.loadCustomer(c)
+	if !ok {
+		return
+	}
+	limit, offset := ticketPage(c)
+
+	var types sql.NullString
+	if v := c.Query("type"); v != "" {
+		var keep []string
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if !validTimelineType(t) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of " + strings.Join(timelineTypes, ", ")})
+				return
+			}
+			keep = append(keep, t)
+		}
+		types = sql.NullString{String: strings.Join(keep, ","), Valid: true}
+	}
+
+	tx, err := controller.DB.BeginTx(c, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	defer tx.Rollback()
+	qtx := controller.Queries.WithTx(tx)
+
+	rows, err := qtx.ListCustomerTimeline(c, db.ListCustomerTimelineParams{
+		CustomerID: customer.ID,
+		ItemTypes:  types,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		slog.Error("Failed to list customer timeline", "id", customer.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	total, err := qtx.CountCustomerTimeline(c, db.CountCustomerTimelineParams{
+		CustomerID: customer.ID,
+		ItemTypes:  types,
+	})
+	if err != nil {
+		slog.Error("Failed to count customer timeline", "id", customer.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	items := make([]TimelineItem, 0, len(rows))
+	for _, row := range rows {
+		item := TimelineItem{Type: row.ItemType, ID: row.ItemID}
+		if row.OccurredAt.Valid {
+			item.OccurredAt = &row.OccurredAt.Time
+		}
+		items = append(items, item)
+	}
+	if err := loadTimelineDetails(c, qtx, customer, items); err != nil {
+		slog.Error("Failed to load timeline items", "id", customer.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// loadTimelineDetails fills in the records the items refer to.
+func loadTimelineDetails(c *gin.Context, q *db.Queries, customer db.Customer, items []TimelineItem) error {
+	ids := map[string][]int64{}
+	for _, item := range items {
+		ids[item.Type] = append(ids[item.Type], item.ID)
+	}
+
+	merges := map[int64]CustomerMergeResponse{}
+	if len(ids[timelineMerge]) > 0 {
+		rows, err := q.ListCustomerMergesByIDs(c, ids[timelineMerge])
+		if err != nil {
+			return err
+		}
+		for _, m := range rows {
+			merges[m.ID] = newCustomerMergeResponse(m)
+		}
+	}
+	tickets := map[int64]TicketResponse{}
+	if len(ids[timelineTicket]) > 0 {
+		rows, err := q.ListTicketsByIDs(c, ids[timelineTicket])
+		if err != nil {
+			return err
+		}
+		for _, t := range rows {
+			tickets[t.ID] = newTicketResponse(t)
+		}
+	}
+	transactions := map[int64]TransactionResponse{}
+	if len(ids[timelineTransaction]) > 0 {
+		txIDs := make([]int32, len(ids[timelineTransaction]))
+		for i, id := range ids[timelineTransaction] {
+			txIDs[i] = int32(id)
+		}
+		rows, err := q.ListTransactionsByIDs(c, txIDs)
+		if err != nil {
+			return err
+		}
+		for _, t := range rows {
+			transactions[int64(t.ID)] = newTransactionResponse(t)
+		}
+	}
+	events := map[int64]StatusChangeResponse{}
+	if len(ids[timelineStatusChange]) > 0 {
+		rows, err := q.ListEventsByIDs(c, ids[timelineStatusChange])
+		if err != nil {
+			return err
+		}
+		for _, ev := range rows {
+			events[ev.ID] = StatusChangeResponse{
+				Type:      ev.Type,
+				SubjectID: ev.SubjectID.Int64,
+				Payload:   json.RawMessage(ev.Payload),
+			}
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		switch item.Type {
+		case timelineCustomer:
+			r := newCustomerResponse(customer)
+			item.Customer = &r
+		case timelineMerge:
+			if r, ok := merges[item.ID]; ok {
+				item.Merge = &r
+			}
+		case timelineTicket:
+			if r, ok := tickets[item.ID]; ok {
+				item.Ticket = &r
+			}
+		case timelineTransaction:
+			if r, ok := transactions[item.ID]; ok {
+				item.Transaction = &r
+			}
+		case timelineStatusChange:
+			if r, ok := events[item.ID]; ok {
+				item.StatusChange = &r
+			}
+		}
+	}
+	return nil
+}
+
+func validTimelineType(t string) bool {
+	for _, v := range timelineTypes {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,18 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	db "tickets/db/sqlc"
+	"tickets/importer"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
 )
 
 type CustomerController struct {
@@ -21,43 +28,98 @@ func NewCustomerController(queries *db.Queries, db *sql.DB) *CustomerController
 	}
 }
 
+// CustomerResponse is the JSON shape of a customer.
+type CustomerResponse struct {
+	ID          int64      `json:"id"`
+	FullName    string     `json:"full_name"`
+	Email       string     `json:"email"`
+	PhoneNumber string     `json:"phone_number"`
+	Segment     string     `json:"segment,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+func newCustomerResponse(cu db.Customer) CustomerResponse {
+	r := CustomerResponse{
+		ID:          cu.ID,
+		FullName:    cu.FullName,
+		Email:       cu.Email,
+		PhoneNumber: cu.PhoneNumber,
+		Segment:     cu.Segment,
+	}
+	if cu.CreatedAt.Valid {
+		r.CreatedAt = &cu.CreatedAt.Time
+	}
+	return r
+}
+
+// CustomerRequest is the body of both create and update; an update replaces
+// every field. Bulk imports validate their rows with the same type.
+type CustomerRequest = importer.CustomerRow
+
 func (controller *CustomerController) CreateCustomer(c *gin.Context) {
-	var req CreateCustomerRequest
+	var req CustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		slog.Error("Invalid request payload", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
+	req.Normalize()
+
+	if msg, err := controller.checkDuplicate(c, 0, req); err != nil {
+		slog.Error("Failed to check existing customer", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	} else if msg != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
 
 	arg := db.CreateCustomerParams{
 		FullName:    req.FullName,
 		Email:       req.Email,
 		PhoneNumber: req.PhoneNumber,
+		Segment:     req.Segment,
 	}
-	customer, err := controller.Queries.CreateCustomer(c, arg)
+	result, err := controller.Queries.CreateCustomer(c, arg)
 	if err != nil {
+		if isDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email or phone number already in use"})
+			return
+		}
 		slog.Error("Failed to create customer", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		slog.Error("Failed to retrieve insert ID", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	customer, err := controller.Queries.GetCustomer(c, id)
+	if err != nil {
+		slog.Error("Failed to load created customer", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
 
-	c.JSON(http.StatusOK, customer)
-}
-
-type CreateCustomerRequest struct {
-	FullName    string `json:"full_name" binding:"required"`
-	Email       string `json:"email" binding:"required,email"`
-	PhoneNumber string `json:"phone_number" binding:"required,min=6"`
+	c.JSON(http.StatusCreated, newCustomerResponse(customer))
+	slog.Info("Customer created", "id", id)
 }
 
+// GetCustomers lists customers newest first. ?q= searches name, email and
+// phone number; limit (at most 100) and offset page through the results.
 func (controller *CustomerController) GetCustomers(c *gin.Context) {
-	// Parse pagination parameters
-	limit := 10
-	offset := 0
+	limit, offset := ticketPage(c)
+	var search sql.NullString
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		search = sql.NullString{String: "%" + escapeLike(q) + "%", Valid: true}
+	}
 
 	params := db.GetCustomersParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+		Search: search,
+		Limit:  limit,
+		Offset: offset,
 	}
 	customers, err := controller.Queries.GetCustomers(c, params)
 	if err != nil {
@@ -65,9 +127,151 @@ func (controller *CustomerController) GetCustomers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	total, err := controller.Queries.CountCustomers(c, db.CountCustomersParams{Search: search})
+	if err != nil {
+		slog.Error("Failed to count customers", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
 
-	c.JSON(http.StatusOK, customers)
+	resp := make([]CustomerResponse, 0, len(customers))
+	for _, cu := range customers {
+		resp = append(resp, newCustomerResponse(cu))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"customers": resp,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
 	slog.Info("Fetched customers successfully", "count", len(customers))
 }
 
-// const {tatus, data, send, open, close } = useWebSocket('ws://websocketurl')
+func (controller *CustomerController) GetCustomer(c *gin.Context) {
+	customer, ok := controller.loadCustomer(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newCustomerResponse(customer))
+}
+
+func (controller *CustomerController) UpdateCustomer(c *gin.Context) {
+	customer, ok := controller.loadCustomer(c)
+	if !ok {
+		return
+	}
+	var req CustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("Invalid request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Normalize()
+
+	if msg, err := controller.checkDuplicate(c, customer.ID, req); err != nil {
+		slog.Error("Failed to check existing customer", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	} else if msg != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
+
+	err := controller.Queries.UpdateCustomer(c, db.UpdateCustomerParams{
+		FullName:    req.FullName,
+		Email:       req.Email,
+		PhoneNumber: req.PhoneNumber,
+		Segment:     req.Segment,
+		ID:          customer.ID,
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email or phone number already in use"})
+			return
+		}
+		slog.Error("Failed to update customer", "id", customer.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	customer.FullName = req.FullName
+	customer.Email = req.Email
+	customer.PhoneNumber = req.PhoneNumber
+	customer.Segment = req.Segment
+	c.JSON(http.StatusOK, newCustomerResponse(customer))
+	slog.Info("Customer updated", "id", customer.ID)
+}
+
+// DeleteCustomer removes a customer. Customers with linked tickets cannot be
+// deleted.
+func (controller *CustomerController) DeleteCustomer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	n, err := controller.Queries.DeleteCustomer(c, id)
+	if err != nil {
+		var me *mysql.MySQLError
+		if errors.As(err, &me) && me.Number == 1451 {
+			c.JSON(http.StatusConflict, gin.H{"error": "customer has linked tickets"})
+			return
+		}
+		slog.Error("Failed to delete customer", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+	slog.Info("Customer deleted", "id", id)
+}
+
+func (controller *CustomerController) loadCustomer(c *gin.Context) (db.Customer, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return db.Customer{}, false
+	}
+	customer, err := controller.Queries.GetCustomer(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			return db.Customer{}, false
+		}
+		slog.Error("Failed to get customer", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return db.Customer{}, false
+	}
+	return customer, true
+}
+
+// checkDuplicate returns a conflict message if another customer than id
+// already has the request's email or phone number. The unique keys still
+// catch a race between this check and the write; see isDuplicateKey.
+func (controller *CustomerController) checkDuplicate(c *gin.Context, id int64, req CustomerRequest) (string, error) {
+	if other, err := controller.Queries.GetCustomerByEmail(c, req.Email); err == nil && other.ID != id {
+		return "email already in use", nil
+	} else if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if other, err := controller.Queries.GetCustomerByPhone(c, req.PhoneNumber); err == nil && other.ID != id {
+		return "phone number already in use", nil
+	} else if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return "", nil
+}
+
+// isDuplicateKey reports whether err is a MySQL unique key violation.
+func isDuplicateKey(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == 1062
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/realtime"
+	"tickets/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sseHeartbeat is how often an idle stream gets a comment line, which
+	// keeps proxies from timing the connection out.
+	sseHeartbeat    = 15 * time.Second
+	sseWriteTimeout = 10 * time.Second
+	// sseSendBuffer is how many live events may queue for a client before it
+	// is disconnected; it catches up from the event log when it reconnects.
+	sseSendBuffer = 256
+	sseReplayPage = 200
+	// sseRetry is the reconnect delay suggested to clients, in milliseconds.
+	sseRetry = 3000
+)
+
+// streamedEvents are the event streams the change feed carries.
+var streamedEvents = map[string]bool{"ticket_events": true, "transaction_events": true}
+
+// StreamEvents is a Server-Sent Events change feed of the ticket and
+// transaction events the caller may see: staff see everything, customers
+// the events about their own tickets and payments. Each event is sent as
+//
+//	id: 1234
+//	data: {"id": 1234, "type": "ticket.created", "payload": {...}}
+//
+// ?type= narrows the feed with the event patterns webhooks use
+// (ticket.created, transaction.*), repeated or comma separated. A client
+// reconnecting with Last-Event-ID (or ?last_event_id= on the first request)
+// is first sent what it missed from the event log, which the worker keeps
+// for EVENT_LOG_RETENTION. An EventSource opened with ?ticket= reconnects on
+// the same URL, as the ticket stays valid for a while after the connection
+// drops (see handlers.CreateStreamTicket).
+func (rc *RealtimeController) StreamEvents(c *gin.Context) {
+	var patterns []string
+	for _, v := range c.QueryArray("type") {
+		patterns = append(patterns, strings.Split(v, ",")...)
+	}
+	if len(patterns) > 0 {
+		var err error
+		if patterns, err = webhook.ParseEvents(patterns); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var after int64
+	resume := lastID != ""
+	if resume {
+		n, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		after = n
+	}
+
+	profileID := middleware.ProfileID(c)
+	role := middleware.Role(c)
+	var owner sql.NullInt64
+	if role != db.ProfilesRoleAdmin && role != db.ProfilesRoleAgent {
+		owner = sql.NullInt64{Int64: profileID, Valid: true}
+	}
+	wanted := func(stream, eventType string) bool {
+		return streamedEvents[stream] && (len(patterns) == 0 || webhook.Matches(patterns, eventType))
+	}
+
+	// subscribe before replaying so nothing published in between is lost
+	sub := rc.Hub.Subscribe(sseSendBuffer, func(ev realtime.Event) bool {
+		if !wanted(ev.Stream, ev.Type) {
+			return false
+		}
+		return !owner.Valid || eventOwner(ev) == owner.Int64
+	})
+	defer rc.Hub.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	out := http.NewResponseController(c.Writer)
+	send := func(chunk string) error {
+		// a client that stopped reading must not hold the handler forever
+		out.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+		if _, err := c.Writer.WriteString(chunk); err != nil {
+			return err
+		}
+		return out.Flush()
+	}
+	if err := send(fmt.Sprintf("retry: %d\n\n", sseRetry)); err != nil {
+		return
+	}
+
+	// live events up to the last replayed id were already sent
+	var replayed int64
+	if resume {
+		for {
+			page, err := rc.Queries.ListEventsAfter(c.Request.Context(), db.ListEventsAfterParams{
+				AfterID: after,
+				OwnerID: owner,
+				Limit:   sseReplayPage,
+			})
+			if err != nil {
+				slog.Error("Failed to replay events", "after", after, "error", err)
+				return
+			}
+			for _, e := range page {
+				if !wanted(e.Stream, e.Type) {
+					continue
+				}
+				ev := realtime.Event{ID: e.ID, Type: e.Type, Payload: json.RawMessage(e.Payload)}
+				if err := send(sseEvent(ev)); err != nil {
+					return
+				}
+			}
+			if len(page) > 0 {
+				after = page[len(page)-1].ID
+				replayed = after
+			}
+			if len(page) < sseReplayPage {
+				break
+			}
+		}
+	}
+
+	slog.Info("Event stream connected", "profile_id", profileID, "resumed_after", lastID, "types", patterns)
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-sub.Lagged():
+			slog.Warn("Dropping slow event stream client", "profile_id", profileID)
+			return
+		case ev := <-sub.Events():
+			if ev.ID != 0 && ev.ID <= replayed {
+				continue
+			}
+			if err := send(sseEvent(ev)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := send(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func sseEvent(ev realtime.Event) string {
+	data, _ := json.Marshal(ev)
+	if ev.ID == 0 {
+		return "data: " + string(data) + "\n\n"
+	}
+	return fmt.Sprintf("id: %d\ndata: %s\n\n", ev.ID, data)
+}
+
+// eventOwner returns the profile a live event is about, as recorded in
+// event_log.owner_id: a ticket's creator or a transaction's user.
+func eventOwner(ev realtime.Event) int64 {
+	var p struct {
+		CreatedBy int64 `json:"created_by"`
+		UserID    int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(ev.Payload, &p); err != nil {
+		return 0
+	}
+	if ev.Stream == "transaction_events" {
+		return p.UserID
+	}
+	return p.CreatedBy
+}
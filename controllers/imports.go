@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/importer"
+	"tickets/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultImportAsyncBytes is the upload size above which an import runs in
+// the background; IMPORT_ASYNC_BYTES overrides it.
+const defaultImportAsyncBytes = 1 << 20
+
+type ImportController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+type ImportJobResponse struct {
+	ID            int64            `json:"id"`
+	Kind          string           `json:"kind"`
+	Status        string           `json:"status"`
+	DryRun        bool             `json:"dry_run"`
+	Filename      string           `json:"filename"`
+	RowsProcessed int32            `json:"rows_processed"`
+	Report        *importer.Report `json:"report,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	CreatedBy     int32            `json:"created_by"`
+	CreatedAt     time.Time        `json:"created_at"`
+	StartedAt     *time.Time       `json:"started_at,omitempty"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty"`
+}
+
+func newImportJobResponse(j db.ImportJob) ImportJobResponse {
+	r := ImportJobResponse{
+		ID:            j.ID,
+		Kind:          string(j.Kind),
+		Status:        string(j.Status),
+		DryRun:        j.DryRun,
+		Filename:      j.Filename,
+		RowsProcessed: j.RowsProcessed,
+		Error:         j.ErrorMessage,
+		CreatedBy:     j.CreatedBy,
+		CreatedAt:     j.CreatedAt,
+	}
+	if j.Report.Valid {
+		var report importer.Report
+		if err := json.Unmarshal([]byte(j.Report.String), &report); err == nil {
+			r.Report = &report
+		}
+	}
+	if j.StartedAt.Valid {
+		r.StartedAt = &j.StartedAt.Time
+	}
+	if j.FinishedAt.Valid {
+		r.FinishedAt = &j.FinishedAt.Time
+	}
+	return r
+}
+
+// ImportCustomers bulk-loads customers from a CSV uploaded as the "file"
+// form field; see importCSV.
+func (ic *ImportController) ImportCustomers(c *gin.Context) {
+	ic.importCSV(c, importer.KindCustomers)
+}
+
+// ImportUsers bulk-loads users from a CSV uploaded as the "file" form field;
+// see importCSV.
+func (ic *ImportController) ImportUsers(c *gin.Context) {
+	ic.importCSV(c, importer.KindUsers)
+}
+
+// importCSV runs an import. ?dry_run=true validates and writes every row
+// but rolls the writes back. Files larger than IMPORT_ASYNC_BYTES (default
+// 1 MiB), or any file with ?async=true, are imported in the background: the
+// response is 202 with a job whose progress and final report are at
+// GET /imports/:id. Smaller files are imported while the request waits and
+// the report is returned directly.
+func (ic *ImportController) importCSV(c *gin.Context, kind importer.Kind) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	async, _ := strconv.ParseBool(c.Query("async"))
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
+		return
+	}
+	if fh.Size > importAsyncBytes() {
+		async = true
+	}
+	opts := importer.Options{Kind: kind, DryRun: dryRun}
+
+	if async {
+		ic.startImportJob(c, fh, opts)
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer f.Close()
+	report, err := importer.Run(c, ic.DB, ic.Queries, f, opts)
+	if err != nil {
+		if errors.Is(err, importer.ErrBadFile) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("Import failed", "kind", kind, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed", "report": report})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+	slog.Info("Import finished", "kind", kind, "dry_run", dryRun, "rows", report.Rows,
+		"created", report.Created, "updated", report.Updated, "failed", report.Failed,
+		"created_by", middleware.ProfileID(c))
+}
+
+// startImportJob copies the upload to a temporary file, which outlives the
+// request, records a job and runs the import in the background.
+func (ic *ImportController) startImportJob(c *gin.Context, fh *multipart.FileHeader, opts importer.Options) {
+	src, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp("", "import-*.csv")
+	if err != nil {
+		slog.Error("Failed to create import file", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+		return
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		slog.Error("Failed to store import file", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+		return
+	}
+	tmp.Close()
+
+	result, err := ic.Queries.CreateImportJob(c, db.CreateImportJobParams{
+		Kind:      db.ImportJobsKind(opts.Kind),
+		DryRun:    opts.DryRun,
+		Filename:  truncateString(fh.Filename, 255),
+		CreatedBy: int32(middleware.ProfileID(c)),
+	})
+	var id int64
+	if err == nil {
+		id, err = result.LastInsertId()
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		slog.Error("Failed to create import job", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+		return
+	}
+
+	go ic.runImportJob(id, tmp.Name(), opts)
+
+	job, err := ic.Queries.GetImportJob(c, id)
+	if err != nil {
+		slog.Error("Failed to load import job", "id", id, "error", err)
+		c.JSON(http.StatusAccepted, gin.H{"id": id})
+		return
+	}
+	c.Header("Location", "/imports/"+strconv.FormatInt(id, 10))
+	c.JSON(http.StatusAccepted, newImportJobResponse(job))
+	slog.Info("Import job queued", "id", id, "kind", opts.Kind, "dry_run", opts.DryRun, "bytes", fh.Size)
+}
+
+func (ic *ImportController) runImportJob(id int64, path string, opts importer.Options) {
+	defer os.Remove(path)
+	ctx := context.Background()
+
+	if err := ic.Queries.StartImportJob(ctx, db.StartImportJobParams{
+		StartedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:        id,
+	}); err != nil {
+		slog.Error("Failed to start import job", "id", id, "error", err)
+	}
+	opts.Progress = func(rows int) {
+		if err := ic.Queries.UpdateImportJobProgress(ctx, db.UpdateImportJobProgressParams{
+			RowsProcessed: int32(rows),
+			ID:            id,
+		}); err != nil {
+			slog.Error("Failed to record import progress", "id", id, "error", err)
+		}
+	}
+
+	var report *importer.Report
+	f, err := os.Open(path)
+	if err == nil {
+		report, err = importer.Run(ctx, ic.DB, ic.Queries, f, opts)
+		f.Close()
+	}
+
+	finish := db.FinishImportJobParams{
+		Status:     db.ImportJobsStatusSucceeded,
+		FinishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:         id,
+	}
+	if report != nil {
+		finish.RowsProcessed = int32(report.Rows)
+		if b, merr := json.Marshal(report); merr == nil {
+			finish.Report = sql.NullString{String: string(b), Valid: true}
+		}
+	}
+	if err != nil {
+		finish.Status = db.ImportJobsStatusFailed
+		finish.ErrorMessage = truncateString(err.Error(), 255)
+		slog.Error("Import job failed", "id", id, "error", err)
+	} else {
+		slog.Info("Import job finished", "id", id, "kind", opts.Kind, "rows", report.Rows,
+			"created", report.Created, "updated", report.Updated, "failed", report.Failed)
+	}
+	if err := ic.Queries.FinishImportJob(ctx, finish); err != nil {
+		slog.Error("Failed to record import job result", "id", id, "error", err)
+	}
+}
+
+// FailStaleImportJobs runs every interval until the process exits and marks
+// as failed the jobs left queued or running by an API process that stopped,
+// for instance in a restart: their upload was a temporary file of that
+// process, so they cannot be resumed and have to be uploaded again.
+func (ic *ImportController) FailStaleImportJobs(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := ic.Queries.FailStaleImportJobs(context.Background(), db.FailStaleImportJobsParams{
+			ErrorMessage: "interrupted, upload the file again",
+			FinishedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			slog.Error("Failed to fail stale import jobs", "error", err)
+		} else if n > 0 {
+			slog.Warn("Marked interrupted import jobs failed", "jobs", n)
+		}
+		<-ticker.C
+	}
+}
+
+// GetImportJob returns an import job and, once it has finished, its report.
+// Staff other than admins only see their own jobs.
+func (ic *ImportController) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	job, err := ic.Queries.GetImportJob(c, id)
+	if err == nil && middleware.Role(c) != db.ProfilesRoleAdmin && int64(job.CreatedBy) != middleware.ProfileID(c) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "import job not found"})
+			return
+		}
+		slog.Error("Failed to get import job", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, newImportJobResponse(job))
+}
+
+func importAsyncBytes() int64 {
+	if v := os.Getenv("IMPORT_ASYNC_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		slog.Error("invalid IMPORT_ASYNC_BYTES, using default", "value", v)
+	}
+	return defaultImportAsyncBytes
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/money"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+type LedgerAccountResponse struct {
+	ID        int64  `json:"id"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Currency  string `json:"currency"`
+	ProfileID *int32 `json:"profile_id,omitempty"`
+}
+
+func newLedgerAccountResponse(a db.LedgerAccount) LedgerAccountResponse {
+	r := LedgerAccountResponse{
+		ID:       a.ID,
+		Code:     a.Code,
+		Name:     a.Name,
+		Type:     string(a.Type),
+		Currency: a.Currency,
+	}
+	if a.ProfileID.Valid {
+		r.ProfileID = &a.ProfileID.Int32
+	}
+	return r
+}
+
+// ListAccounts lists ledger accounts, optionally only those of ?profile_id=.
+func (lc *LedgerController) ListAccounts(c *gin.Context) {
+	var profileID sql.NullInt32
+	if v := c.Query("profile_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid profile_id"})
+			return
+		}
+		profileID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	accounts, err := lc.Queries.ListLedgerAccounts(c, db.ListLedgerAccountsParams{ProfileID: profileID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list accounts"})
+		slog.Error("Failed to list ledger accounts", "error", err)
+		return
+	}
+
+	resp := make([]LedgerAccountResponse, 0, len(accounts))
+	for _, a := range accounts {
+		resp = append(resp, newLedgerAccountResponse(a))
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": resp})
+}
+
+// GetBalance returns an account balance, as of ?as_of= if given. as_of is
+// RFC 3339 or a plain date, which means the end of that day. Balances are
+// debit-positive, so a customer (liability) account holding money for the
+// customer has a negative balance.
+func (lc *LedgerController) GetBalance(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	asOf := time.Now()
+	if v := c.Query("as_of"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			asOf = t
+		} else if d, err := time.ParseInLocation(time.DateOnly, v, time.Local); err == nil {
+			asOf = d.Add(24*time.Hour - time.Second)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be RFC 3339 or YYYY-MM-DD"})
+			return
+		}
+	}
+
+	account, err := lc.Queries.GetLedgerAccount(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get account"})
+		slog.Error("Failed to get ledger account", "id", id, "error", err)
+		return
+	}
+
+	balance, err := lc.Queries.GetAccountBalance(c, db.GetAccountBalanceParams{
+		AccountID:  id,
+		OccurredAt: asOf,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get balance"})
+		slog.Error("Failed to get account balance", "id", id, "error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account": newLedgerAccountResponse(account),
+		"balance": money.Money{Amount: balance, Currency: account.Currency},
+		"as_of":   asOf,
+	})
+}
@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/ledger"
+	"tickets/middleware"
+	"tickets/money"
+	"tickets/payments"
+	"tickets/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentsController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+	Mpesa   *payments.MpesaClient
+}
+
+// STKPushRequest charges the caller. Staff and API keys may set UserID to
+// charge another user.
+type STKPushRequest struct {
+	Phone            string `json:"phone" binding:"required"`
+	Amount           int64  `json:"amount" binding:"required,min=1,max=250000"`
+	UserID           int64  `json:"user_id"`
+	AccountReference string `json:"account_reference" binding:"omitempty,max=12"`
+	Description      string `json:"description" binding:"omitempty,max=13"`
+}
+
+// InitiateSTKPush records a pending KES transaction and asks Daraja to prompt
+// the customer's phone. The outcome arrives on MpesaCallback.
+func (pc *PaymentsController) InitiateSTKPush(c *gin.Context) {
+	var req STKPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	phone, err := payments.MSISDN(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := middleware.ProfileID(c)
+	if req.UserID != 0 && req.UserID != userID {
+		if middleware.Role(c) == db.ProfilesRoleCustomer {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only staff can charge another user"})
+			return
+		}
+		userID = req.UserID
+	}
+
+	amount, err := money.Parse(strconv.FormatInt(req.Amount, 10), "KES")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ref, err := transactionRef("MP")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to generate transaction reference", "error", err)
+		return
+	}
+	result, err := pc.Queries.CreateTransaction(c, db.CreateTransactionParams{
+		TransactionID: ref,
+		UserID:        int32(userID),
+		AmountMinor:   amount.Amount,
+		Currency:      amount.Currency,
+		Status:        payments.StatusPending,
+		PaymentMethod: sql.NullString{String: "mpesa", Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to create transaction"})
+		slog.Error("Failed to create transaction", "error", err)
+		return
+	}
+	id, _ := result.LastInsertId()
+	resp := TransactionResponse{
+		ID:            int32(id),
+		TransactionID: ref,
+		UserID:        int32(userID),
+		Amount:        amount,
+		Status:        payments.StatusName(payments.StatusPending),
+		PaymentMethod: "mpesa",
+		Kind:          string(db.TransactionsKindPayment),
+	}
+	publishTransactionEvent(c, pc.Queries, "transaction.created", resp, nil)
+
+	accountRef := req.AccountReference
+	if accountRef == "" {
+		accountRef = fmt.Sprintf("TX%d", id)
+	}
+	desc := req.Description
+	if desc == "" {
+		desc = "Payment"
+	}
+
+	push, err := pc.Mpesa.STKPush(c.Request.Context(), payments.STKPushRequest{
+		Phone:            phone,
+		Amount:           req.Amount,
+		AccountReference: accountRef,
+		Description:      desc,
+	})
+	if err != nil {
+		slog.Error("STK push failed", "transaction_id", ref, "error", err)
+		if err := transitionTransaction(c, pc.Queries, int32(id), payments.StatusPending, payments.StatusFailed); err != nil {
+			slog.Error("Failed to mark transaction failed", "transaction_id", ref, "error", err)
+		} else {
+			resp.Status = payments.StatusName(payments.StatusFailed)
+			publishTransactionEvent(c, pc.Queries, "transaction.failed", resp, gin.H{"previous_status": payments.StatusName(payments.StatusPending)})
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to initiate M-Pesa payment"})
+		return
+	}
+
+	if err := pc.Queries.CreateMpesaStkRequest(c, db.CreateMpesaStkRequestParams{
+		TransactionID:     int32(id),
+		MerchantRequestID: push.MerchantRequestID,
+		CheckoutRequestID: push.CheckoutRequestID,
+		Phone:             phone,
+	}); err != nil {
+		// the customer has already been prompted, so make sure this can be matched by hand
+		slog.Error("Failed to record STK push", "transaction_id", ref,
+			"checkout_request_id", push.CheckoutRequestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record M-Pesa payment"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":                  id,
+		"transaction_id":      ref,
+		"status":              "pending",
+		"checkout_request_id": push.CheckoutRequestID,
+		"customer_message":    push.CustomerMessage,
+	})
+	slog.Info("STK push initiated", "id", id, "transaction_id", ref, "checkout_request_id", push.CheckoutRequestID)
+}
+
+// MpesaCallback receives Daraja's STK Push result. It always acknowledges
+// with ResultCode 0 once the body is understood, so Daraja stops retrying.
+// The callback URL must carry ?token=<MPESA_CALLBACK_TOKEN>; while the token
+// is not configured every callback is refused. A successful result only
+// settles the transaction if its amount and phone match the STK request;
+// otherwise the result is kept on the STK request with the mismatch.
+func (pc *PaymentsController) MpesaCallback(c *gin.Context) {
+	token := os.Getenv("MPESA_CALLBACK_TOKEN")
+	if token == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "callbacks are not configured"})
+		slog.Error("M-Pesa callback refused: MPESA_CALLBACK_TOKEN is not set", "ip", c.ClientIP())
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid callback token"})
+		slog.Warn("M-Pesa callback with bad token", "ip", c.ClientIP())
+		return
+	}
+
+	var cb payments.STKCallback
+	if err := c.ShouldBindJSON(&cb); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ResultCode": 1, "ResultDesc": "invalid payload"})
+		return
+	}
+	res := cb.Body.StkCallback
+	ack := gin.H{"ResultCode": 0, "ResultDesc": "Accepted"}
+
+	stk, err := pc.Queries.GetMpesaStkRequestByCheckoutID(c, res.CheckoutRequestID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			slog.Warn("M-Pesa callback for unknown checkout request", "checkout_request_id", res.CheckoutRequestID)
+			c.JSON(http.StatusOK, ack)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+		slog.Error("Failed to get STK request", "checkout_request_id", res.CheckoutRequestID, "error", err)
+		return
+	}
+	if stk.CompletedAt.Valid {
+		c.JSON(http.StatusOK, ack)
+		return
+	}
+
+	receipt := cb.Metadata("MpesaReceiptNumber")
+	status := payments.StatusFailed
+	if res.ResultCode == 0 {
+		status = payments.StatusCompleted
+	}
+
+	tx, err := pc.DB.BeginTx(c, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+		slog.Error("Failed to begin tx", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := pc.Queries.WithTx(tx)
+
+	t, err := qtx.GetTransactionForUpdate(c, stk.TransactionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+		slog.Error("Failed to get transaction", "id", stk.TransactionID, "error", err)
+		return
+	}
+	var mismatch error
+	if res.ResultCode == 0 {
+		mismatch = matchSTKCallback(cb, stk, t)
+	}
+	var mismatchDesc sql.NullString
+	if mismatch != nil {
+		mismatchDesc = sql.NullString{String: truncateString(mismatch.Error(), 255), Valid: true}
+	}
+	n, err := qtx.CompleteMpesaStkRequest(c, db.CompleteMpesaStkRequestParams{
+		ResultCode:   sql.NullInt32{Int32: int32(res.ResultCode), Valid: true},
+		ResultDesc:   sql.NullString{String: truncateString(res.ResultDesc, 255), Valid: true},
+		MpesaReceipt: utils.NullString(receipt),
+		Mismatch:     mismatchDesc,
+		CompletedAt:  sql.NullTime{Time: time.Now(), Valid: true},
+		ID:           stk.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+		slog.Error("Failed to complete STK request", "checkout_request_id", res.CheckoutRequestID, "error", err)
+		return
+	}
+	if n == 0 {
+		// a concurrent delivery of the same callback got there first
+		c.JSON(http.StatusOK, ack)
+		return
+	}
+	if mismatch != nil {
+		// keep the result and receipt on the STK row, and leave the
+		// transaction pending for reconciliation
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+			slog.Error("Failed to commit M-Pesa callback", "checkout_request_id", res.CheckoutRequestID, "error", err)
+			return
+		}
+		c.JSON(http.StatusOK, ack)
+		slog.Error("M-Pesa callback does not match the payment request", "id", t.ID,
+			"checkout_request_id", res.CheckoutRequestID, "receipt", receipt, "error", mismatch)
+		return
+	}
+	from := t.Status
+	changed := true
+	if err := transitionTransaction(c, qtx, t.ID, from, status); err != nil {
+		if !errors.Is(err, payments.ErrInvalidTransition) {
+			c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+			slog.Error("Failed to update transaction status", "id", stk.TransactionID, "error", err)
+			return
+		}
+		// e.g. staff already failed it by hand; keep the callback result on the STK row
+		slog.Warn("M-Pesa callback does not apply to transaction", "id", t.ID, "error", err)
+		changed = false
+	}
+	if changed {
+		if err := ledger.RecordStatusChange(c, qtx, t, status, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+			slog.Error("Failed to post transaction to ledger", "id", t.ID, "error", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "server error"})
+		slog.Error("Failed to commit M-Pesa callback", "checkout_request_id", res.CheckoutRequestID, "error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ack)
+	slog.Info("M-Pesa callback processed", "id", stk.TransactionID, "checkout_request_id", res.CheckoutRequestID,
+		"result_code", res.ResultCode, "receipt", receipt)
+	if changed {
+		t.Status = status
+		publishTransactionEvent(c, pc.Queries, "transaction."+payments.StatusName(status), newTransactionResponse(t), gin.H{
+			"previous_status": payments.StatusName(from),
+			"mpesa_receipt":   receipt,
+		})
+	}
+}
+
+// matchSTKCallback checks that a successful callback paid what was asked,
+// from the phone that was prompted.
+func matchSTKCallback(cb payments.STKCallback, stk db.MpesaStkRequest, t db.Transaction) error {
+	paid, err := money.Parse(cb.Metadata("Amount"), t.Currency)
+	if err != nil {
+		return fmt.Errorf("amount: %w", err)
+	}
+	if expected := (money.Money{Amount: t.AmountMinor, Currency: t.Currency}); paid != expected {
+		return fmt.Errorf("paid %s, expected %s", paid, expected)
+	}
+	// Daraja may leave the phone out or mask some of its digits
+	if p := cb.Metadata("PhoneNumber"); p != "" && !maskedEqual(p, stk.Phone) {
+		return errors.New("paid from another phone")
+	}
+	return nil
+}
+
+// maskedEqual compares two MSISDNs where masked may hide digits with '*'.
+func maskedEqual(masked, msisdn string) bool {
+	if len(masked) != len(msisdn) {
+		return false
+	}
+	for i := 0; i < len(masked); i++ {
+		if masked[i] != '*' && masked[i] != msisdn[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionRef returns an opaque reference for transactions.transaction_id.
+func transactionRef(prefix string) (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return prefix + strings.ToUpper(hex.EncodeToString(b[:])), nil
+}
+
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
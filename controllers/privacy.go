@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/privacy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyController serves data subject requests: export and erasure of
+// everything held about a person. Both are admin only and audited.
+type PrivacyController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+// DataSubjectRequest names the person by email, phone or both.
+type DataSubjectRequest struct {
+	Email  string `json:"email" binding:"omitempty,email,max=255"`
+	Phone  string `json:"phone" binding:"max=40"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type DataSubjectRequestResponse struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	SubjectHash string          `json:"subject_hash"`
+	ProfileIDs  []int64         `json:"profile_ids"`
+	CustomerIDs []int64         `json:"customer_ids"`
+	Summary     json.RawMessage `json:"summary"`
+	Reason      string          `json:"reason,omitempty"`
+	RequestedBy int32           `json:"requested_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func newDataSubjectRequestResponse(r db.DataSubjectRequest) DataSubjectRequestResponse {
+	return DataSubjectRequestResponse{
+		ID:          r.ID,
+		Kind:        string(r.Kind),
+		SubjectHash: r.SubjectHash,
+		ProfileIDs:  splitIDs(r.ProfileIds),
+		CustomerIDs: splitIDs(r.CustomerIds),
+		Summary:     json.RawMessage(r.Summary),
+		Reason:      r.Reason,
+		RequestedBy: r.RequestedBy,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// ExportSubject returns everything held about a person as a ZIP of JSON
+// files, one per kind of record, or with ?format=json as one JSON document.
+func (pc *PrivacyController) ExportSubject(c *gin.Context) {
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be zip or json"})
+		return
+	}
+	subject, opts, ok := pc.findSubject(c)
+	if !ok {
+		return
+	}
+
+	archive, err := privacy.Export(c, pc.Queries, subject)
+	if err != nil {
+		slog.Error("Failed to collect data subject export", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+	var body bytes.Buffer
+	if format == "zip" {
+		err = privacy.WriteZip(&body, archive, map[string]any{
+			"profile_ids":  splitIDs(subject.ProfileIDs()),
+			"customer_ids": splitIDs(subject.CustomerIDs()),
+		})
+	} else {
+		err = json.NewEncoder(&body).Encode(archive)
+	}
+	if err != nil {
+		slog.Error("Failed to write data subject export", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+
+	// audited before anything is sent, so no export goes unrecorded
+	id, err := privacy.RecordExport(c, pc.Queries, subject, archive, opts)
+	if err != nil {
+		slog.Error("Failed to record data subject export", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+
+	contentType := "application/json"
+	if format == "zip" {
+		contentType = "application/zip"
+	}
+	filename := "data-export-" + strconv.FormatInt(id, 10) + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, contentType, body.Bytes())
+	slog.Info("Data subject export", "request_id", id, "profiles", subject.ProfileIDs(),
+		"customers", subject.CustomerIDs(), "requested_by", middleware.ProfileID(c))
+}
+
+// EraseSubject anonymizes a person's personal data and keeps the financial
+// records the law requires; see privacy.Erase. ?dry_run=true reports what
+// would change without changing or recording anything.
+func (pc *PrivacyController) EraseSubject(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	subject, opts, ok := pc.findSubject(c)
+	if !ok {
+		return
+	}
+	opts.DryRun = dryRun
+	summary, id, err := privacy.Erase(c, pc.DB, pc.Queries, subject, opts)
+	if err != nil {
+		if errors.Is(err, privacy.ErrProtected) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("Failed to erase data subject", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to erase data"})
+		return
+	}
+
+	resp := gin.H{
+		"dry_run":      dryRun,
+		"profile_ids":  splitIDs(subject.ProfileIDs()),
+		"customer_ids": splitIDs(subject.CustomerIDs()),
+		"summary":      summary,
+	}
+	if !dryRun {
+		resp["request_id"] = id
+		slog.Info("Data subject erased", "request_id", id, "profiles", subject.ProfileIDs(),
+			"customers", subject.CustomerIDs(), "requested_by", middleware.ProfileID(c))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListDataSubjectRequests is the audit trail of exports and erasures,
+// newest first. ?email= and ?phone= narrow it to the requests made with
+// exactly those identifiers.
+func (pc *PrivacyController) ListDataSubjectRequests(c *gin.Context) {
+	limit, offset := ticketPage(c)
+	var hash sql.NullString
+	if email, phone := c.Query("email"), c.Query("phone"); email != "" || phone != "" {
+		h, err := privacy.HashIdentifiers(email, phone)
+		if err != nil {
+			slog.Error("Failed to hash data subject identifiers", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		hash = sql.NullString{String: h, Valid: true}
+	}
+
+	rows, err := pc.Queries.ListDataSubjectRequests(c, db.ListDataSubjectRequestsParams{
+		SubjectHash: hash,
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		slog.Error("Failed to list data subject requests", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	resp := make([]DataSubjectRequestResponse, 0, len(rows))
+	for _, r := range rows {
+		resp = append(resp, newDataSubjectRequestResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": resp, "limit": limit, "offset": offset})
+}
+
+// findSubject binds the request and looks the person up, answering 404 when
+// nothing is held about them. The options carry the request's audit details.
+func (pc *PrivacyController) findSubject(c *gin.Context) (*privacy.Subject, privacy.Options, bool) {
+	var req DataSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, privacy.Options{}, false
+	}
+	if strings.TrimSpace(req.Email) == "" && strings.TrimSpace(req.Phone) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email or phone is required"})
+		return nil, privacy.Options{}, false
+	}
+	opts := privacy.Options{
+		RequestedBy: int32(middleware.ProfileID(c)),
+		Reason:      strings.TrimSpace(req.Reason),
+	}
+
+	subject, err := privacy.Find(c, pc.Queries, req.Email, req.Phone)
+	if err != nil {
+		slog.Error("Failed to find data subject", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, opts, false
+	}
+	if subject.Empty() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no data held for this email or phone"})
+		return nil, opts, false
+	}
+	return subject, opts, true
+}
@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsPingInterval is how often the server pings; a client that has sent
+	// nothing, not even a pong, for wsReadTimeout is disconnected.
+	wsPingInterval = 30 * time.Second
+	wsReadTimeout  = 75 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	// wsMaxMessage caps the size of a client message.
+	wsMaxMessage = 64 << 10
+	// wsSendBuffer is how many events may queue for a client before it is
+	// considered too slow and disconnected.
+	wsSendBuffer = 64
+	// wsMaxTickets caps the ticket subscriptions of one connection.
+	wsMaxTickets = 100
+)
+
+// wsUpgrader accepts any origin: connections are authenticated by a bearer
+// token or a stream ticket, never by cookies, so another site
+// cannot open one on a user's behalf.
+var wsUpgrader = websocket.Upgrader{
+	HandshakeTimeout: 10 * time.Second,
+	CheckOrigin:      func(*http.Request) bool { return true },
+}
+
+// wsConn serialises writes: a websocket.Conn allows one writer at a time and
+// both the event loop and readLoop reply to the client.
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (conn *wsConn) writeJSON(v any) error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+type RealtimeController struct {
+	Queries *db.Queries
+	Hub     *realtime.Hub
+}
+
+// wsRequest is a message from the client:
+//
+//	{"action": "subscribe", "topic": "ticket", "ticket_id": 42}
+//	{"action": "subscribe", "topic": "queue"}
+//	{"action": "unsubscribe", "topic": "all"}
+//
+// "ticket" follows one ticket, "queue" the tickets assigned to or raised by
+// the caller, and "all" every ticket (staff only).
+type wsRequest struct {
+	Action   string `json:"action"`
+	Topic    string `json:"topic"`
+	TicketID int64  `json:"ticket_id,omitempty"`
+}
+
+// wsSession is what one connection is subscribed to.
+type wsSession struct {
+	profileID int64
+	role      db.ProfilesRole
+
+	mu      sync.Mutex
+	all     bool
+	queue   bool
+	tickets map[int64]bool
+}
+
+// wants is the hub filter for the session.
+func (s *wsSession) wants(ev realtime.Event) bool {
+	if !strings.HasPrefix(ev.Type, "ticket.") {
+		return false
+	}
+	var p struct {
+		ID         int64  `json:"id"`
+		CreatedBy  int64  `json:"created_by"`
+		AssignedTo *int64 `json:"assigned_to"`
+	}
+	if err := json.Unmarshal(ev.Payload, &p); err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.all:
+		return true
+	case s.queue && (p.CreatedBy == s.profileID || p.AssignedTo != nil && *p.AssignedTo == s.profileID):
+		return true
+	}
+	return s.tickets[p.ID]
+}
+
+// Tickets upgrades to a WebSocket that pushes ticket events as
+//
+//	{"type": "event", "event": {"type": "ticket.created", "payload": {...}}}
+//
+// for whatever the client subscribed to; see wsRequest. Replies to client
+// messages are {"type": "subscribed" | "unsubscribed", ...} or
+// {"type": "error", "error": "..."}.
+func (rc *RealtimeController) Tickets(c *gin.Context) {
+	sess := &wsSession{
+		profileID: middleware.ProfileID(c),
+		role:      middleware.Role(c),
+		tickets:   map[int64]bool{},
+	}
+
+	ws, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	conn := &wsConn{Conn: ws}
+	defer conn.Close()
+
+	sub := rc.Hub.Subscribe(wsSendBuffer, sess.wants)
+	defer rc.Hub.Unsubscribe(sub)
+
+	conn.SetReadLimit(wsMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPongHandler(func(string) error { return conn.SetReadDeadline(time.Now().Add(wsReadTimeout)) })
+
+	slog.Info("WebSocket connected", "profile_id", sess.profileID, "remote", conn.RemoteAddr().String())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rc.readLoop(c, conn, sess)
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-done:
+			slog.Info("WebSocket disconnected", "profile_id", sess.profileID)
+			return
+		case <-sub.Lagged():
+			slog.Warn("Dropping slow WebSocket client", "profile_id", sess.profileID)
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "client too slow"),
+				time.Now().Add(wsWriteTimeout))
+			return
+		case ev := <-sub.Events():
+			if err := conn.writeJSON(gin.H{"type": "event", "event": ev}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop handles client messages until the connection fails or closes.
+func (rc *RealtimeController) readLoop(c *gin.Context, conn *wsConn, sess *wsSession) {
+	for {
+		op, data, err := conn.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if !errors.As(err, &ce) {
+				slog.Debug("WebSocket read failed", "profile_id", sess.profileID, "error", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+
+		var req wsRequest
+		if op != websocket.TextMessage || json.Unmarshal(data, &req) != nil {
+			if err := conn.writeJSON(gin.H{"type": "error", "error": "messages must be JSON text"}); err != nil {
+				return
+			}
+			continue
+		}
+		reply := rc.handle(c, sess, req)
+		if err := conn.writeJSON(reply); err != nil {
+			return
+		}
+	}
+}
+
+func (rc *RealtimeController) handle(c *gin.Context, sess *wsSession, req wsRequest) gin.H {
+	var on bool
+	switch req.Action {
+	case "subscribe":
+		on = true
+	case "unsubscribe":
+	default:
+		return gin.H{"type": "error", "error": "action must be subscribe or unsubscribe"}
+	}
+
+	switch req.Topic {
+	case "all":
+		if on && sess.role != db.ProfilesRoleAdmin && sess.role != db.ProfilesRoleAgent {
+			return gin.H{"type": "error", "error": "only staff may subscribe to all tickets"}
+		}
+		sess.mu.Lock()
+		sess.all = on
+		sess.mu.Unlock()
+	case "queue":
+		sess.mu.Lock()
+		sess.queue = on
+		sess.mu.Unlock()
+	case "ticket":
+		if req.TicketID <= 0 {
+			return gin.H{"type": "error", "error": "ticket_id is required"}
+		}
+		if on {
+			if msg := rc.checkTicket(c, sess, req.TicketID); msg != "" {
+				return gin.H{"type": "error", "error": msg, "ticket_id": req.TicketID}
+			}
+		}
+		sess.mu.Lock()
+		if on && !sess.tickets[req.TicketID] && len(sess.tickets) >= wsMaxTickets {
+			sess.mu.Unlock()
+			return gin.H{"type": "error", "error": "too many ticket subscriptions"}
+		}
+		if on {
+			sess.tickets[req.TicketID] = true
+		} else {
+			delete(sess.tickets, req.TicketID)
+		}
+		sess.mu.Unlock()
+	default:
+		return gin.H{"type": "error", "error": "topic must be ticket, queue or all"}
+	}
+
+	reply := gin.H{"type": req.Action + "d", "topic": req.Topic}
+	if req.Topic == "ticket" {
+		reply["ticket_id"] = req.TicketID
+	}
+	return reply
+}
+
+// checkTicket returns why the session may not follow a ticket, or "".
+// Customers may only follow their own tickets.
+func (rc *RealtimeController) checkTicket(c *gin.Context, sess *wsSession, id int64) string {
+	ticket, err := rc.Queries.GetTicket(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "ticket not found"
+		}
+		slog.Error("Failed to get ticket", "id", id, "error", err)
+		return "failed to subscribe"
+	}
+	if sess.role == db.ProfilesRoleCustomer && ticket.CreatedBy != sess.profileID {
+		return "ticket not found"
+	}
+	return ""
+}
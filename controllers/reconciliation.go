@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/money"
+	"tickets/reconcile"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReconciliationController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+type ReconciliationRunResponse struct {
+	ID          int64          `json:"id"`
+	Format      string         `json:"format"`
+	Filename    string         `json:"filename"`
+	LineCount   int32          `json:"line_count"`
+	PeriodStart *time.Time     `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time     `json:"period_end,omitempty"`
+	Window      string         `json:"window"`
+	Counts      map[string]int `json:"counts,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type ReconciliationItemResponse struct {
+	Status        string       `json:"status"`
+	LineNumber    *int32       `json:"line_number,omitempty"`
+	Reference     string       `json:"reference,omitempty"`
+	Amount        *money.Money `json:"amount,omitempty"`
+	OccurredAt    *time.Time   `json:"occurred_at,omitempty"`
+	TransactionID *int32       `json:"transaction_id,omitempty"`
+	Note          string       `json:"note,omitempty"`
+}
+
+func newReconciliationRunResponse(r db.ReconciliationRun) ReconciliationRunResponse {
+	resp := ReconciliationRunResponse{
+		ID:        r.ID,
+		Format:    r.Format,
+		Filename:  r.Filename,
+		LineCount: r.LineCount,
+		Window:    (time.Duration(r.WindowSeconds) * time.Second).String(),
+		CreatedAt: r.CreatedAt,
+	}
+	if r.PeriodStart.Valid {
+		resp.PeriodStart = &r.PeriodStart.Time
+	}
+	if r.PeriodEnd.Valid {
+		resp.PeriodEnd = &r.PeriodEnd.Time
+	}
+	return resp
+}
+
+func newReconciliationItemResponse(it db.ReconciliationItem) ReconciliationItemResponse {
+	resp := ReconciliationItemResponse{
+		Status:    string(it.Status),
+		Reference: it.Reference.String,
+		Note:      it.Note,
+	}
+	if it.LineNumber.Valid {
+		resp.LineNumber = &it.LineNumber.Int32
+	}
+	if it.AmountMinor.Valid {
+		resp.Amount = &money.Money{Amount: it.AmountMinor.Int64, Currency: it.Currency.String}
+	}
+	if it.OccurredAt.Valid {
+		resp.OccurredAt = &it.OccurredAt.Time
+	}
+	if it.TransactionID.Valid {
+		resp.TransactionID = &it.TransactionID.Int32
+	}
+	return resp
+}
+
+// CreateReconciliation imports a provider statement uploaded as the "file"
+// form field and reconciles it against our transactions. Form fields:
+// format (csv or mpesa, default csv), window (a duration such as 15m,
+// default 10m) and currency (for CSV files without a currency column,
+// default KES). The run and its findings are saved.
+func (rc *ReconciliationController) CreateReconciliation(c *gin.Context) {
+	format := c.DefaultPostForm("format", reconcile.FormatCSV)
+	window := reconcile.DefaultWindow
+	if v := c.PostForm("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration such as 10m"})
+			return
+		}
+		window = d
+	}
+	currency := c.DefaultPostForm("currency", "KES")
+	if !money.Valid(currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown currency"})
+		return
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statement file is required"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read statement"})
+		return
+	}
+	defer f.Close()
+
+	lines, err := reconcile.Parse(format, f, currency)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if len(lines) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "statement has no lines"})
+		return
+	}
+
+	start, end := reconcile.Period(lines)
+	txns, err := reconcile.Load(c, rc.Queries, start, end, window)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reconcile statement"})
+		slog.Error("Failed to load transactions for reconciliation", "error", err)
+		return
+	}
+	report := reconcile.Match(lines, txns, window)
+
+	createdBy := sql.NullInt32{Int32: int32(middleware.ProfileID(c)), Valid: middleware.ProfileID(c) != 0}
+	runID, err := reconcile.Save(c, rc.DB, rc.Queries, reconcile.Run{
+		Format:    format,
+		Filename:  fh.Filename,
+		LineCount: len(lines),
+		CreatedBy: createdBy,
+	}, report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save reconciliation"})
+		slog.Error("Failed to save reconciliation", "error", err)
+		return
+	}
+
+	rc.respondWithRun(c, runID, http.StatusCreated)
+	slog.Info("Statement reconciled", "id", runID, "format", format, "lines", len(lines),
+		"transactions", len(txns), "created_by", middleware.ProfileID(c))
+}
+
+// ListReconciliations lists past runs, newest first.
+func (rc *ReconciliationController) ListReconciliations(c *gin.Context) {
+	limit := int32(20)
+	offset := int32(0)
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "20")); err == nil {
+		limit = int32(l)
+	}
+	if o, err := strconv.Atoi(c.DefaultQuery("offset", "0")); err == nil {
+		offset = int32(o)
+	}
+
+	runs, err := rc.Queries.ListReconciliationRuns(c, db.ListReconciliationRunsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list reconciliations"})
+		slog.Error("Failed to list reconciliation runs", "error", err)
+		return
+	}
+
+	resp := make([]ReconciliationRunResponse, 0, len(runs))
+	for _, r := range runs {
+		resp = append(resp, newReconciliationRunResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"reconciliations": resp})
+}
+
+// GetReconciliation returns a run with its per-status counts and items,
+// only those with ?status= if given.
+func (rc *ReconciliationController) GetReconciliation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	rc.respondWithRun(c, id, http.StatusOK)
+}
+
+func (rc *ReconciliationController) respondWithRun(c *gin.Context, id int64, code int) {
+	var status db.NullReconciliationItemsStatus
+	if v := c.Query("status"); v != "" {
+		status = db.NullReconciliationItemsStatus{ReconciliationItemsStatus: db.ReconciliationItemsStatus(v), Valid: true}
+	}
+
+	run, err := rc.Queries.GetReconciliationRun(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reconciliation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reconciliation"})
+		slog.Error("Failed to get reconciliation run", "id", id, "error", err)
+		return
+	}
+	counts, err := rc.Queries.CountReconciliationItems(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reconciliation"})
+		slog.Error("Failed to count reconciliation items", "id", id, "error", err)
+		return
+	}
+	items, err := rc.Queries.ListReconciliationItems(c, db.ListReconciliationItemsParams{
+		RunID:  id,
+		Status: status,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reconciliation"})
+		slog.Error("Failed to list reconciliation items", "id", id, "error", err)
+		return
+	}
+
+	resp := newReconciliationRunResponse(run)
+	resp.Counts = map[string]int{}
+	for _, n := range counts {
+		resp.Counts[string(n.Status)] = int(n.Total)
+	}
+	itemsResp := make([]ReconciliationItemResponse, 0, len(items))
+	for _, it := range items {
+		itemsResp = append(itemsResp, newReconciliationItemResponse(it))
+	}
+	c.JSON(code, gin.H{"reconciliation": resp, "items": itemsResp})
+}
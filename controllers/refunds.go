@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/ledger"
+	"tickets/middleware"
+	"tickets/money"
+	"tickets/payments"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateRefundRequest refunds Amount in the payment's currency, or whatever
+// is still refundable when Amount is omitted.
+type CreateRefundRequest struct {
+	Amount json.Number `json:"amount"`
+	Reason string      `json:"reason" binding:"max=255"`
+}
+
+// CreateRefund records a refund as its own completed transaction linked to
+// the payment, and moves the payment to partially_refunded or refunded.
+// Refunds together can never exceed the original amount.
+func (ct *TransactionsController) CreateRefund(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := ct.DB.BeginTx(c, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to begin tx", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := ct.Queries.WithTx(tx)
+
+	// the row lock serialises concurrent refunds of the same payment
+	t, err := qtx.GetTransactionForUpdate(c, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund transaction"})
+		slog.Error("Failed to get transaction", "id", id, "error", err)
+		return
+	}
+	if t.Kind != db.TransactionsKindPayment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only payments can be refunded"})
+		return
+	}
+	if t.Status != payments.StatusCompleted && t.Status != payments.StatusPartiallyRefunded {
+		c.JSON(http.StatusConflict, gin.H{"error": "a " + payments.StatusName(t.Status) + " transaction cannot be refunded"})
+		return
+	}
+
+	refunded, err := qtx.SumRefunds(c, sql.NullInt32{Int32: t.ID, Valid: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund transaction"})
+		slog.Error("Failed to sum refunds", "id", id, "error", err)
+		return
+	}
+	refundable := money.Money{Amount: t.AmountMinor - refunded, Currency: t.Currency}
+
+	amount := refundable
+	if req.Amount != "" {
+		amount, err = money.Parse(req.Amount.String(), t.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refund amount must be positive"})
+		return
+	}
+	if amount.Amount > refundable.Amount {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "refund exceeds the refundable amount",
+			"refundable": refundable,
+		})
+		return
+	}
+
+	to := payments.StatusPartiallyRefunded
+	if amount.Amount == refundable.Amount {
+		to = payments.StatusRefunded
+	}
+	from := t.Status
+	if err := transitionTransaction(c, qtx, t.ID, from, to); err != nil {
+		if errors.Is(err, payments.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund transaction"})
+		slog.Error("Failed to update transaction status", "id", id, "error", err)
+		return
+	}
+
+	ref, err := transactionRef("RF")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to generate transaction reference", "error", err)
+		return
+	}
+	result, err := qtx.CreateRefund(c, db.CreateRefundParams{
+		TransactionID: ref,
+		UserID:        t.UserID,
+		AmountMinor:   amount.Amount,
+		Currency:      amount.Currency,
+		Status:        payments.StatusCompleted,
+		PaymentMethod: t.PaymentMethod,
+		ParentID:      sql.NullInt32{Int32: t.ID, Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund transaction"})
+		slog.Error("Failed to create refund", "id", id, "error", err)
+		return
+	}
+	refundID, _ := result.LastInsertId()
+	refundRow := db.Transaction{
+		ID:            int32(refundID),
+		TransactionID: ref,
+		UserID:        t.UserID,
+		AmountMinor:   amount.Amount,
+		Currency:      amount.Currency,
+		Status:        payments.StatusCompleted,
+		PaymentMethod: t.PaymentMethod,
+		Kind:          db.TransactionsKindRefund,
+		ParentID:      sql.NullInt32{Int32: t.ID, Valid: true},
+	}
+	if err := ledger.RecordRefund(c, qtx, refundRow, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund transaction"})
+		slog.Error("Failed to post refund to ledger", "id", refundID, "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund transaction"})
+		slog.Error("Failed to commit refund", "id", id, "error", err)
+		return
+	}
+
+	t.Status = to
+	original := newTransactionResponse(t)
+	refund := newTransactionResponse(refundRow)
+	remaining, _ := refundable.Sub(amount)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"refund":      refund,
+		"transaction": original,
+		"refundable":  remaining,
+	})
+	slog.Info("Refund created", "id", refundID, "parent_id", t.ID, "amount", amount.String(),
+		"currency", amount.Currency, "reason", req.Reason, "created_by", middleware.ProfileID(c))
+	publishTransactionEvent(c, ct.Queries, "transaction.created", refund, gin.H{"reason": req.Reason})
+	publishTransactionEvent(c, ct.Queries, "transaction."+payments.StatusName(to), original, gin.H{
+		"previous_status": payments.StatusName(from),
+		"refund_id":       refundID,
+		"refund_amount":   amount,
+	})
+}
+
+// ListRefunds returns the refunds made against a payment. Customers only
+// see those of their own payments.
+func (ct *TransactionsController) ListRefunds(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, ok := ct.loadOwnTransaction(c, int32(id)); !ok {
+		return
+	}
+
+	refunds, err := ct.Queries.ListRefunds(c, sql.NullInt32{Int32: int32(id), Valid: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list refunds"})
+		slog.Error("Failed to list refunds", "id", id, "error", err)
+		return
+	}
+
+	resp := make([]TransactionResponse, 0, len(refunds))
+	for _, r := range refunds {
+		resp = append(resp, newTransactionResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"refunds": resp})
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/routing"
+	"tickets/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoutingRuleResponse is the JSON shape of a routing rule. Conditions that
+// are not set are omitted; a rule without any matches every ticket.
+type RoutingRuleResponse struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Priority        int32     `json:"priority"`
+	TeamID          int64     `json:"team_id"`
+	TicketPriority  string    `json:"ticket_priority,omitempty"`
+	TicketType      string    `json:"ticket_type,omitempty"`
+	CustomerSegment string    `json:"customer_segment,omitempty"`
+	Keywords        []string  `json:"keywords"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func newRoutingRuleResponse(r db.RoutingRule) RoutingRuleResponse {
+	resp := RoutingRuleResponse{
+		ID:              r.ID,
+		Name:            r.Name,
+		Priority:        r.Priority,
+		TeamID:          r.TeamID,
+		TicketPriority:  r.TicketPriority.String,
+		TicketType:      string(r.TicketType.RoutingRulesTicketType),
+		CustomerSegment: r.CustomerSegment.String,
+		Keywords:        routing.SplitKeywords(r.Keywords),
+		Active:          r.Active,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+	}
+	if resp.Keywords == nil {
+		resp.Keywords = []string{}
+	}
+	return resp
+}
+
+// RoutingRuleRequest is the body of both create and update; an update
+// replaces every field. Rules are tried by priority, lowest first. Any
+// keyword found in the ticket's title or description satisfies the keyword
+// condition.
+type RoutingRuleRequest struct {
+	Name            string   `json:"name" binding:"required,max=100"`
+	Priority        int32    `json:"priority"`
+	TeamID          int64    `json:"team_id" binding:"required"`
+	TicketPriority  string   `json:"ticket_priority" binding:"max=50"`
+	TicketType      string   `json:"ticket_type" binding:"omitempty,oneof=general payment_dispute"`
+	CustomerSegment string   `json:"customer_segment" binding:"max=50"`
+	Keywords        []string `json:"keywords" binding:"max=50"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+func (tc *TeamController) CreateRoutingRule(c *gin.Context) {
+	params, ok := tc.bindRoutingRule(c)
+	if !ok {
+		return
+	}
+	result, err := tc.Queries.CreateRoutingRule(c, db.CreateRoutingRuleParams(params))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create routing rule"})
+		slog.Error("Failed to create routing rule", "error", err)
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	rule, err := tc.Queries.GetRoutingRule(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create routing rule"})
+		slog.Error("Failed to load routing rule", "id", id, "error", err)
+		return
+	}
+	c.JSON(http.StatusCreated, newRoutingRuleResponse(rule))
+	slog.Info("Routing rule created", "id", id, "team_id", rule.TeamID, "priority", rule.Priority,
+		"created_by", middleware.ProfileID(c))
+}
+
+// ListRoutingRules lists every rule in the order they are tried.
+func (tc *TeamController) ListRoutingRules(c *gin.Context) {
+	rules, err := tc.Queries.ListRoutingRules(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list routing rules"})
+		slog.Error("Failed to list routing rules", "error", err)
+		return
+	}
+	resp := make([]RoutingRuleResponse, 0, len(rules))
+	for _, r := range rules {
+		resp = append(resp, newRoutingRuleResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": resp})
+}
+
+func (tc *TeamController) GetRoutingRule(c *gin.Context) {
+	rule, ok := tc.loadRoutingRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newRoutingRuleResponse(rule))
+}
+
+func (tc *TeamController) UpdateRoutingRule(c *gin.Context) {
+	rule, ok := tc.loadRoutingRule(c)
+	if !ok {
+		return
+	}
+	params, ok := tc.bindRoutingRule(c)
+	if !ok {
+		return
+	}
+	err := tc.Queries.UpdateRoutingRule(c, db.UpdateRoutingRuleParams{
+		Name:            params.Name,
+		Priority:        params.Priority,
+		TeamID:          params.TeamID,
+		TicketPriority:  params.TicketPriority,
+		TicketType:      params.TicketType,
+		CustomerSegment: params.CustomerSegment,
+		Keywords:        params.Keywords,
+		Active:          params.Active,
+		ID:              rule.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update routing rule"})
+		slog.Error("Failed to update routing rule", "id", rule.ID, "error", err)
+		return
+	}
+	rule, err = tc.Queries.GetRoutingRule(c, rule.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update routing rule"})
+		slog.Error("Failed to load routing rule", "id", rule.ID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, newRoutingRuleResponse(rule))
+	slog.Info("Routing rule updated", "id", rule.ID, "active", rule.Active, "updated_by", middleware.ProfileID(c))
+}
+
+func (tc *TeamController) DeleteRoutingRule(c *gin.Context) {
+	rule, ok := tc.loadRoutingRule(c)
+	if !ok {
+		return
+	}
+	if _, err := tc.Queries.DeleteRoutingRule(c, rule.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete routing rule"})
+		slog.Error("Failed to delete routing rule", "id", rule.ID, "error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	slog.Info("Routing rule deleted", "id", rule.ID, "deleted_by", middleware.ProfileID(c))
+}
+
+// bindRoutingRule binds and validates a RoutingRuleRequest, checking that
+// its team exists.
+func (tc *TeamController) bindRoutingRule(c *gin.Context) (db.CreateRoutingRuleParams, bool) {
+	var req RoutingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return db.CreateRoutingRuleParams{}, false
+	}
+	keywords, err := routing.ParseKeywords(req.Keywords)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return db.CreateRoutingRuleParams{}, false
+	}
+	joined := routing.JoinKeywords(keywords)
+	if len(joined) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keywords are too long"})
+		return db.CreateRoutingRuleParams{}, false
+	}
+
+	if _, err := tc.Queries.GetTeam(c, req.TeamID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "team not found"})
+			return db.CreateRoutingRuleParams{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save routing rule"})
+		slog.Error("Failed to get team", "id", req.TeamID, "error", err)
+		return db.CreateRoutingRuleParams{}, false
+	}
+
+	params := db.CreateRoutingRuleParams{
+		Name:            strings.TrimSpace(req.Name),
+		Priority:        req.Priority,
+		TeamID:          req.TeamID,
+		TicketPriority:  utils.NullString(strings.TrimSpace(req.TicketPriority)),
+		CustomerSegment: utils.NullString(strings.ToLower(strings.TrimSpace(req.CustomerSegment))),
+		Keywords:        joined,
+		Active:          req.Active == nil || *req.Active,
+	}
+	if req.TicketType != "" {
+		params.TicketType = db.NullRoutingRulesTicketType{
+			RoutingRulesTicketType: db.RoutingRulesTicketType(req.TicketType),
+			Valid:                  true,
+		}
+	}
+	return params, true
+}
+
+// loadRoutingRule fetches the :id rule, answering 404 if it does not exist.
+func (tc *TeamController) loadRoutingRule(c *gin.Context) (db.RoutingRule, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return db.RoutingRule{}, false
+	}
+	rule, err := tc.Queries.GetRoutingRule(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "routing rule not found"})
+			return db.RoutingRule{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get routing rule"})
+		slog.Error("Failed to get routing rule", "id", id, "error", err)
+		return db.RoutingRule{}, false
+	}
+	return rule, true
+}
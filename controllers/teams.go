@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tickets/assignment"
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TeamController manages teams of agents and the routing rules that send
+// new tickets to them.
+type TeamController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+type TeamResponse struct {
+	ID                 int64                `json:"id"`
+	Name               string               `json:"name"`
+	Description        string               `json:"description,omitempty"`
+	AssignmentStrategy string               `json:"assignment_strategy"`
+	Members            []TeamMemberResponse `json:"members,omitempty"`
+	CreatedAt          time.Time            `json:"created_at"`
+}
+
+func newTeamResponse(t db.Team) TeamResponse {
+	return TeamResponse{
+		ID:                 t.ID,
+		Name:               t.Name,
+		Description:        t.Description,
+		AssignmentStrategy: t.AssignmentStrategy,
+		CreatedAt:          t.CreatedAt,
+	}
+}
+
+type TeamMemberResponse struct {
+	ProfileID int32     `json:"profile_id"`
+	FullName  string    `json:"full_name,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Phone     string    `json:"phone,omitempty"`
+	Role      string    `json:"role"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+func newTeamMemberResponse(m db.ListTeamMembersRow) TeamMemberResponse {
+	return TeamMemberResponse{
+		ProfileID: m.ProfileID,
+		FullName:  m.FullName.String,
+		Email:     m.Email.String,
+		Phone:     m.Phone.String,
+		Role:      string(m.Role),
+		AddedAt:   m.CreatedAt,
+	}
+}
+
+// TeamRequest is the body of both create and update. AssignmentStrategy is
+// how the worker assigns the team's new tickets; see package assignment.
+// It defaults to manual.
+type TeamRequest struct {
+	Name               string `json:"name" binding:"required,max=100"`
+	Description        string `json:"description" binding:"max=255"`
+	AssignmentStrategy string `json:"assignment_strategy" binding:"max=30"`
+}
+
+// bindTeam binds a TeamRequest and checks its assignment strategy.
+func bindTeam(c *gin.Context) (TeamRequest, bool) {
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	req.AssignmentStrategy = strings.TrimSpace(req.AssignmentStrategy)
+	if req.AssignmentStrategy == "" {
+		req.AssignmentStrategy = assignment.Manual
+	}
+	if !assignment.Valid(req.AssignmentStrategy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "assignment_strategy must be one of " + strings.Join(assignment.Names(), ", "),
+		})
+		return req, false
+	}
+	return req, true
+}
+
+type TeamMemberRequest struct {
+	ProfileID int32 `json:"profile_id" binding:"required"`
+}
+
+// CreateTeam adds a team. Names are unique.
+func (tc *TeamController) CreateTeam(c *gin.Context) {
+	req, ok := bindTeam(c)
+	if !ok {
+		return
+	}
+	result, err := tc.Queries.CreateTeam(c, db.CreateTeamParams{
+		Name:               req.Name,
+		Description:        req.Description,
+		AssignmentStrategy: req.AssignmentStrategy,
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a team with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create team"})
+		slog.Error("Failed to create team", "error", err)
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	team, err := tc.Queries.GetTeam(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create team"})
+		slog.Error("Failed to load team", "id", id, "error", err)
+		return
+	}
+	c.JSON(http.StatusCreated, newTeamResponse(team))
+	slog.Info("Team created", "id", id, "name", team.Name, "created_by", middleware.ProfileID(c))
+}
+
+func (tc *TeamController) ListTeams(c *gin.Context) {
+	teams, err := tc.Queries.ListTeams(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list teams"})
+		slog.Error("Failed to list teams", "error", err)
+		return
+	}
+	resp := make([]TeamResponse, 0, len(teams))
+	for _, t := range teams {
+		resp = append(resp, newTeamResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"teams": resp})
+}
+
+// GetTeam returns a team with its members.
+func (tc *TeamController) GetTeam(c *gin.Context) {
+	team, ok := tc.loadTeam(c)
+	if !ok {
+		return
+	}
+	members, err := tc.Queries.ListTeamMembers(c, team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get team"})
+		slog.Error("Failed to list team members", "id", team.ID, "error", err)
+		return
+	}
+	resp := newTeamResponse(team)
+	resp.Members = make([]TeamMemberResponse, 0, len(members))
+	for _, m := range members {
+		resp.Members = append(resp.Members, newTeamMemberResponse(m))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (tc *TeamController) UpdateTeam(c *gin.Context) {
+	team, ok := tc.loadTeam(c)
+	if !ok {
+		return
+	}
+	req, ok := bindTeam(c)
+	if !ok {
+		return
+	}
+	team.Name = req.Name
+	team.Description = req.Description
+	team.AssignmentStrategy = req.AssignmentStrategy
+	err := tc.Queries.UpdateTeam(c, db.UpdateTeamParams{
+		Name:               team.Name,
+		Description:        team.Description,
+		AssignmentStrategy: team.AssignmentStrategy,
+		ID:                 team.ID,
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a team with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update team"})
+		slog.Error("Failed to update team", "id", team.ID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, newTeamResponse(team))
+	slog.Info("Team updated", "id", team.ID, "strategy", team.AssignmentStrategy, "updated_by", middleware.ProfileID(c))
+}
+
+// DeleteTeam removes a team with its members and routing rules. Its
+// tickets are kept without a team.
+func (tc *TeamController) DeleteTeam(c *gin.Context) {
+	team, ok := tc.loadTeam(c)
+	if !ok {
+		return
+	}
+	if _, err := tc.Queries.DeleteTeam(c, team.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete team"})
+		slog.Error("Failed to delete team", "id", team.ID, "error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	slog.Info("Team deleted", "id", team.ID, "name", team.Name, "deleted_by", middleware.ProfileID(c))
+}
+
+// AddTeamMember puts an agent or admin in the team. Adding a member twice
+// is not an error.
+func (tc *TeamController) AddTeamMember(c *gin.Context) {
+	team, ok := tc.loadTeam(c)
+	if !ok {
+		return
+	}
+	var req TeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	profile, err := tc.Queries.GetProfileByID(c, req.ProfileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add team member"})
+		slog.Error("Failed to get profile", "id", req.ProfileID, "error", err)
+		return
+	}
+	if profile.Role != db.ProfilesRoleAgent && profile.Role != db.ProfilesRoleAdmin {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "only agents and admins can be team members"})
+		return
+	}
+
+	err = tc.Queries.AddTeamMember(c, db.AddTeamMemberParams{TeamID: team.ID, ProfileID: profile.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add team member"})
+		slog.Error("Failed to add team member", "team_id", team.ID, "profile_id", profile.ID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "team member added"})
+	slog.Info("Team member added", "team_id", team.ID, "profile_id", profile.ID, "added_by", middleware.ProfileID(c))
+}
+
+func (tc *TeamController) RemoveTeamMember(c *gin.Context) {
+	team, ok := tc.loadTeam(c)
+	if !ok {
+		return
+	}
+	profileID, err := strconv.ParseInt(c.Param("profile_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+		return
+	}
+	n, err := tc.Queries.RemoveTeamMember(c, db.RemoveTeamMemberParams{TeamID: team.ID, ProfileID: int32(profileID)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove team member"})
+		slog.Error("Failed to remove team member", "team_id", team.ID, "profile_id", profileID, "error", err)
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not a member of this team"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+	slog.Info("Team member removed", "team_id", team.ID, "profile_id", profileID, "removed_by", middleware.ProfileID(c))
+}
+
+// ListTeamTickets lists the tickets routed to a team, newest first.
+func (tc *TeamController) ListTeamTickets(c *gin.Context) {
+	team, ok := tc.loadTeam(c)
+	if !ok {
+		return
+	}
+	limit, offset := ticketPage(c)
+
+	tickets, err := tc.Queries.ListTicketsByTeam(c, db.ListTicketsByTeamParams{
+		TeamID: sql.NullInt64{Int64: team.ID, Valid: true},
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		slog.Error("Failed to list team tickets", "id", team.ID, "error", err)
+		return
+	}
+
+	resp := make([]TicketResponse, 0, len(tickets))
+	for _, t := range tickets {
+		resp = append(resp, newTicketResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"tickets": resp})
+}
+
+// loadTeam fetches the :id team, answering 404 if it does not exist.
+func (tc *TeamController) loadTeam(c *gin.Context) (db.Team, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return db.Team{}, false
+	}
+	team, err := tc.Queries.GetTeam(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "team not found"})
+			return db.Team{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get team"})
+		slog.Error("Failed to get team", "id", id, "error", err)
+		return db.Team{}, false
+	}
+	return team, true
+}
@@ -2,12 +2,16 @@ package controllers
 
 import (
 	"database/sql"
-	"encoding/json"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
 	db "tickets/db/sqlc"
-	"tickets/publish"
+	"tickets/middleware"
+	"tickets/realtime"
+	"tickets/routing"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,13 +21,61 @@ type TicketController struct {
 	DB      *sql.DB
 }
 
+// TicketResponse is the JSON shape of a ticket. Transaction is filled in for
+// tickets linked to a transaction, so payment disputes carry the payment.
+type TicketResponse struct {
+	ID            int64                `json:"id"`
+	Title         string               `json:"title"`
+	Description   string               `json:"description"`
+	Status        int16                `json:"status"`
+	Priority      string               `json:"priority"`
+	Type          string               `json:"type"`
+	CreatedBy     int64                `json:"created_by"`
+	AssignedTo    *int64               `json:"assigned_to,omitempty"`
+	CustomerID    *int64               `json:"customer_id,omitempty"`
+	TransactionID *int32               `json:"transaction_id,omitempty"`
+	TeamID        *int64               `json:"team_id,omitempty"`
+	Transaction   *TransactionResponse `json:"transaction,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+func newTicketResponse(t db.Ticket) TicketResponse {
+	r := TicketResponse{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Priority:    t.Priority,
+		Type:        string(t.Type),
+		CreatedBy:   t.CreatedBy,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+	if t.AssignedTo.Valid {
+		r.AssignedTo = &t.AssignedTo.Int64
+	}
+	if t.CustomerID.Valid {
+		r.CustomerID = &t.CustomerID.Int64
+	}
+	if t.TransactionID.Valid {
+		r.TransactionID = &t.TransactionID.Int32
+	}
+	if t.TeamID.Valid {
+		r.TeamID = &t.TeamID.Int64
+	}
+	return r
+}
+
 // Create Ticket
 type CreateTicketRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	CreatedBy   int64  `json:"created_by"`
-	Priority    string `json:"priority"`
-	Status      int16  `json:"status"` // should be int16, not string
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	Priority      string `json:"priority"`
+	Status        int16  `json:"status"` // should be int16, not string
+	Type          string `json:"type" binding:"omitempty,oneof=general payment_dispute"`
+	CustomerID    int64  `json:"customer_id"`
+	TransactionID int32  `json:"transaction_id"`
 }
 
 func (t *TicketController) CreateTicket(c *gin.Context) {
@@ -36,13 +88,81 @@ func (t *TicketController) CreateTicket(c *gin.Context) {
 		return
 	}
 
-	// 2️⃣ Insert into DB
-	result, err := t.Queries.CreateTicket(c, db.CreateTicketParams{
+	// the creator is always the authenticated profile
+	createdBy := middleware.ProfileID(c)
+
+	ticketType := db.TicketsTypeGeneral
+	if req.Type != "" {
+		ticketType = db.TicketsType(req.Type)
+	}
+	if ticketType == db.TicketsTypePaymentDispute && req.TransactionID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a payment_dispute ticket needs transaction_id"})
+		return
+	}
+
+	var customerID sql.NullInt64
+	var segment string
+	if req.CustomerID != 0 {
+		customer, err := t.Queries.GetCustomer(c, req.CustomerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "customer not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ticket"})
+			slog.Error("Failed to get customer", "id", req.CustomerID, "error", err)
+			return
+		}
+		customerID = sql.NullInt64{Int64: req.CustomerID, Valid: true}
+		segment = customer.Segment
+	}
+
+	var transactionID sql.NullInt32
+	var transaction *TransactionResponse
+	if req.TransactionID != 0 {
+		txn, err := t.Queries.GetTransanctionByID(c, req.TransactionID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "transaction not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ticket"})
+			slog.Error("Failed to get transaction", "id", req.TransactionID, "error", err)
+			return
+		}
+		// customers may only raise tickets about their own payments
+		if middleware.Role(c) == db.ProfilesRoleCustomer && int64(txn.UserID) != createdBy {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "transaction not found"})
+			return
+		}
+		if ticketType == db.TicketsTypePaymentDispute && txn.Kind != db.TransactionsKindPayment {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "only payments can be disputed"})
+			return
+		}
+		transactionID = sql.NullInt32{Int32: txn.ID, Valid: true}
+		resp := newTransactionResponse(txn)
+		transaction = &resp
+	}
+
+	teamID := t.routeTicket(c, routing.Ticket{
 		Title:       req.Title,
 		Description: req.Description,
-		CreatedBy:   req.CreatedBy,
 		Priority:    req.Priority,
-		Status:      req.Status, // int16 matches
+		Type:        ticketType,
+		Segment:     segment,
+	})
+
+	// 2️⃣ Insert into DB
+	result, err := t.Queries.CreateTicket(c, db.CreateTicketParams{
+		Title:         req.Title,
+		Description:   req.Description,
+		CreatedBy:     createdBy,
+		Priority:      req.Priority,
+		Status:        req.Status, // int16 matches
+		Type:          ticketType,
+		CustomerID:    customerID,
+		TransactionID: transactionID,
+		TeamID:        teamID,
 	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to create ticket", "details": err.Error()})
@@ -59,44 +179,89 @@ func (t *TicketController) CreateTicket(c *gin.Context) {
 	}
 
 	// 4️⃣ Publish to RabbitMQ
-	event := map[string]interface{}{
-		"type": "ticket.created",
-		"payload": map[string]interface{}{
-			"id":          ticketID,
-			"title":       req.Title,
-			"description": req.Description,
-			"created_by":  req.CreatedBy,
-			"priority":    req.Priority,
-			"status":      req.Status,
-		},
-	}
-
-	body, _ := json.Marshal(event)
-	if err := publish.Publish("ticket_events", body); err != nil {
+	payload := map[string]interface{}{
+		"id":          ticketID,
+		"title":       req.Title,
+		"description": req.Description,
+		"created_by":  createdBy,
+		"priority":    req.Priority,
+		"status":      req.Status,
+		"type":        ticketType,
+	}
+	if customerID.Valid {
+		payload["customer_id"] = customerID.Int64
+	}
+	if teamID.Valid {
+		payload["team_id"] = teamID.Int64
+	}
+	if transaction != nil {
+		payload["transaction"] = transaction
+	}
+	if err := realtime.Publish(c, t.Queries, "ticket_events", "ticket.created", createdBy, payload); err != nil {
 		slog.Error("Failed to publish ticket event", "error", err)
 	}
 
 	// 5️⃣ Response
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"ticket_id": ticketID,
 		"message":   "Ticket created successfully",
-	})
-	slog.Info("Ticket created successfully", "ticket_id", ticketID)
+	}
+	if teamID.Valid {
+		resp["team_id"] = teamID.Int64
+	}
+	if transaction != nil {
+		resp["transaction"] = transaction
+	}
+	c.JSON(http.StatusOK, resp)
+	slog.Info("Ticket created successfully", "ticket_id", ticketID, "type", ticketType)
+}
+
+// routeTicket returns the team the routing rules pick for a new ticket. A
+// ticket no rule matches, or that cannot be routed because the rules failed
+// to load, is created without a team for staff to route by hand.
+func (t *TicketController) routeTicket(c *gin.Context, ticket routing.Ticket) sql.NullInt64 {
+	rules, err := t.Queries.ListActiveRoutingRules(c)
+	if err != nil {
+		slog.Error("Failed to load routing rules", "error", err)
+		return sql.NullInt64{}
+	}
+	rule, ok := routing.Match(rules, ticket)
+	if !ok {
+		return sql.NullInt64{}
+	}
+	slog.Info("Ticket routed", "rule_id", rule.ID, "rule", rule.Name, "team_id", rule.TeamID)
+	return sql.NullInt64{Int64: rule.TeamID, Valid: true}
 }
 
-// List Tickets
+// List Tickets, newest first, paged by ticketPage. Customers only see the
+// tickets GetTicket would show them.
 func (tc *TicketController) ListTickets(c *gin.Context) {
-	tickets, err := tc.Queries.ListTickets(c.Request.Context(), db.ListTicketsParams{
-		Limit:  10,
-		Offset: 0,
-	})
+	limit, offset := ticketPage(c)
+	var tickets []db.Ticket
+	var err error
+	if middleware.Role(c) == db.ProfilesRoleCustomer {
+		tickets, err = tc.Queries.ListOwnTickets(c.Request.Context(), db.ListOwnTicketsParams{
+			ProfileID: int32(middleware.ProfileID(c)),
+			Limit:     limit,
+			Offset:    offset,
+		})
+	} else {
+		tickets, err = tc.Queries.ListTickets(c.Request.Context(), db.ListTicketsParams{
+			Limit:  limit,
+			Offset: offset,
+		})
+	}
 	if err != nil {
 		slog.Error("Failed to fetch tickets", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to fetch tickets"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tickets)
+	resp := make([]TicketResponse, 0, len(tickets))
+	for _, t := range tickets {
+		resp = append(resp, newTicketResponse(t))
+	}
+	c.JSON(http.StatusOK, resp)
 	slog.Info("Fetched tickets successfully", "count", len(tickets))
 }
 
@@ -111,11 +276,60 @@ func (tc *TicketController) GetTicket(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
 		return
 	}
+	if middleware.Role(c) == db.ProfilesRoleCustomer {
+		own, err := tc.ownsTicket(c, ticket)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ticket"})
+			slog.Error("Failed to check ticket owner", "ticket_id", ticket.ID, "error", err)
+			return
+		}
+		if !own {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, ticket)
+	resp := newTicketResponse(ticket)
+	if ticket.TransactionID.Valid {
+		txn, err := tc.Queries.GetTransanctionByID(c.Request.Context(), ticket.TransactionID.Int32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ticket"})
+			slog.Error("Failed to get ticket transaction", "ticket_id", ticket.ID, "error", err)
+			return
+		}
+		t := newTransactionResponse(txn)
+		resp.Transaction = &t
+	}
+
+	c.JSON(http.StatusOK, resp)
 	slog.Info("Fetched ticket successfully", "ticket_id", ticket.ID)
 }
 
+// ownsTicket reports whether the calling customer created the ticket or is
+// its customer, that is has the customer's email or phone.
+func (tc *TicketController) ownsTicket(c *gin.Context, ticket db.Ticket) (bool, error) {
+	profileID := middleware.ProfileID(c)
+	if ticket.CreatedBy == profileID {
+		return true, nil
+	}
+	if !ticket.CustomerID.Valid {
+		return false, nil
+	}
+	customer, err := tc.Queries.GetCustomer(c, ticket.CustomerID.Int64)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	profile, err := tc.Queries.GetProfileByID(c, int32(profileID))
+	if err != nil {
+		return false, err
+	}
+	return profile.Email.Valid && strings.EqualFold(profile.Email.String, customer.Email) ||
+		profile.Phone.Valid && profile.Phone.String == customer.PhoneNumber, nil
+}
+
 // Update Ticket Status
 func (tc *TicketController) UpdateTicketStatus(c *gin.Context) {
 	idStr := c.Param("id")
@@ -148,5 +362,162 @@ func (tc *TicketController) UpdateTicketStatus(c *gin.Context) {
 		return
 	}
 
+	// the event carries the whole ticket so subscribers can tell whose it is
+	if ticket, err := tc.Queries.GetTicket(c.Request.Context(), id); err != nil {
+		slog.Error("Failed to load ticket for event", "ticket_id", id, "error", err)
+	} else {
+		err := realtime.Publish(c, tc.Queries, "ticket_events", "ticket.status_changed", ticket.CreatedBy, newTicketResponse(ticket))
+		if err != nil {
+			slog.Error("Failed to publish ticket event", "error", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Ticket status updated"})
 }
+
+// UpdateTicketTeam moves a ticket to another team, or out of any team with
+// team_id 0, overriding the routing rules.
+func (tc *TicketController) UpdateTicketTeam(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req struct {
+		TeamID *int64 `json:"team_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var teamID sql.NullInt64
+	if *req.TeamID != 0 {
+		if _, err := tc.Queries.GetTeam(c, *req.TeamID); err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "team not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+			slog.Error("Failed to get team", "id", *req.TeamID, "error", err)
+			return
+		}
+		teamID = sql.NullInt64{Int64: *req.TeamID, Valid: true}
+	}
+
+	ticket, err := tc.Queries.GetTicket(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		slog.Error("Failed to get ticket", "id", id, "error", err)
+		return
+	}
+	if _, err := tc.Queries.UpdateTicketTeam(c, db.UpdateTicketTeamParams{TeamID: teamID, ID: id}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		slog.Error("Failed to update ticket team", "id", id, "error", err)
+		return
+	}
+	ticket.TeamID = teamID
+
+	resp := newTicketResponse(ticket)
+	if err := realtime.Publish(c, tc.Queries, "ticket_events", "ticket.team_changed", ticket.CreatedBy, resp); err != nil {
+		slog.Error("Failed to publish ticket event", "error", err)
+	}
+	c.JSON(http.StatusOK, resp)
+	slog.Info("Ticket team updated", "ticket_id", id, "team_id", teamID.Int64, "updated_by", middleware.ProfileID(c))
+}
+
+// ListCustomerTickets lists the tickets linked to a customer, newest first.
+func (tc *TicketController) ListCustomerTickets(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, err := tc.Queries.GetCustomer(c, id); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		slog.Error("Failed to get customer", "id", id, "error", err)
+		return
+	}
+	limit, offset := ticketPage(c)
+
+	tickets, err := tc.Queries.ListTicketsByCustomer(c, db.ListTicketsByCustomerParams{
+		CustomerID: sql.NullInt64{Int64: id, Valid: true},
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		slog.Error("Failed to list customer tickets", "id", id, "error", err)
+		return
+	}
+
+	resp := make([]TicketResponse, 0, len(tickets))
+	for _, t := range tickets {
+		resp = append(resp, newTicketResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"tickets": resp})
+}
+
+// ListTransactionTickets lists the tickets raised about a transaction, newest
+// first. Customers only see tickets about their own transactions.
+func (tc *TicketController) ListTransactionTickets(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	txn, err := tc.Queries.GetTransanctionByID(c, int32(id))
+	if err == nil && middleware.Role(c) == db.ProfilesRoleCustomer && int64(txn.UserID) != middleware.ProfileID(c) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		slog.Error("Failed to get transaction", "id", id, "error", err)
+		return
+	}
+	limit, offset := ticketPage(c)
+
+	tickets, err := tc.Queries.ListTicketsByTransaction(c, db.ListTicketsByTransactionParams{
+		TransactionID: sql.NullInt32{Int32: int32(id), Valid: true},
+		Limit:         limit,
+		Offset:        offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		slog.Error("Failed to list transaction tickets", "id", id, "error", err)
+		return
+	}
+
+	resp := make([]TicketResponse, 0, len(tickets))
+	for _, t := range tickets {
+		resp = append(resp, newTicketResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transaction": newTransactionResponse(txn),
+		"tickets":     resp,
+	})
+}
+
+// ticketPage reads ?limit= (default 10, at most 100) and ?offset=.
+func ticketPage(c *gin.Context) (limit, offset int32) {
+	limit = 10
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = int32(min(l, 100))
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o >= 0 {
+		offset = int32(o)
+	}
+	return limit, offset
+}
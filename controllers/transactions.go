@@ -1,15 +1,24 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
-	// "encoding/json"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"log/slog"
 	db "tickets/db/sqlc"
+	"tickets/ledger"
+	"tickets/middleware"
+	"tickets/money"
+	"tickets/payments"
 
-	// "tickets/publish"
+	"tickets/realtime"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,32 +28,258 @@ type TransactionsController struct {
 	DB      *sql.DB
 }
 
+// TransactionResponse is the JSON shape of a transaction. Amounts are always
+// money.Money so they are encoded as exact decimal strings.
+type TransactionResponse struct {
+	ID            int32       `json:"id"`
+	TransactionID string      `json:"transaction_id"`
+	UserID        int32       `json:"user_id,omitempty"`
+	Amount        money.Money `json:"amount"`
+	Status        string      `json:"status,omitempty"`
+	PaymentMethod string      `json:"payment_method"`
+	Kind          string      `json:"kind,omitempty"`
+	ParentID      *int32      `json:"parent_id,omitempty"`
+	CreatedAt     *time.Time  `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time  `json:"updated_at,omitempty"`
+}
+
+func newTransactionResponse(t db.Transaction) TransactionResponse {
+	r := TransactionResponse{
+		ID:            t.ID,
+		TransactionID: t.TransactionID,
+		UserID:        t.UserID,
+		Amount:        t.Money(),
+		Status:        payments.StatusName(t.Status),
+		PaymentMethod: t.PaymentMethod.String,
+		Kind:          string(t.Kind),
+	}
+	if t.ParentID.Valid {
+		r.ParentID = &t.ParentID.Int32
+	}
+	if t.CreatedAt.Valid {
+		r.CreatedAt = &t.CreatedAt.Time
+	}
+	if t.UpdatedAt.Valid {
+		r.UpdatedAt = &t.UpdatedAt.Time
+	}
+	return r
+}
+
+// transitionTransaction moves a transaction from one status to another,
+// enforcing the lifecycle in package payments. It returns an error wrapping
+// payments.ErrInvalidTransition if the move is not allowed or the row is no
+// longer in the from state.
+//
+// A move to the same status (another partial refund) changes no column, so
+// MySQL would report no affected rows; it is not written at all. Its callers
+// hold the row locked with GetTransactionForUpdate, which already keeps
+// concurrent updates apart.
+func transitionTransaction(ctx context.Context, q *db.Queries, id int32, from, to int16) error {
+	if err := payments.CheckTransition(from, to); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	n, err := q.TransitionTransactionStatus(ctx, db.TransitionTransactionStatusParams{
+		NewStatus: to,
+		ID:        id,
+		OldStatus: from,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: transaction %d is no longer %s", payments.ErrInvalidTransition, id, payments.StatusName(from))
+	}
+	return nil
+}
+
+// publishTransactionEvent emits a transaction.* event on the transaction_events
+// queue. extra is merged into the payload.
+func publishTransactionEvent(ctx context.Context, q *db.Queries, eventType string, t TransactionResponse, extra gin.H) {
+	payload := map[string]interface{}{
+		"id":             t.ID,
+		"transaction_id": t.TransactionID,
+		"user_id":        t.UserID,
+		"amount":         t.Amount,
+		"status":         t.Status,
+		"kind":           t.Kind,
+	}
+	if t.ParentID != nil {
+		payload["parent_id"] = *t.ParentID
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+
+	if err := realtime.Publish(ctx, q, "transaction_events", eventType, int64(t.UserID), payload); err != nil {
+		slog.Error("Failed to publish transaction event", "type", eventType, "id", t.ID, "error", err)
+	}
+}
+
+// TransactionTotal is the sum of the matching transactions in one currency.
+type TransactionTotal struct {
+	Currency string      `json:"currency"`
+	Count    int64       `json:"count"`
+	Total    money.Money `json:"total"`
+}
+
+// ListTransactions lists transactions newest first. It takes the filters
+// parsed by parseTransactionFilter plus limit (at most 100) and offset, and
+// returns totals per currency over everything matching, not just the page.
 func (ct *TransactionsController) ListTransactions(c *gin.Context) {
-	// Example: parse limit and offset from query params, or set defaults
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	limit := int32(10)
 	offset := int32(0)
-	if l, err := strconv.Atoi(c.DefaultQuery("limit", "10")); err == nil {
-		limit = int32(l)
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "10")); err == nil && l > 0 {
+		limit = int32(min(l, 100))
 	}
-	if o, err := strconv.Atoi(c.DefaultQuery("offset", "0")); err == nil {
+	if o, err := strconv.Atoi(c.DefaultQuery("offset", "0")); err == nil && o >= 0 {
 		offset = int32(o)
 	}
 
-	params := db.ListTransactionsParams{
-		Limit:  limit,
-		Offset: offset,
-	}
-
-	transactions, err := ct.Queries.ListTransactions(c.Request.Context(), params)
+	transactions, err := ct.Queries.ListTransactions(c.Request.Context(), db.ListTransactionsParams{
+		UserID:        filter.UserID,
+		Status:        filter.Status,
+		Currency:      filter.Currency,
+		PaymentMethod: filter.PaymentMethod,
+		Kind:          filter.Kind,
+		MinAmount:     filter.MinAmount,
+		MaxAmount:     filter.MaxAmount,
+		CreatedFrom:   filter.CreatedFrom,
+		CreatedTo:     filter.CreatedTo,
+		Limit:         limit,
+		Offset:        offset,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list transactions"})
 		slog.Error("Failed to list transactions", "error", err)
 		return
 	}
+	sums, err := ct.Queries.SumTransactionsByCurrency(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list transactions"})
+		slog.Error("Failed to sum transactions", "error", err)
+		return
+	}
+
+	resp := make([]TransactionResponse, 0, len(transactions))
+	for _, t := range transactions {
+		resp = append(resp, newTransactionResponse(t))
+	}
+	totals := make([]TransactionTotal, 0, len(sums))
+	for _, s := range sums {
+		totals = append(totals, TransactionTotal{
+			Currency: s.Currency,
+			Count:    s.Count,
+			Total:    money.Money{Amount: s.TotalMinor, Currency: s.Currency},
+		})
+	}
 
-	c.JSON(http.StatusOK, gin.H{"transactions": transactions})
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": resp,
+		"totals":       totals,
+		"limit":        limit,
+		"offset":       offset,
+	})
 	slog.Info("transactions listed successfully", "count", len(transactions))
 }
+
+// parseTransactionFilter reads the transaction filters from the query string:
+// user_id, status, currency, payment_method, kind, min_amount and max_amount
+// (decimals, which need currency) and from/to (RFC 3339 or YYYY-MM-DD; a
+// plain to date includes that whole day). Customers only ever see their own
+// transactions.
+func parseTransactionFilter(c *gin.Context) (db.SumTransactionsByCurrencyParams, error) {
+	var f db.SumTransactionsByCurrencyParams
+
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return f, errors.New("invalid user_id")
+		}
+		f.UserID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	if middleware.Role(c) == db.ProfilesRoleCustomer {
+		f.UserID = sql.NullInt32{Int32: int32(middleware.ProfileID(c)), Valid: true}
+	}
+	if v := c.Query("status"); v != "" {
+		status, ok := payments.ParseStatus(v)
+		if !ok {
+			return f, fmt.Errorf("unknown status %q", v)
+		}
+		f.Status = sql.NullInt16{Int16: status, Valid: true}
+	}
+	if v := c.Query("currency"); v != "" {
+		v = strings.ToUpper(v)
+		if !money.Valid(v) {
+			return f, fmt.Errorf("unknown currency %q", v)
+		}
+		f.Currency = sql.NullString{String: v, Valid: true}
+	}
+	if v := c.Query("payment_method"); v != "" {
+		f.PaymentMethod = sql.NullString{String: v, Valid: true}
+	}
+	if v := c.Query("kind"); v != "" {
+		kind := db.TransactionsKind(v)
+		if kind != db.TransactionsKindPayment && kind != db.TransactionsKindRefund {
+			return f, fmt.Errorf("unknown kind %q", v)
+		}
+		f.Kind = db.NullTransactionsKind{TransactionsKind: kind, Valid: true}
+	}
+
+	for _, a := range []struct {
+		name string
+		dst  *sql.NullInt64
+	}{{"min_amount", &f.MinAmount}, {"max_amount", &f.MaxAmount}} {
+		v := c.Query(a.name)
+		if v == "" {
+			continue
+		}
+		if !f.Currency.Valid {
+			return f, errors.New(a.name + " needs currency")
+		}
+		m, err := money.Parse(v, f.Currency.String)
+		if err != nil {
+			return f, fmt.Errorf("%s: %w", a.name, err)
+		}
+		*a.dst = sql.NullInt64{Int64: m.Amount, Valid: true}
+	}
+
+	if v := c.Query("from"); v != "" {
+		t, _, err := parseFilterTime(v)
+		if err != nil {
+			return f, errors.New("from must be RFC 3339 or YYYY-MM-DD")
+		}
+		f.CreatedFrom = sql.NullTime{Time: t, Valid: true}
+	}
+	if v := c.Query("to"); v != "" {
+		t, isDate, err := parseFilterTime(v)
+		if err != nil {
+			return f, errors.New("to must be RFC 3339 or YYYY-MM-DD")
+		}
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+		f.CreatedTo = sql.NullTime{Time: t, Valid: true}
+	}
+	return f, nil
+}
+
+func parseFilterTime(v string) (t time.Time, isDate bool, err error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	t, err = time.ParseInLocation(time.DateOnly, v, time.Local)
+	return t, true, err
+}
+
+// GetByID returns a transaction. Customers only see their own.
 func (ct *TransactionsController) GetByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
@@ -53,25 +288,44 @@ func (ct *TransactionsController) GetByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
-	transaction, err := ct.Queries.GetTransanctionByID(c.Request.Context(), int32(id))
-	if err != nil {
-		// slog.Error("Transaction not found", "error", err)
-		slog.Error(err.Error(), "error", "not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	transaction, ok := ct.loadOwnTransaction(c, int32(id))
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, transaction)
+	c.JSON(http.StatusOK, newTransactionResponse(transaction))
 	slog.Info("Fetched transaction successfully", "transaction_id", transaction.ID)
 }
 
+// loadOwnTransaction loads a transaction, answering 404 when it does not
+// exist or a customer asks for someone else's. It writes the response and
+// returns false if the caller should stop.
+func (ct *TransactionsController) loadOwnTransaction(c *gin.Context, id int32) (db.Transaction, bool) {
+	txn, err := ct.Queries.GetTransanctionByID(c, id)
+	if err == nil && middleware.Role(c) == db.ProfilesRoleCustomer && int64(txn.UserID) != middleware.ProfileID(c) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return db.Transaction{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to get transaction", "id", id, "error", err)
+		return db.Transaction{}, false
+	}
+	return txn, true
+}
+
+// CreateTransactionRequest takes the amount as a decimal string or number,
+// e.g. "10.50"; it is parsed exactly for the currency and never via float64.
 type CreateTransactionRequest struct {
-	TransactionID string  `json:"transaction_id"`
-	UserID        int64   `json:"user_id"`
-	Amount        float64 `json:"amount"`
-	Currency      string  `json:"currency"`
-	Status        int     `json:"status"`
-	PaymentMethod string  `json:"payment_method"`
+	TransactionID string      `json:"transaction_id"`
+	UserID        int64       `json:"user_id"`
+	Amount        json.Number `json:"amount" binding:"required"`
+	Currency      string      `json:"currency" binding:"required,len=3"`
+	Status        string      `json:"status" binding:"omitempty,oneof=pending completed failed"`
+	PaymentMethod string      `json:"payment_method"`
 }
 
 func (ct *TransactionsController) CreateTransactions(c *gin.Context) {
@@ -80,19 +334,54 @@ func (ct *TransactionsController) CreateTransactions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	amount, err := money.Parse(req.Amount.String(), req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		return
+	}
+	status := payments.StatusPending
+	if req.Status != "" {
+		status, _ = payments.ParseStatus(req.Status)
+	}
+	// customers record their own pending payments; only staff, API keys and
+	// the M-Pesa callback complete them, which posts them to the ledger
+	if middleware.Role(c) == db.ProfilesRoleCustomer {
+		if req.UserID != 0 && req.UserID != middleware.ProfileID(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only staff can record another user's transaction"})
+			return
+		}
+		if status != payments.StatusPending {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only staff can record a transaction that is not pending"})
+			return
+		}
+		req.UserID = middleware.ProfileID(c)
+	}
 	params := db.CreateTransactionParams{
 		TransactionID: req.TransactionID,
 		UserID:        int32(req.UserID),
-		Amount:        strconv.FormatFloat(req.Amount, 'f', -1, 64), // Convert float64 to string for Amount
-		Currency:      req.Currency,
-		Status:        int16(req.Status),
+		AmountMinor:   amount.Amount,
+		Currency:      amount.Currency,
+		Status:        status,
 		PaymentMethod: sql.NullString{
 			String: req.PaymentMethod,
 			Valid:  true,
 		},
 	}
 
-	transaction, err := ct.Queries.CreateTransaction(c.Request.Context(), params)
+	tx, err := ct.DB.BeginTx(c, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to begin tx", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := ct.Queries.WithTx(tx)
+
+	transaction, err := qtx.CreateTransaction(c.Request.Context(), params)
 	if err != nil {
 		slog.Error("Failed to create transaction", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create transaction"})
@@ -100,10 +389,108 @@ func (ct *TransactionsController) CreateTransactions(c *gin.Context) {
 	}
 
 	id, _ := transaction.LastInsertId()
+	if status == payments.StatusCompleted {
+		if err := ledger.RecordPayment(c, qtx, db.Transaction{
+			ID:            int32(id),
+			TransactionID: params.TransactionID,
+			UserID:        params.UserID,
+			AmountMinor:   params.AmountMinor,
+			Currency:      params.Currency,
+			Status:        status,
+			PaymentMethod: params.PaymentMethod,
+			Kind:          db.TransactionsKindPayment,
+		}, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
+			slog.Error("Failed to post transaction to ledger", "id", id, "error", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
+		slog.Error("Failed to commit transaction", "id", id, "error", err)
+		return
+	}
+	resp := TransactionResponse{
+		ID:            int32(id),
+		TransactionID: req.TransactionID,
+		UserID:        int32(req.UserID),
+		Amount:        amount,
+		Status:        payments.StatusName(status),
+		PaymentMethod: req.PaymentMethod,
+		Kind:          string(db.TransactionsKindPayment),
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"message":     "Transaction created",
 		"id":          id,
-		"transaction": transaction,
+		"transaction": resp,
 	})
 	slog.Info("Transaction created successfully", "id", id)
+	publishTransactionEvent(c, ct.Queries, "transaction.created", resp, nil)
+}
+
+type UpdateTransactionStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=completed failed reversed"`
+}
+
+// UpdateTransactionStatus moves a transaction along its lifecycle. Refund
+// states are set by CreateRefund instead.
+func (ct *TransactionsController) UpdateTransactionStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req UpdateTransactionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, _ := payments.ParseStatus(req.Status)
+
+	tx, err := ct.DB.BeginTx(c, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to begin tx", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := ct.Queries.WithTx(tx)
+
+	t, err := qtx.GetTransactionForUpdate(c, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update transaction"})
+		slog.Error("Failed to get transaction", "id", id, "error", err)
+		return
+	}
+	from := t.Status
+	if err := transitionTransaction(c, qtx, t.ID, from, to); err != nil {
+		if errors.Is(err, payments.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update transaction"})
+		slog.Error("Failed to update transaction status", "id", id, "error", err)
+		return
+	}
+	if err := ledger.RecordStatusChange(c, qtx, t, to, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update transaction"})
+		slog.Error("Failed to post transaction to ledger", "id", id, "error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update transaction"})
+		slog.Error("Failed to commit transaction status", "id", id, "error", err)
+		return
+	}
+
+	t.Status = to
+	resp := newTransactionResponse(t)
+	c.JSON(http.StatusOK, gin.H{"transaction": resp})
+	slog.Info("Transaction status updated", "id", id, "from", payments.StatusName(from),
+		"to", req.Status, "updated_by", middleware.ProfileID(c))
+	publishTransactionEvent(c, ct.Queries, "transaction."+req.Status, resp, gin.H{"previous_status": payments.StatusName(from)})
 }
@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"log/slog"
 	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/phone"
 	"tickets/publish"
+	"tickets/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,10 +23,14 @@ type UserController struct {
 }
 
 // Create User
+//
+// Users are profiles created by an admin, typically agents. They have no
+// password until they go through /auth/password/forgot.
 type CreateUserRequest struct {
-	FullName string `json:"full_name"`
-	Email    string `json:"email"`
-	// Password string `json:"password"`
+	FullName string `json:"full_name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Phone    string `json:"phone"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin agent customer"`
 }
 
 func (u *UserController) CreateUser(c *gin.Context) {
@@ -34,10 +42,33 @@ func (u *UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
+	req.Email = strings.ToLower(req.Email)
+	if req.Phone != "" {
+		p, err := phone.Normalize(req.Phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone number"})
+			return
+		}
+		req.Phone = p
+	}
+	if req.Role == "" {
+		req.Role = string(db.ProfilesRoleCustomer)
+	}
+
+	if _, err := u.Queries.GetProfileByEmail(c, utils.NullString(req.Email)); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
+		return
+	} else if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		slog.Error("Failed to check existing user", "error", err)
+		return
+	}
+
 	user, err := u.Queries.CreateUser(c, db.CreateUserParams{
-		FullName: req.FullName,
-		Email:    req.Email,
-		// Password: req.Password,
+		FullName: utils.NullString(req.FullName),
+		Email:    utils.NullString(req.Email),
+		Phone:    utils.NullString(req.Phone),
+		Role:     db.ProfilesRole(req.Role),
 	})
 
 	if err != nil {
@@ -46,11 +77,18 @@ func (u *UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
+	userID, err := user.LastInsertId()
+	if err != nil {
+		slog.Error("Failed to get last insert ID", "error", err)
+	}
+
 	payload := map[string]interface{}{
 		"type": "user.created",
-		"payload": map[string]string{
+		"payload": map[string]interface{}{
+			"id":        userID,
 			"email":     req.Email,
 			"full_name": req.FullName,
+			"role":      req.Role,
 		},
 	}
 	if data, err := json.Marshal(payload); err == nil {
@@ -59,14 +97,8 @@ func (u *UserController) CreateUser(c *gin.Context) {
 		slog.Error("Failed to marshal user created event", "error", err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
-
-	userID, err := user.LastInsertId()
-	if err != nil {
-		slog.Error("Failed to get last insert ID", "error", err)
-	} else {
-		slog.Info("User created successfully", "user_id", userID)
-	}
+	c.JSON(http.StatusOK, gin.H{"user": user, "id": userID})
+	slog.Info("User created successfully", "user_id", userID, "created_by", middleware.ProfileID(c))
 
 }
 
@@ -88,7 +120,7 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 
 func (u *UserController) UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	id, err := strconv.ParseInt(idParam, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
@@ -96,9 +128,9 @@ func (u *UserController) UpdateUser(c *gin.Context) {
 
 	var req struct {
 		FullName string `json:"full_name"`
-		Email    string `json:"email"`
-
-		// Password string `json:"password"`
+		Email    string `json:"email" binding:"omitempty,email"`
+		Phone    string `json:"phone"`
+		Role     string `json:"role" binding:"omitempty,oneof=admin agent customer"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -106,11 +138,55 @@ func (u *UserController) UpdateUser(c *gin.Context) {
 		slog.Error("Invalid request payload", "error", err)
 		return
 	}
+	// fields left out of the request keep their current value
+	if req.FullName == "" && req.Email == "" && req.Phone == "" && req.Role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+	req.Email = strings.ToLower(req.Email)
+	if req.Phone != "" {
+		p, err := phone.Normalize(req.Phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone number"})
+			return
+		}
+		req.Phone = p
+	}
+
+	if req.Email != "" {
+		if _, err := u.Queries.GetUserByEmailExcludingID(c, db.GetUserByEmailExcludingIDParams{
+			Email: utils.NullString(req.Email),
+			ID:    int32(id),
+		}); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
+			return
+		} else if err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+			slog.Error("Failed to check existing user", "error", err)
+			return
+		}
+	}
+
+	if req.Phone != "" {
+		if _, err := u.Queries.GetUserByPhoneExcludingID(c, db.GetUserByPhoneExcludingIDParams{
+			Phone: utils.NullString(req.Phone),
+			ID:    int32(id),
+		}); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "phone already in use"})
+			return
+		} else if err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+			slog.Error("Failed to check existing user", "error", err)
+			return
+		}
+	}
+
 	err = u.Queries.UpdateUser(c, db.UpdateUserParams{
-		ID:       id,
-		FullName: req.FullName,
-		Email:    req.Email,
-		// Password: req.Password,
+		ID:       int32(id),
+		FullName: utils.NullString(req.FullName),
+		Email:    utils.NullString(req.Email),
+		Phone:    utils.NullString(req.Phone),
+		Role:     db.NullProfilesRole{ProfilesRole: db.ProfilesRole(req.Role), Valid: req.Role != ""},
 	})
 
 	if err != nil {
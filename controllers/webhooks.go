@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/utils"
+	"tickets/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	Queries *db.Queries
+	DB      *sql.DB
+}
+
+// WebhookResponse never includes the secret; it is only shown when the
+// subscription is created or the secret rotated.
+type WebhookResponse struct {
+	ID                  int64      `json:"id"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Scope               string     `json:"scope"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func newWebhookResponse(s db.WebhookSubscription) WebhookResponse {
+	r := WebhookResponse{
+		ID:                  s.ID,
+		URL:                 s.Url,
+		Events:              webhook.SplitEvents(s.Events),
+		Scope:               string(s.Scope),
+		Active:              s.Active,
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		DisabledReason:      s.DisabledReason,
+		CreatedAt:           s.CreatedAt,
+	}
+	if s.DisabledAt.Valid {
+		r.DisabledAt = &s.DisabledAt.Time
+	}
+	return r
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int32     `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	RedeliveryOf   *int64     `json:"redelivery_of,omitempty"`
+	Payload        *string    `json:"payload,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newWebhookDeliveryResponse(d db.WebhookDelivery) WebhookDeliveryResponse {
+	r := WebhookDeliveryResponse{
+		ID:        d.ID,
+		EventID:   d.EventID,
+		EventType: d.EventType,
+		Status:    string(d.Status),
+		Attempts:  d.Attempts,
+		LastError: d.LastError,
+		CreatedAt: d.CreatedAt,
+	}
+	if d.Status == db.WebhookDeliveriesStatusPending {
+		r.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastAttemptAt.Valid {
+		r.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.ResponseStatus.Valid {
+		r.ResponseStatus = &d.ResponseStatus.Int32
+	}
+	if d.RedeliveryOf.Valid {
+		r.RedeliveryOf = &d.RedeliveryOf.Int64
+	}
+	return r
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=2048"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=2048"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Active *bool    `json:"active" binding:"required"`
+}
+
+// CreateWebhook subscribes a URL to events. Only staff and API keys manage
+// webhooks, and their subscriptions receive every matching event. The
+// signing secret is returned once.
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := webhook.CheckURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := webhook.ParseEvents(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, sealed, err := newWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to generate webhook secret", "error", err)
+		return
+	}
+	scope := db.WebhookSubscriptionsScopeAll
+	result, err := wc.Queries.CreateWebhookSubscription(c, db.CreateWebhookSubscriptionParams{
+		ProfileID: int32(middleware.ProfileID(c)),
+		Url:       req.URL,
+		Secret:    sealed,
+		Events:    webhook.JoinEvents(events),
+		Scope:     scope,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		slog.Error("Failed to create webhook subscription", "error", err)
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	sub, err := wc.Queries.GetWebhookSubscription(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		slog.Error("Failed to load webhook subscription", "id", id, "error", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"webhook": newWebhookResponse(sub), "secret": secret})
+	slog.Info("Webhook subscription created", "id", id, "events", sub.Events, "scope", scope,
+		"created_by", middleware.ProfileID(c))
+}
+
+func newWebhookSecret() (secret, sealed string, err error) {
+	secret, err = webhook.NewSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err = utils.Encrypt(secret)
+	return secret, sealed, err
+}
+
+// ListWebhooks lists every subscription.
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	subs, err := wc.Queries.ListWebhookSubscriptions(c, db.ListWebhookSubscriptionsParams{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		slog.Error("Failed to list webhook subscriptions", "error", err)
+		return
+	}
+
+	resp := make([]WebhookResponse, 0, len(subs))
+	for _, s := range subs {
+		resp = append(resp, newWebhookResponse(s))
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": resp})
+}
+
+// loadWebhook fetches the :id subscription, answering 404 if it does not
+// exist.
+func (wc *WebhookController) loadWebhook(c *gin.Context) (db.WebhookSubscription, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return db.WebhookSubscription{}, false
+	}
+	sub, err := wc.Queries.GetWebhookSubscription(c, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return db.WebhookSubscription{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook"})
+		slog.Error("Failed to get webhook subscription", "id", id, "error", err)
+		return db.WebhookSubscription{}, false
+	}
+	return sub, true
+}
+
+func (wc *WebhookController) GetWebhook(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookResponse(sub))
+}
+
+// UpdateWebhook replaces the URL, events and active flag. Saving a disabled
+// subscription with active true re-enables it with its failure count reset;
+// deliveries that were waiting go out again.
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := webhook.CheckURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := webhook.ParseEvents(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := wc.Queries.UpdateWebhookSubscription(c, db.UpdateWebhookSubscriptionParams{
+		Url:    req.URL,
+		Events: webhook.JoinEvents(events),
+		Active: *req.Active,
+		ID:     sub.ID,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		slog.Error("Failed to update webhook subscription", "id", sub.ID, "error", err)
+		return
+	}
+	sub, err = wc.Queries.GetWebhookSubscription(c, sub.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		slog.Error("Failed to load webhook subscription", "id", sub.ID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookResponse(sub))
+	slog.Info("Webhook subscription updated", "id", sub.ID, "active", sub.Active, "updated_by", middleware.ProfileID(c))
+}
+
+// RotateWebhookSecret replaces the signing secret and returns the new one.
+// Deliveries are signed with the new secret from their next attempt.
+func (wc *WebhookController) RotateWebhookSecret(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	secret, sealed, err := newWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("Failed to generate webhook secret", "error", err)
+		return
+	}
+	if err := wc.Queries.UpdateWebhookSecret(c, db.UpdateWebhookSecretParams{Secret: sealed, ID: sub.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate secret"})
+		slog.Error("Failed to rotate webhook secret", "id", sub.ID, "error", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": newWebhookResponse(sub), "secret": secret})
+	slog.Info("Webhook secret rotated", "id", sub.ID, "rotated_by", middleware.ProfileID(c))
+}
+
+// DeleteWebhook removes a subscription and its delivery log.
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if err := wc.Queries.DeleteWebhookSubscription(c, sub.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+		slog.Error("Failed to delete webhook subscription", "id", sub.ID, "error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	slog.Info("Webhook subscription deleted", "id", sub.ID, "deleted_by", middleware.ProfileID(c))
+}
+
+// ListWebhookDeliveries returns a subscription's delivery log, newest first,
+// optionally only ?status=pending|succeeded|failed.
+func (wc *WebhookController) ListWebhookDeliveries(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	var status db.NullWebhookDeliveriesStatus
+	if v := c.Query("status"); v != "" {
+		s := db.WebhookDeliveriesStatus(v)
+		if s != db.WebhookDeliveriesStatusPending && s != db.WebhookDeliveriesStatusSucceeded && s != db.WebhookDeliveriesStatusFailed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, succeeded or failed"})
+			return
+		}
+		status = db.NullWebhookDeliveriesStatus{WebhookDeliveriesStatus: s, Valid: true}
+	}
+	limit, offset := ticketPage(c)
+
+	deliveries, err := wc.Queries.ListWebhookDeliveries(c, db.ListWebhookDeliveriesParams{
+		SubscriptionID: sub.ID,
+		Status:         status,
+		Limit:          limit,
+		Offset:         offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deliveries"})
+		slog.Error("Failed to list webhook deliveries", "id", sub.ID, "error", err)
+		return
+	}
+
+	resp := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(d))
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": resp})
+}
+
+// loadDelivery fetches :delivery_id, which must belong to sub.
+func (wc *WebhookController) loadDelivery(c *gin.Context, sub db.WebhookSubscription) (db.WebhookDelivery, bool) {
+	id, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return db.WebhookDelivery{}, false
+	}
+	d, err := wc.Queries.GetWebhookDelivery(c, id)
+	if err == nil && d.SubscriptionID != sub.ID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return db.WebhookDelivery{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get delivery"})
+		slog.Error("Failed to get webhook delivery", "id", id, "error", err)
+		return db.WebhookDelivery{}, false
+	}
+	return d, true
+}
+
+// GetWebhookDelivery returns one delivery including the payload sent.
+func (wc *WebhookController) GetWebhookDelivery(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	d, ok := wc.loadDelivery(c, sub)
+	if !ok {
+		return
+	}
+	resp := newWebhookDeliveryResponse(d)
+	resp.Payload = &d.Payload
+	c.JSON(http.StatusOK, resp)
+}
+
+// RedeliverWebhook queues the same event again as a new delivery, which
+// keeps the original in the log. The event id is unchanged so receivers
+// can recognise the repeat.
+func (wc *WebhookController) RedeliverWebhook(c *gin.Context) {
+	sub, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+	d, ok := wc.loadDelivery(c, sub)
+	if !ok {
+		return
+	}
+
+	result, err := wc.Queries.CreateWebhookDelivery(c, db.CreateWebhookDeliveryParams{
+		SubscriptionID: sub.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOf:   sql.NullInt64{Int64: d.ID, Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to redeliver"})
+		slog.Error("Failed to create webhook redelivery", "id", d.ID, "error", err)
+		return
+	}
+	id, _ := result.LastInsertId()
+	redelivery, err := wc.Queries.GetWebhookDelivery(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to redeliver"})
+		slog.Error("Failed to load webhook redelivery", "id", id, "error", err)
+		return
+	}
+
+	resp := gin.H{"delivery": newWebhookDeliveryResponse(redelivery)}
+	if !sub.Active {
+		resp["warning"] = "the webhook is disabled; the delivery is sent once it is enabled again"
+	}
+	c.JSON(http.StatusAccepted, resp)
+	slog.Info("Webhook redelivery queued", "id", id, "original_id", d.ID, "subscription_id", sub.ID,
+		"requested_by", middleware.ProfileID(c))
+}
@@ -0,0 +1,13 @@
+-- Columns for password reset and session revocation, on databases created
+-- before them. Run before 001_unify_identity.sql.
+--
+-- token_version is copied into every JWT and bumped when the password
+-- changes, which invalidates the tokens issued before. otp_codes.purpose
+-- keeps login codes and password reset codes apart; existing codes were all
+-- issued for login.
+
+ALTER TABLE profiles
+  ADD COLUMN token_version INT NOT NULL DEFAULT 0 AFTER full_name;
+
+ALTER TABLE otp_codes
+  ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'login' AFTER otp_code;
@@ -0,0 +1,59 @@
+-- Merge the users table into profiles so there is a single identity model.
+--
+-- Before this migration tickets.created_by, tickets.assigned_to and
+-- transactions.user_id held users.id values. Users are copied into profiles
+-- and those columns are rewritten to the new profile ids. Run once, inside
+-- a maintenance window.
+
+ALTER TABLE profiles
+  MODIFY phone VARCHAR(40) NULL,
+  ADD COLUMN email VARCHAR(255) NULL UNIQUE AFTER phone,
+  ADD COLUMN role ENUM('admin','agent','customer') NOT NULL DEFAULT 'customer' AFTER full_name;
+
+-- Profiles have no email before this migration, so no user can be matched
+-- to an existing profile: every user is copied. Users whose emails differ
+-- only in case or spacing become one profile, with the email trimmed and
+-- lowercased as the API stores it.
+CREATE TEMPORARY TABLE legacy_users AS
+SELECT u.id AS user_id, LOWER(TRIM(u.email)) AS email
+FROM users u;
+
+-- One profile per email, with the earliest user's name and the highest role
+-- (MIN compares enums as strings, and admin < agent < customer).
+-- users.password was never filled, so these profiles have no password and
+-- no phone: an admin adds their phone through POST /updateuser/:id, after
+-- which they set a password with /auth/password/forgot.
+INSERT INTO profiles (email, full_name, role, password_hash, created_at)
+SELECT l.email,
+       LEFT(SUBSTRING(MIN(CONCAT(LPAD(u.id, 20, '0'), u.full_name)), 21), 100),
+       MIN(COALESCE(u.role, 'customer')),
+       '',
+       MIN(COALESCE(u.created_at, NOW()))
+FROM legacy_users l
+JOIN users u ON u.id = l.user_id
+GROUP BY l.email;
+
+CREATE TEMPORARY TABLE legacy_user_profiles AS
+SELECT l.user_id, p.id AS profile_id
+FROM legacy_users l
+JOIN profiles p ON p.email = l.email;
+
+UPDATE tickets t
+JOIN legacy_user_profiles m ON m.user_id = t.created_by
+SET t.created_by = m.profile_id;
+
+UPDATE tickets t
+JOIN legacy_user_profiles m ON m.user_id = t.assigned_to
+SET t.assigned_to = m.profile_id;
+
+UPDATE transactions tr
+JOIN legacy_user_profiles m ON m.user_id = tr.user_id
+SET tr.user_id = m.profile_id;
+
+DROP TEMPORARY TABLE legacy_user_profiles;
+DROP TEMPORARY TABLE legacy_users;
+
+ALTER TABLE profiles
+  ADD CONSTRAINT chk_profiles_login CHECK (phone IS NOT NULL OR email IS NOT NULL);
+
+DROP TABLE users;
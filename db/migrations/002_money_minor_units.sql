@@ -0,0 +1,27 @@
+-- Store transaction amounts as integer minor units instead of DECIMAL(10,2),
+-- which could not hold three-decimal currencies such as KWD and rounded
+-- whatever the float64 request amount happened to be.
+--
+-- Exponents follow money/currency.go; anything else is converted with two
+-- decimals. The final SELECT lists rows whose currency is not even a
+-- three-letter code so they can be fixed by hand.
+
+UPDATE transactions SET currency = UPPER(TRIM(currency));
+
+ALTER TABLE transactions ADD COLUMN amount_minor BIGINT NULL AFTER amount;
+
+UPDATE transactions SET amount_minor = CASE
+  WHEN currency IN ('BIF','CLP','DJF','GNF','ISK','JPY','KMF','KRW','PYG',
+                    'RWF','UGX','UYI','VND','VUV','XAF','XOF','XPF') THEN ROUND(amount)
+  WHEN currency IN ('BHD','IQD','JOD','KWD','LYD','OMR','TND') THEN ROUND(amount * 1000)
+  WHEN currency IN ('CLF','UYW') THEN ROUND(amount * 10000)
+  ELSE ROUND(amount * 100)
+END;
+
+ALTER TABLE transactions
+  MODIFY amount_minor BIGINT NOT NULL,
+  DROP COLUMN amount;
+
+SELECT id, transaction_id, currency
+FROM transactions
+WHERE CHAR_LENGTH(currency) <> 3 OR currency REGEXP '[^A-Z]';
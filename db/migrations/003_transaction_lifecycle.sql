@@ -0,0 +1,15 @@
+-- Refunds become their own transaction rows linked to the payment they
+-- refund, and status is limited to the lifecycle in payments/status.go:
+-- 1 pending, 2 completed, 3 failed, 4 refunded, 5 partially_refunded, 6 reversed.
+
+ALTER TABLE transactions
+  ADD COLUMN kind ENUM('payment', 'refund') NOT NULL DEFAULT 'payment' AFTER payment_method,
+  ADD COLUMN parent_id INT NULL AFTER kind,
+  ADD FOREIGN KEY (parent_id) REFERENCES transactions(id);
+
+-- status used to be whatever the client sent. Anything outside the known
+-- states is listed here and must be fixed by hand before the new API will
+-- move those rows.
+SELECT id, transaction_id, status
+FROM transactions
+WHERE status NOT BETWEEN 1 AND 6;
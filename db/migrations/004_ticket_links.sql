@@ -0,0 +1,10 @@
+-- Tickets can reference the customer and the transaction they are about.
+-- payment_dispute tickets ("I paid but didn't get service") must reference
+-- a transaction; this is enforced by the API, not the schema.
+
+ALTER TABLE tickets
+  ADD COLUMN type ENUM('general', 'payment_dispute') NOT NULL DEFAULT 'general',
+  ADD COLUMN customer_id BIGINT NULL DEFAULT NULL,
+  ADD COLUMN transaction_id INT NULL DEFAULT NULL,
+  ADD FOREIGN KEY (customer_id) REFERENCES customers(id),
+  ADD FOREIGN KEY (transaction_id) REFERENCES transactions(id);
@@ -0,0 +1,15 @@
+-- Phones are stored in E.164 form (+254712345678) so one number cannot be
+-- entered as several, which let duplicates past the unique keys and broke
+-- lookups by phone.
+--
+-- Existing rows are normalized by the Go parser the API uses, not in SQL:
+-- run `make phone-migrate` to see the changes and any conflicts, then
+-- `make phone-migrate APPLY=1`. Merge or fix what it reports and repeat
+-- until it exits 0, then apply this file, which rejects any other form.
+-- Erased customers keep their "erased-<id>" placeholder.
+
+ALTER TABLE profiles
+  ADD CONSTRAINT chk_profiles_phone_e164 CHECK (phone IS NULL OR phone REGEXP '^[+][1-9][0-9]{6,14}$');
+
+ALTER TABLE customers
+  ADD CONSTRAINT chk_customers_phone_e164 CHECK (phone_number REGEXP '^[+][1-9][0-9]{6,14}$' OR phone_number LIKE 'erased-%');
@@ -0,0 +1,45 @@
+-- Teams of agents (billing, technical, onboarding, ...) and the rules that
+-- route a new ticket to one. Rules are tried in priority order, lowest
+-- first; the first whose conditions all hold picks the ticket's team.
+-- Customers gain a segment for rules to match on.
+
+CREATE TABLE teams (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(100) NOT NULL UNIQUE,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE team_members (
+  team_id BIGINT NOT NULL,
+  profile_id INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (team_id, profile_id),
+  FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_team_members_profile ON team_members(profile_id);
+
+CREATE TABLE routing_rules (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  priority INT NOT NULL DEFAULT 0,
+  team_id BIGINT NOT NULL,
+  ticket_priority VARCHAR(50) NULL DEFAULT NULL,
+  ticket_type ENUM('general', 'payment_dispute') NULL DEFAULT NULL,
+  customer_segment VARCHAR(50) NULL DEFAULT NULL,
+  keywords VARCHAR(1000) NOT NULL DEFAULT '',
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_routing_rules_priority ON routing_rules(active, priority, id);
+
+ALTER TABLE tickets
+  ADD COLUMN team_id BIGINT NULL DEFAULT NULL,
+  ADD FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL;
+CREATE INDEX idx_tickets_team ON tickets(team_id);
+
+ALTER TABLE customers
+  ADD COLUMN segment VARCHAR(50) NOT NULL DEFAULT '';
@@ -0,0 +1,36 @@
+-- Automatic assignment of routed tickets to agents. A team's
+-- assignment_strategy names how the worker picks one of its available
+-- members when a ticket is created: round_robin, least_open or
+-- skill_match; manual leaves tickets for someone to assign.
+--
+-- agent_settings holds an agent's availability and skills. An agent without
+-- a row is offline and never assigned. Every decision, including finding
+-- no one, is logged in ticket_assignments with the candidates considered.
+
+ALTER TABLE teams
+  ADD COLUMN assignment_strategy VARCHAR(30) NOT NULL DEFAULT 'manual';
+
+CREATE TABLE agent_settings (
+  profile_id INT PRIMARY KEY,
+  status ENUM('online', 'away', 'offline') NOT NULL DEFAULT 'offline',
+  max_concurrent INT NOT NULL DEFAULT 10,
+  skills VARCHAR(1000) NOT NULL DEFAULT '',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE ticket_assignments (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  ticket_id BIGINT NOT NULL,
+  team_id BIGINT NULL DEFAULT NULL,
+  strategy VARCHAR(30) NOT NULL,
+  assigned_to INT NULL DEFAULT NULL,
+  reason VARCHAR(255) NOT NULL,
+  candidates TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_ticket_assignments_ticket ON ticket_assignments(ticket_id);
+CREATE INDEX idx_ticket_assignments_team ON ticket_assignments(team_id, id);
+CREATE INDEX idx_tickets_assigned ON tickets(assigned_to, status);
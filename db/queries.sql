@@ -1,6 +1,6 @@
 -- name: CreateTicket :execresult
-INSERT INTO tickets (title, description, created_by, priority, status)
-VALUES (?, ?, ?, ?, ?);
+INSERT INTO tickets (title, description, created_by, priority, status, type, customer_id, transaction_id, team_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
 
 -- name: ListTickets :many
 SELECT
@@ -12,11 +12,39 @@ SELECT
     created_by,
     assigned_to,
     created_at,
-    updated_at
+    updated_at,
+    type,
+    customer_id,
+    transaction_id,
+    team_id
 FROM tickets
 ORDER BY created_at DESC
 LIMIT ? OFFSET ?;
 
+-- name: ListOwnTickets :many
+-- what GetTicket shows a customer: tickets they raised, or about a customer
+-- with their email or phone
+SELECT t.* FROM tickets t
+JOIN profiles p ON p.id = sqlc.arg(profile_id)
+LEFT JOIN customers cu ON cu.id = t.customer_id
+WHERE t.created_by = p.id
+   OR LOWER(cu.email) = LOWER(p.email)
+   OR cu.phone_number = p.phone
+ORDER BY t.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListTicketsByCustomer :many
+SELECT * FROM tickets
+WHERE customer_id = ?
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListTicketsByTransaction :many
+SELECT * FROM tickets
+WHERE transaction_id = ?
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?;
+
 
 -- name: GetTicket :one
 SELECT * FROM tickets
@@ -37,107 +65,953 @@ WHERE title = ? AND created_by = ?
 LIMIT 1;
 
 
+-- users are profiles managed by an admin; they have no password until
+-- they go through the reset flow
 -- name: CreateUser :execresult
-INSERT INTO users (full_name, email,  role)
-VALUES (?, ?, ?);
--- name: GetUserByEmail :one
-SELECT * FROM users
-WHERE email = ? LIMIT 1;
--- name: GetUserByID :one
-SELECT * FROM users
-WHERE id = ? LIMIT 1;
+INSERT INTO profiles (full_name, email, phone, role, password_hash)
+VALUES (?, ?, ?, ?, '');
 
 -- name: ListUsers :many
 SELECT
     id,
-    full_name,
+    phone,
     email,
+    full_name,
     role,
     created_at
-FROM users
+FROM profiles
 ORDER BY created_at DESC
 LIMIT ? OFFSET ?;
 
 -- name: UpdateUser :exec
-UPDATE users
+UPDATE profiles
 SET
-  full_name = sqlc.arg(full_name),
-  email     = sqlc.arg(email),
-  role      = sqlc.arg(role)
+  full_name = COALESCE(sqlc.narg(full_name), full_name),
+  email     = COALESCE(sqlc.narg(email), email),
+  phone     = COALESCE(sqlc.narg(phone), phone),
+  role      = COALESCE(sqlc.narg(role), role)
 WHERE id = sqlc.arg(id);
 
 -- name: GetUserByEmailExcludingID :one
-SELECT * FROM users
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
 WHERE email = ? AND id != ?
 LIMIT 1;
 
+-- name: GetUserByPhoneExcludingID :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE phone = ? AND id != ?
+LIMIT 1;
+
 -- name: CreateTransaction :execresult
-INSERT INTO transactions (transaction_id, user_id,amount,currency,status,payment_method)
+INSERT INTO transactions (transaction_id, user_id,amount_minor,currency,status,payment_method)
 VALUES(?,?,?,?,?,?);
 
 -- name: ListTransactions :many
-SELECT
-id,
-transaction_id,
-amount,
-currency,
-payment_method
-FROM transactions
-ORDER BY created_at DESC
+-- every filter is optional; amounts are in minor units of the currency
+-- filter and created_to is exclusive
+SELECT * FROM transactions
+WHERE (sqlc.narg(user_id) IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(currency) IS NULL OR currency = sqlc.narg(currency))
+  AND (sqlc.narg(payment_method) IS NULL OR payment_method = sqlc.narg(payment_method))
+  AND (sqlc.narg(kind) IS NULL OR kind = sqlc.narg(kind))
+  AND (sqlc.narg(min_amount) IS NULL OR amount_minor >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount) IS NULL OR amount_minor <= sqlc.narg(max_amount))
+  AND (sqlc.narg(created_from) IS NULL OR created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_to) IS NULL OR created_at < sqlc.narg(created_to))
+ORDER BY created_at DESC, id DESC
 LIMIT ? OFFSET ?;
 
+-- name: SumTransactionsByCurrency :many
+-- totals for the same filters as ListTransactions
+SELECT currency, COUNT(*) AS count, CAST(COALESCE(SUM(amount_minor), 0) AS SIGNED) AS total_minor
+FROM transactions
+WHERE (sqlc.narg(user_id) IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(currency) IS NULL OR currency = sqlc.narg(currency))
+  AND (sqlc.narg(payment_method) IS NULL OR payment_method = sqlc.narg(payment_method))
+  AND (sqlc.narg(kind) IS NULL OR kind = sqlc.narg(kind))
+  AND (sqlc.narg(min_amount) IS NULL OR amount_minor >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount) IS NULL OR amount_minor <= sqlc.narg(max_amount))
+  AND (sqlc.narg(created_from) IS NULL OR created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_to) IS NULL OR created_at < sqlc.narg(created_to))
+GROUP BY currency
+ORDER BY currency;
+
+-- name: ExportTransactions :many
+-- keyset pages for streaming exports: pass the last id of the previous page
+SELECT * FROM transactions
+WHERE (sqlc.narg(user_id) IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(currency) IS NULL OR currency = sqlc.narg(currency))
+  AND (sqlc.narg(payment_method) IS NULL OR payment_method = sqlc.narg(payment_method))
+  AND (sqlc.narg(kind) IS NULL OR kind = sqlc.narg(kind))
+  AND (sqlc.narg(min_amount) IS NULL OR amount_minor >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount) IS NULL OR amount_minor <= sqlc.narg(max_amount))
+  AND (sqlc.narg(created_from) IS NULL OR created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_to) IS NULL OR created_at < sqlc.narg(created_to))
+  AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT ?;
+
 -- name: GetTransanctionByID :one
 SELECT *FROM transactions
 WHERE id = ?
 LIMIT 1;
 
 -- name: CreateCustomer :execresult
-INSERT INTO customers (full_name, email, phone_number)
-VALUES (?, ?, ?);
+INSERT INTO customers (full_name, email, phone_number, segment)
+VALUES (?, ?, ?, ?);
+
+-- name: GetCustomer :one
+SELECT * FROM customers
+WHERE id = ? LIMIT 1;
 
 -- name: GetCustomerByEmail :one
 SELECT * FROM customers
 WHERE email = ? LIMIT 1;
 
+-- name: GetCustomerByPhone :one
+SELECT * FROM customers
+WHERE phone_number = ? LIMIT 1;
+
 -- name: GetCustomers :many
-SELECT * FROM customers LIMIT ? OFFSET ?;
+-- search is a LIKE pattern matched against name, email and phone
+SELECT * FROM customers
+WHERE sqlc.narg(search) IS NULL
+   OR full_name LIKE sqlc.narg(search)
+   OR email LIKE sqlc.narg(search)
+   OR phone_number LIKE sqlc.narg(search)
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountCustomers :one
+SELECT COUNT(*) FROM customers
+WHERE sqlc.narg(search) IS NULL
+   OR full_name LIKE sqlc.narg(search)
+   OR email LIKE sqlc.narg(search)
+   OR phone_number LIKE sqlc.narg(search);
+
+-- name: UpdateCustomer :exec
+UPDATE customers
+SET full_name = ?, email = ?, phone_number = ?, segment = ?
+WHERE id = ?;
+
+-- name: DeleteCustomer :execrows
+DELETE FROM customers
+WHERE id = ?;
 
 
 
 -- db/queries.sql
 
 -- name: GetProfileByPhone :one
-SELECT id, phone, password_hash, full_name, created_at, updated_at
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
 FROM profiles
 WHERE phone = ?;
 
+-- name: GetProfileByEmail :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE email = ?;
+
+-- name: GetProfileByID :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE id = ?;
+
 -- name: CreateOTP :execresult
-INSERT INTO otp_codes (profile_id, otp_code, expires_at)
-VALUES (?, ?, ?);
+INSERT INTO otp_codes (profile_id, otp_code, purpose, expires_at)
+VALUES (?, ?, ?, ?);
 SELECT LAST_INSERT_ID() as id;
 
 -- name: GetLatestOTPByProfileID :one
-SELECT id, profile_id, otp_code, expires_at, verified, attempts, created_at
+SELECT id, profile_id, otp_code, purpose, expires_at, verified, attempts, created_at
 FROM otp_codes
-WHERE profile_id = ?
+WHERE profile_id = ? AND purpose = ?
 ORDER BY created_at DESC
 LIMIT 1;
 
--- name: MarkOTPVerified :exec
+-- name: MarkOTPVerified :execrows
 UPDATE otp_codes
 SET verified = TRUE
-WHERE id = ?;
+WHERE id = ? AND verified = FALSE;
 
--- name: IncrementOTPAttempts :exec
+-- name: IncrementOTPAttempts :execrows
 UPDATE otp_codes
 SET attempts = attempts + 1
-WHERE id = ?;
+WHERE id = sqlc.arg(id) AND verified = FALSE AND attempts < sqlc.arg(max_attempts);
 
 -- name: DeleteExpiredOTPs :exec
 DELETE FROM otp_codes
 WHERE expires_at < NOW();
 
 -- name: CreateProfile :execresult
-INSERT INTO profiles (full_name, phone, password_hash)
+INSERT INTO profiles (full_name, phone, email, password_hash)
+VALUES (?, ?, ?, ?);
+
+-- name: UpdateProfilePassword :exec
+-- bumping token_version revokes every JWT issued before the change
+UPDATE profiles
+SET password_hash = ?, token_version = token_version + 1
+WHERE id = ?;
+
+-- name: UpsertTOTPSecret :exec
+-- re-enrolling replaces an unconfirmed secret and resets its state
+INSERT INTO totp_secrets (profile_id, secret_enc)
+VALUES (?, ?)
+ON DUPLICATE KEY UPDATE
+  secret_enc = VALUES(secret_enc),
+  confirmed_at = NULL,
+  last_used_step = 0;
+
+-- name: GetTOTPSecret :one
+SELECT * FROM totp_secrets
+WHERE profile_id = ? LIMIT 1;
+
+-- name: ConfirmTOTPSecret :exec
+UPDATE totp_secrets
+SET confirmed_at = NOW()
+WHERE profile_id = ?;
+
+-- name: UpdateTOTPLastUsedStep :execrows
+-- only moves forward, so a code can't be replayed within its window
+UPDATE totp_secrets
+SET last_used_step = sqlc.arg(step)
+WHERE profile_id = sqlc.arg(profile_id) AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE profile_id = ?;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (profile_id, code_hash)
+VALUES (?, ?);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE profile_id = ? AND code_hash = ? AND used_at IS NULL;
+
+-- name: GetProfileLockout :one
+SELECT * FROM profile_lockouts
+WHERE profile_id = ? LIMIT 1;
+
+-- name: RecordLoginFailure :exec
+INSERT INTO profile_lockouts (profile_id, failed_attempts, last_failed_at)
+VALUES (?, 1, ?)
+ON DUPLICATE KEY UPDATE
+  failed_attempts = failed_attempts + 1,
+  last_failed_at = VALUES(last_failed_at);
+
+-- name: LockProfile :exec
+UPDATE profile_lockouts
+SET locked_until = ?
+WHERE profile_id = ?;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM profile_lockouts
+WHERE profile_id = ?;
+
+-- name: CreateLoginEvent :exec
+INSERT INTO login_events (profile_id, identifier, ip, user_agent, success, reason)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: CountLoginSources :one
+-- how often this profile has logged in successfully from the ip and the device
+SELECT
+  COUNT(*) AS total,
+  CAST(COALESCE(SUM(ip = sqlc.arg(ip)), 0) AS SIGNED) AS from_ip,
+  CAST(COALESCE(SUM(user_agent = sqlc.arg(user_agent)), 0) AS SIGNED) AS from_device
+FROM login_events
+WHERE profile_id = sqlc.arg(profile_id) AND success = TRUE;
+
+-- name: CreateAPIKey :execresult
+INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetAPIKeyByPrefix :one
+SELECT sqlc.embed(k), p.role AS creator_role
+FROM api_keys k
+JOIN profiles p ON p.id = k.created_by
+WHERE k.prefix = ? LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT id, name, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at
+FROM api_keys
+ORDER BY created_at DESC;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = ?
+WHERE id = ? AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = ?
+WHERE id = ?;
+
+-- name: TransitionTransactionStatus :execrows
+-- compare-and-set so that two concurrent updates cannot both apply
+UPDATE transactions
+SET status = sqlc.arg(new_status)
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(old_status);
+
+-- name: GetTransactionForUpdate :one
+SELECT * FROM transactions
+WHERE id = ?
+FOR UPDATE;
+
+-- name: CreateRefund :execresult
+INSERT INTO transactions (transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id)
+VALUES (?, ?, ?, ?, ?, ?, 'refund', ?);
+
+-- name: SumRefunds :one
+SELECT CAST(COALESCE(SUM(amount_minor), 0) AS SIGNED) AS refunded
+FROM transactions
+WHERE parent_id = ? AND kind = 'refund';
+
+-- name: ListRefunds :many
+SELECT * FROM transactions
+WHERE parent_id = ? AND kind = 'refund'
+ORDER BY id;
+
+-- name: CreateMpesaStkRequest :exec
+INSERT INTO mpesa_stk_requests (transaction_id, merchant_request_id, checkout_request_id, phone)
+VALUES (?, ?, ?, ?);
+
+-- name: GetMpesaStkRequestByCheckoutID :one
+SELECT * FROM mpesa_stk_requests
+WHERE checkout_request_id = ? LIMIT 1;
+
+-- name: CompleteMpesaStkRequest :execrows
+-- only the first callback for a request wins; Daraja may deliver it more than once
+UPDATE mpesa_stk_requests
+SET result_code = ?, result_desc = ?, mpesa_receipt = ?, mismatch = ?, completed_at = ?
+WHERE id = ? AND completed_at IS NULL;
+
+-- name: CreateIdempotencyKey :execrows
+-- affects no rows when the key is already taken in this scope
+INSERT IGNORE INTO idempotency_keys (scope, idempotency_key, request_path, fingerprint, expires_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE scope = ? AND idempotency_key = ? LIMIT 1;
+
+-- name: SaveIdempotentResponse :exec
+UPDATE idempotency_keys
+SET status_code = ?, content_type = ?, response_body = ?
+WHERE id = ?;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE id = ?;
+
+-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE expires_at < ?;
+
+-- name: EnsureLedgerAccount :exec
+-- no-op if an account with this code already exists
+INSERT IGNORE INTO ledger_accounts (code, name, type, currency, profile_id)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetLedgerAccountByCode :one
+SELECT * FROM ledger_accounts
+WHERE code = ? LIMIT 1;
+
+-- name: GetLedgerAccount :one
+SELECT * FROM ledger_accounts
+WHERE id = ? LIMIT 1;
+
+-- name: ListLedgerAccounts :many
+SELECT * FROM ledger_accounts
+WHERE sqlc.narg(profile_id) IS NULL OR profile_id = sqlc.narg(profile_id)
+ORDER BY code;
+
+-- name: CreateJournalEntry :execresult
+INSERT INTO journal_entries (transaction_id, description, occurred_at)
+VALUES (?, ?, ?);
+
+-- name: CreatePosting :exec
+INSERT INTO postings (entry_id, account_id, amount_minor, currency)
+VALUES (?, ?, ?, ?);
+
+-- name: GetAccountBalance :one
+-- balance of an account from all entries that occurred at or before as_of
+SELECT CAST(COALESCE(SUM(p.amount_minor), 0) AS SIGNED) AS balance
+FROM postings p
+JOIN journal_entries e ON e.id = p.entry_id
+WHERE p.account_id = ? AND e.occurred_at <= ?;
+
+-- name: ListUnbalancedEntries :many
+SELECT p.entry_id, p.currency, CAST(SUM(p.amount_minor) AS SIGNED) AS total
+FROM postings p
+GROUP BY p.entry_id, p.currency
+HAVING SUM(p.amount_minor) <> 0;
+
+-- name: ListEntriesWithTooFewPostings :many
+SELECT e.id, COUNT(p.id) AS postings
+FROM journal_entries e
+LEFT JOIN postings p ON p.entry_id = e.id
+GROUP BY e.id
+HAVING COUNT(p.id) < 2;
+
+-- name: ListPostingCurrencyMismatches :many
+SELECT p.id, p.entry_id, p.currency, a.currency AS account_currency
+FROM postings p
+JOIN ledger_accounts a ON a.id = p.account_id
+WHERE p.currency <> a.currency;
+
+-- name: ListUnpostedTransactions :many
+-- settled transactions that have no journal entry
+SELECT t.id, t.transaction_id, t.kind, t.status
+FROM transactions t
+LEFT JOIN journal_entries e ON e.transaction_id = t.id
+WHERE e.id IS NULL
+  AND (t.kind = 'refund' OR t.status IN (2, 4, 5, 6));
+
+-- name: ListTransactionsForReconciliation :many
+SELECT t.id, t.transaction_id, t.amount_minor, t.currency, t.status, t.kind, t.created_at, m.mpesa_receipt
+FROM transactions t
+LEFT JOIN mpesa_stk_requests m ON m.transaction_id = t.id
+WHERE t.created_at >= sqlc.arg(from_time) AND t.created_at <= sqlc.arg(to_time);
+
+-- name: CreateReconciliationRun :execresult
+INSERT INTO reconciliation_runs (format, filename, line_count, period_start, period_end, window_seconds, created_by)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: CreateReconciliationItem :exec
+INSERT INTO reconciliation_items (run_id, status, line_number, reference, amount_minor, currency, occurred_at, transaction_id, note)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetReconciliationRun :one
+SELECT * FROM reconciliation_runs
+WHERE id = ? LIMIT 1;
+
+-- name: ListReconciliationRuns :many
+SELECT * FROM reconciliation_runs
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListReconciliationItems :many
+SELECT * FROM reconciliation_items
+WHERE run_id = sqlc.arg(run_id) AND (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+ORDER BY id;
+
+-- name: CountReconciliationItems :many
+SELECT status, COUNT(*) AS total
+FROM reconciliation_items
+WHERE run_id = ?
+GROUP BY status;
+
+-- name: CreateWebhookSubscription :execresult
+INSERT INTO webhook_subscriptions (profile_id, url, secret, events, scope)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = ? LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE (sqlc.narg(profile_id) IS NULL OR profile_id = sqlc.narg(profile_id))
+ORDER BY id;
+
+-- name: ListActiveWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE active = TRUE;
+
+-- name: UpdateWebhookSubscription :exec
+-- an update also gives a disabled subscription a fresh start
+UPDATE webhook_subscriptions
+SET url = ?, events = ?, active = ?, consecutive_failures = 0, disabled_at = NULL, disabled_reason = ''
+WHERE id = ?;
+
+-- name: UpdateWebhookSecret :exec
+UPDATE webhook_subscriptions
+SET secret = ?
+WHERE id = ?;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = ?;
+
+-- name: ResetWebhookFailures :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = 0
+WHERE id = ?;
+
+-- name: IncrementWebhookFailures :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = consecutive_failures + 1
+WHERE id = ?;
+
+-- name: DisableWebhookSubscription :execrows
+-- affects no rows unless the subscription is active and has failed often enough
+UPDATE webhook_subscriptions
+SET active = FALSE, disabled_at = ?, disabled_reason = ?
+WHERE id = ? AND active = TRUE AND consecutive_failures >= ?;
+
+-- name: CreateWebhookDelivery :execresult
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, next_attempt_at, redelivery_of)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = ? LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = sqlc.arg(subscription_id)
+  AND (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListDueWebhookDeliveries :many
+-- deliveries of disabled subscriptions wait until it is enabled again
+SELECT d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, d.next_attempt_at, s.url, s.secret
+FROM webhook_deliveries d
+JOIN webhook_subscriptions s ON s.id = d.subscription_id
+WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND s.active = TRUE
+ORDER BY d.next_attempt_at
+LIMIT ?;
+
+-- name: ClaimWebhookDelivery :execrows
+-- pushes next_attempt_at out as a lease so only one worker sends a delivery
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id = sqlc.arg(id) AND status = 'pending' AND next_attempt_at = sqlc.arg(next_attempt_at);
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_attempt_at = ?, response_status = ?, last_error = ?
+WHERE id = ?;
+
+-- name: CreateEvent :execresult
+INSERT INTO event_log (stream, type, owner_id, subject_id, payload)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: DeleteEventsBefore :execrows
+-- keeps the ticket and transaction events that ListCustomerTimeline shows
+-- as status changes, whatever their age
+DELETE FROM event_log
+WHERE created_at < ?
+  AND NOT (
+    (stream = 'ticket_events' AND type <> 'ticket.created' AND subject_id IS NOT NULL)
+    OR (stream = 'transaction_events' AND type <> 'transaction.created' AND subject_id IS NOT NULL)
+  )
+ORDER BY id
+LIMIT ?;
+
+-- name: ListEventsAfter :many
+SELECT * FROM event_log
+WHERE id > sqlc.arg(after_id)
+  AND (sqlc.narg(owner_id) IS NULL OR owner_id = sqlc.narg(owner_id))
+ORDER BY id
+LIMIT ?;
+
+-- name: CreateStreamTicket :exec
+INSERT INTO stream_tickets (ticket_hash, profile_id, token_version, expires_at)
+VALUES (?, ?, ?, ?);
+
+-- name: GetStreamTicket :one
+SELECT * FROM stream_tickets
+WHERE ticket_hash = ? LIMIT 1;
+
+-- name: RenewStreamTicket :exec
+-- recreates the ticket if it expired and was cleaned up while its
+-- connection was open
+INSERT INTO stream_tickets (ticket_hash, profile_id, token_version, expires_at)
+VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at);
+
+-- name: DeleteExpiredStreamTickets :exec
+DELETE FROM stream_tickets
+WHERE expires_at < ?;
+
+-- name: ListCustomersAfter :many
+SELECT * FROM customers
+WHERE id > ?
+ORDER BY id
+LIMIT ?;
+
+-- name: DeleteCustomerDuplicates :exec
+DELETE FROM customer_duplicates;
+
+-- name: CreateCustomerDuplicate :exec
+INSERT INTO customer_duplicates (customer_id, duplicate_id, reasons, name_similarity)
+VALUES (?, ?, ?, ?);
+
+-- name: ListCustomerDuplicates :many
+-- pairs linked by phone or email, or by names at least min_similarity alike
+SELECT d.id, d.reasons, d.name_similarity, d.found_at, sqlc.embed(a), sqlc.embed(b)
+FROM customer_duplicates d
+JOIN customers a ON a.id = d.customer_id
+JOIN customers b ON b.id = d.duplicate_id
+WHERE d.id > sqlc.arg(after_id)
+  AND (d.reasons <> 'name' OR d.name_similarity >= sqlc.arg(min_similarity))
+ORDER BY d.id
+LIMIT ?;
+
+-- name: LockCustomer :one
+SELECT * FROM customers
+WHERE id = ? LIMIT 1
+FOR UPDATE;
+
+-- name: ListCustomerTicketLinks :many
+SELECT id, transaction_id FROM tickets
+WHERE customer_id = ?
+ORDER BY id
+FOR UPDATE;
+
+-- name: MoveCustomerTickets :execrows
+UPDATE tickets
+SET customer_id = sqlc.arg(to_id)
+WHERE customer_id = sqlc.arg(from_id);
+
+-- name: CreateCustomerMerge :execresult
+INSERT INTO customer_merges (survivor_id, merged_id, merged_full_name, merged_email, merged_phone_number, ticket_ids, transaction_ids, merged_by, note)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetCustomerMerge :one
+SELECT * FROM customer_merges
+WHERE id = ? LIMIT 1;
+
+-- name: ListCustomerMerges :many
+-- merges into the customer, or of it into another
+SELECT * FROM customer_merges
+WHERE survivor_id = sqlc.arg(customer_id) OR merged_id = sqlc.arg(customer_id)
+ORDER BY id DESC;
+
+-- name: CreateImportJob :execresult
+INSERT INTO import_jobs (kind, dry_run, filename, created_by)
+VALUES (?, ?, ?, ?);
+
+-- name: GetImportJob :one
+SELECT * FROM import_jobs
+WHERE id = ? LIMIT 1;
+
+-- name: StartImportJob :exec
+UPDATE import_jobs
+SET status = 'running', started_at = ?, updated_at = NOW()
+WHERE id = ?;
+
+-- name: UpdateImportJobProgress :exec
+UPDATE import_jobs
+SET rows_processed = ?, updated_at = NOW()
+WHERE id = ?;
+
+-- name: FailStaleImportJobs :execrows
+-- jobs whose API process went away: queued or running, untouched for 15 minutes
+UPDATE import_jobs
+SET status = 'failed', error_message = ?, finished_at = ?
+WHERE status IN ('queued', 'running')
+  AND updated_at < NOW() - INTERVAL 15 MINUTE;
+
+-- name: FinishImportJob :exec
+UPDATE import_jobs
+SET status = ?, rows_processed = ?, report = ?, error_message = ?, finished_at = ?
+WHERE id = ?;
+
+-- name: ListCustomerTimeline :many
+-- item_types is a comma separated list of the item types to keep
+SELECT item_type, item_id, occurred_at FROM (
+  SELECT 'customer' AS item_type, c.id AS item_id, c.created_at AS occurred_at
+  FROM customers c
+  WHERE c.id = sqlc.arg(customer_id)
+  UNION ALL
+  SELECT 'merge', m.id, m.created_at
+  FROM customer_merges m
+  WHERE m.survivor_id = sqlc.arg(customer_id)
+  UNION ALL
+  SELECT 'ticket', t.id, t.created_at
+  FROM tickets t
+  JOIN customers tc ON tc.id = t.customer_id
+  WHERE tc.id = sqlc.arg(customer_id)
+  UNION ALL
+  SELECT 'transaction', x.id, x.created_at
+  FROM transactions x
+  WHERE x.id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = sqlc.arg(customer_id))
+  UNION ALL
+  SELECT 'status_change', e.id, e.created_at
+  FROM event_log e
+  WHERE (e.stream = 'ticket_events' AND e.type <> 'ticket.created'
+      AND e.subject_id IN (SELECT tt.id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = sqlc.arg(customer_id)))
+     OR (e.stream = 'transaction_events' AND e.type <> 'transaction.created'
+      AND e.subject_id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = sqlc.arg(customer_id)))
+) timeline
+WHERE sqlc.narg(item_types) IS NULL OR FIND_IN_SET(item_type, sqlc.narg(item_types)) > 0
+ORDER BY occurred_at DESC, item_type, item_id DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListCustomerMergesByIDs :many
+SELECT * FROM customer_merges
+WHERE id IN (sqlc.slice(ids));
+
+-- name: ListTicketsByIDs :many
+SELECT * FROM tickets
+WHERE id IN (sqlc.slice(ids));
+
+-- name: ListTransactionsByIDs :many
+SELECT * FROM transactions
+WHERE id IN (sqlc.slice(ids));
+
+-- name: ListEventsByIDs :many
+SELECT * FROM event_log
+WHERE id IN (sqlc.slice(ids));
+
+-- name: CountCustomerTimeline :one
+SELECT COUNT(*) FROM (
+  SELECT 'customer' AS item_type, c.id AS item_id, c.created_at AS occurred_at
+  FROM customers c
+  WHERE c.id = sqlc.arg(customer_id)
+  UNION ALL
+  SELECT 'merge', m.id, m.created_at
+  FROM customer_merges m
+  WHERE m.survivor_id = sqlc.arg(customer_id)
+  UNION ALL
+  SELECT 'ticket', t.id, t.created_at
+  FROM tickets t
+  JOIN customers tc ON tc.id = t.customer_id
+  WHERE tc.id = sqlc.arg(customer_id)
+  UNION ALL
+  SELECT 'transaction', x.id, x.created_at
+  FROM transactions x
+  WHERE x.id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = sqlc.arg(customer_id))
+  UNION ALL
+  SELECT 'status_change', e.id, e.created_at
+  FROM event_log e
+  WHERE (e.stream = 'ticket_events' AND e.type <> 'ticket.created'
+      AND e.subject_id IN (SELECT tt.id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = sqlc.arg(customer_id)))
+     OR (e.stream = 'transaction_events' AND e.type <> 'transaction.created'
+      AND e.subject_id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = sqlc.arg(customer_id)))
+) timeline
+WHERE sqlc.narg(item_types) IS NULL OR FIND_IN_SET(item_type, sqlc.narg(item_types)) > 0;
+
+-- name: ListTicketsByCreator :many
+SELECT * FROM tickets
+WHERE created_by = ?
+ORDER BY id;
+
+-- name: ListTransactionsByUser :many
+SELECT * FROM transactions
+WHERE user_id = ?
+ORDER BY id;
+
+-- name: ListMpesaStkRequestsByUser :many
+SELECT m.* FROM mpesa_stk_requests m
+JOIN transactions t ON t.id = m.transaction_id
+WHERE t.user_id = ?
+ORDER BY m.id;
+
+-- name: ListOTPsByProfile :many
+SELECT * FROM otp_codes
+WHERE profile_id = ?
+ORDER BY id;
+
+-- name: ListLoginEventsForSubject :many
+SELECT * FROM login_events
+WHERE profile_id = sqlc.arg(profile_id) OR identifier = sqlc.arg(identifier)
+ORDER BY id;
+
+-- name: ListCustomerMergesByContact :many
+SELECT * FROM customer_merges
+WHERE merged_email = sqlc.arg(email) OR merged_phone_number = sqlc.arg(phone)
+ORDER BY id;
+
+-- name: AnonymizeProfile :exec
+UPDATE profiles
+SET full_name = ?, email = ?, phone = NULL, password_hash = ?, token_version = token_version + 1
+WHERE id = ?;
+
+-- name: DeleteOTPsByProfile :exec
+DELETE FROM otp_codes WHERE profile_id = ?;
+
+-- name: DeleteTOTPSecret :exec
+DELETE FROM totp_secrets WHERE profile_id = ?;
+
+-- name: AnonymizeLoginEvents :execrows
+UPDATE login_events
+SET identifier = sqlc.arg(replacement), ip = '', user_agent = ''
+WHERE profile_id = sqlc.arg(profile_id) OR identifier = sqlc.arg(identifier);
+
+-- name: AnonymizeCustomer :exec
+UPDATE customers
+SET full_name = ?, email = ?, phone_number = ?, segment = ?
+WHERE id = ?;
+
+-- name: AnonymizeTicket :exec
+UPDATE tickets
+SET title = ?, description = ?
+WHERE id = ?;
+
+-- name: DeleteEventsBySubject :execrows
+DELETE FROM event_log
+WHERE stream = ? AND subject_id = ?;
+
+-- name: DeleteWebhookDeliveriesBySubject :execrows
+-- deliveries of events about one ticket or profile, e.g. ticket.% and an id
+DELETE FROM webhook_deliveries
+WHERE event_type LIKE sqlc.arg(event_type)
+  AND JSON_EXTRACT(payload, '$.payload.id') = CAST(sqlc.arg(subject_id) AS SIGNED);
+
+-- name: DeleteWebhookSubscriptionsByProfile :execrows
+DELETE FROM webhook_subscriptions WHERE profile_id = ?;
+
+-- name: AnonymizeCustomerMerge :exec
+UPDATE customer_merges
+SET merged_full_name = ?, merged_email = ?, merged_phone_number = ?, note = ''
+WHERE id = ?;
+
+-- name: CreateDataSubjectRequest :execresult
+INSERT INTO data_subject_requests (kind, subject_hash, profile_ids, customer_ids, summary, reason, requested_by)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListDataSubjectRequests :many
+SELECT * FROM data_subject_requests
+WHERE sqlc.narg(subject_hash) IS NULL OR subject_hash = sqlc.narg(subject_hash)
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListProfilePhones :many
+SELECT id, phone FROM profiles
+WHERE phone IS NOT NULL
+ORDER BY id;
+
+-- name: UpdateProfilePhone :exec
+UPDATE profiles SET phone = ? WHERE id = ?;
+
+-- name: UpdateCustomerPhone :exec
+UPDATE customers SET phone_number = ? WHERE id = ?;
+
+-- name: CreateTeam :execresult
+INSERT INTO teams (name, description, assignment_strategy)
 VALUES (?, ?, ?);
 
+-- name: GetTeam :one
+SELECT * FROM teams
+WHERE id = ? LIMIT 1;
+
+-- name: ListTeams :many
+SELECT * FROM teams
+ORDER BY name;
+
+-- name: UpdateTeam :exec
+UPDATE teams SET name = ?, description = ?, assignment_strategy = ?
+WHERE id = ?;
+
+-- name: DeleteTeam :execrows
+DELETE FROM teams
+WHERE id = ?;
+
+-- name: AddTeamMember :exec
+INSERT IGNORE INTO team_members (team_id, profile_id)
+VALUES (?, ?);
+
+-- name: RemoveTeamMember :execrows
+DELETE FROM team_members
+WHERE team_id = ? AND profile_id = ?;
+
+-- name: ListTeamMembers :many
+SELECT tm.team_id, tm.profile_id, p.full_name, p.email, p.phone, p.role, tm.created_at
+FROM team_members tm
+JOIN profiles p ON p.id = tm.profile_id
+WHERE tm.team_id = ?
+ORDER BY tm.profile_id;
+
+-- name: CreateRoutingRule :execresult
+INSERT INTO routing_rules (name, priority, team_id, ticket_priority, ticket_type, customer_segment, keywords, active)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetRoutingRule :one
+SELECT * FROM routing_rules
+WHERE id = ? LIMIT 1;
+
+-- name: ListRoutingRules :many
+SELECT * FROM routing_rules
+ORDER BY priority, id;
+
+-- name: ListActiveRoutingRules :many
+SELECT * FROM routing_rules
+WHERE active = TRUE
+ORDER BY priority, id;
+
+-- name: UpdateRoutingRule :exec
+UPDATE routing_rules
+SET name = ?, priority = ?, team_id = ?, ticket_priority = ?, ticket_type = ?, customer_segment = ?, keywords = ?, active = ?
+WHERE id = ?;
+
+-- name: DeleteRoutingRule :execrows
+DELETE FROM routing_rules
+WHERE id = ?;
+
+-- name: UpdateTicketTeam :execrows
+UPDATE tickets SET team_id = ?
+WHERE id = ?;
+
+-- name: ListTicketsByTeam :many
+SELECT * FROM tickets
+WHERE team_id = ?
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: GetAgentSettings :one
+SELECT * FROM agent_settings
+WHERE profile_id = ? LIMIT 1;
+
+-- name: UpsertAgentSettings :exec
+INSERT INTO agent_settings (profile_id, status, max_concurrent, skills)
+VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE status = VALUES(status), max_concurrent = VALUES(max_concurrent), skills = VALUES(skills);
+
+-- name: UpdateAgentStatus :exec
+INSERT INTO agent_settings (profile_id, status)
+VALUES (?, ?)
+ON DUPLICATE KEY UPDATE status = VALUES(status);
+
+-- name: ListAgents :many
+-- agents and admins with their availability and open tickets: those whose
+-- status is not in the comma separated closed_statuses
+SELECT p.id, p.full_name, p.email, p.role, a.status, a.max_concurrent, a.skills,
+  (SELECT COUNT(*) FROM tickets t
+   WHERE t.assigned_to = p.id AND FIND_IN_SET(t.status, sqlc.arg(closed_statuses)) = 0) AS open_tickets
+FROM profiles p
+LEFT JOIN agent_settings a ON a.profile_id = p.id
+WHERE p.role IN ('agent', 'admin')
+ORDER BY p.id;
+
+-- name: ListTeamCandidates :many
+-- the team's agents and admins with their availability and open tickets,
+-- as ListAgents
+SELECT tm.profile_id, a.status, a.max_concurrent, a.skills,
+  (SELECT COUNT(*) FROM tickets t
+   WHERE t.assigned_to = tm.profile_id AND FIND_IN_SET(t.status, sqlc.arg(closed_statuses)) = 0) AS open_tickets
+FROM team_members tm
+JOIN profiles p ON p.id = tm.profile_id
+LEFT JOIN agent_settings a ON a.profile_id = tm.profile_id
+WHERE tm.team_id = sqlc.arg(team_id) AND p.role IN ('agent', 'admin')
+ORDER BY tm.profile_id;
+
+-- name: GetLastTeamAssignee :one
+SELECT assigned_to FROM ticket_assignments
+WHERE team_id = ? AND assigned_to IS NOT NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ClaimUnassignedTicket :execrows
+-- only assigns tickets nobody has assigned in the meantime
+UPDATE tickets SET assigned_to = ?, updated_at = NOW()
+WHERE id = ? AND assigned_to IS NULL;
+
+-- name: CreateTicketAssignment :execresult
+INSERT INTO ticket_assignments (ticket_id, team_id, strategy, assigned_to, reason, candidates)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: ListTicketAssignments :many
+SELECT * FROM ticket_assignments
+WHERE ticket_id = ?
+ORDER BY id;
@@ -8,7 +8,12 @@ CREATE TABLE tickets (
     created_by BIGINT NOT NULL,
     assigned_to BIGINT DEFAULT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    -- payment_dispute tickets always reference the transaction in question
+    type ENUM('general', 'payment_dispute') NOT NULL DEFAULT 'general',
+    customer_id BIGINT NULL DEFAULT NULL,
+    transaction_id INT NULL DEFAULT NULL,
+    team_id BIGINT NULL DEFAULT NULL -- set by routing_rules on creation
 );
 
 -- transactions table
@@ -17,24 +22,17 @@ CREATE TABLE transactions (
     id INT AUTO_INCREMENT PRIMARY KEY,
     transaction_id VARCHAR(255) NOT NULL UNIQUE,
     user_id INT NOT NULL,
-    amount DECIMAL(10, 2) NOT NULL,
+    amount_minor BIGINT NOT NULL, -- minor units of currency, see package money
     currency VARCHAR(3) NOT NULL,
     status  SmallINT NOT NULL DEFAULT 1,
     payment_method VARCHAR(50),
+    kind ENUM('payment', 'refund') NOT NULL DEFAULT 'payment',
+    parent_id INT NULL, -- the payment a refund belongs to
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (parent_id) REFERENCES transactions(id)
 );
 
--- users table
-CREATE TABLE users (
-    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
-    full_name  VARCHAR(255) NOT NULL,
-    email      VARCHAR(255) NOT NULL UNIQUE,
-    password   VARCHAR(255) NOT NULL,
-    role       ENUM('admin','agent','customer') DEFAULT 'customer',
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-CREATE INDEX idx_users_email ON users(email);
 CREATE INDEX idx_tickets_created_by ON tickets(created_by);
 
 
@@ -42,28 +40,405 @@ CREATE TABLE customers (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
     full_name VARCHAR(255) NOT NULL,
     email VARCHAR(255) NOT NULL UNIQUE,
-    phone_number VARCHAR(20) NOT NULL UNIQUE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    phone_number VARCHAR(20) NOT NULL UNIQUE, -- E.164, or erased-<id> once erased
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    segment VARCHAR(50) NOT NULL DEFAULT '', -- e.g. enterprise, sme; matched by routing_rules
+    CONSTRAINT chk_customers_phone_e164 CHECK (phone_number REGEXP '^[+][1-9][0-9]{6,14}$' OR phone_number LIKE 'erased-%')
 );
 
 
 -- db/schema.sql
+-- profiles is the single identity table: end users, agents and admins.
+-- A profile logs in with its phone and/or email; tickets.created_by and
+-- tickets.assigned_to hold profile ids.
 CREATE TABLE profiles (
   id INT AUTO_INCREMENT PRIMARY KEY,
-  phone VARCHAR(40) NOT NULL UNIQUE,
+  phone VARCHAR(40) UNIQUE, -- E.164, see package phone
+  email VARCHAR(255) UNIQUE,
   password_hash VARCHAR(255) NOT NULL,
   full_name VARCHAR(100),
+  role ENUM('admin','agent','customer') NOT NULL DEFAULT 'customer',
+  token_version INT NOT NULL DEFAULT 0,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  CONSTRAINT chk_profiles_login CHECK (phone IS NOT NULL OR email IS NOT NULL),
+  CONSTRAINT chk_profiles_phone_e164 CHECK (phone IS NULL OR phone REGEXP '^[+][1-9][0-9]{6,14}$')
 );
 
 CREATE TABLE otp_codes (
   id INT AUTO_INCREMENT PRIMARY KEY,
   profile_id INT NOT NULL,
   otp_code VARCHAR(6) NOT NULL,
+  purpose VARCHAR(20) NOT NULL DEFAULT 'login',
   expires_at DATETIME NOT NULL,
   verified BOOLEAN NOT NULL DEFAULT FALSE,
   attempts INT NOT NULL DEFAULT 0,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
 );
+
+CREATE TABLE totp_secrets (
+  profile_id INT PRIMARY KEY,
+  secret_enc VARCHAR(255) NOT NULL,
+  confirmed_at TIMESTAMP NULL DEFAULT NULL,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE recovery_codes (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  profile_id INT NOT NULL,
+  code_hash CHAR(64) NOT NULL,
+  used_at TIMESTAMP NULL DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_recovery_codes_profile_hash (profile_id, code_hash),
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+
+-- failed password attempts; the row is removed on a successful login or an
+-- admin unlock. Times are written by the app, like otp_codes.expires_at.
+CREATE TABLE profile_lockouts (
+  profile_id INT PRIMARY KEY,
+  failed_attempts INT NOT NULL DEFAULT 0,
+  last_failed_at DATETIME NOT NULL,
+  locked_until DATETIME NULL DEFAULT NULL,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE login_events (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  profile_id INT NULL,
+  identifier VARCHAR(255) NOT NULL,
+  ip VARCHAR(45) NOT NULL,
+  user_agent VARCHAR(255) NOT NULL,
+  success BOOLEAN NOT NULL,
+  reason VARCHAR(50) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_login_events_profile ON login_events(profile_id, created_at);
+
+-- machine-to-machine credentials. Only the SHA-256 of the full key is kept;
+-- prefix identifies the key in logs and lookups.
+CREATE TABLE api_keys (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(16) NOT NULL UNIQUE,
+  key_hash CHAR(64) NOT NULL,
+  scopes VARCHAR(500) NOT NULL,
+  created_by INT NOT NULL,
+  expires_at DATETIME NULL DEFAULT NULL,
+  last_used_at DATETIME NULL DEFAULT NULL,
+  revoked_at DATETIME NULL DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (created_by) REFERENCES profiles(id)
+);
+
+-- one row per Lipa Na M-Pesa STK Push. Daraja reports the result
+-- asynchronously, keyed by checkout_request_id.
+CREATE TABLE mpesa_stk_requests (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  transaction_id INT NOT NULL,
+  merchant_request_id VARCHAR(100) NOT NULL,
+  checkout_request_id VARCHAR(100) NOT NULL UNIQUE,
+  phone VARCHAR(20) NOT NULL,
+  result_code INT NULL DEFAULT NULL,
+  result_desc VARCHAR(255) NULL DEFAULT NULL,
+  mpesa_receipt VARCHAR(50) NULL DEFAULT NULL,
+  -- why a successful callback did not settle the transaction, e.g. a
+  -- different amount; the transaction is left for reconciliation
+  mismatch VARCHAR(255) NULL DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  completed_at DATETIME NULL DEFAULT NULL,
+  FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+);
+
+-- responses to POSTs sent with an Idempotency-Key header, replayed on retries.
+-- status_code is NULL while the first request is still being handled.
+CREATE TABLE idempotency_keys (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  scope VARCHAR(64) NOT NULL,
+  idempotency_key VARCHAR(255) NOT NULL,
+  request_path VARCHAR(255) NOT NULL,
+  fingerprint CHAR(64) NOT NULL,
+  status_code INT NULL DEFAULT NULL,
+  content_type VARCHAR(100) NULL DEFAULT NULL,
+  response_body MEDIUMBLOB NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at DATETIME NOT NULL,
+  UNIQUE KEY uq_idempotency_scope_key (scope, idempotency_key),
+  INDEX idx_idempotency_expires (expires_at)
+);
+
+-- double-entry ledger. Every journal entry has postings that sum to zero per
+-- currency: debits are positive, credits negative, in minor units.
+CREATE TABLE ledger_accounts (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  code VARCHAR(100) NOT NULL UNIQUE,
+  name VARCHAR(255) NOT NULL,
+  type ENUM('asset', 'liability', 'equity', 'income', 'expense') NOT NULL,
+  currency CHAR(3) NOT NULL,
+  profile_id INT NULL DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id)
+);
+
+CREATE TABLE journal_entries (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  transaction_id INT NULL DEFAULT NULL,
+  description VARCHAR(255) NOT NULL,
+  occurred_at DATETIME NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+);
+CREATE INDEX idx_journal_entries_transaction ON journal_entries(transaction_id);
+CREATE INDEX idx_journal_entries_occurred ON journal_entries(occurred_at);
+
+CREATE TABLE postings (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  entry_id BIGINT NOT NULL,
+  account_id BIGINT NOT NULL,
+  amount_minor BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  FOREIGN KEY (entry_id) REFERENCES journal_entries(id),
+  FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+);
+CREATE INDEX idx_postings_account ON postings(account_id, entry_id);
+
+-- provider statement imports and what each line or transaction matched to
+CREATE TABLE reconciliation_runs (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  format VARCHAR(20) NOT NULL,
+  filename VARCHAR(255) NOT NULL,
+  line_count INT NOT NULL,
+  period_start DATETIME NULL DEFAULT NULL,
+  period_end DATETIME NULL DEFAULT NULL,
+  window_seconds INT NOT NULL,
+  created_by INT NULL DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (created_by) REFERENCES profiles(id)
+);
+
+CREATE TABLE reconciliation_items (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  run_id BIGINT NOT NULL,
+  status ENUM('matched', 'amount_mismatch', 'status_mismatch', 'duplicate', 'missing_transaction', 'missing_line') NOT NULL,
+  line_number INT NULL DEFAULT NULL,
+  reference VARCHAR(255) NULL DEFAULT NULL,
+  amount_minor BIGINT NULL DEFAULT NULL,
+  currency CHAR(3) NULL DEFAULT NULL,
+  occurred_at DATETIME NULL DEFAULT NULL,
+  transaction_id INT NULL DEFAULT NULL,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  FOREIGN KEY (run_id) REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+  FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+);
+CREATE INDEX idx_reconciliation_items_run ON reconciliation_items(run_id, status);
+
+-- tickets is created before the tables it references
+ALTER TABLE tickets
+  ADD FOREIGN KEY (customer_id) REFERENCES customers(id),
+  ADD FOREIGN KEY (transaction_id) REFERENCES transactions(id);
+
+-- outbound webhooks: partners subscribe a URL to event types and every
+-- matching event is queued as a delivery that the worker sends and retries
+CREATE TABLE webhook_subscriptions (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  profile_id INT NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  secret VARCHAR(255) NOT NULL, -- encrypted with DATA_ENCRYPTION_KEY
+  events VARCHAR(1000) NOT NULL, -- comma separated patterns: ticket.created, transaction.*, *
+  scope ENUM('own', 'all') NOT NULL DEFAULT 'own', -- own: only events about the profile's tickets and payments
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  consecutive_failures INT NOT NULL DEFAULT 0,
+  disabled_at DATETIME NULL DEFAULT NULL,
+  disabled_reason VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id)
+);
+
+CREATE TABLE webhook_deliveries (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  subscription_id BIGINT NOT NULL,
+  event_id CHAR(32) NOT NULL,
+  event_type VARCHAR(100) NOT NULL,
+  payload MEDIUMTEXT NOT NULL,
+  status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at DATETIME NOT NULL,
+  last_attempt_at DATETIME NULL DEFAULT NULL,
+  response_status INT NULL DEFAULT NULL,
+  last_error VARCHAR(255) NOT NULL DEFAULT '',
+  redelivery_of BIGINT NULL DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+  FOREIGN KEY (redelivery_of) REFERENCES webhook_deliveries(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id);
+
+-- every ticket and transaction event, so change feeds can resume where a
+-- client left off (SSE Last-Event-ID)
+CREATE TABLE event_log (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  stream VARCHAR(64) NOT NULL,
+  type VARCHAR(100) NOT NULL,
+  owner_id BIGINT NULL DEFAULT NULL, -- the profile the event is about: ticket created_by, transaction user_id
+  subject_id BIGINT NULL DEFAULT NULL, -- the ticket or transaction id
+  payload MEDIUMTEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_event_log_owner ON event_log(owner_id, id);
+CREATE INDEX idx_event_log_subject ON event_log(stream, subject_id);
+CREATE INDEX idx_event_log_created ON event_log(created_at);
+
+-- short-lived tickets that authenticate WebSocket and SSE connections, so
+-- the JWT never goes in a URL. expires_at moves forward each time a
+-- connection opens or closes with the ticket, so a client can reconnect with
+-- it. Only the SHA-256 of the ticket is stored.
+CREATE TABLE stream_tickets (
+  ticket_hash CHAR(64) PRIMARY KEY,
+  profile_id INT NOT NULL,
+  token_version INT NOT NULL,
+  expires_at DATETIME NOT NULL,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+
+-- likely duplicate customers, found by the worker's periodic scan (see
+-- dedupe.Pairs). Every scan replaces the whole table.
+CREATE TABLE customer_duplicates (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  customer_id BIGINT NOT NULL, -- the older of the two
+  duplicate_id BIGINT NOT NULL,
+  reasons VARCHAR(20) NOT NULL, -- comma separated: phone, email, name
+  name_similarity DOUBLE NOT NULL DEFAULT 0,
+  found_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+  FOREIGN KEY (duplicate_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- audit trail of customer merges. The merged customer row is deleted, so its
+-- details are copied here; no foreign keys, so history survives later merges
+-- and deletes.
+CREATE TABLE customer_merges (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  survivor_id BIGINT NOT NULL,
+  merged_id BIGINT NOT NULL,
+  merged_full_name VARCHAR(255) NOT NULL,
+  merged_email VARCHAR(255) NOT NULL,
+  merged_phone_number VARCHAR(20) NOT NULL,
+  ticket_ids TEXT NOT NULL, -- comma separated ids of the tickets moved to the survivor
+  transaction_ids TEXT NOT NULL, -- the transactions linked to those tickets
+  merged_by INT NOT NULL,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_customer_merges_survivor ON customer_merges(survivor_id);
+CREATE INDEX idx_customer_merges_merged ON customer_merges(merged_id);
+
+-- bulk CSV imports run in the background; the report is kept as JSON
+CREATE TABLE import_jobs (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  kind ENUM('customers', 'users') NOT NULL,
+  status ENUM('queued', 'running', 'succeeded', 'failed') NOT NULL DEFAULT 'queued',
+  dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+  filename VARCHAR(255) NOT NULL,
+  rows_processed INT NOT NULL DEFAULT 0,
+  report MEDIUMTEXT NULL,
+  error_message VARCHAR(255) NOT NULL DEFAULT '',
+  created_by INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  started_at DATETIME NULL DEFAULT NULL,
+  finished_at DATETIME NULL DEFAULT NULL,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- touched after every batch while running
+  FOREIGN KEY (created_by) REFERENCES profiles(id)
+);
+
+-- audit trail of data subject requests (export and erasure). The subject is
+-- kept only as a hash of the email and phone asked about, so the trail does
+-- not hold the personal data an erasure removed.
+CREATE TABLE data_subject_requests (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  kind ENUM('export', 'erasure') NOT NULL,
+  subject_hash CHAR(64) NOT NULL,
+  profile_ids TEXT NOT NULL, -- comma separated ids of the profiles found
+  customer_ids TEXT NOT NULL, -- and of the customers
+  summary TEXT NOT NULL, -- JSON: records exported, or anonymized and retained
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  requested_by INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (requested_by) REFERENCES profiles(id)
+);
+CREATE INDEX idx_data_subject_requests_subject ON data_subject_requests(subject_hash);
+
+-- teams of agents, and the rules that route new tickets to them. Rules are
+-- tried in priority order, lowest first; the first whose conditions all
+-- hold sets tickets.team_id. A NULL or empty condition matches anything.
+CREATE TABLE teams (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(100) NOT NULL UNIQUE,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  -- how the worker assigns the team's new tickets; see package assignment
+  assignment_strategy VARCHAR(30) NOT NULL DEFAULT 'manual'
+);
+
+CREATE TABLE team_members (
+  team_id BIGINT NOT NULL,
+  profile_id INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (team_id, profile_id),
+  FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_team_members_profile ON team_members(profile_id);
+
+CREATE TABLE routing_rules (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  priority INT NOT NULL DEFAULT 0,
+  team_id BIGINT NOT NULL,
+  ticket_priority VARCHAR(50) NULL DEFAULT NULL,
+  ticket_type ENUM('general', 'payment_dispute') NULL DEFAULT NULL,
+  customer_segment VARCHAR(50) NULL DEFAULT NULL,
+  keywords VARCHAR(1000) NOT NULL DEFAULT '', -- comma separated; any one in the title or description matches
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_routing_rules_priority ON routing_rules(active, priority, id);
+
+ALTER TABLE tickets
+  ADD FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL;
+CREATE INDEX idx_tickets_team ON tickets(team_id);
+
+-- an agent's availability and skills for automatic assignment. Agents
+-- without a row are offline.
+CREATE TABLE agent_settings (
+  profile_id INT PRIMARY KEY,
+  status ENUM('online', 'away', 'offline') NOT NULL DEFAULT 'offline',
+  max_concurrent INT NOT NULL DEFAULT 10, -- open tickets beyond which the agent is not assigned more
+  skills VARCHAR(1000) NOT NULL DEFAULT '', -- comma separated, lowercase
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+);
+
+-- every automatic assignment decision, and why it was made
+CREATE TABLE ticket_assignments (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  ticket_id BIGINT NOT NULL,
+  team_id BIGINT NULL DEFAULT NULL,
+  strategy VARCHAR(30) NOT NULL,
+  assigned_to INT NULL DEFAULT NULL, -- NULL when no agent was available
+  reason VARCHAR(255) NOT NULL,
+  candidates TEXT NOT NULL, -- JSON: each member considered, with its load, score or why it was skipped
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_ticket_assignments_ticket ON ticket_assignments(ticket_id);
+CREATE INDEX idx_ticket_assignments_team ON ticket_assignments(team_id, id);
+CREATE INDEX idx_tickets_assigned ON tickets(assigned_to, status);
@@ -24,111 +24,737 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addTeamMemberStmt, err = db.PrepareContext(ctx, addTeamMember); err != nil {
+		return nil, fmt.Errorf("error preparing query AddTeamMember: %w", err)
+	}
+	if q.anonymizeCustomerStmt, err = db.PrepareContext(ctx, anonymizeCustomer); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizeCustomer: %w", err)
+	}
+	if q.anonymizeCustomerMergeStmt, err = db.PrepareContext(ctx, anonymizeCustomerMerge); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizeCustomerMerge: %w", err)
+	}
+	if q.anonymizeLoginEventsStmt, err = db.PrepareContext(ctx, anonymizeLoginEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizeLoginEvents: %w", err)
+	}
+	if q.anonymizeProfileStmt, err = db.PrepareContext(ctx, anonymizeProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizeProfile: %w", err)
+	}
+	if q.anonymizeTicketStmt, err = db.PrepareContext(ctx, anonymizeTicket); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizeTicket: %w", err)
+	}
 	if q.assignTicketStmt, err = db.PrepareContext(ctx, assignTicket); err != nil {
 		return nil, fmt.Errorf("error preparing query AssignTicket: %w", err)
 	}
+	if q.claimUnassignedTicketStmt, err = db.PrepareContext(ctx, claimUnassignedTicket); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimUnassignedTicket: %w", err)
+	}
+	if q.claimWebhookDeliveryStmt, err = db.PrepareContext(ctx, claimWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimWebhookDelivery: %w", err)
+	}
+	if q.clearLoginFailuresStmt, err = db.PrepareContext(ctx, clearLoginFailures); err != nil {
+		return nil, fmt.Errorf("error preparing query ClearLoginFailures: %w", err)
+	}
+	if q.completeMpesaStkRequestStmt, err = db.PrepareContext(ctx, completeMpesaStkRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CompleteMpesaStkRequest: %w", err)
+	}
+	if q.confirmTOTPSecretStmt, err = db.PrepareContext(ctx, confirmTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query ConfirmTOTPSecret: %w", err)
+	}
+	if q.countCustomerTimelineStmt, err = db.PrepareContext(ctx, countCustomerTimeline); err != nil {
+		return nil, fmt.Errorf("error preparing query CountCustomerTimeline: %w", err)
+	}
+	if q.countCustomersStmt, err = db.PrepareContext(ctx, countCustomers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountCustomers: %w", err)
+	}
+	if q.countLoginSourcesStmt, err = db.PrepareContext(ctx, countLoginSources); err != nil {
+		return nil, fmt.Errorf("error preparing query CountLoginSources: %w", err)
+	}
+	if q.countReconciliationItemsStmt, err = db.PrepareContext(ctx, countReconciliationItems); err != nil {
+		return nil, fmt.Errorf("error preparing query CountReconciliationItems: %w", err)
+	}
+	if q.createAPIKeyStmt, err = db.PrepareContext(ctx, createAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAPIKey: %w", err)
+	}
 	if q.createCustomerStmt, err = db.PrepareContext(ctx, createCustomer); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateCustomer: %w", err)
 	}
+	if q.createCustomerDuplicateStmt, err = db.PrepareContext(ctx, createCustomerDuplicate); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCustomerDuplicate: %w", err)
+	}
+	if q.createCustomerMergeStmt, err = db.PrepareContext(ctx, createCustomerMerge); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCustomerMerge: %w", err)
+	}
+	if q.createDataSubjectRequestStmt, err = db.PrepareContext(ctx, createDataSubjectRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDataSubjectRequest: %w", err)
+	}
+	if q.createEventStmt, err = db.PrepareContext(ctx, createEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateEvent: %w", err)
+	}
+	if q.createIdempotencyKeyStmt, err = db.PrepareContext(ctx, createIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateIdempotencyKey: %w", err)
+	}
+	if q.createImportJobStmt, err = db.PrepareContext(ctx, createImportJob); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateImportJob: %w", err)
+	}
+	if q.createJournalEntryStmt, err = db.PrepareContext(ctx, createJournalEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateJournalEntry: %w", err)
+	}
+	if q.createLoginEventStmt, err = db.PrepareContext(ctx, createLoginEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateLoginEvent: %w", err)
+	}
+	if q.createMpesaStkRequestStmt, err = db.PrepareContext(ctx, createMpesaStkRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateMpesaStkRequest: %w", err)
+	}
 	if q.createOTPStmt, err = db.PrepareContext(ctx, createOTP); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateOTP: %w", err)
 	}
+	if q.createPostingStmt, err = db.PrepareContext(ctx, createPosting); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePosting: %w", err)
+	}
 	if q.createProfileStmt, err = db.PrepareContext(ctx, createProfile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateProfile: %w", err)
 	}
+	if q.createReconciliationItemStmt, err = db.PrepareContext(ctx, createReconciliationItem); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateReconciliationItem: %w", err)
+	}
+	if q.createReconciliationRunStmt, err = db.PrepareContext(ctx, createReconciliationRun); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateReconciliationRun: %w", err)
+	}
+	if q.createRecoveryCodeStmt, err = db.PrepareContext(ctx, createRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRecoveryCode: %w", err)
+	}
+	if q.createRefundStmt, err = db.PrepareContext(ctx, createRefund); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRefund: %w", err)
+	}
+	if q.createRoutingRuleStmt, err = db.PrepareContext(ctx, createRoutingRule); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRoutingRule: %w", err)
+	}
+	if q.createStreamTicketStmt, err = db.PrepareContext(ctx, createStreamTicket); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateStreamTicket: %w", err)
+	}
+	if q.createTeamStmt, err = db.PrepareContext(ctx, createTeam); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTeam: %w", err)
+	}
 	if q.createTicketStmt, err = db.PrepareContext(ctx, createTicket); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateTicket: %w", err)
 	}
+	if q.createTicketAssignmentStmt, err = db.PrepareContext(ctx, createTicketAssignment); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTicketAssignment: %w", err)
+	}
 	if q.createTransactionStmt, err = db.PrepareContext(ctx, createTransaction); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateTransaction: %w", err)
 	}
 	if q.createUserStmt, err = db.PrepareContext(ctx, createUser); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
 	}
+	if q.createWebhookDeliveryStmt, err = db.PrepareContext(ctx, createWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhookDelivery: %w", err)
+	}
+	if q.createWebhookSubscriptionStmt, err = db.PrepareContext(ctx, createWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhookSubscription: %w", err)
+	}
+	if q.deleteCustomerStmt, err = db.PrepareContext(ctx, deleteCustomer); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCustomer: %w", err)
+	}
+	if q.deleteCustomerDuplicatesStmt, err = db.PrepareContext(ctx, deleteCustomerDuplicates); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCustomerDuplicates: %w", err)
+	}
+	if q.deleteEventsBeforeStmt, err = db.PrepareContext(ctx, deleteEventsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteEventsBefore: %w", err)
+	}
+	if q.deleteEventsBySubjectStmt, err = db.PrepareContext(ctx, deleteEventsBySubject); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteEventsBySubject: %w", err)
+	}
+	if q.deleteExpiredIdempotencyKeysStmt, err = db.PrepareContext(ctx, deleteExpiredIdempotencyKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredIdempotencyKeys: %w", err)
+	}
 	if q.deleteExpiredOTPsStmt, err = db.PrepareContext(ctx, deleteExpiredOTPs); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteExpiredOTPs: %w", err)
 	}
+	if q.deleteExpiredStreamTicketsStmt, err = db.PrepareContext(ctx, deleteExpiredStreamTickets); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredStreamTickets: %w", err)
+	}
+	if q.deleteIdempotencyKeyStmt, err = db.PrepareContext(ctx, deleteIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteIdempotencyKey: %w", err)
+	}
+	if q.deleteOTPsByProfileStmt, err = db.PrepareContext(ctx, deleteOTPsByProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOTPsByProfile: %w", err)
+	}
+	if q.deleteRecoveryCodesStmt, err = db.PrepareContext(ctx, deleteRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRecoveryCodes: %w", err)
+	}
+	if q.deleteRoutingRuleStmt, err = db.PrepareContext(ctx, deleteRoutingRule); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRoutingRule: %w", err)
+	}
+	if q.deleteTOTPSecretStmt, err = db.PrepareContext(ctx, deleteTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteTOTPSecret: %w", err)
+	}
+	if q.deleteTeamStmt, err = db.PrepareContext(ctx, deleteTeam); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteTeam: %w", err)
+	}
+	if q.deleteWebhookDeliveriesBySubjectStmt, err = db.PrepareContext(ctx, deleteWebhookDeliveriesBySubject); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookDeliveriesBySubject: %w", err)
+	}
+	if q.deleteWebhookSubscriptionStmt, err = db.PrepareContext(ctx, deleteWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookSubscription: %w", err)
+	}
+	if q.deleteWebhookSubscriptionsByProfileStmt, err = db.PrepareContext(ctx, deleteWebhookSubscriptionsByProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookSubscriptionsByProfile: %w", err)
+	}
+	if q.disableWebhookSubscriptionStmt, err = db.PrepareContext(ctx, disableWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query DisableWebhookSubscription: %w", err)
+	}
+	if q.ensureLedgerAccountStmt, err = db.PrepareContext(ctx, ensureLedgerAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query EnsureLedgerAccount: %w", err)
+	}
+	if q.exportTransactionsStmt, err = db.PrepareContext(ctx, exportTransactions); err != nil {
+		return nil, fmt.Errorf("error preparing query ExportTransactions: %w", err)
+	}
+	if q.failStaleImportJobsStmt, err = db.PrepareContext(ctx, failStaleImportJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query FailStaleImportJobs: %w", err)
+	}
+	if q.finishImportJobStmt, err = db.PrepareContext(ctx, finishImportJob); err != nil {
+		return nil, fmt.Errorf("error preparing query FinishImportJob: %w", err)
+	}
+	if q.getAPIKeyByPrefixStmt, err = db.PrepareContext(ctx, getAPIKeyByPrefix); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAPIKeyByPrefix: %w", err)
+	}
+	if q.getAccountBalanceStmt, err = db.PrepareContext(ctx, getAccountBalance); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountBalance: %w", err)
+	}
+	if q.getAgentSettingsStmt, err = db.PrepareContext(ctx, getAgentSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAgentSettings: %w", err)
+	}
+	if q.getCustomerStmt, err = db.PrepareContext(ctx, getCustomer); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCustomer: %w", err)
+	}
 	if q.getCustomerByEmailStmt, err = db.PrepareContext(ctx, getCustomerByEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCustomerByEmail: %w", err)
 	}
+	if q.getCustomerByPhoneStmt, err = db.PrepareContext(ctx, getCustomerByPhone); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCustomerByPhone: %w", err)
+	}
+	if q.getCustomerMergeStmt, err = db.PrepareContext(ctx, getCustomerMerge); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCustomerMerge: %w", err)
+	}
 	if q.getCustomersStmt, err = db.PrepareContext(ctx, getCustomers); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCustomers: %w", err)
 	}
+	if q.getIdempotencyKeyStmt, err = db.PrepareContext(ctx, getIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIdempotencyKey: %w", err)
+	}
+	if q.getImportJobStmt, err = db.PrepareContext(ctx, getImportJob); err != nil {
+		return nil, fmt.Errorf("error preparing query GetImportJob: %w", err)
+	}
+	if q.getLastTeamAssigneeStmt, err = db.PrepareContext(ctx, getLastTeamAssignee); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLastTeamAssignee: %w", err)
+	}
 	if q.getLatestOTPByProfileIDStmt, err = db.PrepareContext(ctx, getLatestOTPByProfileID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetLatestOTPByProfileID: %w", err)
 	}
+	if q.getLedgerAccountStmt, err = db.PrepareContext(ctx, getLedgerAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLedgerAccount: %w", err)
+	}
+	if q.getLedgerAccountByCodeStmt, err = db.PrepareContext(ctx, getLedgerAccountByCode); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLedgerAccountByCode: %w", err)
+	}
+	if q.getMpesaStkRequestByCheckoutIDStmt, err = db.PrepareContext(ctx, getMpesaStkRequestByCheckoutID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMpesaStkRequestByCheckoutID: %w", err)
+	}
+	if q.getProfileByEmailStmt, err = db.PrepareContext(ctx, getProfileByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query GetProfileByEmail: %w", err)
+	}
+	if q.getProfileByIDStmt, err = db.PrepareContext(ctx, getProfileByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetProfileByID: %w", err)
+	}
 	if q.getProfileByPhoneStmt, err = db.PrepareContext(ctx, getProfileByPhone); err != nil {
 		return nil, fmt.Errorf("error preparing query GetProfileByPhone: %w", err)
 	}
+	if q.getProfileLockoutStmt, err = db.PrepareContext(ctx, getProfileLockout); err != nil {
+		return nil, fmt.Errorf("error preparing query GetProfileLockout: %w", err)
+	}
+	if q.getReconciliationRunStmt, err = db.PrepareContext(ctx, getReconciliationRun); err != nil {
+		return nil, fmt.Errorf("error preparing query GetReconciliationRun: %w", err)
+	}
+	if q.getRoutingRuleStmt, err = db.PrepareContext(ctx, getRoutingRule); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRoutingRule: %w", err)
+	}
+	if q.getStreamTicketStmt, err = db.PrepareContext(ctx, getStreamTicket); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStreamTicket: %w", err)
+	}
+	if q.getTOTPSecretStmt, err = db.PrepareContext(ctx, getTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTOTPSecret: %w", err)
+	}
+	if q.getTeamStmt, err = db.PrepareContext(ctx, getTeam); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTeam: %w", err)
+	}
 	if q.getTicketStmt, err = db.PrepareContext(ctx, getTicket); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTicket: %w", err)
 	}
 	if q.getTicketByTitleAndUserStmt, err = db.PrepareContext(ctx, getTicketByTitleAndUser); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTicketByTitleAndUser: %w", err)
 	}
+	if q.getTransactionForUpdateStmt, err = db.PrepareContext(ctx, getTransactionForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTransactionForUpdate: %w", err)
+	}
 	if q.getTransanctionByIDStmt, err = db.PrepareContext(ctx, getTransanctionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTransanctionByID: %w", err)
 	}
-	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
-		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
-	}
 	if q.getUserByEmailExcludingIDStmt, err = db.PrepareContext(ctx, getUserByEmailExcludingID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByEmailExcludingID: %w", err)
 	}
-	if q.getUserByIDStmt, err = db.PrepareContext(ctx, getUserByID); err != nil {
-		return nil, fmt.Errorf("error preparing query GetUserByID: %w", err)
+	if q.getUserByPhoneExcludingIDStmt, err = db.PrepareContext(ctx, getUserByPhoneExcludingID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByPhoneExcludingID: %w", err)
+	}
+	if q.getWebhookDeliveryStmt, err = db.PrepareContext(ctx, getWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookDelivery: %w", err)
+	}
+	if q.getWebhookSubscriptionStmt, err = db.PrepareContext(ctx, getWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookSubscription: %w", err)
 	}
 	if q.incrementOTPAttemptsStmt, err = db.PrepareContext(ctx, incrementOTPAttempts); err != nil {
 		return nil, fmt.Errorf("error preparing query IncrementOTPAttempts: %w", err)
 	}
+	if q.incrementWebhookFailuresStmt, err = db.PrepareContext(ctx, incrementWebhookFailures); err != nil {
+		return nil, fmt.Errorf("error preparing query IncrementWebhookFailures: %w", err)
+	}
+	if q.listAPIKeysStmt, err = db.PrepareContext(ctx, listAPIKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAPIKeys: %w", err)
+	}
+	if q.listActiveRoutingRulesStmt, err = db.PrepareContext(ctx, listActiveRoutingRules); err != nil {
+		return nil, fmt.Errorf("error preparing query ListActiveRoutingRules: %w", err)
+	}
+	if q.listActiveWebhookSubscriptionsStmt, err = db.PrepareContext(ctx, listActiveWebhookSubscriptions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListActiveWebhookSubscriptions: %w", err)
+	}
+	if q.listAgentsStmt, err = db.PrepareContext(ctx, listAgents); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAgents: %w", err)
+	}
+	if q.listCustomerDuplicatesStmt, err = db.PrepareContext(ctx, listCustomerDuplicates); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomerDuplicates: %w", err)
+	}
+	if q.listCustomerMergesStmt, err = db.PrepareContext(ctx, listCustomerMerges); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomerMerges: %w", err)
+	}
+	if q.listCustomerMergesByContactStmt, err = db.PrepareContext(ctx, listCustomerMergesByContact); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomerMergesByContact: %w", err)
+	}
+	if q.listCustomerMergesByIDsStmt, err = db.PrepareContext(ctx, listCustomerMergesByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomerMergesByIDs: %w", err)
+	}
+	if q.listCustomerTicketLinksStmt, err = db.PrepareContext(ctx, listCustomerTicketLinks); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomerTicketLinks: %w", err)
+	}
+	if q.listCustomerTimelineStmt, err = db.PrepareContext(ctx, listCustomerTimeline); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomerTimeline: %w", err)
+	}
+	if q.listCustomersAfterStmt, err = db.PrepareContext(ctx, listCustomersAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCustomersAfter: %w", err)
+	}
+	if q.listDataSubjectRequestsStmt, err = db.PrepareContext(ctx, listDataSubjectRequests); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDataSubjectRequests: %w", err)
+	}
+	if q.listDueWebhookDeliveriesStmt, err = db.PrepareContext(ctx, listDueWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDueWebhookDeliveries: %w", err)
+	}
+	if q.listEntriesWithTooFewPostingsStmt, err = db.PrepareContext(ctx, listEntriesWithTooFewPostings); err != nil {
+		return nil, fmt.Errorf("error preparing query ListEntriesWithTooFewPostings: %w", err)
+	}
+	if q.listEventsAfterStmt, err = db.PrepareContext(ctx, listEventsAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query ListEventsAfter: %w", err)
+	}
+	if q.listEventsByIDsStmt, err = db.PrepareContext(ctx, listEventsByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListEventsByIDs: %w", err)
+	}
+	if q.listLedgerAccountsStmt, err = db.PrepareContext(ctx, listLedgerAccounts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLedgerAccounts: %w", err)
+	}
+	if q.listLoginEventsForSubjectStmt, err = db.PrepareContext(ctx, listLoginEventsForSubject); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLoginEventsForSubject: %w", err)
+	}
+	if q.listMpesaStkRequestsByUserStmt, err = db.PrepareContext(ctx, listMpesaStkRequestsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMpesaStkRequestsByUser: %w", err)
+	}
+	if q.listOTPsByProfileStmt, err = db.PrepareContext(ctx, listOTPsByProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query ListOTPsByProfile: %w", err)
+	}
+	if q.listOwnTicketsStmt, err = db.PrepareContext(ctx, listOwnTickets); err != nil {
+		return nil, fmt.Errorf("error preparing query ListOwnTickets: %w", err)
+	}
+	if q.listPostingCurrencyMismatchesStmt, err = db.PrepareContext(ctx, listPostingCurrencyMismatches); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPostingCurrencyMismatches: %w", err)
+	}
+	if q.listProfilePhonesStmt, err = db.PrepareContext(ctx, listProfilePhones); err != nil {
+		return nil, fmt.Errorf("error preparing query ListProfilePhones: %w", err)
+	}
+	if q.listReconciliationItemsStmt, err = db.PrepareContext(ctx, listReconciliationItems); err != nil {
+		return nil, fmt.Errorf("error preparing query ListReconciliationItems: %w", err)
+	}
+	if q.listReconciliationRunsStmt, err = db.PrepareContext(ctx, listReconciliationRuns); err != nil {
+		return nil, fmt.Errorf("error preparing query ListReconciliationRuns: %w", err)
+	}
+	if q.listRefundsStmt, err = db.PrepareContext(ctx, listRefunds); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRefunds: %w", err)
+	}
+	if q.listRoutingRulesStmt, err = db.PrepareContext(ctx, listRoutingRules); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRoutingRules: %w", err)
+	}
+	if q.listTeamCandidatesStmt, err = db.PrepareContext(ctx, listTeamCandidates); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTeamCandidates: %w", err)
+	}
+	if q.listTeamMembersStmt, err = db.PrepareContext(ctx, listTeamMembers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTeamMembers: %w", err)
+	}
+	if q.listTeamsStmt, err = db.PrepareContext(ctx, listTeams); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTeams: %w", err)
+	}
+	if q.listTicketAssignmentsStmt, err = db.PrepareContext(ctx, listTicketAssignments); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTicketAssignments: %w", err)
+	}
 	if q.listTicketsStmt, err = db.PrepareContext(ctx, listTickets); err != nil {
 		return nil, fmt.Errorf("error preparing query ListTickets: %w", err)
 	}
+	if q.listTicketsByCreatorStmt, err = db.PrepareContext(ctx, listTicketsByCreator); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTicketsByCreator: %w", err)
+	}
+	if q.listTicketsByCustomerStmt, err = db.PrepareContext(ctx, listTicketsByCustomer); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTicketsByCustomer: %w", err)
+	}
+	if q.listTicketsByIDsStmt, err = db.PrepareContext(ctx, listTicketsByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTicketsByIDs: %w", err)
+	}
+	if q.listTicketsByTeamStmt, err = db.PrepareContext(ctx, listTicketsByTeam); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTicketsByTeam: %w", err)
+	}
+	if q.listTicketsByTransactionStmt, err = db.PrepareContext(ctx, listTicketsByTransaction); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTicketsByTransaction: %w", err)
+	}
 	if q.listTransactionsStmt, err = db.PrepareContext(ctx, listTransactions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListTransactions: %w", err)
 	}
+	if q.listTransactionsByIDsStmt, err = db.PrepareContext(ctx, listTransactionsByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTransactionsByIDs: %w", err)
+	}
+	if q.listTransactionsByUserStmt, err = db.PrepareContext(ctx, listTransactionsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTransactionsByUser: %w", err)
+	}
+	if q.listTransactionsForReconciliationStmt, err = db.PrepareContext(ctx, listTransactionsForReconciliation); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTransactionsForReconciliation: %w", err)
+	}
+	if q.listUnbalancedEntriesStmt, err = db.PrepareContext(ctx, listUnbalancedEntries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUnbalancedEntries: %w", err)
+	}
+	if q.listUnpostedTransactionsStmt, err = db.PrepareContext(ctx, listUnpostedTransactions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUnpostedTransactions: %w", err)
+	}
 	if q.listUsersStmt, err = db.PrepareContext(ctx, listUsers); err != nil {
 		return nil, fmt.Errorf("error preparing query ListUsers: %w", err)
 	}
+	if q.listWebhookDeliveriesStmt, err = db.PrepareContext(ctx, listWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWebhookDeliveries: %w", err)
+	}
+	if q.listWebhookSubscriptionsStmt, err = db.PrepareContext(ctx, listWebhookSubscriptions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWebhookSubscriptions: %w", err)
+	}
+	if q.lockCustomerStmt, err = db.PrepareContext(ctx, lockCustomer); err != nil {
+		return nil, fmt.Errorf("error preparing query LockCustomer: %w", err)
+	}
+	if q.lockProfileStmt, err = db.PrepareContext(ctx, lockProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query LockProfile: %w", err)
+	}
 	if q.markOTPVerifiedStmt, err = db.PrepareContext(ctx, markOTPVerified); err != nil {
 		return nil, fmt.Errorf("error preparing query MarkOTPVerified: %w", err)
 	}
+	if q.moveCustomerTicketsStmt, err = db.PrepareContext(ctx, moveCustomerTickets); err != nil {
+		return nil, fmt.Errorf("error preparing query MoveCustomerTickets: %w", err)
+	}
+	if q.recordLoginFailureStmt, err = db.PrepareContext(ctx, recordLoginFailure); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordLoginFailure: %w", err)
+	}
+	if q.recordWebhookAttemptStmt, err = db.PrepareContext(ctx, recordWebhookAttempt); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordWebhookAttempt: %w", err)
+	}
+	if q.removeTeamMemberStmt, err = db.PrepareContext(ctx, removeTeamMember); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveTeamMember: %w", err)
+	}
+	if q.renewStreamTicketStmt, err = db.PrepareContext(ctx, renewStreamTicket); err != nil {
+		return nil, fmt.Errorf("error preparing query RenewStreamTicket: %w", err)
+	}
+	if q.resetWebhookFailuresStmt, err = db.PrepareContext(ctx, resetWebhookFailures); err != nil {
+		return nil, fmt.Errorf("error preparing query ResetWebhookFailures: %w", err)
+	}
+	if q.revokeAPIKeyStmt, err = db.PrepareContext(ctx, revokeAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeAPIKey: %w", err)
+	}
+	if q.saveIdempotentResponseStmt, err = db.PrepareContext(ctx, saveIdempotentResponse); err != nil {
+		return nil, fmt.Errorf("error preparing query SaveIdempotentResponse: %w", err)
+	}
+	if q.startImportJobStmt, err = db.PrepareContext(ctx, startImportJob); err != nil {
+		return nil, fmt.Errorf("error preparing query StartImportJob: %w", err)
+	}
+	if q.sumRefundsStmt, err = db.PrepareContext(ctx, sumRefunds); err != nil {
+		return nil, fmt.Errorf("error preparing query SumRefunds: %w", err)
+	}
+	if q.sumTransactionsByCurrencyStmt, err = db.PrepareContext(ctx, sumTransactionsByCurrency); err != nil {
+		return nil, fmt.Errorf("error preparing query SumTransactionsByCurrency: %w", err)
+	}
+	if q.touchAPIKeyStmt, err = db.PrepareContext(ctx, touchAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchAPIKey: %w", err)
+	}
+	if q.transitionTransactionStatusStmt, err = db.PrepareContext(ctx, transitionTransactionStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query TransitionTransactionStatus: %w", err)
+	}
+	if q.updateAgentStatusStmt, err = db.PrepareContext(ctx, updateAgentStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateAgentStatus: %w", err)
+	}
+	if q.updateCustomerStmt, err = db.PrepareContext(ctx, updateCustomer); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateCustomer: %w", err)
+	}
+	if q.updateCustomerPhoneStmt, err = db.PrepareContext(ctx, updateCustomerPhone); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateCustomerPhone: %w", err)
+	}
+	if q.updateImportJobProgressStmt, err = db.PrepareContext(ctx, updateImportJobProgress); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateImportJobProgress: %w", err)
+	}
+	if q.updateProfilePasswordStmt, err = db.PrepareContext(ctx, updateProfilePassword); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateProfilePassword: %w", err)
+	}
+	if q.updateProfilePhoneStmt, err = db.PrepareContext(ctx, updateProfilePhone); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateProfilePhone: %w", err)
+	}
+	if q.updateRoutingRuleStmt, err = db.PrepareContext(ctx, updateRoutingRule); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateRoutingRule: %w", err)
+	}
+	if q.updateTOTPLastUsedStepStmt, err = db.PrepareContext(ctx, updateTOTPLastUsedStep); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateTOTPLastUsedStep: %w", err)
+	}
+	if q.updateTeamStmt, err = db.PrepareContext(ctx, updateTeam); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateTeam: %w", err)
+	}
 	if q.updateTicketStatusStmt, err = db.PrepareContext(ctx, updateTicketStatus); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateTicketStatus: %w", err)
 	}
+	if q.updateTicketTeamStmt, err = db.PrepareContext(ctx, updateTicketTeam); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateTicketTeam: %w", err)
+	}
 	if q.updateUserStmt, err = db.PrepareContext(ctx, updateUser); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateUser: %w", err)
 	}
+	if q.updateWebhookSecretStmt, err = db.PrepareContext(ctx, updateWebhookSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateWebhookSecret: %w", err)
+	}
+	if q.updateWebhookSubscriptionStmt, err = db.PrepareContext(ctx, updateWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateWebhookSubscription: %w", err)
+	}
+	if q.upsertAgentSettingsStmt, err = db.PrepareContext(ctx, upsertAgentSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertAgentSettings: %w", err)
+	}
+	if q.upsertTOTPSecretStmt, err = db.PrepareContext(ctx, upsertTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertTOTPSecret: %w", err)
+	}
+	if q.useRecoveryCodeStmt, err = db.PrepareContext(ctx, useRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query UseRecoveryCode: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addTeamMemberStmt != nil {
+		if cerr := q.addTeamMemberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addTeamMemberStmt: %w", cerr)
+		}
+	}
+	if q.anonymizeCustomerStmt != nil {
+		if cerr := q.anonymizeCustomerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizeCustomerStmt: %w", cerr)
+		}
+	}
+	if q.anonymizeCustomerMergeStmt != nil {
+		if cerr := q.anonymizeCustomerMergeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizeCustomerMergeStmt: %w", cerr)
+		}
+	}
+	if q.anonymizeLoginEventsStmt != nil {
+		if cerr := q.anonymizeLoginEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizeLoginEventsStmt: %w", cerr)
+		}
+	}
+	if q.anonymizeProfileStmt != nil {
+		if cerr := q.anonymizeProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizeProfileStmt: %w", cerr)
+		}
+	}
+	if q.anonymizeTicketStmt != nil {
+		if cerr := q.anonymizeTicketStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizeTicketStmt: %w", cerr)
+		}
+	}
 	if q.assignTicketStmt != nil {
 		if cerr := q.assignTicketStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing assignTicketStmt: %w", cerr)
 		}
 	}
+	if q.claimUnassignedTicketStmt != nil {
+		if cerr := q.claimUnassignedTicketStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimUnassignedTicketStmt: %w", cerr)
+		}
+	}
+	if q.claimWebhookDeliveryStmt != nil {
+		if cerr := q.claimWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimWebhookDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.clearLoginFailuresStmt != nil {
+		if cerr := q.clearLoginFailuresStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing clearLoginFailuresStmt: %w", cerr)
+		}
+	}
+	if q.completeMpesaStkRequestStmt != nil {
+		if cerr := q.completeMpesaStkRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing completeMpesaStkRequestStmt: %w", cerr)
+		}
+	}
+	if q.confirmTOTPSecretStmt != nil {
+		if cerr := q.confirmTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing confirmTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.countCustomerTimelineStmt != nil {
+		if cerr := q.countCustomerTimelineStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countCustomerTimelineStmt: %w", cerr)
+		}
+	}
+	if q.countCustomersStmt != nil {
+		if cerr := q.countCustomersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countCustomersStmt: %w", cerr)
+		}
+	}
+	if q.countLoginSourcesStmt != nil {
+		if cerr := q.countLoginSourcesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countLoginSourcesStmt: %w", cerr)
+		}
+	}
+	if q.countReconciliationItemsStmt != nil {
+		if cerr := q.countReconciliationItemsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countReconciliationItemsStmt: %w", cerr)
+		}
+	}
+	if q.createAPIKeyStmt != nil {
+		if cerr := q.createAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAPIKeyStmt: %w", cerr)
+		}
+	}
 	if q.createCustomerStmt != nil {
 		if cerr := q.createCustomerStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createCustomerStmt: %w", cerr)
 		}
 	}
+	if q.createCustomerDuplicateStmt != nil {
+		if cerr := q.createCustomerDuplicateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCustomerDuplicateStmt: %w", cerr)
+		}
+	}
+	if q.createCustomerMergeStmt != nil {
+		if cerr := q.createCustomerMergeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCustomerMergeStmt: %w", cerr)
+		}
+	}
+	if q.createDataSubjectRequestStmt != nil {
+		if cerr := q.createDataSubjectRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDataSubjectRequestStmt: %w", cerr)
+		}
+	}
+	if q.createEventStmt != nil {
+		if cerr := q.createEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createEventStmt: %w", cerr)
+		}
+	}
+	if q.createIdempotencyKeyStmt != nil {
+		if cerr := q.createIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.createImportJobStmt != nil {
+		if cerr := q.createImportJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createImportJobStmt: %w", cerr)
+		}
+	}
+	if q.createJournalEntryStmt != nil {
+		if cerr := q.createJournalEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createJournalEntryStmt: %w", cerr)
+		}
+	}
+	if q.createLoginEventStmt != nil {
+		if cerr := q.createLoginEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createLoginEventStmt: %w", cerr)
+		}
+	}
+	if q.createMpesaStkRequestStmt != nil {
+		if cerr := q.createMpesaStkRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createMpesaStkRequestStmt: %w", cerr)
+		}
+	}
 	if q.createOTPStmt != nil {
 		if cerr := q.createOTPStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createOTPStmt: %w", cerr)
 		}
 	}
+	if q.createPostingStmt != nil {
+		if cerr := q.createPostingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPostingStmt: %w", cerr)
+		}
+	}
 	if q.createProfileStmt != nil {
 		if cerr := q.createProfileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createProfileStmt: %w", cerr)
 		}
 	}
+	if q.createReconciliationItemStmt != nil {
+		if cerr := q.createReconciliationItemStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createReconciliationItemStmt: %w", cerr)
+		}
+	}
+	if q.createReconciliationRunStmt != nil {
+		if cerr := q.createReconciliationRunStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createReconciliationRunStmt: %w", cerr)
+		}
+	}
+	if q.createRecoveryCodeStmt != nil {
+		if cerr := q.createRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRecoveryCodeStmt: %w", cerr)
+		}
+	}
+	if q.createRefundStmt != nil {
+		if cerr := q.createRefundStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRefundStmt: %w", cerr)
+		}
+	}
+	if q.createRoutingRuleStmt != nil {
+		if cerr := q.createRoutingRuleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRoutingRuleStmt: %w", cerr)
+		}
+	}
+	if q.createStreamTicketStmt != nil {
+		if cerr := q.createStreamTicketStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createStreamTicketStmt: %w", cerr)
+		}
+	}
+	if q.createTeamStmt != nil {
+		if cerr := q.createTeamStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTeamStmt: %w", cerr)
+		}
+	}
 	if q.createTicketStmt != nil {
 		if cerr := q.createTicketStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createTicketStmt: %w", cerr)
 		}
 	}
+	if q.createTicketAssignmentStmt != nil {
+		if cerr := q.createTicketAssignmentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTicketAssignmentStmt: %w", cerr)
+		}
+	}
 	if q.createTransactionStmt != nil {
 		if cerr := q.createTransactionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createTransactionStmt: %w", cerr)
@@ -139,31 +765,241 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
 		}
 	}
+	if q.createWebhookDeliveryStmt != nil {
+		if cerr := q.createWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookSubscriptionStmt != nil {
+		if cerr := q.createWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.deleteCustomerStmt != nil {
+		if cerr := q.deleteCustomerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCustomerStmt: %w", cerr)
+		}
+	}
+	if q.deleteCustomerDuplicatesStmt != nil {
+		if cerr := q.deleteCustomerDuplicatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCustomerDuplicatesStmt: %w", cerr)
+		}
+	}
+	if q.deleteEventsBeforeStmt != nil {
+		if cerr := q.deleteEventsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteEventsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.deleteEventsBySubjectStmt != nil {
+		if cerr := q.deleteEventsBySubjectStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteEventsBySubjectStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredIdempotencyKeysStmt != nil {
+		if cerr := q.deleteExpiredIdempotencyKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredIdempotencyKeysStmt: %w", cerr)
+		}
+	}
 	if q.deleteExpiredOTPsStmt != nil {
 		if cerr := q.deleteExpiredOTPsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteExpiredOTPsStmt: %w", cerr)
 		}
 	}
+	if q.deleteExpiredStreamTicketsStmt != nil {
+		if cerr := q.deleteExpiredStreamTicketsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredStreamTicketsStmt: %w", cerr)
+		}
+	}
+	if q.deleteIdempotencyKeyStmt != nil {
+		if cerr := q.deleteIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.deleteOTPsByProfileStmt != nil {
+		if cerr := q.deleteOTPsByProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOTPsByProfileStmt: %w", cerr)
+		}
+	}
+	if q.deleteRecoveryCodesStmt != nil {
+		if cerr := q.deleteRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRecoveryCodesStmt: %w", cerr)
+		}
+	}
+	if q.deleteRoutingRuleStmt != nil {
+		if cerr := q.deleteRoutingRuleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRoutingRuleStmt: %w", cerr)
+		}
+	}
+	if q.deleteTOTPSecretStmt != nil {
+		if cerr := q.deleteTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.deleteTeamStmt != nil {
+		if cerr := q.deleteTeamStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteTeamStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookDeliveriesBySubjectStmt != nil {
+		if cerr := q.deleteWebhookDeliveriesBySubjectStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookDeliveriesBySubjectStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookSubscriptionStmt != nil {
+		if cerr := q.deleteWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookSubscriptionsByProfileStmt != nil {
+		if cerr := q.deleteWebhookSubscriptionsByProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookSubscriptionsByProfileStmt: %w", cerr)
+		}
+	}
+	if q.disableWebhookSubscriptionStmt != nil {
+		if cerr := q.disableWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing disableWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.ensureLedgerAccountStmt != nil {
+		if cerr := q.ensureLedgerAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing ensureLedgerAccountStmt: %w", cerr)
+		}
+	}
+	if q.exportTransactionsStmt != nil {
+		if cerr := q.exportTransactionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing exportTransactionsStmt: %w", cerr)
+		}
+	}
+	if q.failStaleImportJobsStmt != nil {
+		if cerr := q.failStaleImportJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing failStaleImportJobsStmt: %w", cerr)
+		}
+	}
+	if q.finishImportJobStmt != nil {
+		if cerr := q.finishImportJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing finishImportJobStmt: %w", cerr)
+		}
+	}
+	if q.getAPIKeyByPrefixStmt != nil {
+		if cerr := q.getAPIKeyByPrefixStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAPIKeyByPrefixStmt: %w", cerr)
+		}
+	}
+	if q.getAccountBalanceStmt != nil {
+		if cerr := q.getAccountBalanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountBalanceStmt: %w", cerr)
+		}
+	}
+	if q.getAgentSettingsStmt != nil {
+		if cerr := q.getAgentSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAgentSettingsStmt: %w", cerr)
+		}
+	}
+	if q.getCustomerStmt != nil {
+		if cerr := q.getCustomerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCustomerStmt: %w", cerr)
+		}
+	}
 	if q.getCustomerByEmailStmt != nil {
 		if cerr := q.getCustomerByEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getCustomerByEmailStmt: %w", cerr)
 		}
 	}
+	if q.getCustomerByPhoneStmt != nil {
+		if cerr := q.getCustomerByPhoneStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCustomerByPhoneStmt: %w", cerr)
+		}
+	}
+	if q.getCustomerMergeStmt != nil {
+		if cerr := q.getCustomerMergeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCustomerMergeStmt: %w", cerr)
+		}
+	}
 	if q.getCustomersStmt != nil {
 		if cerr := q.getCustomersStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getCustomersStmt: %w", cerr)
 		}
 	}
+	if q.getIdempotencyKeyStmt != nil {
+		if cerr := q.getIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.getImportJobStmt != nil {
+		if cerr := q.getImportJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getImportJobStmt: %w", cerr)
+		}
+	}
+	if q.getLastTeamAssigneeStmt != nil {
+		if cerr := q.getLastTeamAssigneeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLastTeamAssigneeStmt: %w", cerr)
+		}
+	}
 	if q.getLatestOTPByProfileIDStmt != nil {
 		if cerr := q.getLatestOTPByProfileIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getLatestOTPByProfileIDStmt: %w", cerr)
 		}
 	}
+	if q.getLedgerAccountStmt != nil {
+		if cerr := q.getLedgerAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLedgerAccountStmt: %w", cerr)
+		}
+	}
+	if q.getLedgerAccountByCodeStmt != nil {
+		if cerr := q.getLedgerAccountByCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLedgerAccountByCodeStmt: %w", cerr)
+		}
+	}
+	if q.getMpesaStkRequestByCheckoutIDStmt != nil {
+		if cerr := q.getMpesaStkRequestByCheckoutIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMpesaStkRequestByCheckoutIDStmt: %w", cerr)
+		}
+	}
+	if q.getProfileByEmailStmt != nil {
+		if cerr := q.getProfileByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getProfileByEmailStmt: %w", cerr)
+		}
+	}
+	if q.getProfileByIDStmt != nil {
+		if cerr := q.getProfileByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getProfileByIDStmt: %w", cerr)
+		}
+	}
 	if q.getProfileByPhoneStmt != nil {
 		if cerr := q.getProfileByPhoneStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getProfileByPhoneStmt: %w", cerr)
 		}
 	}
+	if q.getProfileLockoutStmt != nil {
+		if cerr := q.getProfileLockoutStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getProfileLockoutStmt: %w", cerr)
+		}
+	}
+	if q.getReconciliationRunStmt != nil {
+		if cerr := q.getReconciliationRunStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getReconciliationRunStmt: %w", cerr)
+		}
+	}
+	if q.getRoutingRuleStmt != nil {
+		if cerr := q.getRoutingRuleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRoutingRuleStmt: %w", cerr)
+		}
+	}
+	if q.getStreamTicketStmt != nil {
+		if cerr := q.getStreamTicketStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStreamTicketStmt: %w", cerr)
+		}
+	}
+	if q.getTOTPSecretStmt != nil {
+		if cerr := q.getTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.getTeamStmt != nil {
+		if cerr := q.getTeamStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTeamStmt: %w", cerr)
+		}
+	}
 	if q.getTicketStmt != nil {
 		if cerr := q.getTicketStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getTicketStmt: %w", cerr)
@@ -174,24 +1010,34 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getTicketByTitleAndUserStmt: %w", cerr)
 		}
 	}
+	if q.getTransactionForUpdateStmt != nil {
+		if cerr := q.getTransactionForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTransactionForUpdateStmt: %w", cerr)
+		}
+	}
 	if q.getTransanctionByIDStmt != nil {
 		if cerr := q.getTransanctionByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getTransanctionByIDStmt: %w", cerr)
 		}
 	}
-	if q.getUserByEmailStmt != nil {
-		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
-		}
-	}
 	if q.getUserByEmailExcludingIDStmt != nil {
 		if cerr := q.getUserByEmailExcludingIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserByEmailExcludingIDStmt: %w", cerr)
 		}
 	}
-	if q.getUserByIDStmt != nil {
-		if cerr := q.getUserByIDStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing getUserByIDStmt: %w", cerr)
+	if q.getUserByPhoneExcludingIDStmt != nil {
+		if cerr := q.getUserByPhoneExcludingIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByPhoneExcludingIDStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookDeliveryStmt != nil {
+		if cerr := q.getWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookSubscriptionStmt != nil {
+		if cerr := q.getWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookSubscriptionStmt: %w", cerr)
 		}
 	}
 	if q.incrementOTPAttemptsStmt != nil {
@@ -199,36 +1045,406 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing incrementOTPAttemptsStmt: %w", cerr)
 		}
 	}
+	if q.incrementWebhookFailuresStmt != nil {
+		if cerr := q.incrementWebhookFailuresStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing incrementWebhookFailuresStmt: %w", cerr)
+		}
+	}
+	if q.listAPIKeysStmt != nil {
+		if cerr := q.listAPIKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAPIKeysStmt: %w", cerr)
+		}
+	}
+	if q.listActiveRoutingRulesStmt != nil {
+		if cerr := q.listActiveRoutingRulesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listActiveRoutingRulesStmt: %w", cerr)
+		}
+	}
+	if q.listActiveWebhookSubscriptionsStmt != nil {
+		if cerr := q.listActiveWebhookSubscriptionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listActiveWebhookSubscriptionsStmt: %w", cerr)
+		}
+	}
+	if q.listAgentsStmt != nil {
+		if cerr := q.listAgentsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAgentsStmt: %w", cerr)
+		}
+	}
+	if q.listCustomerDuplicatesStmt != nil {
+		if cerr := q.listCustomerDuplicatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomerDuplicatesStmt: %w", cerr)
+		}
+	}
+	if q.listCustomerMergesStmt != nil {
+		if cerr := q.listCustomerMergesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomerMergesStmt: %w", cerr)
+		}
+	}
+	if q.listCustomerMergesByContactStmt != nil {
+		if cerr := q.listCustomerMergesByContactStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomerMergesByContactStmt: %w", cerr)
+		}
+	}
+	if q.listCustomerMergesByIDsStmt != nil {
+		if cerr := q.listCustomerMergesByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomerMergesByIDsStmt: %w", cerr)
+		}
+	}
+	if q.listCustomerTicketLinksStmt != nil {
+		if cerr := q.listCustomerTicketLinksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomerTicketLinksStmt: %w", cerr)
+		}
+	}
+	if q.listCustomerTimelineStmt != nil {
+		if cerr := q.listCustomerTimelineStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomerTimelineStmt: %w", cerr)
+		}
+	}
+	if q.listCustomersAfterStmt != nil {
+		if cerr := q.listCustomersAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCustomersAfterStmt: %w", cerr)
+		}
+	}
+	if q.listDataSubjectRequestsStmt != nil {
+		if cerr := q.listDataSubjectRequestsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDataSubjectRequestsStmt: %w", cerr)
+		}
+	}
+	if q.listDueWebhookDeliveriesStmt != nil {
+		if cerr := q.listDueWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDueWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.listEntriesWithTooFewPostingsStmt != nil {
+		if cerr := q.listEntriesWithTooFewPostingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listEntriesWithTooFewPostingsStmt: %w", cerr)
+		}
+	}
+	if q.listEventsAfterStmt != nil {
+		if cerr := q.listEventsAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listEventsAfterStmt: %w", cerr)
+		}
+	}
+	if q.listEventsByIDsStmt != nil {
+		if cerr := q.listEventsByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listEventsByIDsStmt: %w", cerr)
+		}
+	}
+	if q.listLedgerAccountsStmt != nil {
+		if cerr := q.listLedgerAccountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLedgerAccountsStmt: %w", cerr)
+		}
+	}
+	if q.listLoginEventsForSubjectStmt != nil {
+		if cerr := q.listLoginEventsForSubjectStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLoginEventsForSubjectStmt: %w", cerr)
+		}
+	}
+	if q.listMpesaStkRequestsByUserStmt != nil {
+		if cerr := q.listMpesaStkRequestsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMpesaStkRequestsByUserStmt: %w", cerr)
+		}
+	}
+	if q.listOTPsByProfileStmt != nil {
+		if cerr := q.listOTPsByProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listOTPsByProfileStmt: %w", cerr)
+		}
+	}
+	if q.listOwnTicketsStmt != nil {
+		if cerr := q.listOwnTicketsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listOwnTicketsStmt: %w", cerr)
+		}
+	}
+	if q.listPostingCurrencyMismatchesStmt != nil {
+		if cerr := q.listPostingCurrencyMismatchesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPostingCurrencyMismatchesStmt: %w", cerr)
+		}
+	}
+	if q.listProfilePhonesStmt != nil {
+		if cerr := q.listProfilePhonesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listProfilePhonesStmt: %w", cerr)
+		}
+	}
+	if q.listReconciliationItemsStmt != nil {
+		if cerr := q.listReconciliationItemsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listReconciliationItemsStmt: %w", cerr)
+		}
+	}
+	if q.listReconciliationRunsStmt != nil {
+		if cerr := q.listReconciliationRunsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listReconciliationRunsStmt: %w", cerr)
+		}
+	}
+	if q.listRefundsStmt != nil {
+		if cerr := q.listRefundsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRefundsStmt: %w", cerr)
+		}
+	}
+	if q.listRoutingRulesStmt != nil {
+		if cerr := q.listRoutingRulesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRoutingRulesStmt: %w", cerr)
+		}
+	}
+	if q.listTeamCandidatesStmt != nil {
+		if cerr := q.listTeamCandidatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTeamCandidatesStmt: %w", cerr)
+		}
+	}
+	if q.listTeamMembersStmt != nil {
+		if cerr := q.listTeamMembersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTeamMembersStmt: %w", cerr)
+		}
+	}
+	if q.listTeamsStmt != nil {
+		if cerr := q.listTeamsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTeamsStmt: %w", cerr)
+		}
+	}
+	if q.listTicketAssignmentsStmt != nil {
+		if cerr := q.listTicketAssignmentsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTicketAssignmentsStmt: %w", cerr)
+		}
+	}
 	if q.listTicketsStmt != nil {
 		if cerr := q.listTicketsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listTicketsStmt: %w", cerr)
 		}
 	}
+	if q.listTicketsByCreatorStmt != nil {
+		if cerr := q.listTicketsByCreatorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTicketsByCreatorStmt: %w", cerr)
+		}
+	}
+	if q.listTicketsByCustomerStmt != nil {
+		if cerr := q.listTicketsByCustomerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTicketsByCustomerStmt: %w", cerr)
+		}
+	}
+	if q.listTicketsByIDsStmt != nil {
+		if cerr := q.listTicketsByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTicketsByIDsStmt: %w", cerr)
+		}
+	}
+	if q.listTicketsByTeamStmt != nil {
+		if cerr := q.listTicketsByTeamStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTicketsByTeamStmt: %w", cerr)
+		}
+	}
+	if q.listTicketsByTransactionStmt != nil {
+		if cerr := q.listTicketsByTransactionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTicketsByTransactionStmt: %w", cerr)
+		}
+	}
 	if q.listTransactionsStmt != nil {
 		if cerr := q.listTransactionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listTransactionsStmt: %w", cerr)
 		}
 	}
+	if q.listTransactionsByIDsStmt != nil {
+		if cerr := q.listTransactionsByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTransactionsByIDsStmt: %w", cerr)
+		}
+	}
+	if q.listTransactionsByUserStmt != nil {
+		if cerr := q.listTransactionsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTransactionsByUserStmt: %w", cerr)
+		}
+	}
+	if q.listTransactionsForReconciliationStmt != nil {
+		if cerr := q.listTransactionsForReconciliationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTransactionsForReconciliationStmt: %w", cerr)
+		}
+	}
+	if q.listUnbalancedEntriesStmt != nil {
+		if cerr := q.listUnbalancedEntriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUnbalancedEntriesStmt: %w", cerr)
+		}
+	}
+	if q.listUnpostedTransactionsStmt != nil {
+		if cerr := q.listUnpostedTransactionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUnpostedTransactionsStmt: %w", cerr)
+		}
+	}
 	if q.listUsersStmt != nil {
 		if cerr := q.listUsersStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listUsersStmt: %w", cerr)
 		}
 	}
+	if q.listWebhookDeliveriesStmt != nil {
+		if cerr := q.listWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.listWebhookSubscriptionsStmt != nil {
+		if cerr := q.listWebhookSubscriptionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWebhookSubscriptionsStmt: %w", cerr)
+		}
+	}
+	if q.lockCustomerStmt != nil {
+		if cerr := q.lockCustomerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockCustomerStmt: %w", cerr)
+		}
+	}
+	if q.lockProfileStmt != nil {
+		if cerr := q.lockProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockProfileStmt: %w", cerr)
+		}
+	}
 	if q.markOTPVerifiedStmt != nil {
 		if cerr := q.markOTPVerifiedStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing markOTPVerifiedStmt: %w", cerr)
 		}
 	}
+	if q.moveCustomerTicketsStmt != nil {
+		if cerr := q.moveCustomerTicketsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing moveCustomerTicketsStmt: %w", cerr)
+		}
+	}
+	if q.recordLoginFailureStmt != nil {
+		if cerr := q.recordLoginFailureStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordLoginFailureStmt: %w", cerr)
+		}
+	}
+	if q.recordWebhookAttemptStmt != nil {
+		if cerr := q.recordWebhookAttemptStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordWebhookAttemptStmt: %w", cerr)
+		}
+	}
+	if q.removeTeamMemberStmt != nil {
+		if cerr := q.removeTeamMemberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeTeamMemberStmt: %w", cerr)
+		}
+	}
+	if q.renewStreamTicketStmt != nil {
+		if cerr := q.renewStreamTicketStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing renewStreamTicketStmt: %w", cerr)
+		}
+	}
+	if q.resetWebhookFailuresStmt != nil {
+		if cerr := q.resetWebhookFailuresStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resetWebhookFailuresStmt: %w", cerr)
+		}
+	}
+	if q.revokeAPIKeyStmt != nil {
+		if cerr := q.revokeAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.saveIdempotentResponseStmt != nil {
+		if cerr := q.saveIdempotentResponseStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing saveIdempotentResponseStmt: %w", cerr)
+		}
+	}
+	if q.startImportJobStmt != nil {
+		if cerr := q.startImportJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing startImportJobStmt: %w", cerr)
+		}
+	}
+	if q.sumRefundsStmt != nil {
+		if cerr := q.sumRefundsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing sumRefundsStmt: %w", cerr)
+		}
+	}
+	if q.sumTransactionsByCurrencyStmt != nil {
+		if cerr := q.sumTransactionsByCurrencyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing sumTransactionsByCurrencyStmt: %w", cerr)
+		}
+	}
+	if q.touchAPIKeyStmt != nil {
+		if cerr := q.touchAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.transitionTransactionStatusStmt != nil {
+		if cerr := q.transitionTransactionStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing transitionTransactionStatusStmt: %w", cerr)
+		}
+	}
+	if q.updateAgentStatusStmt != nil {
+		if cerr := q.updateAgentStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateAgentStatusStmt: %w", cerr)
+		}
+	}
+	if q.updateCustomerStmt != nil {
+		if cerr := q.updateCustomerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateCustomerStmt: %w", cerr)
+		}
+	}
+	if q.updateCustomerPhoneStmt != nil {
+		if cerr := q.updateCustomerPhoneStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateCustomerPhoneStmt: %w", cerr)
+		}
+	}
+	if q.updateImportJobProgressStmt != nil {
+		if cerr := q.updateImportJobProgressStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateImportJobProgressStmt: %w", cerr)
+		}
+	}
+	if q.updateProfilePasswordStmt != nil {
+		if cerr := q.updateProfilePasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateProfilePasswordStmt: %w", cerr)
+		}
+	}
+	if q.updateProfilePhoneStmt != nil {
+		if cerr := q.updateProfilePhoneStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateProfilePhoneStmt: %w", cerr)
+		}
+	}
+	if q.updateRoutingRuleStmt != nil {
+		if cerr := q.updateRoutingRuleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateRoutingRuleStmt: %w", cerr)
+		}
+	}
+	if q.updateTOTPLastUsedStepStmt != nil {
+		if cerr := q.updateTOTPLastUsedStepStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateTOTPLastUsedStepStmt: %w", cerr)
+		}
+	}
+	if q.updateTeamStmt != nil {
+		if cerr := q.updateTeamStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateTeamStmt: %w", cerr)
+		}
+	}
 	if q.updateTicketStatusStmt != nil {
 		if cerr := q.updateTicketStatusStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateTicketStatusStmt: %w", cerr)
 		}
 	}
+	if q.updateTicketTeamStmt != nil {
+		if cerr := q.updateTicketTeamStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateTicketTeamStmt: %w", cerr)
+		}
+	}
 	if q.updateUserStmt != nil {
 		if cerr := q.updateUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateUserStmt: %w", cerr)
 		}
 	}
+	if q.updateWebhookSecretStmt != nil {
+		if cerr := q.updateWebhookSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateWebhookSecretStmt: %w", cerr)
+		}
+	}
+	if q.updateWebhookSubscriptionStmt != nil {
+		if cerr := q.updateWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.upsertAgentSettingsStmt != nil {
+		if cerr := q.upsertAgentSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertAgentSettingsStmt: %w", cerr)
+		}
+	}
+	if q.upsertTOTPSecretStmt != nil {
+		if cerr := q.upsertTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.useRecoveryCodeStmt != nil {
+		if cerr := q.useRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useRecoveryCodeStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -266,63 +1482,367 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                            DBTX
-	tx                            *sql.Tx
-	assignTicketStmt              *sql.Stmt
-	createCustomerStmt            *sql.Stmt
-	createOTPStmt                 *sql.Stmt
-	createProfileStmt             *sql.Stmt
-	createTicketStmt              *sql.Stmt
-	createTransactionStmt         *sql.Stmt
-	createUserStmt                *sql.Stmt
-	deleteExpiredOTPsStmt         *sql.Stmt
-	getCustomerByEmailStmt        *sql.Stmt
-	getCustomersStmt              *sql.Stmt
-	getLatestOTPByProfileIDStmt   *sql.Stmt
-	getProfileByPhoneStmt         *sql.Stmt
-	getTicketStmt                 *sql.Stmt
-	getTicketByTitleAndUserStmt   *sql.Stmt
-	getTransanctionByIDStmt       *sql.Stmt
-	getUserByEmailStmt            *sql.Stmt
-	getUserByEmailExcludingIDStmt *sql.Stmt
-	getUserByIDStmt               *sql.Stmt
-	incrementOTPAttemptsStmt      *sql.Stmt
-	listTicketsStmt               *sql.Stmt
-	listTransactionsStmt          *sql.Stmt
-	listUsersStmt                 *sql.Stmt
-	markOTPVerifiedStmt           *sql.Stmt
-	updateTicketStatusStmt        *sql.Stmt
-	updateUserStmt                *sql.Stmt
+	db                                      DBTX
+	tx                                      *sql.Tx
+	addTeamMemberStmt                       *sql.Stmt
+	anonymizeCustomerStmt                   *sql.Stmt
+	anonymizeCustomerMergeStmt              *sql.Stmt
+	anonymizeLoginEventsStmt                *sql.Stmt
+	anonymizeProfileStmt                    *sql.Stmt
+	anonymizeTicketStmt                     *sql.Stmt
+	assignTicketStmt                        *sql.Stmt
+	claimUnassignedTicketStmt               *sql.Stmt
+	claimWebhookDeliveryStmt                *sql.Stmt
+	clearLoginFailuresStmt                  *sql.Stmt
+	completeMpesaStkRequestStmt             *sql.Stmt
+	confirmTOTPSecretStmt                   *sql.Stmt
+	countCustomerTimelineStmt               *sql.Stmt
+	countCustomersStmt                      *sql.Stmt
+	countLoginSourcesStmt                   *sql.Stmt
+	countReconciliationItemsStmt            *sql.Stmt
+	createAPIKeyStmt                        *sql.Stmt
+	createCustomerStmt                      *sql.Stmt
+	createCustomerDuplicateStmt             *sql.Stmt
+	createCustomerMergeStmt                 *sql.Stmt
+	createDataSubjectRequestStmt            *sql.Stmt
+	createEventStmt                         *sql.Stmt
+	createIdempotencyKeyStmt                *sql.Stmt
+	createImportJobStmt                     *sql.Stmt
+	createJournalEntryStmt                  *sql.Stmt
+	createLoginEventStmt                    *sql.Stmt
+	createMpesaStkRequestStmt               *sql.Stmt
+	createOTPStmt                           *sql.Stmt
+	createPostingStmt                       *sql.Stmt
+	createProfileStmt                       *sql.Stmt
+	createReconciliationItemStmt            *sql.Stmt
+	createReconciliationRunStmt             *sql.Stmt
+	createRecoveryCodeStmt                  *sql.Stmt
+	createRefundStmt                        *sql.Stmt
+	createRoutingRuleStmt                   *sql.Stmt
+	createStreamTicketStmt                  *sql.Stmt
+	createTeamStmt                          *sql.Stmt
+	createTicketStmt                        *sql.Stmt
+	createTicketAssignmentStmt              *sql.Stmt
+	createTransactionStmt                   *sql.Stmt
+	createUserStmt                          *sql.Stmt
+	createWebhookDeliveryStmt               *sql.Stmt
+	createWebhookSubscriptionStmt           *sql.Stmt
+	deleteCustomerStmt                      *sql.Stmt
+	deleteCustomerDuplicatesStmt            *sql.Stmt
+	deleteEventsBeforeStmt                  *sql.Stmt
+	deleteEventsBySubjectStmt               *sql.Stmt
+	deleteExpiredIdempotencyKeysStmt        *sql.Stmt
+	deleteExpiredOTPsStmt                   *sql.Stmt
+	deleteExpiredStreamTicketsStmt          *sql.Stmt
+	deleteIdempotencyKeyStmt                *sql.Stmt
+	deleteOTPsByProfileStmt                 *sql.Stmt
+	deleteRecoveryCodesStmt                 *sql.Stmt
+	deleteRoutingRuleStmt                   *sql.Stmt
+	deleteTOTPSecretStmt                    *sql.Stmt
+	deleteTeamStmt                          *sql.Stmt
+	deleteWebhookDeliveriesBySubjectStmt    *sql.Stmt
+	deleteWebhookSubscriptionStmt           *sql.Stmt
+	deleteWebhookSubscriptionsByProfileStmt *sql.Stmt
+	disableWebhookSubscriptionStmt          *sql.Stmt
+	ensureLedgerAccountStmt                 *sql.Stmt
+	exportTransactionsStmt                  *sql.Stmt
+	failStaleImportJobsStmt                 *sql.Stmt
+	finishImportJobStmt                     *sql.Stmt
+	getAPIKeyByPrefixStmt                   *sql.Stmt
+	getAccountBalanceStmt                   *sql.Stmt
+	getAgentSettingsStmt                    *sql.Stmt
+	getCustomerStmt                         *sql.Stmt
+	getCustomerByEmailStmt                  *sql.Stmt
+	getCustomerByPhoneStmt                  *sql.Stmt
+	getCustomerMergeStmt                    *sql.Stmt
+	getCustomersStmt                        *sql.Stmt
+	getIdempotencyKeyStmt                   *sql.Stmt
+	getImportJobStmt                        *sql.Stmt
+	getLastTeamAssigneeStmt                 *sql.Stmt
+	getLatestOTPByProfileIDStmt             *sql.Stmt
+	getLedgerAccountStmt                    *sql.Stmt
+	getLedgerAccountByCodeStmt              *sql.Stmt
+	getMpesaStkRequestByCheckoutIDStmt      *sql.Stmt
+	getProfileByEmailStmt                   *sql.Stmt
+	getProfileByIDStmt                      *sql.Stmt
+	getProfileByPhoneStmt                   *sql.Stmt
+	getProfileLockoutStmt                   *sql.Stmt
+	getReconciliationRunStmt                *sql.Stmt
+	getRoutingRuleStmt                      *sql.Stmt
+	getStreamTicketStmt                     *sql.Stmt
+	getTOTPSecretStmt                       *sql.Stmt
+	getTeamStmt                             *sql.Stmt
+	getTicketStmt                           *sql.Stmt
+	getTicketByTitleAndUserStmt             *sql.Stmt
+	getTransactionForUpdateStmt             *sql.Stmt
+	getTransanctionByIDStmt                 *sql.Stmt
+	getUserByEmailExcludingIDStmt           *sql.Stmt
+	getUserByPhoneExcludingIDStmt           *sql.Stmt
+	getWebhookDeliveryStmt                  *sql.Stmt
+	getWebhookSubscriptionStmt              *sql.Stmt
+	incrementOTPAttemptsStmt                *sql.Stmt
+	incrementWebhookFailuresStmt            *sql.Stmt
+	listAPIKeysStmt                         *sql.Stmt
+	listActiveRoutingRulesStmt              *sql.Stmt
+	listActiveWebhookSubscriptionsStmt      *sql.Stmt
+	listAgentsStmt                          *sql.Stmt
+	listCustomerDuplicatesStmt              *sql.Stmt
+	listCustomerMergesStmt                  *sql.Stmt
+	listCustomerMergesByContactStmt         *sql.Stmt
+	listCustomerMergesByIDsStmt             *sql.Stmt
+	listCustomerTicketLinksStmt             *sql.Stmt
+	listCustomerTimelineStmt                *sql.Stmt
+	listCustomersAfterStmt                  *sql.Stmt
+	listDataSubjectRequestsStmt             *sql.Stmt
+	listDueWebhookDeliveriesStmt            *sql.Stmt
+	listEntriesWithTooFewPostingsStmt       *sql.Stmt
+	listEventsAfterStmt                     *sql.Stmt
+	listEventsByIDsStmt                     *sql.Stmt
+	listLedgerAccountsStmt                  *sql.Stmt
+	listLoginEventsForSubjectStmt           *sql.Stmt
+	listMpesaStkRequestsByUserStmt          *sql.Stmt
+	listOTPsByProfileStmt                   *sql.Stmt
+	listOwnTicketsStmt                      *sql.Stmt
+	listPostingCurrencyMismatchesStmt       *sql.Stmt
+	listProfilePhonesStmt                   *sql.Stmt
+	listReconciliationItemsStmt             *sql.Stmt
+	listReconciliationRunsStmt              *sql.Stmt
+	listRefundsStmt                         *sql.Stmt
+	listRoutingRulesStmt                    *sql.Stmt
+	listTeamCandidatesStmt                  *sql.Stmt
+	listTeamMembersStmt                     *sql.Stmt
+	listTeamsStmt                           *sql.Stmt
+	listTicketAssignmentsStmt               *sql.Stmt
+	listTicketsStmt                         *sql.Stmt
+	listTicketsByCreatorStmt                *sql.Stmt
+	listTicketsByCustomerStmt               *sql.Stmt
+	listTicketsByIDsStmt                    *sql.Stmt
+	listTicketsByTeamStmt                   *sql.Stmt
+	listTicketsByTransactionStmt            *sql.Stmt
+	listTransactionsStmt                    *sql.Stmt
+	listTransactionsByIDsStmt               *sql.Stmt
+	listTransactionsByUserStmt              *sql.Stmt
+	listTransactionsForReconciliationStmt   *sql.Stmt
+	listUnbalancedEntriesStmt               *sql.Stmt
+	listUnpostedTransactionsStmt            *sql.Stmt
+	listUsersStmt                           *sql.Stmt
+	listWebhookDeliveriesStmt               *sql.Stmt
+	listWebhookSubscriptionsStmt            *sql.Stmt
+	lockCustomerStmt                        *sql.Stmt
+	lockProfileStmt                         *sql.Stmt
+	markOTPVerifiedStmt                     *sql.Stmt
+	moveCustomerTicketsStmt                 *sql.Stmt
+	recordLoginFailureStmt                  *sql.Stmt
+	recordWebhookAttemptStmt                *sql.Stmt
+	removeTeamMemberStmt                    *sql.Stmt
+	renewStreamTicketStmt                   *sql.Stmt
+	resetWebhookFailuresStmt                *sql.Stmt
+	revokeAPIKeyStmt                        *sql.Stmt
+	saveIdempotentResponseStmt              *sql.Stmt
+	startImportJobStmt                      *sql.Stmt
+	sumRefundsStmt                          *sql.Stmt
+	sumTransactionsByCurrencyStmt           *sql.Stmt
+	touchAPIKeyStmt                         *sql.Stmt
+	transitionTransactionStatusStmt         *sql.Stmt
+	updateAgentStatusStmt                   *sql.Stmt
+	updateCustomerStmt                      *sql.Stmt
+	updateCustomerPhoneStmt                 *sql.Stmt
+	updateImportJobProgressStmt             *sql.Stmt
+	updateProfilePasswordStmt               *sql.Stmt
+	updateProfilePhoneStmt                  *sql.Stmt
+	updateRoutingRuleStmt                   *sql.Stmt
+	updateTOTPLastUsedStepStmt              *sql.Stmt
+	updateTeamStmt                          *sql.Stmt
+	updateTicketStatusStmt                  *sql.Stmt
+	updateTicketTeamStmt                    *sql.Stmt
+	updateUserStmt                          *sql.Stmt
+	updateWebhookSecretStmt                 *sql.Stmt
+	updateWebhookSubscriptionStmt           *sql.Stmt
+	upsertAgentSettingsStmt                 *sql.Stmt
+	upsertTOTPSecretStmt                    *sql.Stmt
+	useRecoveryCodeStmt                     *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                            tx,
-		tx:                            tx,
-		assignTicketStmt:              q.assignTicketStmt,
-		createCustomerStmt:            q.createCustomerStmt,
-		createOTPStmt:                 q.createOTPStmt,
-		createProfileStmt:             q.createProfileStmt,
-		createTicketStmt:              q.createTicketStmt,
-		createTransactionStmt:         q.createTransactionStmt,
-		createUserStmt:                q.createUserStmt,
-		deleteExpiredOTPsStmt:         q.deleteExpiredOTPsStmt,
-		getCustomerByEmailStmt:        q.getCustomerByEmailStmt,
-		getCustomersStmt:              q.getCustomersStmt,
-		getLatestOTPByProfileIDStmt:   q.getLatestOTPByProfileIDStmt,
-		getProfileByPhoneStmt:         q.getProfileByPhoneStmt,
-		getTicketStmt:                 q.getTicketStmt,
-		getTicketByTitleAndUserStmt:   q.getTicketByTitleAndUserStmt,
-		getTransanctionByIDStmt:       q.getTransanctionByIDStmt,
-		getUserByEmailStmt:            q.getUserByEmailStmt,
-		getUserByEmailExcludingIDStmt: q.getUserByEmailExcludingIDStmt,
-		getUserByIDStmt:               q.getUserByIDStmt,
-		incrementOTPAttemptsStmt:      q.incrementOTPAttemptsStmt,
-		listTicketsStmt:               q.listTicketsStmt,
-		listTransactionsStmt:          q.listTransactionsStmt,
-		listUsersStmt:                 q.listUsersStmt,
-		markOTPVerifiedStmt:           q.markOTPVerifiedStmt,
-		updateTicketStatusStmt:        q.updateTicketStatusStmt,
-		updateUserStmt:                q.updateUserStmt,
+		db:                                      tx,
+		tx:                                      tx,
+		addTeamMemberStmt:                       q.addTeamMemberStmt,
+		anonymizeCustomerStmt:                   q.anonymizeCustomerStmt,
+		anonymizeCustomerMergeStmt:              q.anonymizeCustomerMergeStmt,
+		anonymizeLoginEventsStmt:                q.anonymizeLoginEventsStmt,
+		anonymizeProfileStmt:                    q.anonymizeProfileStmt,
+		anonymizeTicketStmt:                     q.anonymizeTicketStmt,
+		assignTicketStmt:                        q.assignTicketStmt,
+		claimUnassignedTicketStmt:               q.claimUnassignedTicketStmt,
+		claimWebhookDeliveryStmt:                q.claimWebhookDeliveryStmt,
+		clearLoginFailuresStmt:                  q.clearLoginFailuresStmt,
+		completeMpesaStkRequestStmt:             q.completeMpesaStkRequestStmt,
+		confirmTOTPSecretStmt:                   q.confirmTOTPSecretStmt,
+		countCustomerTimelineStmt:               q.countCustomerTimelineStmt,
+		countCustomersStmt:                      q.countCustomersStmt,
+		countLoginSourcesStmt:                   q.countLoginSourcesStmt,
+		countReconciliationItemsStmt:            q.countReconciliationItemsStmt,
+		createAPIKeyStmt:                        q.createAPIKeyStmt,
+		createCustomerStmt:                      q.createCustomerStmt,
+		createCustomerDuplicateStmt:             q.createCustomerDuplicateStmt,
+		createCustomerMergeStmt:                 q.createCustomerMergeStmt,
+		createDataSubjectRequestStmt:            q.createDataSubjectRequestStmt,
+		createEventStmt:                         q.createEventStmt,
+		createIdempotencyKeyStmt:                q.createIdempotencyKeyStmt,
+		createImportJobStmt:                     q.createImportJobStmt,
+		createJournalEntryStmt:                  q.createJournalEntryStmt,
+		createLoginEventStmt:                    q.createLoginEventStmt,
+		createMpesaStkRequestStmt:               q.createMpesaStkRequestStmt,
+		createOTPStmt:                           q.createOTPStmt,
+		createPostingStmt:                       q.createPostingStmt,
+		createProfileStmt:                       q.createProfileStmt,
+		createReconciliationItemStmt:            q.createReconciliationItemStmt,
+		createReconciliationRunStmt:             q.createReconciliationRunStmt,
+		createRecoveryCodeStmt:                  q.createRecoveryCodeStmt,
+		createRefundStmt:                        q.createRefundStmt,
+		createRoutingRuleStmt:                   q.createRoutingRuleStmt,
+		createStreamTicketStmt:                  q.createStreamTicketStmt,
+		createTeamStmt:                          q.createTeamStmt,
+		createTicketStmt:                        q.createTicketStmt,
+		createTicketAssignmentStmt:              q.createTicketAssignmentStmt,
+		createTransactionStmt:                   q.createTransactionStmt,
+		createUserStmt:                          q.createUserStmt,
+		createWebhookDeliveryStmt:               q.createWebhookDeliveryStmt,
+		createWebhookSubscriptionStmt:           q.createWebhookSubscriptionStmt,
+		deleteCustomerStmt:                      q.deleteCustomerStmt,
+		deleteCustomerDuplicatesStmt:            q.deleteCustomerDuplicatesStmt,
+		deleteEventsBeforeStmt:                  q.deleteEventsBeforeStmt,
+		deleteEventsBySubjectStmt:               q.deleteEventsBySubjectStmt,
+		deleteExpiredIdempotencyKeysStmt:        q.deleteExpiredIdempotencyKeysStmt,
+		deleteExpiredOTPsStmt:                   q.deleteExpiredOTPsStmt,
+		deleteExpiredStreamTicketsStmt:          q.deleteExpiredStreamTicketsStmt,
+		deleteIdempotencyKeyStmt:                q.deleteIdempotencyKeyStmt,
+		deleteOTPsByProfileStmt:                 q.deleteOTPsByProfileStmt,
+		deleteRecoveryCodesStmt:                 q.deleteRecoveryCodesStmt,
+		deleteRoutingRuleStmt:                   q.deleteRoutingRuleStmt,
+		deleteTOTPSecretStmt:                    q.deleteTOTPSecretStmt,
+		deleteTeamStmt:                          q.deleteTeamStmt,
+		deleteWebhookDeliveriesBySubjectStmt:    q.deleteWebhookDeliveriesBySubjectStmt,
+		deleteWebhookSubscriptionStmt:           q.deleteWebhookSubscriptionStmt,
+		deleteWebhookSubscriptionsByProfileStmt: q.deleteWebhookSubscriptionsByProfileStmt,
+		disableWebhookSubscriptionStmt:          q.disableWebhookSubscriptionStmt,
+		ensureLedgerAccountStmt:                 q.ensureLedgerAccountStmt,
+		exportTransactionsStmt:                  q.exportTransactionsStmt,
+		failStaleImportJobsStmt:                 q.failStaleImportJobsStmt,
+		finishImportJobStmt:                     q.finishImportJobStmt,
+		getAPIKeyByPrefixStmt:                   q.getAPIKeyByPrefixStmt,
+		getAccountBalanceStmt:                   q.getAccountBalanceStmt,
+		getAgentSettingsStmt:                    q.getAgentSettingsStmt,
+		getCustomerStmt:                         q.getCustomerStmt,
+		getCustomerByEmailStmt:                  q.getCustomerByEmailStmt,
+		getCustomerByPhoneStmt:                  q.getCustomerByPhoneStmt,
+		getCustomerMergeStmt:                    q.getCustomerMergeStmt,
+		getCustomersStmt:                        q.getCustomersStmt,
+		getIdempotencyKeyStmt:                   q.getIdempotencyKeyStmt,
+		getImportJobStmt:                        q.getImportJobStmt,
+		getLastTeamAssigneeStmt:                 q.getLastTeamAssigneeStmt,
+		getLatestOTPByProfileIDStmt:             q.getLatestOTPByProfileIDStmt,
+		getLedgerAccountStmt:                    q.getLedgerAccountStmt,
+		getLedgerAccountByCodeStmt:              q.getLedgerAccountByCodeStmt,
+		getMpesaStkRequestByCheckoutIDStmt:      q.getMpesaStkRequestByCheckoutIDStmt,
+		getProfileByEmailStmt:                   q.getProfileByEmailStmt,
+		getProfileByIDStmt:                      q.getProfileByIDStmt,
+		getProfileByPhoneStmt:                   q.getProfileByPhoneStmt,
+		getProfileLockoutStmt:                   q.getProfileLockoutStmt,
+		getReconciliationRunStmt:                q.getReconciliationRunStmt,
+		getRoutingRuleStmt:                      q.getRoutingRuleStmt,
+		getStreamTicketStmt:                     q.getStreamTicketStmt,
+		getTOTPSecretStmt:                       q.getTOTPSecretStmt,
+		getTeamStmt:                             q.getTeamStmt,
+		getTicketStmt:                           q.getTicketStmt,
+		getTicketByTitleAndUserStmt:             q.getTicketByTitleAndUserStmt,
+		getTransactionForUpdateStmt:             q.getTransactionForUpdateStmt,
+		getTransanctionByIDStmt:                 q.getTransanctionByIDStmt,
+		getUserByEmailExcludingIDStmt:           q.getUserByEmailExcludingIDStmt,
+		getUserByPhoneExcludingIDStmt:           q.getUserByPhoneExcludingIDStmt,
+		getWebhookDeliveryStmt:                  q.getWebhookDeliveryStmt,
+		getWebhookSubscriptionStmt:              q.getWebhookSubscriptionStmt,
+		incrementOTPAttemptsStmt:                q.incrementOTPAttemptsStmt,
+		incrementWebhookFailuresStmt:            q.incrementWebhookFailuresStmt,
+		listAPIKeysStmt:                         q.listAPIKeysStmt,
+		listActiveRoutingRulesStmt:              q.listActiveRoutingRulesStmt,
+		listActiveWebhookSubscriptionsStmt:      q.listActiveWebhookSubscriptionsStmt,
+		listAgentsStmt:                          q.listAgentsStmt,
+		listCustomerDuplicatesStmt:              q.listCustomerDuplicatesStmt,
+		listCustomerMergesStmt:                  q.listCustomerMergesStmt,
+		listCustomerMergesByContactStmt:         q.listCustomerMergesByContactStmt,
+		listCustomerMergesByIDsStmt:             q.listCustomerMergesByIDsStmt,
+		listCustomerTicketLinksStmt:             q.listCustomerTicketLinksStmt,
+		listCustomerTimelineStmt:                q.listCustomerTimelineStmt,
+		listCustomersAfterStmt:                  q.listCustomersAfterStmt,
+		listDataSubjectRequestsStmt:             q.listDataSubjectRequestsStmt,
+		listDueWebhookDeliveriesStmt:            q.listDueWebhookDeliveriesStmt,
+		listEntriesWithTooFewPostingsStmt:       q.listEntriesWithTooFewPostingsStmt,
+		listEventsAfterStmt:                     q.listEventsAfterStmt,
+		listEventsByIDsStmt:                     q.listEventsByIDsStmt,
+		listLedgerAccountsStmt:                  q.listLedgerAccountsStmt,
+		listLoginEventsForSubjectStmt:           q.listLoginEventsForSubjectStmt,
+		listMpesaStkRequestsByUserStmt:          q.listMpesaStkRequestsByUserStmt,
+		listOTPsByProfileStmt:                   q.listOTPsByProfileStmt,
+		listOwnTicketsStmt:                      q.listOwnTicketsStmt,
+		listPostingCurrencyMismatchesStmt:       q.listPostingCurrencyMismatchesStmt,
+		listProfilePhonesStmt:                   q.listProfilePhonesStmt,
+		listReconciliationItemsStmt:             q.listReconciliationItemsStmt,
+		listReconciliationRunsStmt:              q.listReconciliationRunsStmt,
+		listRefundsStmt:                         q.listRefundsStmt,
+		listRoutingRulesStmt:                    q.listRoutingRulesStmt,
+		listTeamCandidatesStmt:                  q.listTeamCandidatesStmt,
+		listTeamMembersStmt:                     q.listTeamMembersStmt,
+		listTeamsStmt:                           q.listTeamsStmt,
+		listTicketAssignmentsStmt:               q.listTicketAssignmentsStmt,
+		listTicketsStmt:                         q.listTicketsStmt,
+		listTicketsByCreatorStmt:                q.listTicketsByCreatorStmt,
+		listTicketsByCustomerStmt:               q.listTicketsByCustomerStmt,
+		listTicketsByIDsStmt:                    q.listTicketsByIDsStmt,
+		listTicketsByTeamStmt:                   q.listTicketsByTeamStmt,
+		listTicketsByTransactionStmt:            q.listTicketsByTransactionStmt,
+		listTransactionsStmt:                    q.listTransactionsStmt,
+		listTransactionsByIDsStmt:               q.listTransactionsByIDsStmt,
+		listTransactionsByUserStmt:              q.listTransactionsByUserStmt,
+		listTransactionsForReconciliationStmt:   q.listTransactionsForReconciliationStmt,
+		listUnbalancedEntriesStmt:               q.listUnbalancedEntriesStmt,
+		listUnpostedTransactionsStmt:            q.listUnpostedTransactionsStmt,
+		listUsersStmt:                           q.listUsersStmt,
+		listWebhookDeliveriesStmt:               q.listWebhookDeliveriesStmt,
+		listWebhookSubscriptionsStmt:            q.listWebhookSubscriptionsStmt,
+		lockCustomerStmt:                        q.lockCustomerStmt,
+		lockProfileStmt:                         q.lockProfileStmt,
+		markOTPVerifiedStmt:                     q.markOTPVerifiedStmt,
+		moveCustomerTicketsStmt:                 q.moveCustomerTicketsStmt,
+		recordLoginFailureStmt:                  q.recordLoginFailureStmt,
+		recordWebhookAttemptStmt:                q.recordWebhookAttemptStmt,
+		removeTeamMemberStmt:                    q.removeTeamMemberStmt,
+		renewStreamTicketStmt:                   q.renewStreamTicketStmt,
+		resetWebhookFailuresStmt:                q.resetWebhookFailuresStmt,
+		revokeAPIKeyStmt:                        q.revokeAPIKeyStmt,
+		saveIdempotentResponseStmt:              q.saveIdempotentResponseStmt,
+		startImportJobStmt:                      q.startImportJobStmt,
+		sumRefundsStmt:                          q.sumRefundsStmt,
+		sumTransactionsByCurrencyStmt:           q.sumTransactionsByCurrencyStmt,
+		touchAPIKeyStmt:                         q.touchAPIKeyStmt,
+		transitionTransactionStatusStmt:         q.transitionTransactionStatusStmt,
+		updateAgentStatusStmt:                   q.updateAgentStatusStmt,
+		updateCustomerStmt:                      q.updateCustomerStmt,
+		updateCustomerPhoneStmt:                 q.updateCustomerPhoneStmt,
+		updateImportJobProgressStmt:             q.updateImportJobProgressStmt,
+		updateProfilePasswordStmt:               q.updateProfilePasswordStmt,
+		updateProfilePhoneStmt:                  q.updateProfilePhoneStmt,
+		updateRoutingRuleStmt:                   q.updateRoutingRuleStmt,
+		updateTOTPLastUsedStepStmt:              q.updateTOTPLastUsedStepStmt,
+		updateTeamStmt:                          q.updateTeamStmt,
+		updateTicketStatusStmt:                  q.updateTicketStatusStmt,
+		updateTicketTeamStmt:                    q.updateTicketTeamStmt,
+		updateUserStmt:                          q.updateUserStmt,
+		updateWebhookSecretStmt:                 q.updateWebhookSecretStmt,
+		updateWebhookSubscriptionStmt:           q.updateWebhookSubscriptionStmt,
+		upsertAgentSettingsStmt:                 q.upsertAgentSettingsStmt,
+		upsertTOTPSecretStmt:                    q.upsertTOTPSecretStmt,
+		useRecoveryCodeStmt:                     q.useRecoveryCodeStmt,
 	}
 }
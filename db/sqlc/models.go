@@ -11,47 +11,541 @@ import (
 	"time"
 )
 
-type UsersRole string
+type AgentSettingsStatus string
 
 const (
-	UsersRoleAdmin    UsersRole = "admin"
-	UsersRoleAgent    UsersRole = "agent"
-	UsersRoleCustomer UsersRole = "customer"
+	AgentSettingsStatusOnline  AgentSettingsStatus = "online"
+	AgentSettingsStatusAway    AgentSettingsStatus = "away"
+	AgentSettingsStatusOffline AgentSettingsStatus = "offline"
 )
 
-func (e *UsersRole) Scan(src interface{}) error {
+func (e *AgentSettingsStatus) Scan(src interface{}) error {
 	switch s := src.(type) {
 	case []byte:
-		*e = UsersRole(s)
+		*e = AgentSettingsStatus(s)
 	case string:
-		*e = UsersRole(s)
+		*e = AgentSettingsStatus(s)
 	default:
-		return fmt.Errorf("unsupported scan type for UsersRole: %T", src)
+		return fmt.Errorf("unsupported scan type for AgentSettingsStatus: %T", src)
 	}
 	return nil
 }
 
-type NullUsersRole struct {
-	UsersRole UsersRole
-	Valid     bool // Valid is true if UsersRole is not NULL
+type NullAgentSettingsStatus struct {
+	AgentSettingsStatus AgentSettingsStatus
+	Valid               bool // Valid is true if AgentSettingsStatus is not NULL
 }
 
 // Scan implements the Scanner interface.
-func (ns *NullUsersRole) Scan(value interface{}) error {
+func (ns *NullAgentSettingsStatus) Scan(value interface{}) error {
 	if value == nil {
-		ns.UsersRole, ns.Valid = "", false
+		ns.AgentSettingsStatus, ns.Valid = "", false
 		return nil
 	}
 	ns.Valid = true
-	return ns.UsersRole.Scan(value)
+	return ns.AgentSettingsStatus.Scan(value)
 }
 
 // Value implements the driver Valuer interface.
-func (ns NullUsersRole) Value() (driver.Value, error) {
+func (ns NullAgentSettingsStatus) Value() (driver.Value, error) {
 	if !ns.Valid {
 		return nil, nil
 	}
-	return string(ns.UsersRole), nil
+	return string(ns.AgentSettingsStatus), nil
+}
+
+type DataSubjectRequestsKind string
+
+const (
+	DataSubjectRequestsKindExport  DataSubjectRequestsKind = "export"
+	DataSubjectRequestsKindErasure DataSubjectRequestsKind = "erasure"
+)
+
+func (e *DataSubjectRequestsKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DataSubjectRequestsKind(s)
+	case string:
+		*e = DataSubjectRequestsKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DataSubjectRequestsKind: %T", src)
+	}
+	return nil
+}
+
+type NullDataSubjectRequestsKind struct {
+	DataSubjectRequestsKind DataSubjectRequestsKind
+	Valid                   bool // Valid is true if DataSubjectRequestsKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullDataSubjectRequestsKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.DataSubjectRequestsKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.DataSubjectRequestsKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullDataSubjectRequestsKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.DataSubjectRequestsKind), nil
+}
+
+type ImportJobsKind string
+
+const (
+	ImportJobsKindCustomers ImportJobsKind = "customers"
+	ImportJobsKindUsers     ImportJobsKind = "users"
+)
+
+func (e *ImportJobsKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ImportJobsKind(s)
+	case string:
+		*e = ImportJobsKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ImportJobsKind: %T", src)
+	}
+	return nil
+}
+
+type NullImportJobsKind struct {
+	ImportJobsKind ImportJobsKind
+	Valid          bool // Valid is true if ImportJobsKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullImportJobsKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.ImportJobsKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ImportJobsKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullImportJobsKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ImportJobsKind), nil
+}
+
+type ImportJobsStatus string
+
+const (
+	ImportJobsStatusQueued    ImportJobsStatus = "queued"
+	ImportJobsStatusRunning   ImportJobsStatus = "running"
+	ImportJobsStatusSucceeded ImportJobsStatus = "succeeded"
+	ImportJobsStatusFailed    ImportJobsStatus = "failed"
+)
+
+func (e *ImportJobsStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ImportJobsStatus(s)
+	case string:
+		*e = ImportJobsStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ImportJobsStatus: %T", src)
+	}
+	return nil
+}
+
+type NullImportJobsStatus struct {
+	ImportJobsStatus ImportJobsStatus
+	Valid            bool // Valid is true if ImportJobsStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullImportJobsStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ImportJobsStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ImportJobsStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullImportJobsStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ImportJobsStatus), nil
+}
+
+type LedgerAccountsType string
+
+const (
+	LedgerAccountsTypeAsset     LedgerAccountsType = "asset"
+	LedgerAccountsTypeLiability LedgerAccountsType = "liability"
+	LedgerAccountsTypeEquity    LedgerAccountsType = "equity"
+	LedgerAccountsTypeIncome    LedgerAccountsType = "income"
+	LedgerAccountsTypeExpense   LedgerAccountsType = "expense"
+)
+
+func (e *LedgerAccountsType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LedgerAccountsType(s)
+	case string:
+		*e = LedgerAccountsType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LedgerAccountsType: %T", src)
+	}
+	return nil
+}
+
+type NullLedgerAccountsType struct {
+	LedgerAccountsType LedgerAccountsType
+	Valid              bool // Valid is true if LedgerAccountsType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLedgerAccountsType) Scan(value interface{}) error {
+	if value == nil {
+		ns.LedgerAccountsType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LedgerAccountsType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLedgerAccountsType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LedgerAccountsType), nil
+}
+
+type ProfilesRole string
+
+const (
+	ProfilesRoleAdmin    ProfilesRole = "admin"
+	ProfilesRoleAgent    ProfilesRole = "agent"
+	ProfilesRoleCustomer ProfilesRole = "customer"
+)
+
+func (e *ProfilesRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ProfilesRole(s)
+	case string:
+		*e = ProfilesRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ProfilesRole: %T", src)
+	}
+	return nil
+}
+
+type NullProfilesRole struct {
+	ProfilesRole ProfilesRole
+	Valid        bool // Valid is true if ProfilesRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullProfilesRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.ProfilesRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ProfilesRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullProfilesRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ProfilesRole), nil
+}
+
+type ReconciliationItemsStatus string
+
+const (
+	ReconciliationItemsStatusMatched            ReconciliationItemsStatus = "matched"
+	ReconciliationItemsStatusAmountMismatch     ReconciliationItemsStatus = "amount_mismatch"
+	ReconciliationItemsStatusStatusMismatch     ReconciliationItemsStatus = "status_mismatch"
+	ReconciliationItemsStatusDuplicate          ReconciliationItemsStatus = "duplicate"
+	ReconciliationItemsStatusMissingTransaction ReconciliationItemsStatus = "missing_transaction"
+	ReconciliationItemsStatusMissingLine        ReconciliationItemsStatus = "missing_line"
+)
+
+func (e *ReconciliationItemsStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReconciliationItemsStatus(s)
+	case string:
+		*e = ReconciliationItemsStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReconciliationItemsStatus: %T", src)
+	}
+	return nil
+}
+
+type NullReconciliationItemsStatus struct {
+	ReconciliationItemsStatus ReconciliationItemsStatus
+	Valid                     bool // Valid is true if ReconciliationItemsStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReconciliationItemsStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReconciliationItemsStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReconciliationItemsStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReconciliationItemsStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReconciliationItemsStatus), nil
+}
+
+type RoutingRulesTicketType string
+
+const (
+	RoutingRulesTicketTypeGeneral        RoutingRulesTicketType = "general"
+	RoutingRulesTicketTypePaymentDispute RoutingRulesTicketType = "payment_dispute"
+)
+
+func (e *RoutingRulesTicketType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RoutingRulesTicketType(s)
+	case string:
+		*e = RoutingRulesTicketType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RoutingRulesTicketType: %T", src)
+	}
+	return nil
+}
+
+type NullRoutingRulesTicketType struct {
+	RoutingRulesTicketType RoutingRulesTicketType
+	Valid                  bool // Valid is true if RoutingRulesTicketType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRoutingRulesTicketType) Scan(value interface{}) error {
+	if value == nil {
+		ns.RoutingRulesTicketType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RoutingRulesTicketType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRoutingRulesTicketType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RoutingRulesTicketType), nil
+}
+
+type TicketsType string
+
+const (
+	TicketsTypeGeneral        TicketsType = "general"
+	TicketsTypePaymentDispute TicketsType = "payment_dispute"
+)
+
+func (e *TicketsType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TicketsType(s)
+	case string:
+		*e = TicketsType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TicketsType: %T", src)
+	}
+	return nil
+}
+
+type NullTicketsType struct {
+	TicketsType TicketsType
+	Valid       bool // Valid is true if TicketsType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTicketsType) Scan(value interface{}) error {
+	if value == nil {
+		ns.TicketsType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TicketsType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTicketsType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TicketsType), nil
+}
+
+type TransactionsKind string
+
+const (
+	TransactionsKindPayment TransactionsKind = "payment"
+	TransactionsKindRefund  TransactionsKind = "refund"
+)
+
+func (e *TransactionsKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TransactionsKind(s)
+	case string:
+		*e = TransactionsKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TransactionsKind: %T", src)
+	}
+	return nil
+}
+
+type NullTransactionsKind struct {
+	TransactionsKind TransactionsKind
+	Valid            bool // Valid is true if TransactionsKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTransactionsKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.TransactionsKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TransactionsKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTransactionsKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TransactionsKind), nil
+}
+
+type WebhookDeliveriesStatus string
+
+const (
+	WebhookDeliveriesStatusPending   WebhookDeliveriesStatus = "pending"
+	WebhookDeliveriesStatusSucceeded WebhookDeliveriesStatus = "succeeded"
+	WebhookDeliveriesStatusFailed    WebhookDeliveriesStatus = "failed"
+)
+
+func (e *WebhookDeliveriesStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveriesStatus(s)
+	case string:
+		*e = WebhookDeliveriesStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveriesStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveriesStatus struct {
+	WebhookDeliveriesStatus WebhookDeliveriesStatus
+	Valid                   bool // Valid is true if WebhookDeliveriesStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveriesStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveriesStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveriesStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveriesStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveriesStatus), nil
+}
+
+type WebhookSubscriptionsScope string
+
+const (
+	WebhookSubscriptionsScopeOwn WebhookSubscriptionsScope = "own"
+	WebhookSubscriptionsScopeAll WebhookSubscriptionsScope = "all"
+)
+
+func (e *WebhookSubscriptionsScope) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookSubscriptionsScope(s)
+	case string:
+		*e = WebhookSubscriptionsScope(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookSubscriptionsScope: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookSubscriptionsScope struct {
+	WebhookSubscriptionsScope WebhookSubscriptionsScope
+	Valid                     bool // Valid is true if WebhookSubscriptionsScope is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookSubscriptionsScope) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookSubscriptionsScope, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookSubscriptionsScope.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookSubscriptionsScope) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookSubscriptionsScope), nil
+}
+
+type AgentSetting struct {
+	ProfileID     int32               `db:"profile_id"`
+	Status        AgentSettingsStatus `db:"status"`
+	MaxConcurrent int32               `db:"max_concurrent"`
+	Skills        string              `db:"skills"`
+	UpdatedAt     time.Time           `db:"updated_at"`
+}
+
+type ApiKey struct {
+	ID         int64        `db:"id"`
+	Name       string       `db:"name"`
+	Prefix     string       `db:"prefix"`
+	KeyHash    string       `db:"key_hash"`
+	Scopes     string       `db:"scopes"`
+	CreatedBy  int32        `db:"created_by"`
+	ExpiresAt  sql.NullTime `db:"expires_at"`
+	LastUsedAt sql.NullTime `db:"last_used_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at"`
+	CreatedAt  time.Time    `db:"created_at"`
 }
 
 type Customer struct {
@@ -60,56 +554,308 @@ type Customer struct {
 	Email       string       `db:"email"`
 	PhoneNumber string       `db:"phone_number"`
 	CreatedAt   sql.NullTime `db:"created_at"`
+	Segment     string       `db:"segment"`
+}
+
+type CustomerDuplicate struct {
+	ID             int64     `db:"id"`
+	CustomerID     int64     `db:"customer_id"`
+	DuplicateID    int64     `db:"duplicate_id"`
+	Reasons        string    `db:"reasons"`
+	NameSimilarity float64   `db:"name_similarity"`
+	FoundAt        time.Time `db:"found_at"`
+}
+
+type CustomerMerge struct {
+	ID                int64     `db:"id"`
+	SurvivorID        int64     `db:"survivor_id"`
+	MergedID          int64     `db:"merged_id"`
+	MergedFullName    string    `db:"merged_full_name"`
+	MergedEmail       string    `db:"merged_email"`
+	MergedPhoneNumber string    `db:"merged_phone_number"`
+	TicketIds         string    `db:"ticket_ids"`
+	TransactionIds    string    `db:"transaction_ids"`
+	MergedBy          int32     `db:"merged_by"`
+	Note              string    `db:"note"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+type DataSubjectRequest struct {
+	ID          int64                   `db:"id"`
+	Kind        DataSubjectRequestsKind `db:"kind"`
+	SubjectHash string                  `db:"subject_hash"`
+	ProfileIds  string                  `db:"profile_ids"`
+	CustomerIds string                  `db:"customer_ids"`
+	Summary     string                  `db:"summary"`
+	Reason      string                  `db:"reason"`
+	RequestedBy int32                   `db:"requested_by"`
+	CreatedAt   time.Time               `db:"created_at"`
+}
+
+type EventLog struct {
+	ID        int64         `db:"id"`
+	Stream    string        `db:"stream"`
+	Type      string        `db:"type"`
+	OwnerID   sql.NullInt64 `db:"owner_id"`
+	SubjectID sql.NullInt64 `db:"subject_id"`
+	Payload   string        `db:"payload"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+type IdempotencyKey struct {
+	ID             int64          `db:"id"`
+	Scope          string         `db:"scope"`
+	IdempotencyKey string         `db:"idempotency_key"`
+	RequestPath    string         `db:"request_path"`
+	Fingerprint    string         `db:"fingerprint"`
+	StatusCode     sql.NullInt32  `db:"status_code"`
+	ContentType    sql.NullString `db:"content_type"`
+	ResponseBody   sql.NullString `db:"response_body"`
+	CreatedAt      time.Time      `db:"created_at"`
+	ExpiresAt      time.Time      `db:"expires_at"`
+}
+
+type ImportJob struct {
+	ID            int64            `db:"id"`
+	Kind          ImportJobsKind   `db:"kind"`
+	Status        ImportJobsStatus `db:"status"`
+	DryRun        bool             `db:"dry_run"`
+	Filename      string           `db:"filename"`
+	RowsProcessed int32            `db:"rows_processed"`
+	Report        sql.NullString   `db:"report"`
+	ErrorMessage  string           `db:"error_message"`
+	CreatedBy     int32            `db:"created_by"`
+	CreatedAt     time.Time        `db:"created_at"`
+	StartedAt     sql.NullTime     `db:"started_at"`
+	FinishedAt    sql.NullTime     `db:"finished_at"`
+	UpdatedAt     time.Time        `db:"updated_at"`
+}
+
+type JournalEntry struct {
+	ID            int64         `db:"id"`
+	TransactionID sql.NullInt32 `db:"transaction_id"`
+	Description   string        `db:"description"`
+	OccurredAt    time.Time     `db:"occurred_at"`
+	CreatedAt     time.Time     `db:"created_at"`
+}
+
+type LedgerAccount struct {
+	ID        int64              `db:"id"`
+	Code      string             `db:"code"`
+	Name      string             `db:"name"`
+	Type      LedgerAccountsType `db:"type"`
+	Currency  string             `db:"currency"`
+	ProfileID sql.NullInt32      `db:"profile_id"`
+	CreatedAt time.Time          `db:"created_at"`
+}
+
+type LoginEvent struct {
+	ID         int64         `db:"id"`
+	ProfileID  sql.NullInt32 `db:"profile_id"`
+	Identifier string        `db:"identifier"`
+	Ip         string        `db:"ip"`
+	UserAgent  string        `db:"user_agent"`
+	Success    bool          `db:"success"`
+	Reason     string        `db:"reason"`
+	CreatedAt  time.Time     `db:"created_at"`
+}
+
+type MpesaStkRequest struct {
+	ID                int64          `db:"id"`
+	TransactionID     int32          `db:"transaction_id"`
+	MerchantRequestID string         `db:"merchant_request_id"`
+	CheckoutRequestID string         `db:"checkout_request_id"`
+	Phone             string         `db:"phone"`
+	ResultCode        sql.NullInt32  `db:"result_code"`
+	ResultDesc        sql.NullString `db:"result_desc"`
+	MpesaReceipt      sql.NullString `db:"mpesa_receipt"`
+	Mismatch          sql.NullString `db:"mismatch"`
+	CreatedAt         time.Time      `db:"created_at"`
+	CompletedAt       sql.NullTime   `db:"completed_at"`
 }
 
 type OtpCode struct {
 	ID        int32     `db:"id"`
 	ProfileID int32     `db:"profile_id"`
 	OtpCode   string    `db:"otp_code"`
+	Purpose   string    `db:"purpose"`
 	ExpiresAt time.Time `db:"expires_at"`
 	Verified  bool      `db:"verified"`
 	Attempts  int32     `db:"attempts"`
 	CreatedAt time.Time `db:"created_at"`
 }
 
+type Posting struct {
+	ID          int64  `db:"id"`
+	EntryID     int64  `db:"entry_id"`
+	AccountID   int64  `db:"account_id"`
+	AmountMinor int64  `db:"amount_minor"`
+	Currency    string `db:"currency"`
+}
+
 type Profile struct {
 	ID           int32          `db:"id"`
-	Phone        string         `db:"phone"`
+	Phone        sql.NullString `db:"phone"`
+	Email        sql.NullString `db:"email"`
 	PasswordHash string         `db:"password_hash"`
 	FullName     sql.NullString `db:"full_name"`
+	Role         ProfilesRole   `db:"role"`
+	TokenVersion int32          `db:"token_version"`
 	CreatedAt    time.Time      `db:"created_at"`
 	UpdatedAt    time.Time      `db:"updated_at"`
 }
 
+type ProfileLockout struct {
+	ProfileID      int32        `db:"profile_id"`
+	FailedAttempts int32        `db:"failed_attempts"`
+	LastFailedAt   time.Time    `db:"last_failed_at"`
+	LockedUntil    sql.NullTime `db:"locked_until"`
+}
+
+type ReconciliationItem struct {
+	ID            int64                     `db:"id"`
+	RunID         int64                     `db:"run_id"`
+	Status        ReconciliationItemsStatus `db:"status"`
+	LineNumber    sql.NullInt32             `db:"line_number"`
+	Reference     sql.NullString            `db:"reference"`
+	AmountMinor   sql.NullInt64             `db:"amount_minor"`
+	Currency      sql.NullString            `db:"currency"`
+	OccurredAt    sql.NullTime              `db:"occurred_at"`
+	TransactionID sql.NullInt32             `db:"transaction_id"`
+	Note          string                    `db:"note"`
+}
+
+type ReconciliationRun struct {
+	ID            int64         `db:"id"`
+	Format        string        `db:"format"`
+	Filename      string        `db:"filename"`
+	LineCount     int32         `db:"line_count"`
+	PeriodStart   sql.NullTime  `db:"period_start"`
+	PeriodEnd     sql.NullTime  `db:"period_end"`
+	WindowSeconds int32         `db:"window_seconds"`
+	CreatedBy     sql.NullInt32 `db:"created_by"`
+	CreatedAt     time.Time     `db:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID        int32        `db:"id"`
+	ProfileID int32        `db:"profile_id"`
+	CodeHash  string       `db:"code_hash"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+type RoutingRule struct {
+	ID              int64                      `db:"id"`
+	Name            string                     `db:"name"`
+	Priority        int32                      `db:"priority"`
+	TeamID          int64                      `db:"team_id"`
+	TicketPriority  sql.NullString             `db:"ticket_priority"`
+	TicketType      NullRoutingRulesTicketType `db:"ticket_type"`
+	CustomerSegment sql.NullString             `db:"customer_segment"`
+	Keywords        string                     `db:"keywords"`
+	Active          bool                       `db:"active"`
+	CreatedAt       time.Time                  `db:"created_at"`
+	UpdatedAt       time.Time                  `db:"updated_at"`
+}
+
+type StreamTicket struct {
+	TicketHash   string    `db:"ticket_hash"`
+	ProfileID    int32     `db:"profile_id"`
+	TokenVersion int32     `db:"token_version"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+type Team struct {
+	ID                 int64     `db:"id"`
+	Name               string    `db:"name"`
+	Description        string    `db:"description"`
+	CreatedAt          time.Time `db:"created_at"`
+	AssignmentStrategy string    `db:"assignment_strategy"`
+}
+
+type TeamMember struct {
+	TeamID    int64     `db:"team_id"`
+	ProfileID int32     `db:"profile_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
 type Ticket struct {
-	ID          int64         `db:"id"`
-	Title       string        `db:"title"`
-	Description string        `db:"description"`
-	Status      int16         `db:"status"`
-	Priority    string        `db:"priority"`
-	CreatedBy   int64         `db:"created_by"`
-	AssignedTo  sql.NullInt64 `db:"assigned_to"`
-	CreatedAt   time.Time     `db:"created_at"`
-	UpdatedAt   time.Time     `db:"updated_at"`
+	ID            int64         `db:"id"`
+	Title         string        `db:"title"`
+	Description   string        `db:"description"`
+	Status        int16         `db:"status"`
+	Priority      string        `db:"priority"`
+	CreatedBy     int64         `db:"created_by"`
+	AssignedTo    sql.NullInt64 `db:"assigned_to"`
+	CreatedAt     time.Time     `db:"created_at"`
+	UpdatedAt     time.Time     `db:"updated_at"`
+	Type          TicketsType   `db:"type"`
+	CustomerID    sql.NullInt64 `db:"customer_id"`
+	TransactionID sql.NullInt32 `db:"transaction_id"`
+	TeamID        sql.NullInt64 `db:"team_id"`
+}
+
+type TicketAssignment struct {
+	ID         int64         `db:"id"`
+	TicketID   int64         `db:"ticket_id"`
+	TeamID     sql.NullInt64 `db:"team_id"`
+	Strategy   string        `db:"strategy"`
+	AssignedTo sql.NullInt32 `db:"assigned_to"`
+	Reason     string        `db:"reason"`
+	Candidates string        `db:"candidates"`
+	CreatedAt  time.Time     `db:"created_at"`
+}
+
+type TotpSecret struct {
+	ProfileID    int32        `db:"profile_id"`
+	SecretEnc    string       `db:"secret_enc"`
+	ConfirmedAt  sql.NullTime `db:"confirmed_at"`
+	LastUsedStep int64        `db:"last_used_step"`
+	CreatedAt    time.Time    `db:"created_at"`
 }
 
 type Transaction struct {
-	ID            int32          `db:"id"`
-	TransactionID string         `db:"transaction_id"`
-	UserID        int32          `db:"user_id"`
-	Amount        string         `db:"amount"`
-	Currency      string         `db:"currency"`
-	Status        int16          `db:"status"`
-	PaymentMethod sql.NullString `db:"payment_method"`
-	CreatedAt     sql.NullTime   `db:"created_at"`
-	UpdatedAt     sql.NullTime   `db:"updated_at"`
-}
-
-type User struct {
-	ID        int64         `db:"id"`
-	FullName  string        `db:"full_name"`
-	Email     string        `db:"email"`
-	Password  string        `db:"password"`
-	Role      NullUsersRole `db:"role"`
-	CreatedAt sql.NullTime  `db:"created_at"`
+	ID            int32            `db:"id"`
+	TransactionID string           `db:"transaction_id"`
+	UserID        int32            `db:"user_id"`
+	AmountMinor   int64            `db:"amount_minor"`
+	Currency      string           `db:"currency"`
+	Status        int16            `db:"status"`
+	PaymentMethod sql.NullString   `db:"payment_method"`
+	Kind          TransactionsKind `db:"kind"`
+	ParentID      sql.NullInt32    `db:"parent_id"`
+	CreatedAt     sql.NullTime     `db:"created_at"`
+	UpdatedAt     sql.NullTime     `db:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64                   `db:"id"`
+	SubscriptionID int64                   `db:"subscription_id"`
+	EventID        string                  `db:"event_id"`
+	EventType      string                  `db:"event_type"`
+	Payload        string                  `db:"payload"`
+	Status         WebhookDeliveriesStatus `db:"status"`
+	Attempts       int32                   `db:"attempts"`
+	NextAttemptAt  time.Time               `db:"next_attempt_at"`
+	LastAttemptAt  sql.NullTime            `db:"last_attempt_at"`
+	ResponseStatus sql.NullInt32           `db:"response_status"`
+	LastError      string                  `db:"last_error"`
+	RedeliveryOf   sql.NullInt64           `db:"redelivery_of"`
+	CreatedAt      time.Time               `db:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID                  int64                     `db:"id"`
+	ProfileID           int32                     `db:"profile_id"`
+	Url                 string                    `db:"url"`
+	Secret              string                    `db:"secret"`
+	Events              string                    `db:"events"`
+	Scope               WebhookSubscriptionsScope `db:"scope"`
+	Active              bool                      `db:"active"`
+	ConsecutiveFailures int32                     `db:"consecutive_failures"`
+	DisabledAt          sql.NullTime              `db:"disabled_at"`
+	DisabledReason      string                    `db:"disabled_reason"`
+	CreatedAt           time.Time                 `db:"created_at"`
+	UpdatedAt           time.Time                 `db:"updated_at"`
 }
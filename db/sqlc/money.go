@@ -0,0 +1,11 @@
+package db
+
+import "tickets/money"
+
+// Hand-written: sqlc leaves files it did not generate alone.
+
+// Money returns the transaction amount. Rows are only written through
+// money.Money, so the currency is always a known ISO 4217 code.
+func (t Transaction) Money() money.Money {
+	return money.Money{Amount: t.AmountMinor, Currency: t.Currency}
+}
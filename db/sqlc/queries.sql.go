@@ -8,186 +8,366 @@ package db
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
-const assignTicket = `-- name: AssignTicket :exec
-UPDATE tickets
-SET assigned_to = ?, updated_at = NOW()
-WHERE id = ?
+const addTeamMember = `-- name: AddTeamMember :exec
+INSERT IGNORE INTO team_members (team_id, profile_id)
+VALUES (?, ?)
 `
 
-type AssignTicketParams struct {
-	AssignedTo sql.NullInt64 `db:"assigned_to"`
-	ID         int64         `db:"id"`
+type AddTeamMemberParams struct {
+	TeamID    int64 `db:"team_id"`
+	ProfileID int32 `db:"profile_id"`
 }
 
-func (q *Queries) AssignTicket(ctx context.Context, arg AssignTicketParams) error {
-	_, err := q.exec(ctx, q.assignTicketStmt, assignTicket, arg.AssignedTo, arg.ID)
+func (q *Queries) AddTeamMember(ctx context.Context, arg AddTeamMemberParams) error {
+	_, err := q.exec(ctx, q.addTeamMemberStmt, addTeamMember, arg.TeamID, arg.ProfileID)
 	return err
 }
 
-const createCustomer = `-- name: CreateCustomer :execresult
-INSERT INTO customers (full_name, email, phone_number)
-VALUES (?, ?, ?)
+const anonymizeCustomer = `-- name: AnonymizeCustomer :exec
+UPDATE customers
+SET full_name = ?, email = ?, phone_number = ?, segment = ?
+WHERE id = ?
 `
 
-type CreateCustomerParams struct {
+type AnonymizeCustomerParams struct {
 	FullName    string `db:"full_name"`
 	Email       string `db:"email"`
 	PhoneNumber string `db:"phone_number"`
+	Segment     string `db:"segment"`
+	ID          int64  `db:"id"`
 }
 
-func (q *Queries) CreateCustomer(ctx context.Context, arg CreateCustomerParams) (sql.Result, error) {
-	return q.exec(ctx, q.createCustomerStmt, createCustomer, arg.FullName, arg.Email, arg.PhoneNumber)
+func (q *Queries) AnonymizeCustomer(ctx context.Context, arg AnonymizeCustomerParams) error {
+	_, err := q.exec(ctx, q.anonymizeCustomerStmt, anonymizeCustomer,
+		arg.FullName,
+		arg.Email,
+		arg.PhoneNumber,
+		arg.Segment,
+		arg.ID,
+	)
+	return err
 }
 
-const createOTP = `-- name: CreateOTP :execresult
-INSERT INTO otp_codes (profile_id, otp_code, expires_at)
-VALUES (?, ?, ?)
+const anonymizeCustomerMerge = `-- name: AnonymizeCustomerMerge :exec
+UPDATE customer_merges
+SET merged_full_name = ?, merged_email = ?, merged_phone_number = ?, note = ''
+WHERE id = ?
 `
 
-type CreateOTPParams struct {
-	ProfileID int32     `db:"profile_id"`
-	OtpCode   string    `db:"otp_code"`
-	ExpiresAt time.Time `db:"expires_at"`
+type AnonymizeCustomerMergeParams struct {
+	MergedFullName    string `db:"merged_full_name"`
+	MergedEmail       string `db:"merged_email"`
+	MergedPhoneNumber string `db:"merged_phone_number"`
+	ID                int64  `db:"id"`
 }
 
-func (q *Queries) CreateOTP(ctx context.Context, arg CreateOTPParams) (sql.Result, error) {
-	return q.exec(ctx, q.createOTPStmt, createOTP, arg.ProfileID, arg.OtpCode, arg.ExpiresAt)
+func (q *Queries) AnonymizeCustomerMerge(ctx context.Context, arg AnonymizeCustomerMergeParams) error {
+	_, err := q.exec(ctx, q.anonymizeCustomerMergeStmt, anonymizeCustomerMerge,
+		arg.MergedFullName,
+		arg.MergedEmail,
+		arg.MergedPhoneNumber,
+		arg.ID,
+	)
+	return err
 }
 
-const createProfile = `-- name: CreateProfile :execresult
-INSERT INTO profiles (full_name, phone, password_hash)
-VALUES (?, ?, ?)
+const anonymizeLoginEvents = `-- name: AnonymizeLoginEvents :execrows
+UPDATE login_events
+SET identifier = ?, ip = '', user_agent = ''
+WHERE profile_id = ? OR identifier = ?
 `
 
-type CreateProfileParams struct {
+type AnonymizeLoginEventsParams struct {
+	Replacement string        `db:"replacement"`
+	ProfileID   sql.NullInt32 `db:"profile_id"`
+	Identifier  string        `db:"identifier"`
+}
+
+func (q *Queries) AnonymizeLoginEvents(ctx context.Context, arg AnonymizeLoginEventsParams) (int64, error) {
+	result, err := q.exec(ctx, q.anonymizeLoginEventsStmt, anonymizeLoginEvents, arg.Replacement, arg.ProfileID, arg.Identifier)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeProfile = `-- name: AnonymizeProfile :exec
+UPDATE profiles
+SET full_name = ?, email = ?, phone = NULL, password_hash = ?, token_version = token_version + 1
+WHERE id = ?
+`
+
+type AnonymizeProfileParams struct {
 	FullName     sql.NullString `db:"full_name"`
-	Phone        string         `db:"phone"`
+	Email        sql.NullString `db:"email"`
 	PasswordHash string         `db:"password_hash"`
+	ID           int32          `db:"id"`
 }
 
-func (q *Queries) CreateProfile(ctx context.Context, arg CreateProfileParams) (sql.Result, error) {
-	return q.exec(ctx, q.createProfileStmt, createProfile, arg.FullName, arg.Phone, arg.PasswordHash)
+func (q *Queries) AnonymizeProfile(ctx context.Context, arg AnonymizeProfileParams) error {
+	_, err := q.exec(ctx, q.anonymizeProfileStmt, anonymizeProfile,
+		arg.FullName,
+		arg.Email,
+		arg.PasswordHash,
+		arg.ID,
+	)
+	return err
 }
 
-const createTicket = `-- name: CreateTicket :execresult
-INSERT INTO tickets (title, description, created_by, priority, status)
-VALUES (?, ?, ?, ?, ?)
+const anonymizeTicket = `-- name: AnonymizeTicket :exec
+UPDATE tickets
+SET title = ?, description = ?
+WHERE id = ?
 `
 
-type CreateTicketParams struct {
+type AnonymizeTicketParams struct {
 	Title       string `db:"title"`
 	Description string `db:"description"`
-	CreatedBy   int64  `db:"created_by"`
-	Priority    string `db:"priority"`
-	Status      int16  `db:"status"`
+	ID          int64  `db:"id"`
 }
 
-func (q *Queries) CreateTicket(ctx context.Context, arg CreateTicketParams) (sql.Result, error) {
-	return q.exec(ctx, q.createTicketStmt, createTicket,
-		arg.Title,
-		arg.Description,
-		arg.CreatedBy,
-		arg.Priority,
-		arg.Status,
-	)
+func (q *Queries) AnonymizeTicket(ctx context.Context, arg AnonymizeTicketParams) error {
+	_, err := q.exec(ctx, q.anonymizeTicketStmt, anonymizeTicket, arg.Title, arg.Description, arg.ID)
+	return err
 }
 
-const createTransaction = `-- name: CreateTransaction :execresult
-INSERT INTO transactions (transaction_id, user_id,amount,currency,status,payment_method)
-VALUES(?,?,?,?,?,?)
+const assignTicket = `-- name: AssignTicket :exec
+UPDATE tickets
+SET assigned_to = ?, updated_at = NOW()
+WHERE id = ?
 `
 
-type CreateTransactionParams struct {
-	TransactionID string         `db:"transaction_id"`
-	UserID        int32          `db:"user_id"`
-	Amount        string         `db:"amount"`
-	Currency      string         `db:"currency"`
-	Status        int16          `db:"status"`
-	PaymentMethod sql.NullString `db:"payment_method"`
+type AssignTicketParams struct {
+	AssignedTo sql.NullInt64 `db:"assigned_to"`
+	ID         int64         `db:"id"`
 }
 
-func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (sql.Result, error) {
-	return q.exec(ctx, q.createTransactionStmt, createTransaction,
-		arg.TransactionID,
-		arg.UserID,
-		arg.Amount,
-		arg.Currency,
-		arg.Status,
-		arg.PaymentMethod,
-	)
+func (q *Queries) AssignTicket(ctx context.Context, arg AssignTicketParams) error {
+	_, err := q.exec(ctx, q.assignTicketStmt, assignTicket, arg.AssignedTo, arg.ID)
+	return err
 }
 
-const createUser = `-- name: CreateUser :execresult
-INSERT INTO users (full_name, email,  role)
-VALUES (?, ?, ?)
+const claimUnassignedTicket = `-- name: ClaimUnassignedTicket :execrows
+UPDATE tickets SET assigned_to = ?, updated_at = NOW()
+WHERE id = ? AND assigned_to IS NULL
 `
 
-type CreateUserParams struct {
-	FullName string        `db:"full_name"`
-	Email    string        `db:"email"`
-	Role     NullUsersRole `db:"role"`
+type ClaimUnassignedTicketParams struct {
+	AssignedTo sql.NullInt64 `db:"assigned_to"`
+	ID         int64         `db:"id"`
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (sql.Result, error) {
-	return q.exec(ctx, q.createUserStmt, createUser, arg.FullName, arg.Email, arg.Role)
+// only assigns tickets nobody has assigned in the meantime
+func (q *Queries) ClaimUnassignedTicket(ctx context.Context, arg ClaimUnassignedTicketParams) (int64, error) {
+	result, err := q.exec(ctx, q.claimUnassignedTicketStmt, claimUnassignedTicket, arg.AssignedTo, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const deleteExpiredOTPs = `-- name: DeleteExpiredOTPs :exec
-DELETE FROM otp_codes
-WHERE expires_at < NOW()
+const claimWebhookDelivery = `-- name: ClaimWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET next_attempt_at = ?
+WHERE id = ? AND status = 'pending' AND next_attempt_at = ?
 `
 
-func (q *Queries) DeleteExpiredOTPs(ctx context.Context) error {
-	_, err := q.exec(ctx, q.deleteExpiredOTPsStmt, deleteExpiredOTPs)
+type ClaimWebhookDeliveryParams struct {
+	LeaseUntil    time.Time `db:"lease_until"`
+	ID            int64     `db:"id"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+}
+
+// pushes next_attempt_at out as a lease so only one worker sends a delivery
+func (q *Queries) ClaimWebhookDelivery(ctx context.Context, arg ClaimWebhookDeliveryParams) (int64, error) {
+	result, err := q.exec(ctx, q.claimWebhookDeliveryStmt, claimWebhookDelivery, arg.LeaseUntil, arg.ID, arg.NextAttemptAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const clearLoginFailures = `-- name: ClearLoginFailures :exec
+DELETE FROM profile_lockouts
+WHERE profile_id = ?
+`
+
+func (q *Queries) ClearLoginFailures(ctx context.Context, profileID int32) error {
+	_, err := q.exec(ctx, q.clearLoginFailuresStmt, clearLoginFailures, profileID)
 	return err
 }
 
-const getCustomerByEmail = `-- name: GetCustomerByEmail :one
-SELECT id, full_name, email, phone_number, created_at FROM customers
-WHERE email = ? LIMIT 1
+const completeMpesaStkRequest = `-- name: CompleteMpesaStkRequest :execrows
+UPDATE mpesa_stk_requests
+SET result_code = ?, result_desc = ?, mpesa_receipt = ?, mismatch = ?, completed_at = ?
+WHERE id = ? AND completed_at IS NULL
 `
 
-func (q *Queries) GetCustomerByEmail(ctx context.Context, email string) (Customer, error) {
-	row := q.queryRow(ctx, q.getCustomerByEmailStmt, getCustomerByEmail, email)
-	var i Customer
-	err := row.Scan(
-		&i.ID,
-		&i.FullName,
-		&i.Email,
-		&i.PhoneNumber,
-		&i.CreatedAt,
+type CompleteMpesaStkRequestParams struct {
+	ResultCode   sql.NullInt32  `db:"result_code"`
+	ResultDesc   sql.NullString `db:"result_desc"`
+	MpesaReceipt sql.NullString `db:"mpesa_receipt"`
+	Mismatch     sql.NullString `db:"mismatch"`
+	CompletedAt  sql.NullTime   `db:"completed_at"`
+	ID           int64          `db:"id"`
+}
+
+// only the first callback for a request wins; Daraja may deliver it more than once
+func (q *Queries) CompleteMpesaStkRequest(ctx context.Context, arg CompleteMpesaStkRequestParams) (int64, error) {
+	result, err := q.exec(ctx, q.completeMpesaStkRequestStmt, completeMpesaStkRequest,
+		arg.ResultCode,
+		arg.ResultDesc,
+		arg.MpesaReceipt,
+		arg.Mismatch,
+		arg.CompletedAt,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const confirmTOTPSecret = `-- name: ConfirmTOTPSecret :exec
+UPDATE totp_secrets
+SET confirmed_at = NOW()
+WHERE profile_id = ?
+`
+
+func (q *Queries) ConfirmTOTPSecret(ctx context.Context, profileID int32) error {
+	_, err := q.exec(ctx, q.confirmTOTPSecretStmt, confirmTOTPSecret, profileID)
+	return err
+}
+
+const countCustomerTimeline = `-- name: CountCustomerTimeline :one
+SELECT COUNT(*) FROM (
+  SELECT 'customer' AS item_type, c.id AS item_id, c.created_at AS occurred_at
+  FROM customers c
+  WHERE c.id = ?
+  UNION ALL
+  SELECT 'merge', m.id, m.created_at
+  FROM customer_merges m
+  WHERE m.survivor_id = ?
+  UNION ALL
+  SELECT 'ticket', t.id, t.created_at
+  FROM tickets t
+  JOIN customers tc ON tc.id = t.customer_id
+  WHERE tc.id = ?
+  UNION ALL
+  SELECT 'transaction', x.id, x.created_at
+  FROM transactions x
+  WHERE x.id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = ?)
+  UNION ALL
+  SELECT 'status_change', e.id, e.created_at
+  FROM event_log e
+  WHERE (e.stream = 'ticket_events' AND e.type <> 'ticket.created'
+      AND e.subject_id IN (SELECT tt.id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = ?))
+     OR (e.stream = 'transaction_events' AND e.type <> 'transaction.created'
+      AND e.subject_id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = ?))
+) timeline
+WHERE ? IS NULL OR FIND_IN_SET(item_type, ?) > 0
+`
+
+type CountCustomerTimelineParams struct {
+	CustomerID int64          `db:"customer_id"`
+	ItemTypes  sql.NullString `db:"item_types"`
+}
+
+func (q *Queries) CountCustomerTimeline(ctx context.Context, arg CountCustomerTimelineParams) (int64, error) {
+	row := q.queryRow(ctx, q.countCustomerTimelineStmt, countCustomerTimeline,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.ItemTypes,
+		arg.ItemTypes,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCustomers = `-- name: CountCustomers :one
+SELECT COUNT(*) FROM customers
+WHERE ? IS NULL
+   OR full_name LIKE ?
+   OR email LIKE ?
+   OR phone_number LIKE ?
+`
+
+type CountCustomersParams struct {
+	Search sql.NullString `db:"search"`
+}
+
+func (q *Queries) CountCustomers(ctx context.Context, arg CountCustomersParams) (int64, error) {
+	row := q.queryRow(ctx, q.countCustomersStmt, countCustomers,
+		arg.Search,
+		arg.Search,
+		arg.Search,
+		arg.Search,
 	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countLoginSources = `-- name: CountLoginSources :one
+SELECT
+  COUNT(*) AS total,
+  CAST(COALESCE(SUM(ip = ?), 0) AS SIGNED) AS from_ip,
+  CAST(COALESCE(SUM(user_agent = ?), 0) AS SIGNED) AS from_device
+FROM login_events
+WHERE profile_id = ? AND success = TRUE
+`
+
+type CountLoginSourcesParams struct {
+	Ip        string        `db:"ip"`
+	UserAgent string        `db:"user_agent"`
+	ProfileID sql.NullInt32 `db:"profile_id"`
+}
+
+type CountLoginSourcesRow struct {
+	Total      int64 `db:"total"`
+	FromIp     int64 `db:"from_ip"`
+	FromDevice int64 `db:"from_device"`
+}
+
+// how often this profile has logged in successfully from the ip and the device
+func (q *Queries) CountLoginSources(ctx context.Context, arg CountLoginSourcesParams) (CountLoginSourcesRow, error) {
+	row := q.queryRow(ctx, q.countLoginSourcesStmt, countLoginSources, arg.Ip, arg.UserAgent, arg.ProfileID)
+	var i CountLoginSourcesRow
+	err := row.Scan(&i.Total, &i.FromIp, &i.FromDevice)
 	return i, err
 }
 
-const getCustomers = `-- name: GetCustomers :many
-SELECT id, full_name, email, phone_number, created_at FROM customers LIMIT ? OFFSET ?
+const countReconciliationItems = `-- name: CountReconciliationItems :many
+SELECT status, COUNT(*) AS total
+FROM reconciliation_items
+WHERE run_id = ?
+GROUP BY status
 `
 
-type GetCustomersParams struct {
-	Limit  int32 `db:"limit"`
-	Offset int32 `db:"offset"`
+type CountReconciliationItemsRow struct {
+	Status ReconciliationItemsStatus `db:"status"`
+	Total  int64                     `db:"total"`
 }
 
-func (q *Queries) GetCustomers(ctx context.Context, arg GetCustomersParams) ([]Customer, error) {
-	rows, err := q.query(ctx, q.getCustomersStmt, getCustomers, arg.Limit, arg.Offset)
+func (q *Queries) CountReconciliationItems(ctx context.Context, runID int64) ([]CountReconciliationItemsRow, error) {
+	rows, err := q.query(ctx, q.countReconciliationItemsStmt, countReconciliationItems, runID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Customer{}
+	items := []CountReconciliationItemsRow{}
 	for rows.Next() {
-		var i Customer
-		if err := rows.Scan(
-			&i.ID,
-			&i.FullName,
-			&i.Email,
-			&i.PhoneNumber,
-			&i.CreatedAt,
-		); err != nil {
+		var i CountReconciliationItemsRow
+		if err := rows.Scan(&i.Status, &i.Total); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -201,239 +381,4360 @@ func (q *Queries) GetCustomers(ctx context.Context, arg GetCustomersParams) ([]C
 	return items, nil
 }
 
-const getLatestOTPByProfileID = `-- name: GetLatestOTPByProfileID :one
-SELECT id, profile_id, otp_code, expires_at, verified, attempts, created_at
-FROM otp_codes
-WHERE profile_id = ?
-ORDER BY created_at DESC
-LIMIT 1
+const createAPIKey = `-- name: CreateAPIKey :execresult
+INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
 `
 
-func (q *Queries) GetLatestOTPByProfileID(ctx context.Context, profileID int32) (OtpCode, error) {
-	row := q.queryRow(ctx, q.getLatestOTPByProfileIDStmt, getLatestOTPByProfileID, profileID)
-	var i OtpCode
-	err := row.Scan(
-		&i.ID,
-		&i.ProfileID,
-		&i.OtpCode,
-		&i.ExpiresAt,
-		&i.Verified,
-		&i.Attempts,
-		&i.CreatedAt,
-	)
-	return i, err
+type CreateAPIKeyParams struct {
+	Name      string       `db:"name"`
+	Prefix    string       `db:"prefix"`
+	KeyHash   string       `db:"key_hash"`
+	Scopes    string       `db:"scopes"`
+	CreatedBy int32        `db:"created_by"`
+	ExpiresAt sql.NullTime `db:"expires_at"`
 }
 
-const getProfileByPhone = `-- name: GetProfileByPhone :one
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (sql.Result, error) {
+	return q.exec(ctx, q.createAPIKeyStmt, createAPIKey,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		arg.Scopes,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+	)
+}
 
-SELECT id, phone, password_hash, full_name, created_at, updated_at
-FROM profiles
-WHERE phone = ?
+const createCustomer = `-- name: CreateCustomer :execresult
+INSERT INTO customers (full_name, email, phone_number, segment)
+VALUES (?, ?, ?, ?)
 `
 
-// db/queries.sql
-func (q *Queries) GetProfileByPhone(ctx context.Context, phone string) (Profile, error) {
-	row := q.queryRow(ctx, q.getProfileByPhoneStmt, getProfileByPhone, phone)
-	var i Profile
-	err := row.Scan(
-		&i.ID,
-		&i.Phone,
-		&i.PasswordHash,
-		&i.FullName,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+type CreateCustomerParams struct {
+	FullName    string `db:"full_name"`
+	Email       string `db:"email"`
+	PhoneNumber string `db:"phone_number"`
+	Segment     string `db:"segment"`
+}
+
+func (q *Queries) CreateCustomer(ctx context.Context, arg CreateCustomerParams) (sql.Result, error) {
+	return q.exec(ctx, q.createCustomerStmt, createCustomer,
+		arg.FullName,
+		arg.Email,
+		arg.PhoneNumber,
+		arg.Segment,
 	)
-	return i, err
 }
 
-const getTicket = `-- name: GetTicket :one
-SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at FROM tickets
-WHERE id = ? LIMIT 1
+const createCustomerDuplicate = `-- name: CreateCustomerDuplicate :exec
+INSERT INTO customer_duplicates (customer_id, duplicate_id, reasons, name_similarity)
+VALUES (?, ?, ?, ?)
 `
 
-func (q *Queries) GetTicket(ctx context.Context, id int64) (Ticket, error) {
-	row := q.queryRow(ctx, q.getTicketStmt, getTicket, id)
-	var i Ticket
-	err := row.Scan(
-		&i.ID,
-		&i.Title,
-		&i.Description,
-		&i.Status,
-		&i.Priority,
-		&i.CreatedBy,
-		&i.AssignedTo,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+type CreateCustomerDuplicateParams struct {
+	CustomerID     int64   `db:"customer_id"`
+	DuplicateID    int64   `db:"duplicate_id"`
+	Reasons        string  `db:"reasons"`
+	NameSimilarity float64 `db:"name_similarity"`
+}
+
+func (q *Queries) CreateCustomerDuplicate(ctx context.Context, arg CreateCustomerDuplicateParams) error {
+	_, err := q.exec(ctx, q.createCustomerDuplicateStmt, createCustomerDuplicate,
+		arg.CustomerID,
+		arg.DuplicateID,
+		arg.Reasons,
+		arg.NameSimilarity,
 	)
-	return i, err
+	return err
 }
 
-const getTicketByTitleAndUser = `-- name: GetTicketByTitleAndUser :one
-SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at FROM tickets
-WHERE title = ? AND created_by = ?
-LIMIT 1
+const createCustomerMerge = `-- name: CreateCustomerMerge :execresult
+INSERT INTO customer_merges (survivor_id, merged_id, merged_full_name, merged_email, merged_phone_number, ticket_ids, transaction_ids, merged_by, note)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
-type GetTicketByTitleAndUserParams struct {
-	Title     string `db:"title"`
-	CreatedBy int64  `db:"created_by"`
+type CreateCustomerMergeParams struct {
+	SurvivorID        int64  `db:"survivor_id"`
+	MergedID          int64  `db:"merged_id"`
+	MergedFullName    string `db:"merged_full_name"`
+	MergedEmail       string `db:"merged_email"`
+	MergedPhoneNumber string `db:"merged_phone_number"`
+	TicketIds         string `db:"ticket_ids"`
+	TransactionIds    string `db:"transaction_ids"`
+	MergedBy          int32  `db:"merged_by"`
+	Note              string `db:"note"`
 }
 
-func (q *Queries) GetTicketByTitleAndUser(ctx context.Context, arg GetTicketByTitleAndUserParams) (Ticket, error) {
-	row := q.queryRow(ctx, q.getTicketByTitleAndUserStmt, getTicketByTitleAndUser, arg.Title, arg.CreatedBy)
-	var i Ticket
-	err := row.Scan(
-		&i.ID,
-		&i.Title,
-		&i.Description,
-		&i.Status,
-		&i.Priority,
-		&i.CreatedBy,
-		&i.AssignedTo,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+func (q *Queries) CreateCustomerMerge(ctx context.Context, arg CreateCustomerMergeParams) (sql.Result, error) {
+	return q.exec(ctx, q.createCustomerMergeStmt, createCustomerMerge,
+		arg.SurvivorID,
+		arg.MergedID,
+		arg.MergedFullName,
+		arg.MergedEmail,
+		arg.MergedPhoneNumber,
+		arg.TicketIds,
+		arg.TransactionIds,
+		arg.MergedBy,
+		arg.Note,
 	)
-	return i, err
 }
 
-const getTransanctionByID = `-- name: GetTransanctionByID :one
-SELECT id, transaction_id, user_id, amount, currency, status, payment_method, created_at, updated_atFROM transactions
-WHERE id = ?
-LIMIT 1
+const createDataSubjectRequest = `-- name: CreateDataSubjectRequest :execresult
+INSERT INTO data_subject_requests (kind, subject_hash, profile_ids, customer_ids, summary, reason, requested_by)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
-func (q *Queries) GetTransanctionByID(ctx context.Context, id int32) (Transaction, error) {
-	row := q.queryRow(ctx, q.getTransanctionByIDStmt, getTransanctionByID, id)
+type CreateDataSubjectRequestParams struct {
+	Kind        DataSubjectRequestsKind `db:"kind"`
+	SubjectHash string                  `db:"subject_hash"`
+	ProfileIds  string                  `db:"profile_ids"`
+	CustomerIds string                  `db:"customer_ids"`
+	Summary     string                  `db:"summary"`
+	Reason      string                  `db:"reason"`
+	RequestedBy int32                   `db:"requested_by"`
+}
+
+func (q *Queries) CreateDataSubjectRequest(ctx context.Context, arg CreateDataSubjectRequestParams) (sql.Result, error) {
+	return q.exec(ctx, q.createDataSubjectRequestStmt, createDataSubjectRequest,
+		arg.Kind,
+		arg.SubjectHash,
+		arg.ProfileIds,
+		arg.CustomerIds,
+		arg.Summary,
+		arg.Reason,
+		arg.RequestedBy,
+	)
+}
+
+const createEvent = `-- name: CreateEvent :execresult
+INSERT INTO event_log (stream, type, owner_id, subject_id, payload)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateEventParams struct {
+	Stream    string        `db:"stream"`
+	Type      string        `db:"type"`
+	OwnerID   sql.NullInt64 `db:"owner_id"`
+	SubjectID sql.NullInt64 `db:"subject_id"`
+	Payload   string        `db:"payload"`
+}
+
+func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (sql.Result, error) {
+	return q.exec(ctx, q.createEventStmt, createEvent,
+		arg.Stream,
+		arg.Type,
+		arg.OwnerID,
+		arg.SubjectID,
+		arg.Payload,
+	)
+}
+
+const createIdempotencyKey = `-- name: CreateIdempotencyKey :execrows
+INSERT IGNORE INTO idempotency_keys (scope, idempotency_key, request_path, fingerprint, expires_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateIdempotencyKeyParams struct {
+	Scope          string    `db:"scope"`
+	IdempotencyKey string    `db:"idempotency_key"`
+	RequestPath    string    `db:"request_path"`
+	Fingerprint    string    `db:"fingerprint"`
+	ExpiresAt      time.Time `db:"expires_at"`
+}
+
+// affects no rows when the key is already taken in this scope
+func (q *Queries) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (int64, error) {
+	result, err := q.exec(ctx, q.createIdempotencyKeyStmt, createIdempotencyKey,
+		arg.Scope,
+		arg.IdempotencyKey,
+		arg.RequestPath,
+		arg.Fingerprint,
+		arg.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createImportJob = `-- name: CreateImportJob :execresult
+INSERT INTO import_jobs (kind, dry_run, filename, created_by)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateImportJobParams struct {
+	Kind      ImportJobsKind `db:"kind"`
+	DryRun    bool           `db:"dry_run"`
+	Filename  string         `db:"filename"`
+	CreatedBy int32          `db:"created_by"`
+}
+
+func (q *Queries) CreateImportJob(ctx context.Context, arg CreateImportJobParams) (sql.Result, error) {
+	return q.exec(ctx, q.createImportJobStmt, createImportJob,
+		arg.Kind,
+		arg.DryRun,
+		arg.Filename,
+		arg.CreatedBy,
+	)
+}
+
+const createJournalEntry = `-- name: CreateJournalEntry :execresult
+INSERT INTO journal_entries (transaction_id, description, occurred_at)
+VALUES (?, ?, ?)
+`
+
+type CreateJournalEntryParams struct {
+	TransactionID sql.NullInt32 `db:"transaction_id"`
+	Description   string        `db:"description"`
+	OccurredAt    time.Time     `db:"occurred_at"`
+}
+
+func (q *Queries) CreateJournalEntry(ctx context.Context, arg CreateJournalEntryParams) (sql.Result, error) {
+	return q.exec(ctx, q.createJournalEntryStmt, createJournalEntry, arg.TransactionID, arg.Description, arg.OccurredAt)
+}
+
+const createLoginEvent = `-- name: CreateLoginEvent :exec
+INSERT INTO login_events (profile_id, identifier, ip, user_agent, success, reason)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type CreateLoginEventParams struct {
+	ProfileID  sql.NullInt32 `db:"profile_id"`
+	Identifier string        `db:"identifier"`
+	Ip         string        `db:"ip"`
+	UserAgent  string        `db:"user_agent"`
+	Success    bool          `db:"success"`
+	Reason     string        `db:"reason"`
+}
+
+func (q *Queries) CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) error {
+	_, err := q.exec(ctx, q.createLoginEventStmt, createLoginEvent,
+		arg.ProfileID,
+		arg.Identifier,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Success,
+		arg.Reason,
+	)
+	return err
+}
+
+const createMpesaStkRequest = `-- name: CreateMpesaStkRequest :exec
+INSERT INTO mpesa_stk_requests (transaction_id, merchant_request_id, checkout_request_id, phone)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateMpesaStkRequestParams struct {
+	TransactionID     int32  `db:"transaction_id"`
+	MerchantRequestID string `db:"merchant_request_id"`
+	CheckoutRequestID string `db:"checkout_request_id"`
+	Phone             string `db:"phone"`
+}
+
+func (q *Queries) CreateMpesaStkRequest(ctx context.Context, arg CreateMpesaStkRequestParams) error {
+	_, err := q.exec(ctx, q.createMpesaStkRequestStmt, createMpesaStkRequest,
+		arg.TransactionID,
+		arg.MerchantRequestID,
+		arg.CheckoutRequestID,
+		arg.Phone,
+	)
+	return err
+}
+
+const createOTP = `-- name: CreateOTP :execresult
+INSERT INTO otp_codes (profile_id, otp_code, purpose, expires_at)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateOTPParams struct {
+	ProfileID int32     `db:"profile_id"`
+	OtpCode   string    `db:"otp_code"`
+	Purpose   string    `db:"purpose"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+func (q *Queries) CreateOTP(ctx context.Context, arg CreateOTPParams) (sql.Result, error) {
+	return q.exec(ctx, q.createOTPStmt, createOTP,
+		arg.ProfileID,
+		arg.OtpCode,
+		arg.Purpose,
+		arg.ExpiresAt,
+	)
+}
+
+const createPosting = `-- name: CreatePosting :exec
+INSERT INTO postings (entry_id, account_id, amount_minor, currency)
+VALUES (?, ?, ?, ?)
+`
+
+type CreatePostingParams struct {
+	EntryID     int64  `db:"entry_id"`
+	AccountID   int64  `db:"account_id"`
+	AmountMinor int64  `db:"amount_minor"`
+	Currency    string `db:"currency"`
+}
+
+func (q *Queries) CreatePosting(ctx context.Context, arg CreatePostingParams) error {
+	_, err := q.exec(ctx, q.createPostingStmt, createPosting,
+		arg.EntryID,
+		arg.AccountID,
+		arg.AmountMinor,
+		arg.Currency,
+	)
+	return err
+}
+
+const createProfile = `-- name: CreateProfile :execresult
+INSERT INTO profiles (full_name, phone, email, password_hash)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateProfileParams struct {
+	FullName     sql.NullString `db:"full_name"`
+	Phone        sql.NullString `db:"phone"`
+	Email        sql.NullString `db:"email"`
+	PasswordHash string         `db:"password_hash"`
+}
+
+func (q *Queries) CreateProfile(ctx context.Context, arg CreateProfileParams) (sql.Result, error) {
+	return q.exec(ctx, q.createProfileStmt, createProfile,
+		arg.FullName,
+		arg.Phone,
+		arg.Email,
+		arg.PasswordHash,
+	)
+}
+
+const createReconciliationItem = `-- name: CreateReconciliationItem :exec
+INSERT INTO reconciliation_items (run_id, status, line_number, reference, amount_minor, currency, occurred_at, transaction_id, note)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateReconciliationItemParams struct {
+	RunID         int64                     `db:"run_id"`
+	Status        ReconciliationItemsStatus `db:"status"`
+	LineNumber    sql.NullInt32             `db:"line_number"`
+	Reference     sql.NullString            `db:"reference"`
+	AmountMinor   sql.NullInt64             `db:"amount_minor"`
+	Currency      sql.NullString            `db:"currency"`
+	OccurredAt    sql.NullTime              `db:"occurred_at"`
+	TransactionID sql.NullInt32             `db:"transaction_id"`
+	Note          string                    `db:"note"`
+}
+
+func (q *Queries) CreateReconciliationItem(ctx context.Context, arg CreateReconciliationItemParams) error {
+	_, err := q.exec(ctx, q.createReconciliationItemStmt, createReconciliationItem,
+		arg.RunID,
+		arg.Status,
+		arg.LineNumber,
+		arg.Reference,
+		arg.AmountMinor,
+		arg.Currency,
+		arg.OccurredAt,
+		arg.TransactionID,
+		arg.Note,
+	)
+	return err
+}
+
+const createReconciliationRun = `-- name: CreateReconciliationRun :execresult
+INSERT INTO reconciliation_runs (format, filename, line_count, period_start, period_end, window_seconds, created_by)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateReconciliationRunParams struct {
+	Format        string        `db:"format"`
+	Filename      string        `db:"filename"`
+	LineCount     int32         `db:"line_count"`
+	PeriodStart   sql.NullTime  `db:"period_start"`
+	PeriodEnd     sql.NullTime  `db:"period_end"`
+	WindowSeconds int32         `db:"window_seconds"`
+	CreatedBy     sql.NullInt32 `db:"created_by"`
+}
+
+func (q *Queries) CreateReconciliationRun(ctx context.Context, arg CreateReconciliationRunParams) (sql.Result, error) {
+	return q.exec(ctx, q.createReconciliationRunStmt, createReconciliationRun,
+		arg.Format,
+		arg.Filename,
+		arg.LineCount,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.WindowSeconds,
+		arg.CreatedBy,
+	)
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (profile_id, code_hash)
+VALUES (?, ?)
+`
+
+type CreateRecoveryCodeParams struct {
+	ProfileID int32  `db:"profile_id"`
+	CodeHash  string `db:"code_hash"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.exec(ctx, q.createRecoveryCodeStmt, createRecoveryCode, arg.ProfileID, arg.CodeHash)
+	return err
+}
+
+const createRefund = `-- name: CreateRefund :execresult
+INSERT INTO transactions (transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id)
+VALUES (?, ?, ?, ?, ?, ?, 'refund', ?)
+`
+
+type CreateRefundParams struct {
+	TransactionID string         `db:"transaction_id"`
+	UserID        int32          `db:"user_id"`
+	AmountMinor   int64          `db:"amount_minor"`
+	Currency      string         `db:"currency"`
+	Status        int16          `db:"status"`
+	PaymentMethod sql.NullString `db:"payment_method"`
+	ParentID      sql.NullInt32  `db:"parent_id"`
+}
+
+func (q *Queries) CreateRefund(ctx context.Context, arg CreateRefundParams) (sql.Result, error) {
+	return q.exec(ctx, q.createRefundStmt, createRefund,
+		arg.TransactionID,
+		arg.UserID,
+		arg.AmountMinor,
+		arg.Currency,
+		arg.Status,
+		arg.PaymentMethod,
+		arg.ParentID,
+	)
+}
+
+const createRoutingRule = `-- name: CreateRoutingRule :execresult
+INSERT INTO routing_rules (name, priority, team_id, ticket_priority, ticket_type, customer_segment, keywords, active)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateRoutingRuleParams struct {
+	Name            string                     `db:"name"`
+	Priority        int32                      `db:"priority"`
+	TeamID          int64                      `db:"team_id"`
+	TicketPriority  sql.NullString             `db:"ticket_priority"`
+	TicketType      NullRoutingRulesTicketType `db:"ticket_type"`
+	CustomerSegment sql.NullString             `db:"customer_segment"`
+	Keywords        string                     `db:"keywords"`
+	Active          bool                       `db:"active"`
+}
+
+func (q *Queries) CreateRoutingRule(ctx context.Context, arg CreateRoutingRuleParams) (sql.Result, error) {
+	return q.exec(ctx, q.createRoutingRuleStmt, createRoutingRule,
+		arg.Name,
+		arg.Priority,
+		arg.TeamID,
+		arg.TicketPriority,
+		arg.TicketType,
+		arg.CustomerSegment,
+		arg.Keywords,
+		arg.Active,
+	)
+}
+
+const createStreamTicket = `-- name: CreateStreamTicket :exec
+INSERT INTO stream_tickets (ticket_hash, profile_id, token_version, expires_at)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateStreamTicketParams struct {
+	TicketHash   string    `db:"ticket_hash"`
+	ProfileID    int32     `db:"profile_id"`
+	TokenVersion int32     `db:"token_version"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func (q *Queries) CreateStreamTicket(ctx context.Context, arg CreateStreamTicketParams) error {
+	_, err := q.exec(ctx, q.createStreamTicketStmt, createStreamTicket,
+		arg.TicketHash,
+		arg.ProfileID,
+		arg.TokenVersion,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createTeam = `-- name: CreateTeam :execresult
+INSERT INTO teams (name, description, assignment_strategy)
+VALUES (?, ?, ?)
+`
+
+type CreateTeamParams struct {
+	Name               string `db:"name"`
+	Description        string `db:"description"`
+	AssignmentStrategy string `db:"assignment_strategy"`
+}
+
+func (q *Queries) CreateTeam(ctx context.Context, arg CreateTeamParams) (sql.Result, error) {
+	return q.exec(ctx, q.createTeamStmt, createTeam, arg.Name, arg.Description, arg.AssignmentStrategy)
+}
+
+const createTicket = `-- name: CreateTicket :execresult
+INSERT INTO tickets (title, description, created_by, priority, status, type, customer_id, transaction_id, team_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateTicketParams struct {
+	Title         string        `db:"title"`
+	Description   string        `db:"description"`
+	CreatedBy     int64         `db:"created_by"`
+	Priority      string        `db:"priority"`
+	Status        int16         `db:"status"`
+	Type          TicketsType   `db:"type"`
+	CustomerID    sql.NullInt64 `db:"customer_id"`
+	TransactionID sql.NullInt32 `db:"transaction_id"`
+	TeamID        sql.NullInt64 `db:"team_id"`
+}
+
+func (q *Queries) CreateTicket(ctx context.Context, arg CreateTicketParams) (sql.Result, error) {
+	return q.exec(ctx, q.createTicketStmt, createTicket,
+		arg.Title,
+		arg.Description,
+		arg.CreatedBy,
+		arg.Priority,
+		arg.Status,
+		arg.Type,
+		arg.CustomerID,
+		arg.TransactionID,
+		arg.TeamID,
+	)
+}
+
+const createTicketAssignment = `-- name: CreateTicketAssignment :execresult
+INSERT INTO ticket_assignments (ticket_id, team_id, strategy, assigned_to, reason, candidates)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type CreateTicketAssignmentParams struct {
+	TicketID   int64         `db:"ticket_id"`
+	TeamID     sql.NullInt64 `db:"team_id"`
+	Strategy   string        `db:"strategy"`
+	AssignedTo sql.NullInt32 `db:"assigned_to"`
+	Reason     string        `db:"reason"`
+	Candidates string        `db:"candidates"`
+}
+
+func (q *Queries) CreateTicketAssignment(ctx context.Context, arg CreateTicketAssignmentParams) (sql.Result, error) {
+	return q.exec(ctx, q.createTicketAssignmentStmt, createTicketAssignment,
+		arg.TicketID,
+		arg.TeamID,
+		arg.Strategy,
+		arg.AssignedTo,
+		arg.Reason,
+		arg.Candidates,
+	)
+}
+
+const createTransaction = `-- name: CreateTransaction :execresult
+INSERT INTO transactions (transaction_id, user_id,amount_minor,currency,status,payment_method)
+VALUES(?,?,?,?,?,?)
+`
+
+type CreateTransactionParams struct {
+	TransactionID string         `db:"transaction_id"`
+	UserID        int32          `db:"user_id"`
+	AmountMinor   int64          `db:"amount_minor"`
+	Currency      string         `db:"currency"`
+	Status        int16          `db:"status"`
+	PaymentMethod sql.NullString `db:"payment_method"`
+}
+
+func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (sql.Result, error) {
+	return q.exec(ctx, q.createTransactionStmt, createTransaction,
+		arg.TransactionID,
+		arg.UserID,
+		arg.AmountMinor,
+		arg.Currency,
+		arg.Status,
+		arg.PaymentMethod,
+	)
+}
+
+const createUser = `-- name: CreateUser :execresult
+INSERT INTO profiles (full_name, email, phone, role, password_hash)
+VALUES (?, ?, ?, ?, '')
+`
+
+type CreateUserParams struct {
+	FullName sql.NullString `db:"full_name"`
+	Email    sql.NullString `db:"email"`
+	Phone    sql.NullString `db:"phone"`
+	Role     ProfilesRole   `db:"role"`
+}
+
+// users are profiles managed by an admin; they have no password until
+// they go through the reset flow
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (sql.Result, error) {
+	return q.exec(ctx, q.createUserStmt, createUser,
+		arg.FullName,
+		arg.Email,
+		arg.Phone,
+		arg.Role,
+	)
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :execresult
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, next_attempt_at, redelivery_of)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type CreateWebhookDeliveryParams struct {
+	SubscriptionID int64         `db:"subscription_id"`
+	EventID        string        `db:"event_id"`
+	EventType      string        `db:"event_type"`
+	Payload        string        `db:"payload"`
+	NextAttemptAt  time.Time     `db:"next_attempt_at"`
+	RedeliveryOf   sql.NullInt64 `db:"redelivery_of"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (sql.Result, error) {
+	return q.exec(ctx, q.createWebhookDeliveryStmt, createWebhookDelivery,
+		arg.SubscriptionID,
+		arg.EventID,
+		arg.EventType,
+		arg.Payload,
+		arg.NextAttemptAt,
+		arg.RedeliveryOf,
+	)
+}
+
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :execresult
+INSERT INTO webhook_subscriptions (profile_id, url, secret, events, scope)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateWebhookSubscriptionParams struct {
+	ProfileID int32                     `db:"profile_id"`
+	Url       string                    `db:"url"`
+	Secret    string                    `db:"secret"`
+	Events    string                    `db:"events"`
+	Scope     WebhookSubscriptionsScope `db:"scope"`
+}
+
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (sql.Result, error) {
+	return q.exec(ctx, q.createWebhookSubscriptionStmt, createWebhookSubscription,
+		arg.ProfileID,
+		arg.Url,
+		arg.Secret,
+		arg.Events,
+		arg.Scope,
+	)
+}
+
+const deleteCustomer = `-- name: DeleteCustomer :execrows
+DELETE FROM customers
+WHERE id = ?
+`
+
+func (q *Queries) DeleteCustomer(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteCustomerStmt, deleteCustomer, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCustomerDuplicates = `-- name: DeleteCustomerDuplicates :exec
+DELETE FROM customer_duplicates
+`
+
+func (q *Queries) DeleteCustomerDuplicates(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteCustomerDuplicatesStmt, deleteCustomerDuplicates)
+	return err
+}
+
+const deleteEventsBefore = `-- name: DeleteEventsBefore :execrows
+DELETE FROM event_log
+WHERE created_at < ?
+  AND NOT (
+    (stream = 'ticket_events' AND type <> 'ticket.created' AND subject_id IS NOT NULL)
+    OR (stream = 'transaction_events' AND type <> 'transaction.created' AND subject_id IS NOT NULL)
+  )
+ORDER BY id
+LIMIT ?
+`
+
+type DeleteEventsBeforeParams struct {
+	CreatedAt time.Time `db:"created_at"`
+	Limit     int32     `db:"limit"`
+}
+
+// keeps the ticket and transaction events that ListCustomerTimeline shows
+// as status changes, whatever their age
+func (q *Queries) DeleteEventsBefore(ctx context.Context, arg DeleteEventsBeforeParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteEventsBeforeStmt, deleteEventsBefore, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteEventsBySubject = `-- name: DeleteEventsBySubject :execrows
+DELETE FROM event_log
+WHERE stream = ? AND subject_id = ?
+`
+
+type DeleteEventsBySubjectParams struct {
+	Stream    string        `db:"stream"`
+	SubjectID sql.NullInt64 `db:"subject_id"`
+}
+
+func (q *Queries) DeleteEventsBySubject(ctx context.Context, arg DeleteEventsBySubjectParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteEventsBySubjectStmt, deleteEventsBySubject, arg.Stream, arg.SubjectID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredIdempotencyKeys = `-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE expires_at < ?
+`
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, expiresAt time.Time) error {
+	_, err := q.exec(ctx, q.deleteExpiredIdempotencyKeysStmt, deleteExpiredIdempotencyKeys, expiresAt)
+	return err
+}
+
+const deleteExpiredOTPs = `-- name: DeleteExpiredOTPs :exec
+DELETE FROM otp_codes
+WHERE expires_at < NOW()
+`
+
+func (q *Queries) DeleteExpiredOTPs(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteExpiredOTPsStmt, deleteExpiredOTPs)
+	return err
+}
+
+const deleteExpiredStreamTickets = `-- name: DeleteExpiredStreamTickets :exec
+DELETE FROM stream_tickets
+WHERE expires_at < ?
+`
+
+func (q *Queries) DeleteExpiredStreamTickets(ctx context.Context, expiresAt time.Time) error {
+	_, err := q.exec(ctx, q.deleteExpiredStreamTicketsStmt, deleteExpiredStreamTickets, expiresAt)
+	return err
+}
+
+const deleteIdempotencyKey = `-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE id = ?
+`
+
+func (q *Queries) DeleteIdempotencyKey(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.deleteIdempotencyKeyStmt, deleteIdempotencyKey, id)
+	return err
+}
+
+const deleteOTPsByProfile = `-- name: DeleteOTPsByProfile :exec
+DELETE FROM otp_codes WHERE profile_id = ?
+`
+
+func (q *Queries) DeleteOTPsByProfile(ctx context.Context, profileID int32) error {
+	_, err := q.exec(ctx, q.deleteOTPsByProfileStmt, deleteOTPsByProfile, profileID)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE profile_id = ?
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, profileID int32) error {
+	_, err := q.exec(ctx, q.deleteRecoveryCodesStmt, deleteRecoveryCodes, profileID)
+	return err
+}
+
+const deleteRoutingRule = `-- name: DeleteRoutingRule :execrows
+DELETE FROM routing_rules
+WHERE id = ?
+`
+
+func (q *Queries) DeleteRoutingRule(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteRoutingRuleStmt, deleteRoutingRule, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteTOTPSecret = `-- name: DeleteTOTPSecret :exec
+DELETE FROM totp_secrets WHERE profile_id = ?
+`
+
+func (q *Queries) DeleteTOTPSecret(ctx context.Context, profileID int32) error {
+	_, err := q.exec(ctx, q.deleteTOTPSecretStmt, deleteTOTPSecret, profileID)
+	return err
+}
+
+const deleteTeam = `-- name: DeleteTeam :execrows
+DELETE FROM teams
+WHERE id = ?
+`
+
+func (q *Queries) DeleteTeam(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteTeamStmt, deleteTeam, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebhookDeliveriesBySubject = `-- name: DeleteWebhookDeliveriesBySubject :execrows
+DELETE FROM webhook_deliveries
+WHERE event_type LIKE ?
+  AND JSON_EXTRACT(payload, '$.payload.id') = CAST(? AS SIGNED)
+`
+
+type DeleteWebhookDeliveriesBySubjectParams struct {
+	EventType string `db:"event_type"`
+	SubjectID int64  `db:"subject_id"`
+}
+
+// deliveries of events about one ticket or profile, e.g. ticket.% and an id
+func (q *Queries) DeleteWebhookDeliveriesBySubject(ctx context.Context, arg DeleteWebhookDeliveriesBySubjectParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteWebhookDeliveriesBySubjectStmt, deleteWebhookDeliveriesBySubject, arg.EventType, arg.SubjectID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = ?
+`
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.deleteWebhookSubscriptionStmt, deleteWebhookSubscription, id)
+	return err
+}
+
+const deleteWebhookSubscriptionsByProfile = `-- name: DeleteWebhookSubscriptionsByProfile :execrows
+DELETE FROM webhook_subscriptions WHERE profile_id = ?
+`
+
+func (q *Queries) DeleteWebhookSubscriptionsByProfile(ctx context.Context, profileID int32) (int64, error) {
+	result, err := q.exec(ctx, q.deleteWebhookSubscriptionsByProfileStmt, deleteWebhookSubscriptionsByProfile, profileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disableWebhookSubscription = `-- name: DisableWebhookSubscription :execrows
+UPDATE webhook_subscriptions
+SET active = FALSE, disabled_at = ?, disabled_reason = ?
+WHERE id = ? AND active = TRUE AND consecutive_failures >= ?
+`
+
+type DisableWebhookSubscriptionParams struct {
+	DisabledAt          sql.NullTime `db:"disabled_at"`
+	DisabledReason      string       `db:"disabled_reason"`
+	ID                  int64        `db:"id"`
+	ConsecutiveFailures int32        `db:"consecutive_failures"`
+}
+
+// affects no rows unless the subscription is active and has failed often enough
+func (q *Queries) DisableWebhookSubscription(ctx context.Context, arg DisableWebhookSubscriptionParams) (int64, error) {
+	result, err := q.exec(ctx, q.disableWebhookSubscriptionStmt, disableWebhookSubscription,
+		arg.DisabledAt,
+		arg.DisabledReason,
+		arg.ID,
+		arg.ConsecutiveFailures,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ensureLedgerAccount = `-- name: EnsureLedgerAccount :exec
+INSERT IGNORE INTO ledger_accounts (code, name, type, currency, profile_id)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type EnsureLedgerAccountParams struct {
+	Code      string             `db:"code"`
+	Name      string             `db:"name"`
+	Type      LedgerAccountsType `db:"type"`
+	Currency  string             `db:"currency"`
+	ProfileID sql.NullInt32      `db:"profile_id"`
+}
+
+// no-op if an account with this code already exists
+func (q *Queries) EnsureLedgerAccount(ctx context.Context, arg EnsureLedgerAccountParams) error {
+	_, err := q.exec(ctx, q.ensureLedgerAccountStmt, ensureLedgerAccount,
+		arg.Code,
+		arg.Name,
+		arg.Type,
+		arg.Currency,
+		arg.ProfileID,
+	)
+	return err
+}
+
+const exportTransactions = `-- name: ExportTransactions :many
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_at FROM transactions
+WHERE (? IS NULL OR user_id = ?)
+  AND (? IS NULL OR status = ?)
+  AND (? IS NULL OR currency = ?)
+  AND (? IS NULL OR payment_method = ?)
+  AND (? IS NULL OR kind = ?)
+  AND (? IS NULL OR amount_minor >= ?)
+  AND (? IS NULL OR amount_minor <= ?)
+  AND (? IS NULL OR created_at >= ?)
+  AND (? IS NULL OR created_at < ?)
+  AND id > ?
+ORDER BY id
+LIMIT ?
+`
+
+type ExportTransactionsParams struct {
+	UserID        sql.NullInt32        `db:"user_id"`
+	Status        sql.NullInt16        `db:"status"`
+	Currency      sql.NullString       `db:"currency"`
+	PaymentMethod sql.NullString       `db:"payment_method"`
+	Kind          NullTransactionsKind `db:"kind"`
+	MinAmount     sql.NullInt64        `db:"min_amount"`
+	MaxAmount     sql.NullInt64        `db:"max_amount"`
+	CreatedFrom   sql.NullTime         `db:"created_from"`
+	CreatedTo     sql.NullTime         `db:"created_to"`
+	AfterID       int32                `db:"after_id"`
+	Limit         int32                `db:"limit"`
+}
+
+// keyset pages for streaming exports: pass the last id of the previous page
+func (q *Queries) ExportTransactions(ctx context.Context, arg ExportTransactionsParams) ([]Transaction, error) {
+	rows, err := q.query(ctx, q.exportTransactionsStmt, exportTransactions,
+		arg.UserID,
+		arg.UserID,
+		arg.Status,
+		arg.Status,
+		arg.Currency,
+		arg.Currency,
+		arg.PaymentMethod,
+		arg.PaymentMethod,
+		arg.Kind,
+		arg.Kind,
+		arg.MinAmount,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.MaxAmount,
+		arg.CreatedFrom,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.CreatedTo,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.Status,
+			&i.PaymentMethod,
+			&i.Kind,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const failStaleImportJobs = `-- name: FailStaleImportJobs :execrows
+UPDATE import_jobs
+SET status = 'failed', error_message = ?, finished_at = ?
+WHERE status IN ('queued', 'running')
+  AND updated_at < NOW() - INTERVAL 15 MINUTE
+`
+
+type FailStaleImportJobsParams struct {
+	ErrorMessage string       `db:"error_message"`
+	FinishedAt   sql.NullTime `db:"finished_at"`
+}
+
+// jobs whose API process went away: queued or running, untouched for 15 minutes
+func (q *Queries) FailStaleImportJobs(ctx context.Context, arg FailStaleImportJobsParams) (int64, error) {
+	result, err := q.exec(ctx, q.failStaleImportJobsStmt, failStaleImportJobs, arg.ErrorMessage, arg.FinishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const finishImportJob = `-- name: FinishImportJob :exec
+UPDATE import_jobs
+SET status = ?, rows_processed = ?, report = ?, error_message = ?, finished_at = ?
+WHERE id = ?
+`
+
+type FinishImportJobParams struct {
+	Status        ImportJobsStatus `db:"status"`
+	RowsProcessed int32            `db:"rows_processed"`
+	Report        sql.NullString   `db:"report"`
+	ErrorMessage  string           `db:"error_message"`
+	FinishedAt    sql.NullTime     `db:"finished_at"`
+	ID            int64            `db:"id"`
+}
+
+func (q *Queries) FinishImportJob(ctx context.Context, arg FinishImportJobParams) error {
+	_, err := q.exec(ctx, q.finishImportJobStmt, finishImportJob,
+		arg.Status,
+		arg.RowsProcessed,
+		arg.Report,
+		arg.ErrorMessage,
+		arg.FinishedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const getAPIKeyByPrefix = `-- name: GetAPIKeyByPrefix :one
+SELECT k.id, k.name, k.prefix, k.key_hash, k.scopes, k.created_by, k.expires_at, k.last_used_at, k.revoked_at, k.created_at, p.role AS creator_role
+FROM api_keys k
+JOIN profiles p ON p.id = k.created_by
+WHERE k.prefix = ? LIMIT 1
+`
+
+type GetAPIKeyByPrefixRow struct {
+	ApiKey      ApiKey       `db:"api_key"`
+	CreatorRole ProfilesRole `db:"creator_role"`
+}
+
+func (q *Queries) GetAPIKeyByPrefix(ctx context.Context, prefix string) (GetAPIKeyByPrefixRow, error) {
+	row := q.queryRow(ctx, q.getAPIKeyByPrefixStmt, getAPIKeyByPrefix, prefix)
+	var i GetAPIKeyByPrefixRow
+	err := row.Scan(
+		&i.ApiKey.ID,
+		&i.ApiKey.Name,
+		&i.ApiKey.Prefix,
+		&i.ApiKey.KeyHash,
+		&i.ApiKey.Scopes,
+		&i.ApiKey.CreatedBy,
+		&i.ApiKey.ExpiresAt,
+		&i.ApiKey.LastUsedAt,
+		&i.ApiKey.RevokedAt,
+		&i.ApiKey.CreatedAt,
+		&i.CreatorRole,
+	)
+	return i, err
+}
+
+const getAccountBalance = `-- name: GetAccountBalance :one
+SELECT CAST(COALESCE(SUM(p.amount_minor), 0) AS SIGNED) AS balance
+FROM postings p
+JOIN journal_entries e ON e.id = p.entry_id
+WHERE p.account_id = ? AND e.occurred_at <= ?
+`
+
+type GetAccountBalanceParams struct {
+	AccountID  int64     `db:"account_id"`
+	OccurredAt time.Time `db:"occurred_at"`
+}
+
+// balance of an account from all entries that occurred at or before as_of
+func (q *Queries) GetAccountBalance(ctx context.Context, arg GetAccountBalanceParams) (int64, error) {
+	row := q.queryRow(ctx, q.getAccountBalanceStmt, getAccountBalance, arg.AccountID, arg.OccurredAt)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
+const getAgentSettings = `-- name: GetAgentSettings :one
+SELECT profile_id, status, max_concurrent, skills, updated_at FROM agent_settings
+WHERE profile_id = ? LIMIT 1
+`
+
+func (q *Queries) GetAgentSettings(ctx context.Context, profileID int32) (AgentSetting, error) {
+	row := q.queryRow(ctx, q.getAgentSettingsStmt, getAgentSettings, profileID)
+	var i AgentSetting
+	err := row.Scan(
+		&i.ProfileID,
+		&i.Status,
+		&i.MaxConcurrent,
+		&i.Skills,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getCustomer = `-- name: GetCustomer :one
+SELECT id, full_name, email, phone_number, created_at, segment FROM customers
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetCustomer(ctx context.Context, id int64) (Customer, error) {
+	row := q.queryRow(ctx, q.getCustomerStmt, getCustomer, id)
+	var i Customer
+	err := row.Scan(
+		&i.ID,
+		&i.FullName,
+		&i.Email,
+		&i.PhoneNumber,
+		&i.CreatedAt,
+		&i.Segment,
+	)
+	return i, err
+}
+
+const getCustomerByEmail = `-- name: GetCustomerByEmail :one
+SELECT id, full_name, email, phone_number, created_at, segment FROM customers
+WHERE email = ? LIMIT 1
+`
+
+func (q *Queries) GetCustomerByEmail(ctx context.Context, email string) (Customer, error) {
+	row := q.queryRow(ctx, q.getCustomerByEmailStmt, getCustomerByEmail, email)
+	var i Customer
+	err := row.Scan(
+		&i.ID,
+		&i.FullName,
+		&i.Email,
+		&i.PhoneNumber,
+		&i.CreatedAt,
+		&i.Segment,
+	)
+	return i, err
+}
+
+const getCustomerByPhone = `-- name: GetCustomerByPhone :one
+SELECT id, full_name, email, phone_number, created_at, segment FROM customers
+WHERE phone_number = ? LIMIT 1
+`
+
+func (q *Queries) GetCustomerByPhone(ctx context.Context, phoneNumber string) (Customer, error) {
+	row := q.queryRow(ctx, q.getCustomerByPhoneStmt, getCustomerByPhone, phoneNumber)
+	var i Customer
+	err := row.Scan(
+		&i.ID,
+		&i.FullName,
+		&i.Email,
+		&i.PhoneNumber,
+		&i.CreatedAt,
+		&i.Segment,
+	)
+	return i, err
+}
+
+const getCustomerMerge = `-- name: GetCustomerMerge :one
+SELECT id, survivor_id, merged_id, merged_full_name, merged_email, merged_phone_number, ticket_ids, transaction_ids, merged_by, note, created_at FROM customer_merges
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetCustomerMerge(ctx context.Context, id int64) (CustomerMerge, error) {
+	row := q.queryRow(ctx, q.getCustomerMergeStmt, getCustomerMerge, id)
+	var i CustomerMerge
+	err := row.Scan(
+		&i.ID,
+		&i.SurvivorID,
+		&i.MergedID,
+		&i.MergedFullName,
+		&i.MergedEmail,
+		&i.MergedPhoneNumber,
+		&i.TicketIds,
+		&i.TransactionIds,
+		&i.MergedBy,
+		&i.Note,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCustomers = `-- name: GetCustomers :many
+SELECT id, full_name, email, phone_number, created_at, segment FROM customers
+WHERE ? IS NULL
+   OR full_name LIKE ?
+   OR email LIKE ?
+   OR phone_number LIKE ?
+ORDER BY id DESC
+LIMIT ? OFFSET ?
+`
+
+type GetCustomersParams struct {
+	Search sql.NullString `db:"search"`
+	Limit  int32          `db:"limit"`
+	Offset int32          `db:"offset"`
+}
+
+// search is a LIKE pattern matched against name, email and phone
+func (q *Queries) GetCustomers(ctx context.Context, arg GetCustomersParams) ([]Customer, error) {
+	rows, err := q.query(ctx, q.getCustomersStmt, getCustomers,
+		arg.Search,
+		arg.Search,
+		arg.Search,
+		arg.Search,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Customer{}
+	for rows.Next() {
+		var i Customer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FullName,
+			&i.Email,
+			&i.PhoneNumber,
+			&i.CreatedAt,
+			&i.Segment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT id, scope, idempotency_key, request_path, fingerprint, status_code, content_type, response_body, created_at, expires_at FROM idempotency_keys
+WHERE scope = ? AND idempotency_key = ? LIMIT 1
+`
+
+type GetIdempotencyKeyParams struct {
+	Scope          string `db:"scope"`
+	IdempotencyKey string `db:"idempotency_key"`
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.queryRow(ctx, q.getIdempotencyKeyStmt, getIdempotencyKey, arg.Scope, arg.IdempotencyKey)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.ID,
+		&i.Scope,
+		&i.IdempotencyKey,
+		&i.RequestPath,
+		&i.Fingerprint,
+		&i.StatusCode,
+		&i.ContentType,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getImportJob = `-- name: GetImportJob :one
+SELECT id, kind, status, dry_run, filename, rows_processed, report, error_message, created_by, created_at, started_at, finished_at, updated_at FROM import_jobs
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetImportJob(ctx context.Context, id int64) (ImportJob, error) {
+	row := q.queryRow(ctx, q.getImportJobStmt, getImportJob, id)
+	var i ImportJob
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Status,
+		&i.DryRun,
+		&i.Filename,
+		&i.RowsProcessed,
+		&i.Report,
+		&i.ErrorMessage,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getLastTeamAssignee = `-- name: GetLastTeamAssignee :one
+SELECT assigned_to FROM ticket_assignments
+WHERE team_id = ? AND assigned_to IS NOT NULL
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastTeamAssignee(ctx context.Context, teamID sql.NullInt64) (sql.NullInt32, error) {
+	row := q.queryRow(ctx, q.getLastTeamAssigneeStmt, getLastTeamAssignee, teamID)
+	var assigned_to sql.NullInt32
+	err := row.Scan(&assigned_to)
+	return assigned_to, err
+}
+
+const getLatestOTPByProfileID = `-- name: GetLatestOTPByProfileID :one
+SELECT id, profile_id, otp_code, purpose, expires_at, verified, attempts, created_at
+FROM otp_codes
+WHERE profile_id = ? AND purpose = ?
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetLatestOTPByProfileIDParams struct {
+	ProfileID int32  `db:"profile_id"`
+	Purpose   string `db:"purpose"`
+}
+
+func (q *Queries) GetLatestOTPByProfileID(ctx context.Context, arg GetLatestOTPByProfileIDParams) (OtpCode, error) {
+	row := q.queryRow(ctx, q.getLatestOTPByProfileIDStmt, getLatestOTPByProfileID, arg.ProfileID, arg.Purpose)
+	var i OtpCode
+	err := row.Scan(
+		&i.ID,
+		&i.ProfileID,
+		&i.OtpCode,
+		&i.Purpose,
+		&i.ExpiresAt,
+		&i.Verified,
+		&i.Attempts,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLedgerAccount = `-- name: GetLedgerAccount :one
+SELECT id, code, name, type, currency, profile_id, created_at FROM ledger_accounts
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetLedgerAccount(ctx context.Context, id int64) (LedgerAccount, error) {
+	row := q.queryRow(ctx, q.getLedgerAccountStmt, getLedgerAccount, id)
+	var i LedgerAccount
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Name,
+		&i.Type,
+		&i.Currency,
+		&i.ProfileID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLedgerAccountByCode = `-- name: GetLedgerAccountByCode :one
+SELECT id, code, name, type, currency, profile_id, created_at FROM ledger_accounts
+WHERE code = ? LIMIT 1
+`
+
+func (q *Queries) GetLedgerAccountByCode(ctx context.Context, code string) (LedgerAccount, error) {
+	row := q.queryRow(ctx, q.getLedgerAccountByCodeStmt, getLedgerAccountByCode, code)
+	var i LedgerAccount
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Name,
+		&i.Type,
+		&i.Currency,
+		&i.ProfileID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMpesaStkRequestByCheckoutID = `-- name: GetMpesaStkRequestByCheckoutID :one
+SELECT id, transaction_id, merchant_request_id, checkout_request_id, phone, result_code, result_desc, mpesa_receipt, mismatch, created_at, completed_at FROM mpesa_stk_requests
+WHERE checkout_request_id = ? LIMIT 1
+`
+
+func (q *Queries) GetMpesaStkRequestByCheckoutID(ctx context.Context, checkoutRequestID string) (MpesaStkRequest, error) {
+	row := q.queryRow(ctx, q.getMpesaStkRequestByCheckoutIDStmt, getMpesaStkRequestByCheckoutID, checkoutRequestID)
+	var i MpesaStkRequest
+	err := row.Scan(
+		&i.ID,
+		&i.TransactionID,
+		&i.MerchantRequestID,
+		&i.CheckoutRequestID,
+		&i.Phone,
+		&i.ResultCode,
+		&i.ResultDesc,
+		&i.MpesaReceipt,
+		&i.Mismatch,
+		&i.CreatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getProfileByEmail = `-- name: GetProfileByEmail :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE email = ?
+`
+
+func (q *Queries) GetProfileByEmail(ctx context.Context, email sql.NullString) (Profile, error) {
+	row := q.queryRow(ctx, q.getProfileByEmailStmt, getProfileByEmail, email)
+	var i Profile
+	err := row.Scan(
+		&i.ID,
+		&i.Phone,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Role,
+		&i.TokenVersion,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProfileByID = `-- name: GetProfileByID :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE id = ?
+`
+
+func (q *Queries) GetProfileByID(ctx context.Context, id int32) (Profile, error) {
+	row := q.queryRow(ctx, q.getProfileByIDStmt, getProfileByID, id)
+	var i Profile
+	err := row.Scan(
+		&i.ID,
+		&i.Phone,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Role,
+		&i.TokenVersion,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProfileByPhone = `-- name: GetProfileByPhone :one
+
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE phone = ?
+`
+
+// db/queries.sql
+func (q *Queries) GetProfileByPhone(ctx context.Context, phone sql.NullString) (Profile, error) {
+	row := q.queryRow(ctx, q.getProfileByPhoneStmt, getProfileByPhone, phone)
+	var i Profile
+	err := row.Scan(
+		&i.ID,
+		&i.Phone,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Role,
+		&i.TokenVersion,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProfileLockout = `-- name: GetProfileLockout :one
+SELECT profile_id, failed_attempts, last_failed_at, locked_until FROM profile_lockouts
+WHERE profile_id = ? LIMIT 1
+`
+
+func (q *Queries) GetProfileLockout(ctx context.Context, profileID int32) (ProfileLockout, error) {
+	row := q.queryRow(ctx, q.getProfileLockoutStmt, getProfileLockout, profileID)
+	var i ProfileLockout
+	err := row.Scan(
+		&i.ProfileID,
+		&i.FailedAttempts,
+		&i.LastFailedAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getReconciliationRun = `-- name: GetReconciliationRun :one
+SELECT id, format, filename, line_count, period_start, period_end, window_seconds, created_by, created_at FROM reconciliation_runs
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetReconciliationRun(ctx context.Context, id int64) (ReconciliationRun, error) {
+	row := q.queryRow(ctx, q.getReconciliationRunStmt, getReconciliationRun, id)
+	var i ReconciliationRun
+	err := row.Scan(
+		&i.ID,
+		&i.Format,
+		&i.Filename,
+		&i.LineCount,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.WindowSeconds,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRoutingRule = `-- name: GetRoutingRule :one
+SELECT id, name, priority, team_id, ticket_priority, ticket_type, customer_segment, keywords, active, created_at, updated_at FROM routing_rules
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetRoutingRule(ctx context.Context, id int64) (RoutingRule, error) {
+	row := q.queryRow(ctx, q.getRoutingRuleStmt, getRoutingRule, id)
+	var i RoutingRule
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Priority,
+		&i.TeamID,
+		&i.TicketPriority,
+		&i.TicketType,
+		&i.CustomerSegment,
+		&i.Keywords,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getStreamTicket = `-- name: GetStreamTicket :one
+SELECT ticket_hash, profile_id, token_version, expires_at FROM stream_tickets
+WHERE ticket_hash = ? LIMIT 1
+`
+
+func (q *Queries) GetStreamTicket(ctx context.Context, ticketHash string) (StreamTicket, error) {
+	row := q.queryRow(ctx, q.getStreamTicketStmt, getStreamTicket, ticketHash)
+	var i StreamTicket
+	err := row.Scan(
+		&i.TicketHash,
+		&i.ProfileID,
+		&i.TokenVersion,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getTOTPSecret = `-- name: GetTOTPSecret :one
+SELECT profile_id, secret_enc, confirmed_at, last_used_step, created_at FROM totp_secrets
+WHERE profile_id = ? LIMIT 1
+`
+
+func (q *Queries) GetTOTPSecret(ctx context.Context, profileID int32) (TotpSecret, error) {
+	row := q.queryRow(ctx, q.getTOTPSecretStmt, getTOTPSecret, profileID)
+	var i TotpSecret
+	err := row.Scan(
+		&i.ProfileID,
+		&i.SecretEnc,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTeam = `-- name: GetTeam :one
+SELECT id, name, description, created_at, assignment_strategy FROM teams
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetTeam(ctx context.Context, id int64) (Team, error) {
+	row := q.queryRow(ctx, q.getTeamStmt, getTeam, id)
+	var i Team
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.AssignmentStrategy,
+	)
+	return i, err
+}
+
+const getTicket = `-- name: GetTicket :one
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetTicket(ctx context.Context, id int64) (Ticket, error) {
+	row := q.queryRow(ctx, q.getTicketStmt, getTicket, id)
+	var i Ticket
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.CreatedBy,
+		&i.AssignedTo,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+		&i.CustomerID,
+		&i.TransactionID,
+		&i.TeamID,
+	)
+	return i, err
+}
+
+const getTicketByTitleAndUser = `-- name: GetTicketByTitleAndUser :one
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE title = ? AND created_by = ?
+LIMIT 1
+`
+
+type GetTicketByTitleAndUserParams struct {
+	Title     string `db:"title"`
+	CreatedBy int64  `db:"created_by"`
+}
+
+func (q *Queries) GetTicketByTitleAndUser(ctx context.Context, arg GetTicketByTitleAndUserParams) (Ticket, error) {
+	row := q.queryRow(ctx, q.getTicketByTitleAndUserStmt, getTicketByTitleAndUser, arg.Title, arg.CreatedBy)
+	var i Ticket
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.CreatedBy,
+		&i.AssignedTo,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+		&i.CustomerID,
+		&i.TransactionID,
+		&i.TeamID,
+	)
+	return i, err
+}
+
+const getTransactionForUpdate = `-- name: GetTransactionForUpdate :one
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_at FROM transactions
+WHERE id = ?
+FOR UPDATE
+`
+
+func (q *Queries) GetTransactionForUpdate(ctx context.Context, id int32) (Transaction, error) {
+	row := q.queryRow(ctx, q.getTransactionForUpdateStmt, getTransactionForUpdate, id)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.TransactionID,
+		&i.UserID,
+		&i.AmountMinor,
+		&i.Currency,
+		&i.Status,
+		&i.PaymentMethod,
+		&i.Kind,
+		&i.ParentID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTransanctionByID = `-- name: GetTransanctionByID :one
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_atFROM transactions
+WHERE id = ?
+LIMIT 1
+`
+
+func (q *Queries) GetTransanctionByID(ctx context.Context, id int32) (Transaction, error) {
+	row := q.queryRow(ctx, q.getTransanctionByIDStmt, getTransanctionByID, id)
 	var i Transaction
 	err := row.Scan(
 		&i.ID,
 		&i.TransactionID,
 		&i.UserID,
-		&i.Amount,
+		&i.AmountMinor,
 		&i.Currency,
 		&i.Status,
 		&i.PaymentMethod,
+		&i.Kind,
+		&i.ParentID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByEmailExcludingID = `-- name: GetUserByEmailExcludingID :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE email = ? AND id != ?
+LIMIT 1
+`
+
+type GetUserByEmailExcludingIDParams struct {
+	Email sql.NullString `db:"email"`
+	ID    int32          `db:"id"`
+}
+
+func (q *Queries) GetUserByEmailExcludingID(ctx context.Context, arg GetUserByEmailExcludingIDParams) (Profile, error) {
+	row := q.queryRow(ctx, q.getUserByEmailExcludingIDStmt, getUserByEmailExcludingID, arg.Email, arg.ID)
+	var i Profile
+	err := row.Scan(
+		&i.ID,
+		&i.Phone,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Role,
+		&i.TokenVersion,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByPhoneExcludingID = `-- name: GetUserByPhoneExcludingID :one
+SELECT id, phone, email, password_hash, full_name, role, token_version, created_at, updated_at
+FROM profiles
+WHERE phone = ? AND id != ?
+LIMIT 1
+`
+
+type GetUserByPhoneExcludingIDParams struct {
+	Phone sql.NullString `db:"phone"`
+	ID    int32          `db:"id"`
+}
+
+func (q *Queries) GetUserByPhoneExcludingID(ctx context.Context, arg GetUserByPhoneExcludingIDParams) (Profile, error) {
+	row := q.queryRow(ctx, q.getUserByPhoneExcludingIDStmt, getUserByPhoneExcludingID, arg.Phone, arg.ID)
+	var i Profile
+	err := row.Scan(
+		&i.ID,
+		&i.Phone,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Role,
+		&i.TokenVersion,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, redelivery_of, created_at FROM webhook_deliveries
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	row := q.queryRow(ctx, q.getWebhookDeliveryStmt, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastAttemptAt,
+		&i.ResponseStatus,
+		&i.LastError,
+		&i.RedeliveryOf,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, profile_id, url, secret, events, scope, active, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at FROM webhook_subscriptions
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	row := q.queryRow(ctx, q.getWebhookSubscriptionStmt, getWebhookSubscription, id)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.ProfileID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.Scope,
+		&i.Active,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.DisabledReason,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
-	return i, err
+	return i, err
+}
+
+const incrementOTPAttempts = `-- name: IncrementOTPAttempts :execrows
+UPDATE otp_codes
+SET attempts = attempts + 1
+WHERE id = ? AND verified = FALSE AND attempts < ?
+`
+
+type IncrementOTPAttemptsParams struct {
+	ID          int32 `db:"id"`
+	MaxAttempts int32 `db:"max_attempts"`
+}
+
+func (q *Queries) IncrementOTPAttempts(ctx context.Context, arg IncrementOTPAttemptsParams) (int64, error) {
+	result, err := q.exec(ctx, q.incrementOTPAttemptsStmt, incrementOTPAttempts, arg.ID, arg.MaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const incrementWebhookFailures = `-- name: IncrementWebhookFailures :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = consecutive_failures + 1
+WHERE id = ?
+`
+
+func (q *Queries) IncrementWebhookFailures(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.incrementWebhookFailuresStmt, incrementWebhookFailures, id)
+	return err
+}
+
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, name, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at
+FROM api_keys
+ORDER BY created_at DESC
+`
+
+type ListAPIKeysRow struct {
+	ID         int64        `db:"id"`
+	Name       string       `db:"name"`
+	Prefix     string       `db:"prefix"`
+	Scopes     string       `db:"scopes"`
+	CreatedBy  int32        `db:"created_by"`
+	ExpiresAt  sql.NullTime `db:"expires_at"`
+	LastUsedAt sql.NullTime `db:"last_used_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at"`
+	CreatedAt  time.Time    `db:"created_at"`
+}
+
+func (q *Queries) ListAPIKeys(ctx context.Context) ([]ListAPIKeysRow, error) {
+	rows, err := q.query(ctx, q.listAPIKeysStmt, listAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAPIKeysRow{}
+	for rows.Next() {
+		var i ListAPIKeysRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Prefix,
+			&i.Scopes,
+			&i.CreatedBy,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActiveRoutingRules = `-- name: ListActiveRoutingRules :many
+SELECT id, name, priority, team_id, ticket_priority, ticket_type, customer_segment, keywords, active, created_at, updated_at FROM routing_rules
+WHERE active = TRUE
+ORDER BY priority, id
+`
+
+func (q *Queries) ListActiveRoutingRules(ctx context.Context) ([]RoutingRule, error) {
+	rows, err := q.query(ctx, q.listActiveRoutingRulesStmt, listActiveRoutingRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RoutingRule{}
+	for rows.Next() {
+		var i RoutingRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Priority,
+			&i.TeamID,
+			&i.TicketPriority,
+			&i.TicketType,
+			&i.CustomerSegment,
+			&i.Keywords,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActiveWebhookSubscriptions = `-- name: ListActiveWebhookSubscriptions :many
+SELECT id, profile_id, url, secret, events, scope, active, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at FROM webhook_subscriptions
+WHERE active = TRUE
+`
+
+func (q *Queries) ListActiveWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := q.query(ctx, q.listActiveWebhookSubscriptionsStmt, listActiveWebhookSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookSubscription{}
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProfileID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.Scope,
+			&i.Active,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAgents = `-- name: ListAgents :many
+SELECT p.id, p.full_name, p.email, p.role, a.status, a.max_concurrent, a.skills,
+  (SELECT COUNT(*) FROM tickets t
+   WHERE t.assigned_to = p.id AND FIND_IN_SET(t.status, ?) = 0) AS open_tickets
+FROM profiles p
+LEFT JOIN agent_settings a ON a.profile_id = p.id
+WHERE p.role IN ('agent', 'admin')
+ORDER BY p.id
+`
+
+type ListAgentsRow struct {
+	ID            int32                   `db:"id"`
+	FullName      sql.NullString          `db:"full_name"`
+	Email         sql.NullString          `db:"email"`
+	Role          ProfilesRole            `db:"role"`
+	Status        NullAgentSettingsStatus `db:"status"`
+	MaxConcurrent sql.NullInt32           `db:"max_concurrent"`
+	Skills        sql.NullString          `db:"skills"`
+	OpenTickets   int64                   `db:"open_tickets"`
+}
+
+// agents and admins with their availability and open tickets: those whose
+// status is not in the comma separated closed_statuses
+func (q *Queries) ListAgents(ctx context.Context, closedStatuses string) ([]ListAgentsRow, error) {
+	rows, err := q.query(ctx, q.listAgentsStmt, listAgents, closedStatuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAgentsRow{}
+	for rows.Next() {
+		var i ListAgentsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FullName,
+			&i.Email,
+			&i.Role,
+			&i.Status,
+			&i.MaxConcurrent,
+			&i.Skills,
+			&i.OpenTickets,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomerDuplicates = `-- name: ListCustomerDuplicates :many
+SELECT d.id, d.reasons, d.name_similarity, d.found_at, a.id, a.full_name, a.email, a.phone_number, a.created_at, a.segment, b.id, b.full_name, b.email, b.phone_number, b.created_at, b.segment
+FROM customer_duplicates d
+JOIN customers a ON a.id = d.customer_id
+JOIN customers b ON b.id = d.duplicate_id
+WHERE d.id > ?
+  AND (d.reasons <> 'name' OR d.name_similarity >= ?)
+ORDER BY d.id
+LIMIT ?
+`
+
+type ListCustomerDuplicatesParams struct {
+	AfterID       int64   `db:"after_id"`
+	MinSimilarity float64 `db:"min_similarity"`
+	Limit         int32   `db:"limit"`
+}
+
+type ListCustomerDuplicatesRow struct {
+	ID             int64     `db:"id"`
+	Reasons        string    `db:"reasons"`
+	NameSimilarity float64   `db:"name_similarity"`
+	FoundAt        time.Time `db:"found_at"`
+	Customer       Customer  `db:"customer"`
+	Customer_2     Customer  `db:"customer_2"`
+}
+
+// pairs linked by phone or email, or by names at least min_similarity alike
+func (q *Queries) ListCustomerDuplicates(ctx context.Context, arg ListCustomerDuplicatesParams) ([]ListCustomerDuplicatesRow, error) {
+	rows, err := q.query(ctx, q.listCustomerDuplicatesStmt, listCustomerDuplicates, arg.AfterID, arg.MinSimilarity, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCustomerDuplicatesRow{}
+	for rows.Next() {
+		var i ListCustomerDuplicatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Reasons,
+			&i.NameSimilarity,
+			&i.FoundAt,
+			&i.Customer.ID,
+			&i.Customer.FullName,
+			&i.Customer.Email,
+			&i.Customer.PhoneNumber,
+			&i.Customer.CreatedAt,
+			&i.Customer.Segment,
+			&i.Customer_2.ID,
+			&i.Customer_2.FullName,
+			&i.Customer_2.Email,
+			&i.Customer_2.PhoneNumber,
+			&i.Customer_2.CreatedAt,
+			&i.Customer_2.Segment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomerMerges = `-- name: ListCustomerMerges :many
+SELECT id, survivor_id, merged_id, merged_full_name, merged_email, merged_phone_number, ticket_ids, transaction_ids, merged_by, note, created_at FROM customer_merges
+WHERE survivor_id = ? OR merged_id = ?
+ORDER BY id DESC
+`
+
+type ListCustomerMergesParams struct {
+	CustomerID int64 `db:"customer_id"`
+}
+
+// merges into the customer, or of it into another
+func (q *Queries) ListCustomerMerges(ctx context.Context, arg ListCustomerMergesParams) ([]CustomerMerge, error) {
+	rows, err := q.query(ctx, q.listCustomerMergesStmt, listCustomerMerges, arg.CustomerID, arg.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CustomerMerge{}
+	for rows.Next() {
+		var i CustomerMerge
+		if err := rows.Scan(
+			&i.ID,
+			&i.SurvivorID,
+			&i.MergedID,
+			&i.MergedFullName,
+			&i.MergedEmail,
+			&i.MergedPhoneNumber,
+			&i.TicketIds,
+			&i.TransactionIds,
+			&i.MergedBy,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomerMergesByContact = `-- name: ListCustomerMergesByContact :many
+SELECT id, survivor_id, merged_id, merged_full_name, merged_email, merged_phone_number, ticket_ids, transaction_ids, merged_by, note, created_at FROM customer_merges
+WHERE merged_email = ? OR merged_phone_number = ?
+ORDER BY id
+`
+
+type ListCustomerMergesByContactParams struct {
+	Email string `db:"email"`
+	Phone string `db:"phone"`
+}
+
+func (q *Queries) ListCustomerMergesByContact(ctx context.Context, arg ListCustomerMergesByContactParams) ([]CustomerMerge, error) {
+	rows, err := q.query(ctx, q.listCustomerMergesByContactStmt, listCustomerMergesByContact, arg.Email, arg.Phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CustomerMerge{}
+	for rows.Next() {
+		var i CustomerMerge
+		if err := rows.Scan(
+			&i.ID,
+			&i.SurvivorID,
+			&i.MergedID,
+			&i.MergedFullName,
+			&i.MergedEmail,
+			&i.MergedPhoneNumber,
+			&i.TicketIds,
+			&i.TransactionIds,
+			&i.MergedBy,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomerMergesByIDs = `-- name: ListCustomerMergesByIDs :many
+SELECT id, survivor_id, merged_id, merged_full_name, merged_email, merged_phone_number, ticket_ids, transaction_ids, merged_by, note, created_at FROM customer_merges
+WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) ListCustomerMergesByIDs(ctx context.Context, ids []int64) ([]CustomerMerge, error) {
+	query := listCustomerMergesByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CustomerMerge{}
+	for rows.Next() {
+		var i CustomerMerge
+		if err := rows.Scan(
+			&i.ID,
+			&i.SurvivorID,
+			&i.MergedID,
+			&i.MergedFullName,
+			&i.MergedEmail,
+			&i.MergedPhoneNumber,
+			&i.TicketIds,
+			&i.TransactionIds,
+			&i.MergedBy,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomerTicketLinks = `-- name: ListCustomerTicketLinks :many
+SELECT id, transaction_id FROM tickets
+WHERE customer_id = ?
+ORDER BY id
+FOR UPDATE
+`
+
+type ListCustomerTicketLinksRow struct {
+	ID            int64         `db:"id"`
+	TransactionID sql.NullInt32 `db:"transaction_id"`
+}
+
+func (q *Queries) ListCustomerTicketLinks(ctx context.Context, customerID sql.NullInt64) ([]ListCustomerTicketLinksRow, error) {
+	rows, err := q.query(ctx, q.listCustomerTicketLinksStmt, listCustomerTicketLinks, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCustomerTicketLinksRow{}
+	for rows.Next() {
+		var i ListCustomerTicketLinksRow
+		if err := rows.Scan(&i.ID, &i.TransactionID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomerTimeline = `-- name: ListCustomerTimeline :many
+SELECT item_type, item_id, occurred_at FROM (
+  SELECT 'customer' AS item_type, c.id AS item_id, c.created_at AS occurred_at
+  FROM customers c
+  WHERE c.id = ?
+  UNION ALL
+  SELECT 'merge', m.id, m.created_at
+  FROM customer_merges m
+  WHERE m.survivor_id = ?
+  UNION ALL
+  SELECT 'ticket', t.id, t.created_at
+  FROM tickets t
+  JOIN customers tc ON tc.id = t.customer_id
+  WHERE tc.id = ?
+  UNION ALL
+  SELECT 'transaction', x.id, x.created_at
+  FROM transactions x
+  WHERE x.id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = ?)
+  UNION ALL
+  SELECT 'status_change', e.id, e.created_at
+  FROM event_log e
+  WHERE (e.stream = 'ticket_events' AND e.type <> 'ticket.created'
+      AND e.subject_id IN (SELECT tt.id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = ?))
+     OR (e.stream = 'transaction_events' AND e.type <> 'transaction.created'
+      AND e.subject_id IN (SELECT tt.transaction_id FROM tickets tt JOIN customers tc ON tc.id = tt.customer_id WHERE tc.id = ?))
+) timeline
+WHERE ? IS NULL OR FIND_IN_SET(item_type, ?) > 0
+ORDER BY occurred_at DESC, item_type, item_id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListCustomerTimelineParams struct {
+	CustomerID int64          `db:"customer_id"`
+	ItemTypes  sql.NullString `db:"item_types"`
+	Limit      int32          `db:"limit"`
+	Offset     int32          `db:"offset"`
+}
+
+type ListCustomerTimelineRow struct {
+	ItemType   string       `db:"item_type"`
+	ItemID     int64        `db:"item_id"`
+	OccurredAt sql.NullTime `db:"occurred_at"`
+}
+
+// item_types is a comma separated list of the item types to keep
+func (q *Queries) ListCustomerTimeline(ctx context.Context, arg ListCustomerTimelineParams) ([]ListCustomerTimelineRow, error) {
+	rows, err := q.query(ctx, q.listCustomerTimelineStmt, listCustomerTimeline,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.CustomerID,
+		arg.ItemTypes,
+		arg.ItemTypes,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCustomerTimelineRow{}
+	for rows.Next() {
+		var i ListCustomerTimelineRow
+		if err := rows.Scan(&i.ItemType, &i.ItemID, &i.OccurredAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomersAfter = `-- name: ListCustomersAfter :many
+SELECT id, full_name, email, phone_number, created_at, segment FROM customers
+WHERE id > ?
+ORDER BY id
+LIMIT ?
+`
+
+type ListCustomersAfterParams struct {
+	ID    int64 `db:"id"`
+	Limit int32 `db:"limit"`
+}
+
+func (q *Queries) ListCustomersAfter(ctx context.Context, arg ListCustomersAfterParams) ([]Customer, error) {
+	rows, err := q.query(ctx, q.listCustomersAfterStmt, listCustomersAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Customer{}
+	for rows.Next() {
+		var i Customer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FullName,
+			&i.Email,
+			&i.PhoneNumber,
+			&i.CreatedAt,
+			&i.Segment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDataSubjectRequests = `-- name: ListDataSubjectRequests :many
+SELECT id, kind, subject_hash, profile_ids, customer_ids, summary, reason, requested_by, created_at FROM data_subject_requests
+WHERE ? IS NULL OR subject_hash = ?
+ORDER BY id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListDataSubjectRequestsParams struct {
+	SubjectHash sql.NullString `db:"subject_hash"`
+	Limit       int32          `db:"limit"`
+	Offset      int32          `db:"offset"`
+}
+
+func (q *Queries) ListDataSubjectRequests(ctx context.Context, arg ListDataSubjectRequestsParams) ([]DataSubjectRequest, error) {
+	rows, err := q.query(ctx, q.listDataSubjectRequestsStmt, listDataSubjectRequests,
+		arg.SubjectHash,
+		arg.SubjectHash,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DataSubjectRequest{}
+	for rows.Next() {
+		var i DataSubjectRequest
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.SubjectHash,
+			&i.ProfileIds,
+			&i.CustomerIds,
+			&i.Summary,
+			&i.Reason,
+			&i.RequestedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueWebhookDeliveries = `-- name: ListDueWebhookDeliveries :many
+SELECT d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, d.next_attempt_at, s.url, s.secret
+FROM webhook_deliveries d
+JOIN webhook_subscriptions s ON s.id = d.subscription_id
+WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND s.active = TRUE
+ORDER BY d.next_attempt_at
+LIMIT ?
+`
+
+type ListDueWebhookDeliveriesParams struct {
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	Limit         int32     `db:"limit"`
+}
+
+type ListDueWebhookDeliveriesRow struct {
+	ID             int64     `db:"id"`
+	SubscriptionID int64     `db:"subscription_id"`
+	EventID        string    `db:"event_id"`
+	EventType      string    `db:"event_type"`
+	Payload        string    `db:"payload"`
+	Attempts       int32     `db:"attempts"`
+	NextAttemptAt  time.Time `db:"next_attempt_at"`
+	Url            string    `db:"url"`
+	Secret         string    `db:"secret"`
+}
+
+// deliveries of disabled subscriptions wait until it is enabled again
+func (q *Queries) ListDueWebhookDeliveries(ctx context.Context, arg ListDueWebhookDeliveriesParams) ([]ListDueWebhookDeliveriesRow, error) {
+	rows, err := q.query(ctx, q.listDueWebhookDeliveriesStmt, listDueWebhookDeliveries, arg.NextAttemptAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDueWebhookDeliveriesRow{}
+	for rows.Next() {
+		var i ListDueWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.EventID,
+			&i.EventType,
+			&i.Payload,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesWithTooFewPostings = `-- name: ListEntriesWithTooFewPostings :many
+SELECT e.id, COUNT(p.id) AS postings
+FROM journal_entries e
+LEFT JOIN postings p ON p.entry_id = e.id
+GROUP BY e.id
+HAVING COUNT(p.id) < 2
+`
+
+type ListEntriesWithTooFewPostingsRow struct {
+	ID       int64 `db:"id"`
+	Postings int64 `db:"postings"`
+}
+
+func (q *Queries) ListEntriesWithTooFewPostings(ctx context.Context) ([]ListEntriesWithTooFewPostingsRow, error) {
+	rows, err := q.query(ctx, q.listEntriesWithTooFewPostingsStmt, listEntriesWithTooFewPostings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListEntriesWithTooFewPostingsRow{}
+	for rows.Next() {
+		var i ListEntriesWithTooFewPostingsRow
+		if err := rows.Scan(&i.ID, &i.Postings); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEventsAfter = `-- name: ListEventsAfter :many
+SELECT id, stream, type, owner_id, subject_id, payload, created_at FROM event_log
+WHERE id > ?
+  AND (? IS NULL OR owner_id = ?)
+ORDER BY id
+LIMIT ?
+`
+
+type ListEventsAfterParams struct {
+	AfterID int64         `db:"after_id"`
+	OwnerID sql.NullInt64 `db:"owner_id"`
+	Limit   int32         `db:"limit"`
+}
+
+func (q *Queries) ListEventsAfter(ctx context.Context, arg ListEventsAfterParams) ([]EventLog, error) {
+	rows, err := q.query(ctx, q.listEventsAfterStmt, listEventsAfter,
+		arg.AfterID,
+		arg.OwnerID,
+		arg.OwnerID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EventLog{}
+	for rows.Next() {
+		var i EventLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Stream,
+			&i.Type,
+			&i.OwnerID,
+			&i.SubjectID,
+			&i.Payload,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEventsByIDs = `-- name: ListEventsByIDs :many
+SELECT id, stream, type, owner_id, subject_id, payload, created_at FROM event_log
+WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) ListEventsByIDs(ctx context.Context, ids []int64) ([]EventLog, error) {
+	query := listEventsByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EventLog{}
+	for rows.Next() {
+		var i EventLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Stream,
+			&i.Type,
+			&i.OwnerID,
+			&i.SubjectID,
+			&i.Payload,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLedgerAccounts = `-- name: ListLedgerAccounts :many
+SELECT id, code, name, type, currency, profile_id, created_at FROM ledger_accounts
+WHERE ? IS NULL OR profile_id = ?
+ORDER BY code
+`
+
+type ListLedgerAccountsParams struct {
+	ProfileID sql.NullInt32 `db:"profile_id"`
+}
+
+func (q *Queries) ListLedgerAccounts(ctx context.Context, arg ListLedgerAccountsParams) ([]LedgerAccount, error) {
+	rows, err := q.query(ctx, q.listLedgerAccountsStmt, listLedgerAccounts, arg.ProfileID, arg.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LedgerAccount{}
+	for rows.Next() {
+		var i LedgerAccount
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.Name,
+			&i.Type,
+			&i.Currency,
+			&i.ProfileID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLoginEventsForSubject = `-- name: ListLoginEventsForSubject :many
+SELECT id, profile_id, identifier, ip, user_agent, success, reason, created_at FROM login_events
+WHERE profile_id = ? OR identifier = ?
+ORDER BY id
+`
+
+type ListLoginEventsForSubjectParams struct {
+	ProfileID  sql.NullInt32 `db:"profile_id"`
+	Identifier string        `db:"identifier"`
+}
+
+func (q *Queries) ListLoginEventsForSubject(ctx context.Context, arg ListLoginEventsForSubjectParams) ([]LoginEvent, error) {
+	rows, err := q.query(ctx, q.listLoginEventsForSubjectStmt, listLoginEventsForSubject, arg.ProfileID, arg.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginEvent{}
+	for rows.Next() {
+		var i LoginEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProfileID,
+			&i.Identifier,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Success,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMpesaStkRequestsByUser = `-- name: ListMpesaStkRequestsByUser :many
+SELECT m.id, m.transaction_id, m.merchant_request_id, m.checkout_request_id, m.phone, m.result_code, m.result_desc, m.mpesa_receipt, m.mismatch, m.created_at, m.completed_at FROM mpesa_stk_requests m
+JOIN transactions t ON t.id = m.transaction_id
+WHERE t.user_id = ?
+ORDER BY m.id
+`
+
+func (q *Queries) ListMpesaStkRequestsByUser(ctx context.Context, userID int32) ([]MpesaStkRequest, error) {
+	rows, err := q.query(ctx, q.listMpesaStkRequestsByUserStmt, listMpesaStkRequestsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MpesaStkRequest{}
+	for rows.Next() {
+		var i MpesaStkRequest
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.MerchantRequestID,
+			&i.CheckoutRequestID,
+			&i.Phone,
+			&i.ResultCode,
+			&i.ResultDesc,
+			&i.MpesaReceipt,
+			&i.Mismatch,
+			&i.CreatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOTPsByProfile = `-- name: ListOTPsByProfile :many
+SELECT id, profile_id, otp_code, purpose, expires_at, verified, attempts, created_at FROM otp_codes
+WHERE profile_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListOTPsByProfile(ctx context.Context, profileID int32) ([]OtpCode, error) {
+	rows, err := q.query(ctx, q.listOTPsByProfileStmt, listOTPsByProfile, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OtpCode{}
+	for rows.Next() {
+		var i OtpCode
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProfileID,
+			&i.OtpCode,
+			&i.Purpose,
+			&i.ExpiresAt,
+			&i.Verified,
+			&i.Attempts,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnTickets = `-- name: ListOwnTickets :many
+SELECT t.id, t.title, t.description, t.status, t.priority, t.created_by, t.assigned_to, t.created_at, t.updated_at, t.type, t.customer_id, t.transaction_id, t.team_id FROM tickets t
+JOIN profiles p ON p.id = ?
+LEFT JOIN customers cu ON cu.id = t.customer_id
+WHERE t.created_by = p.id
+   OR LOWER(cu.email) = LOWER(p.email)
+   OR cu.phone_number = p.phone
+ORDER BY t.created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type ListOwnTicketsParams struct {
+	ProfileID int32 `db:"profile_id"`
+	Limit     int32 `db:"limit"`
+	Offset    int32 `db:"offset"`
+}
+
+// what GetTicket shows a customer: tickets they raised, or about a customer
+// with their email or phone
+func (q *Queries) ListOwnTickets(ctx context.Context, arg ListOwnTicketsParams) ([]Ticket, error) {
+	rows, err := q.query(ctx, q.listOwnTicketsStmt, listOwnTickets, arg.ProfileID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostingCurrencyMismatches = `-- name: ListPostingCurrencyMismatches :many
+SELECT p.id, p.entry_id, p.currency, a.currency AS account_currency
+FROM postings p
+JOIN ledger_accounts a ON a.id = p.account_id
+WHERE p.currency <> a.currency
+`
+
+type ListPostingCurrencyMismatchesRow struct {
+	ID              int64  `db:"id"`
+	EntryID         int64  `db:"entry_id"`
+	Currency        string `db:"currency"`
+	AccountCurrency string `db:"account_currency"`
+}
+
+func (q *Queries) ListPostingCurrencyMismatches(ctx context.Context) ([]ListPostingCurrencyMismatchesRow, error) {
+	rows, err := q.query(ctx, q.listPostingCurrencyMismatchesStmt, listPostingCurrencyMismatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostingCurrencyMismatchesRow{}
+	for rows.Next() {
+		var i ListPostingCurrencyMismatchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.Currency,
+			&i.AccountCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProfilePhones = `-- name: ListProfilePhones :many
+SELECT id, phone FROM profiles
+WHERE phone IS NOT NULL
+ORDER BY id
+`
+
+type ListProfilePhonesRow struct {
+	ID    int32          `db:"id"`
+	Phone sql.NullString `db:"phone"`
+}
+
+func (q *Queries) ListProfilePhones(ctx context.Context) ([]ListProfilePhonesRow, error) {
+	rows, err := q.query(ctx, q.listProfilePhonesStmt, listProfilePhones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProfilePhonesRow{}
+	for rows.Next() {
+		var i ListProfilePhonesRow
+		if err := rows.Scan(&i.ID, &i.Phone); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReconciliationItems = `-- name: ListReconciliationItems :many
+SELECT id, run_id, status, line_number, reference, amount_minor, currency, occurred_at, transaction_id, note FROM reconciliation_items
+WHERE run_id = ? AND (? IS NULL OR status = ?)
+ORDER BY id
+`
+
+type ListReconciliationItemsParams struct {
+	RunID  int64                         `db:"run_id"`
+	Status NullReconciliationItemsStatus `db:"status"`
+}
+
+func (q *Queries) ListReconciliationItems(ctx context.Context, arg ListReconciliationItemsParams) ([]ReconciliationItem, error) {
+	rows, err := q.query(ctx, q.listReconciliationItemsStmt, listReconciliationItems, arg.RunID, arg.Status, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconciliationItem{}
+	for rows.Next() {
+		var i ReconciliationItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.RunID,
+			&i.Status,
+			&i.LineNumber,
+			&i.Reference,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.OccurredAt,
+			&i.TransactionID,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReconciliationRuns = `-- name: ListReconciliationRuns :many
+SELECT id, format, filename, line_count, period_start, period_end, window_seconds, created_by, created_at FROM reconciliation_runs
+ORDER BY id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListReconciliationRunsParams struct {
+	Limit  int32 `db:"limit"`
+	Offset int32 `db:"offset"`
+}
+
+func (q *Queries) ListReconciliationRuns(ctx context.Context, arg ListReconciliationRunsParams) ([]ReconciliationRun, error) {
+	rows, err := q.query(ctx, q.listReconciliationRunsStmt, listReconciliationRuns, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconciliationRun{}
+	for rows.Next() {
+		var i ReconciliationRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.Format,
+			&i.Filename,
+			&i.LineCount,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.WindowSeconds,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRefunds = `-- name: ListRefunds :many
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_at FROM transactions
+WHERE parent_id = ? AND kind = 'refund'
+ORDER BY id
+`
+
+func (q *Queries) ListRefunds(ctx context.Context, parentID sql.NullInt32) ([]Transaction, error) {
+	rows, err := q.query(ctx, q.listRefundsStmt, listRefunds, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.Status,
+			&i.PaymentMethod,
+			&i.Kind,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRoutingRules = `-- name: ListRoutingRules :many
+SELECT id, name, priority, team_id, ticket_priority, ticket_type, customer_segment, keywords, active, created_at, updated_at FROM routing_rules
+ORDER BY priority, id
+`
+
+func (q *Queries) ListRoutingRules(ctx context.Context) ([]RoutingRule, error) {
+	rows, err := q.query(ctx, q.listRoutingRulesStmt, listRoutingRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RoutingRule{}
+	for rows.Next() {
+		var i RoutingRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Priority,
+			&i.TeamID,
+			&i.TicketPriority,
+			&i.TicketType,
+			&i.CustomerSegment,
+			&i.Keywords,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamCandidates = `-- name: ListTeamCandidates :many
+SELECT tm.profile_id, a.status, a.max_concurrent, a.skills,
+  (SELECT COUNT(*) FROM tickets t
+   WHERE t.assigned_to = tm.profile_id AND FIND_IN_SET(t.status, ?) = 0) AS open_tickets
+FROM team_members tm
+JOIN profiles p ON p.id = tm.profile_id
+LEFT JOIN agent_settings a ON a.profile_id = tm.profile_id
+WHERE tm.team_id = ? AND p.role IN ('agent', 'admin')
+ORDER BY tm.profile_id
+`
+
+type ListTeamCandidatesParams struct {
+	ClosedStatuses string `db:"closed_statuses"`
+	TeamID         int64  `db:"team_id"`
+}
+
+type ListTeamCandidatesRow struct {
+	ProfileID     int32                   `db:"profile_id"`
+	Status        NullAgentSettingsStatus `db:"status"`
+	MaxConcurrent sql.NullInt32           `db:"max_concurrent"`
+	Skills        sql.NullString          `db:"skills"`
+	OpenTickets   int64                   `db:"open_tickets"`
+}
+
+// the team's agents and admins with their availability and open tickets,
+// as ListAgents
+func (q *Queries) ListTeamCandidates(ctx context.Context, arg ListTeamCandidatesParams) ([]ListTeamCandidatesRow, error) {
+	rows, err := q.query(ctx, q.listTeamCandidatesStmt, listTeamCandidates, arg.ClosedStatuses, arg.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTeamCandidatesRow{}
+	for rows.Next() {
+		var i ListTeamCandidatesRow
+		if err := rows.Scan(
+			&i.ProfileID,
+			&i.Status,
+			&i.MaxConcurrent,
+			&i.Skills,
+			&i.OpenTickets,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamMembers = `-- name: ListTeamMembers :many
+SELECT tm.team_id, tm.profile_id, p.full_name, p.email, p.phone, p.role, tm.created_at
+FROM team_members tm
+JOIN profiles p ON p.id = tm.profile_id
+WHERE tm.team_id = ?
+ORDER BY tm.profile_id
+`
+
+type ListTeamMembersRow struct {
+	TeamID    int64          `db:"team_id"`
+	ProfileID int32          `db:"profile_id"`
+	FullName  sql.NullString `db:"full_name"`
+	Email     sql.NullString `db:"email"`
+	Phone     sql.NullString `db:"phone"`
+	Role      ProfilesRole   `db:"role"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (q *Queries) ListTeamMembers(ctx context.Context, teamID int64) ([]ListTeamMembersRow, error) {
+	rows, err := q.query(ctx, q.listTeamMembersStmt, listTeamMembers, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTeamMembersRow{}
+	for rows.Next() {
+		var i ListTeamMembersRow
+		if err := rows.Scan(
+			&i.TeamID,
+			&i.ProfileID,
+			&i.FullName,
+			&i.Email,
+			&i.Phone,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeams = `-- name: ListTeams :many
+SELECT id, name, description, created_at, assignment_strategy FROM teams
+ORDER BY name
+`
+
+func (q *Queries) ListTeams(ctx context.Context) ([]Team, error) {
+	rows, err := q.query(ctx, q.listTeamsStmt, listTeams)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Team{}
+	for rows.Next() {
+		var i Team
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.AssignmentStrategy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketAssignments = `-- name: ListTicketAssignments :many
+SELECT id, ticket_id, team_id, strategy, assigned_to, reason, candidates, created_at FROM ticket_assignments
+WHERE ticket_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListTicketAssignments(ctx context.Context, ticketID int64) ([]TicketAssignment, error) {
+	rows, err := q.query(ctx, q.listTicketAssignmentsStmt, listTicketAssignments, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TicketAssignment{}
+	for rows.Next() {
+		var i TicketAssignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.TicketID,
+			&i.TeamID,
+			&i.Strategy,
+			&i.AssignedTo,
+			&i.Reason,
+			&i.Candidates,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTickets = `-- name: ListTickets :many
+SELECT
+    id,
+    title,
+    description,
+    status,
+    priority,
+    created_by,
+    assigned_to,
+    created_at,
+    updated_at,
+    type,
+    customer_id,
+    transaction_id,
+    team_id
+FROM tickets
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type ListTicketsParams struct {
+	Limit  int32 `db:"limit"`
+	Offset int32 `db:"offset"`
+}
+
+func (q *Queries) ListTickets(ctx context.Context, arg ListTicketsParams) ([]Ticket, error) {
+	rows, err := q.query(ctx, q.listTicketsStmt, listTickets, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketsByCreator = `-- name: ListTicketsByCreator :many
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE created_by = ?
+ORDER BY id
+`
+
+func (q *Queries) ListTicketsByCreator(ctx context.Context, createdBy int64) ([]Ticket, error) {
+	rows, err := q.query(ctx, q.listTicketsByCreatorStmt, listTicketsByCreator, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketsByCustomer = `-- name: ListTicketsByCustomer :many
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE customer_id = ?
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type ListTicketsByCustomerParams struct {
+	CustomerID sql.NullInt64 `db:"customer_id"`
+	Limit      int32         `db:"limit"`
+	Offset     int32         `db:"offset"`
+}
+
+func (q *Queries) ListTicketsByCustomer(ctx context.Context, arg ListTicketsByCustomerParams) ([]Ticket, error) {
+	rows, err := q.query(ctx, q.listTicketsByCustomerStmt, listTicketsByCustomer, arg.CustomerID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketsByIDs = `-- name: ListTicketsByIDs :many
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) ListTicketsByIDs(ctx context.Context, ids []int64) ([]Ticket, error) {
+	query := listTicketsByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketsByTeam = `-- name: ListTicketsByTeam :many
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE team_id = ?
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type ListTicketsByTeamParams struct {
+	TeamID sql.NullInt64 `db:"team_id"`
+	Limit  int32         `db:"limit"`
+	Offset int32         `db:"offset"`
+}
+
+func (q *Queries) ListTicketsByTeam(ctx context.Context, arg ListTicketsByTeamParams) ([]Ticket, error) {
+	rows, err := q.query(ctx, q.listTicketsByTeamStmt, listTicketsByTeam, arg.TeamID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketsByTransaction = `-- name: ListTicketsByTransaction :many
+SELECT id, title, description, status, priority, created_by, assigned_to, created_at, updated_at, type, customer_id, transaction_id, team_id FROM tickets
+WHERE transaction_id = ?
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type ListTicketsByTransactionParams struct {
+	TransactionID sql.NullInt32 `db:"transaction_id"`
+	Limit         int32         `db:"limit"`
+	Offset        int32         `db:"offset"`
+}
+
+func (q *Queries) ListTicketsByTransaction(ctx context.Context, arg ListTicketsByTransactionParams) ([]Ticket, error) {
+	rows, err := q.query(ctx, q.listTicketsByTransactionStmt, listTicketsByTransaction, arg.TransactionID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.CreatedBy,
+			&i.AssignedTo,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.CustomerID,
+			&i.TransactionID,
+			&i.TeamID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactions = `-- name: ListTransactions :many
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_at FROM transactions
+WHERE (? IS NULL OR user_id = ?)
+  AND (? IS NULL OR status = ?)
+  AND (? IS NULL OR currency = ?)
+  AND (? IS NULL OR payment_method = ?)
+  AND (? IS NULL OR kind = ?)
+  AND (? IS NULL OR amount_minor >= ?)
+  AND (? IS NULL OR amount_minor <= ?)
+  AND (? IS NULL OR created_at >= ?)
+  AND (? IS NULL OR created_at < ?)
+ORDER BY created_at DESC, id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListTransactionsParams struct {
+	UserID        sql.NullInt32        `db:"user_id"`
+	Status        sql.NullInt16        `db:"status"`
+	Currency      sql.NullString       `db:"currency"`
+	PaymentMethod sql.NullString       `db:"payment_method"`
+	Kind          NullTransactionsKind `db:"kind"`
+	MinAmount     sql.NullInt64        `db:"min_amount"`
+	MaxAmount     sql.NullInt64        `db:"max_amount"`
+	CreatedFrom   sql.NullTime         `db:"created_from"`
+	CreatedTo     sql.NullTime         `db:"created_to"`
+	Limit         int32                `db:"limit"`
+	Offset        int32                `db:"offset"`
+}
+
+// every filter is optional; amounts are in minor units of the currency
+// filter and created_to is exclusive
+func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsParams) ([]Transaction, error) {
+	rows, err := q.query(ctx, q.listTransactionsStmt, listTransactions,
+		arg.UserID,
+		arg.UserID,
+		arg.Status,
+		arg.Status,
+		arg.Currency,
+		arg.Currency,
+		arg.PaymentMethod,
+		arg.PaymentMethod,
+		arg.Kind,
+		arg.Kind,
+		arg.MinAmount,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.MaxAmount,
+		arg.CreatedFrom,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.CreatedTo,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.Status,
+			&i.PaymentMethod,
+			&i.Kind,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByIDs = `-- name: ListTransactionsByIDs :many
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_at FROM transactions
+WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) ListTransactionsByIDs(ctx context.Context, ids []int32) ([]Transaction, error) {
+	query := listTransactionsByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.Status,
+			&i.PaymentMethod,
+			&i.Kind,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByUser = `-- name: ListTransactionsByUser :many
+SELECT id, transaction_id, user_id, amount_minor, currency, status, payment_method, kind, parent_id, created_at, updated_at FROM transactions
+WHERE user_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListTransactionsByUser(ctx context.Context, userID int32) ([]Transaction, error) {
+	rows, err := q.query(ctx, q.listTransactionsByUserStmt, listTransactionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.Status,
+			&i.PaymentMethod,
+			&i.Kind,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsForReconciliation = `-- name: ListTransactionsForReconciliation :many
+SELECT t.id, t.transaction_id, t.amount_minor, t.currency, t.status, t.kind, t.created_at, m.mpesa_receipt
+FROM transactions t
+LEFT JOIN mpesa_stk_requests m ON m.transaction_id = t.id
+WHERE t.created_at >= ? AND t.created_at <= ?
+`
+
+type ListTransactionsForReconciliationParams struct {
+	FromTime sql.NullTime `db:"from_time"`
+	ToTime   sql.NullTime `db:"to_time"`
+}
+
+type ListTransactionsForReconciliationRow struct {
+	ID            int32            `db:"id"`
+	TransactionID string           `db:"transaction_id"`
+	AmountMinor   int64            `db:"amount_minor"`
+	Currency      string           `db:"currency"`
+	Status        int16            `db:"status"`
+	Kind          TransactionsKind `db:"kind"`
+	CreatedAt     sql.NullTime     `db:"created_at"`
+	MpesaReceipt  sql.NullString   `db:"mpesa_receipt"`
+}
+
+func (q *Queries) ListTransactionsForReconciliation(ctx context.Context, arg ListTransactionsForReconciliationParams) ([]ListTransactionsForReconciliationRow, error) {
+	rows, err := q.query(ctx, q.listTransactionsForReconciliationStmt, listTransactionsForReconciliation, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransactionsForReconciliationRow{}
+	for rows.Next() {
+		var i ListTransactionsForReconciliationRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.AmountMinor,
+			&i.Currency,
+			&i.Status,
+			&i.Kind,
+			&i.CreatedAt,
+			&i.MpesaReceipt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedEntries = `-- name: ListUnbalancedEntries :many
+SELECT p.entry_id, p.currency, CAST(SUM(p.amount_minor) AS SIGNED) AS total
+FROM postings p
+GROUP BY p.entry_id, p.currency
+HAVING SUM(p.amount_minor) <> 0
+`
+
+type ListUnbalancedEntriesRow struct {
+	EntryID  int64  `db:"entry_id"`
+	Currency string `db:"currency"`
+	Total    int64  `db:"total"`
+}
+
+func (q *Queries) ListUnbalancedEntries(ctx context.Context) ([]ListUnbalancedEntriesRow, error) {
+	rows, err := q.query(ctx, q.listUnbalancedEntriesStmt, listUnbalancedEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnbalancedEntriesRow{}
+	for rows.Next() {
+		var i ListUnbalancedEntriesRow
+		if err := rows.Scan(&i.EntryID, &i.Currency, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpostedTransactions = `-- name: ListUnpostedTransactions :many
+SELECT t.id, t.transaction_id, t.kind, t.status
+FROM transactions t
+LEFT JOIN journal_entries e ON e.transaction_id = t.id
+WHERE e.id IS NULL
+  AND (t.kind = 'refund' OR t.status IN (2, 4, 5, 6))
+`
+
+type ListUnpostedTransactionsRow struct {
+	ID            int32            `db:"id"`
+	TransactionID string           `db:"transaction_id"`
+	Kind          TransactionsKind `db:"kind"`
+	Status        int16            `db:"status"`
+}
+
+// settled transactions that have no journal entry
+func (q *Queries) ListUnpostedTransactions(ctx context.Context) ([]ListUnpostedTransactionsRow, error) {
+	rows, err := q.query(ctx, q.listUnpostedTransactionsStmt, listUnpostedTransactions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnpostedTransactionsRow{}
+	for rows.Next() {
+		var i ListUnpostedTransactionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.Kind,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT
+    id,
+    phone,
+    email,
+    full_name,
+    role,
+    created_at
+FROM profiles
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type ListUsersParams struct {
+	Limit  int32 `db:"limit"`
+	Offset int32 `db:"offset"`
+}
+
+type ListUsersRow struct {
+	ID        int32          `db:"id"`
+	Phone     sql.NullString `db:"phone"`
+	Email     sql.NullString `db:"email"`
+	FullName  sql.NullString `db:"full_name"`
+	Role      ProfilesRole   `db:"role"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.query(ctx, q.listUsersStmt, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsersRow{}
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Phone,
+			&i.Email,
+			&i.FullName,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, redelivery_of, created_at FROM webhook_deliveries
+WHERE subscription_id = ?
+  AND (? IS NULL OR status = ?)
+ORDER BY id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListWebhookDeliveriesParams struct {
+	SubscriptionID int64                       `db:"subscription_id"`
+	Status         NullWebhookDeliveriesStatus `db:"status"`
+	Limit          int32                       `db:"limit"`
+	Offset         int32                       `db:"offset"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.query(ctx, q.listWebhookDeliveriesStmt, listWebhookDeliveries,
+		arg.SubscriptionID,
+		arg.Status,
+		arg.Status,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.EventID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastAttemptAt,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.RedeliveryOf,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, full_name, email, password, role, created_at FROM users
-WHERE email = ? LIMIT 1
+const listWebhookSubscriptions = `-- name: ListWebhookSubscriptions :many
+SELECT id, profile_id, url, secret, events, scope, active, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at FROM webhook_subscriptions
+WHERE (? IS NULL OR profile_id = ?)
+ORDER BY id
+`
+
+type ListWebhookSubscriptionsParams struct {
+	ProfileID sql.NullInt32 `db:"profile_id"`
+}
+
+func (q *Queries) ListWebhookSubscriptions(ctx context.Context, arg ListWebhookSubscriptionsParams) ([]WebhookSubscription, error) {
+	rows, err := q.query(ctx, q.listWebhookSubscriptionsStmt, listWebhookSubscriptions, arg.ProfileID, arg.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookSubscription{}
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProfileID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.Scope,
+			&i.Active,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockCustomer = `-- name: LockCustomer :one
+SELECT id, full_name, email, phone_number, created_at, segment FROM customers
+WHERE id = ? LIMIT 1
+FOR UPDATE
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.queryRow(ctx, q.getUserByEmailStmt, getUserByEmail, email)
-	var i User
+func (q *Queries) LockCustomer(ctx context.Context, id int64) (Customer, error) {
+	row := q.queryRow(ctx, q.lockCustomerStmt, lockCustomer, id)
+	var i Customer
 	err := row.Scan(
 		&i.ID,
 		&i.FullName,
 		&i.Email,
-		&i.Password,
-		&i.Role,
+		&i.PhoneNumber,
 		&i.CreatedAt,
+		&i.Segment,
 	)
 	return i, err
 }
 
-const getUserByEmailExcludingID = `-- name: GetUserByEmailExcludingID :one
-SELECT id, full_name, email, password, role, created_at FROM users
-WHERE email = ? AND id != ?
-LIMIT 1
+const lockProfile = `-- name: LockProfile :exec
+UPDATE profile_lockouts
+SET locked_until = ?
+WHERE profile_id = ?
 `
 
-type GetUserByEmailExcludingIDParams struct {
-	Email string `db:"email"`
-	ID    int64  `db:"id"`
+type LockProfileParams struct {
+	LockedUntil sql.NullTime `db:"locked_until"`
+	ProfileID   int32        `db:"profile_id"`
 }
 
-func (q *Queries) GetUserByEmailExcludingID(ctx context.Context, arg GetUserByEmailExcludingIDParams) (User, error) {
-	row := q.queryRow(ctx, q.getUserByEmailExcludingIDStmt, getUserByEmailExcludingID, arg.Email, arg.ID)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.FullName,
-		&i.Email,
-		&i.Password,
-		&i.Role,
-		&i.CreatedAt,
+func (q *Queries) LockProfile(ctx context.Context, arg LockProfileParams) error {
+	_, err := q.exec(ctx, q.lockProfileStmt, lockProfile, arg.LockedUntil, arg.ProfileID)
+	return err
+}
+
+const markOTPVerified = `-- name: MarkOTPVerified :execrows
+UPDATE otp_codes
+SET verified = TRUE
+WHERE id = ? AND verified = FALSE
+`
+
+func (q *Queries) MarkOTPVerified(ctx context.Context, id int32) (int64, error) {
+	result, err := q.exec(ctx, q.markOTPVerifiedStmt, markOTPVerified, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveCustomerTickets = `-- name: MoveCustomerTickets :execrows
+UPDATE tickets
+SET customer_id = ?
+WHERE customer_id = ?
+`
+
+type MoveCustomerTicketsParams struct {
+	ToID   sql.NullInt64 `db:"to_id"`
+	FromID sql.NullInt64 `db:"from_id"`
+}
+
+func (q *Queries) MoveCustomerTickets(ctx context.Context, arg MoveCustomerTicketsParams) (int64, error) {
+	result, err := q.exec(ctx, q.moveCustomerTicketsStmt, moveCustomerTickets, arg.ToID, arg.FromID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :exec
+INSERT INTO profile_lockouts (profile_id, failed_attempts, last_failed_at)
+VALUES (?, 1, ?)
+ON DUPLICATE KEY UPDATE
+  failed_attempts = failed_attempts + 1,
+  last_failed_at = VALUES(last_failed_at)
+`
+
+type RecordLoginFailureParams struct {
+	ProfileID    int32     `db:"profile_id"`
+	LastFailedAt time.Time `db:"last_failed_at"`
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) error {
+	_, err := q.exec(ctx, q.recordLoginFailureStmt, recordLoginFailure, arg.ProfileID, arg.LastFailedAt)
+	return err
+}
+
+const recordWebhookAttempt = `-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_attempt_at = ?, response_status = ?, last_error = ?
+WHERE id = ?
+`
+
+type RecordWebhookAttemptParams struct {
+	Status         WebhookDeliveriesStatus `db:"status"`
+	NextAttemptAt  time.Time               `db:"next_attempt_at"`
+	LastAttemptAt  sql.NullTime            `db:"last_attempt_at"`
+	ResponseStatus sql.NullInt32           `db:"response_status"`
+	LastError      string                  `db:"last_error"`
+	ID             int64                   `db:"id"`
+}
+
+func (q *Queries) RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error {
+	_, err := q.exec(ctx, q.recordWebhookAttemptStmt, recordWebhookAttempt,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.LastAttemptAt,
+		arg.ResponseStatus,
+		arg.LastError,
+		arg.ID,
 	)
-	return i, err
+	return err
 }
 
-const getUserByID = `-- name: GetUserByID :one
-SELECT id, full_name, email, password, role, created_at FROM users
-WHERE id = ? LIMIT 1
+const removeTeamMember = `-- name: RemoveTeamMember :execrows
+DELETE FROM team_members
+WHERE team_id = ? AND profile_id = ?
+`
+
+type RemoveTeamMemberParams struct {
+	TeamID    int64 `db:"team_id"`
+	ProfileID int32 `db:"profile_id"`
+}
+
+func (q *Queries) RemoveTeamMember(ctx context.Context, arg RemoveTeamMemberParams) (int64, error) {
+	result, err := q.exec(ctx, q.removeTeamMemberStmt, removeTeamMember, arg.TeamID, arg.ProfileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renewStreamTicket = `-- name: RenewStreamTicket :exec
+INSERT INTO stream_tickets (ticket_hash, profile_id, token_version, expires_at)
+VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)
+`
+
+type RenewStreamTicketParams struct {
+	TicketHash   string    `db:"ticket_hash"`
+	ProfileID    int32     `db:"profile_id"`
+	TokenVersion int32     `db:"token_version"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// recreates the ticket if it expired and was cleaned up while its
+// connection was open
+func (q *Queries) RenewStreamTicket(ctx context.Context, arg RenewStreamTicketParams) error {
+	_, err := q.exec(ctx, q.renewStreamTicketStmt, renewStreamTicket,
+		arg.TicketHash,
+		arg.ProfileID,
+		arg.TokenVersion,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const resetWebhookFailures = `-- name: ResetWebhookFailures :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = 0
+WHERE id = ?
 `
 
-func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
-	row := q.queryRow(ctx, q.getUserByIDStmt, getUserByID, id)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.FullName,
-		&i.Email,
-		&i.Password,
-		&i.Role,
-		&i.CreatedAt,
+func (q *Queries) ResetWebhookFailures(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.resetWebhookFailuresStmt, resetWebhookFailures, id)
+	return err
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = ?
+WHERE id = ? AND revoked_at IS NULL
+`
+
+type RevokeAPIKeyParams struct {
+	RevokedAt sql.NullTime `db:"revoked_at"`
+	ID        int64        `db:"id"`
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error) {
+	result, err := q.exec(ctx, q.revokeAPIKeyStmt, revokeAPIKey, arg.RevokedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const saveIdempotentResponse = `-- name: SaveIdempotentResponse :exec
+UPDATE idempotency_keys
+SET status_code = ?, content_type = ?, response_body = ?
+WHERE id = ?
+`
+
+type SaveIdempotentResponseParams struct {
+	StatusCode   sql.NullInt32  `db:"status_code"`
+	ContentType  sql.NullString `db:"content_type"`
+	ResponseBody sql.NullString `db:"response_body"`
+	ID           int64          `db:"id"`
+}
+
+func (q *Queries) SaveIdempotentResponse(ctx context.Context, arg SaveIdempotentResponseParams) error {
+	_, err := q.exec(ctx, q.saveIdempotentResponseStmt, saveIdempotentResponse,
+		arg.StatusCode,
+		arg.ContentType,
+		arg.ResponseBody,
+		arg.ID,
 	)
-	return i, err
+	return err
 }
 
-const incrementOTPAttempts = `-- name: IncrementOTPAttempts :exec
-UPDATE otp_codes
-SET attempts = attempts + 1
+const startImportJob = `-- name: StartImportJob :exec
+UPDATE import_jobs
+SET status = 'running', started_at = ?, updated_at = NOW()
 WHERE id = ?
 `
 
-func (q *Queries) IncrementOTPAttempts(ctx context.Context, id int32) error {
-	_, err := q.exec(ctx, q.incrementOTPAttemptsStmt, incrementOTPAttempts, id)
+type StartImportJobParams struct {
+	StartedAt sql.NullTime `db:"started_at"`
+	ID        int64        `db:"id"`
+}
+
+func (q *Queries) StartImportJob(ctx context.Context, arg StartImportJobParams) error {
+	_, err := q.exec(ctx, q.startImportJobStmt, startImportJob, arg.StartedAt, arg.ID)
 	return err
 }
 
-const listTickets = `-- name: ListTickets :many
-SELECT
-    id,
-    title,
-    description,
-    status,
-    priority,
-    created_by,
-    assigned_to,
-    created_at,
-    updated_at
-FROM tickets
-ORDER BY created_at DESC
-LIMIT ? OFFSET ?
+const sumRefunds = `-- name: SumRefunds :one
+SELECT CAST(COALESCE(SUM(amount_minor), 0) AS SIGNED) AS refunded
+FROM transactions
+WHERE parent_id = ? AND kind = 'refund'
 `
 
-type ListTicketsParams struct {
-	Limit  int32 `db:"limit"`
-	Offset int32 `db:"offset"`
+func (q *Queries) SumRefunds(ctx context.Context, parentID sql.NullInt32) (int64, error) {
+	row := q.queryRow(ctx, q.sumRefundsStmt, sumRefunds, parentID)
+	var refunded int64
+	err := row.Scan(&refunded)
+	return refunded, err
 }
 
-func (q *Queries) ListTickets(ctx context.Context, arg ListTicketsParams) ([]Ticket, error) {
-	rows, err := q.query(ctx, q.listTicketsStmt, listTickets, arg.Limit, arg.Offset)
+const sumTransactionsByCurrency = `-- name: SumTransactionsByCurrency :many
+SELECT currency, COUNT(*) AS count, CAST(COALESCE(SUM(amount_minor), 0) AS SIGNED) AS total_minor
+FROM transactions
+WHERE (? IS NULL OR user_id = ?)
+  AND (? IS NULL OR status = ?)
+  AND (? IS NULL OR currency = ?)
+  AND (? IS NULL OR payment_method = ?)
+  AND (? IS NULL OR kind = ?)
+  AND (? IS NULL OR amount_minor >= ?)
+  AND (? IS NULL OR amount_minor <= ?)
+  AND (? IS NULL OR created_at >= ?)
+  AND (? IS NULL OR created_at < ?)
+GROUP BY currency
+ORDER BY currency
+`
+
+type SumTransactionsByCurrencyParams struct {
+	UserID        sql.NullInt32        `db:"user_id"`
+	Status        sql.NullInt16        `db:"status"`
+	Currency      sql.NullString       `db:"currency"`
+	PaymentMethod sql.NullString       `db:"payment_method"`
+	Kind          NullTransactionsKind `db:"kind"`
+	MinAmount     sql.NullInt64        `db:"min_amount"`
+	MaxAmount     sql.NullInt64        `db:"max_amount"`
+	CreatedFrom   sql.NullTime         `db:"created_from"`
+	CreatedTo     sql.NullTime         `db:"created_to"`
+}
+
+type SumTransactionsByCurrencyRow struct {
+	Currency   string `db:"currency"`
+	Count      int64  `db:"count"`
+	TotalMinor int64  `db:"total_minor"`
+}
+
+// totals for the same filters as ListTransactions
+func (q *Queries) SumTransactionsByCurrency(ctx context.Context, arg SumTransactionsByCurrencyParams) ([]SumTransactionsByCurrencyRow, error) {
+	rows, err := q.query(ctx, q.sumTransactionsByCurrencyStmt, sumTransactionsByCurrency,
+		arg.UserID,
+		arg.UserID,
+		arg.Status,
+		arg.Status,
+		arg.Currency,
+		arg.Currency,
+		arg.PaymentMethod,
+		arg.PaymentMethod,
+		arg.Kind,
+		arg.Kind,
+		arg.MinAmount,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.MaxAmount,
+		arg.CreatedFrom,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.CreatedTo,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Ticket{}
+	items := []SumTransactionsByCurrencyRow{}
 	for rows.Next() {
-		var i Ticket
-		if err := rows.Scan(
-			&i.ID,
-			&i.Title,
-			&i.Description,
-			&i.Status,
-			&i.Priority,
-			&i.CreatedBy,
-			&i.AssignedTo,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
+		var i SumTransactionsByCurrencyRow
+		if err := rows.Scan(&i.Currency, &i.Count, &i.TotalMinor); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -447,122 +4748,217 @@ func (q *Queries) ListTickets(ctx context.Context, arg ListTicketsParams) ([]Tic
 	return items, nil
 }
 
-const listTransactions = `-- name: ListTransactions :many
-SELECT
-id,
-transaction_id,
-amount,
-currency,
-payment_method
-FROM transactions
-ORDER BY created_at DESC
-LIMIT ? OFFSET ?
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = ?
+WHERE id = ?
 `
 
-type ListTransactionsParams struct {
-	Limit  int32 `db:"limit"`
-	Offset int32 `db:"offset"`
+type TouchAPIKeyParams struct {
+	LastUsedAt sql.NullTime `db:"last_used_at"`
+	ID         int64        `db:"id"`
 }
 
-type ListTransactionsRow struct {
-	ID            int32          `db:"id"`
-	TransactionID string         `db:"transaction_id"`
-	Amount        string         `db:"amount"`
-	Currency      string         `db:"currency"`
-	PaymentMethod sql.NullString `db:"payment_method"`
+func (q *Queries) TouchAPIKey(ctx context.Context, arg TouchAPIKeyParams) error {
+	_, err := q.exec(ctx, q.touchAPIKeyStmt, touchAPIKey, arg.LastUsedAt, arg.ID)
+	return err
+}
+
+const transitionTransactionStatus = `-- name: TransitionTransactionStatus :execrows
+UPDATE transactions
+SET status = ?
+WHERE id = ? AND status = ?
+`
+
+type TransitionTransactionStatusParams struct {
+	NewStatus int16 `db:"new_status"`
+	ID        int32 `db:"id"`
+	OldStatus int16 `db:"old_status"`
 }
 
-func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsParams) ([]ListTransactionsRow, error) {
-	rows, err := q.query(ctx, q.listTransactionsStmt, listTransactions, arg.Limit, arg.Offset)
+// compare-and-set so that two concurrent updates cannot both apply
+func (q *Queries) TransitionTransactionStatus(ctx context.Context, arg TransitionTransactionStatusParams) (int64, error) {
+	result, err := q.exec(ctx, q.transitionTransactionStatusStmt, transitionTransactionStatus, arg.NewStatus, arg.ID, arg.OldStatus)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []ListTransactionsRow{}
-	for rows.Next() {
-		var i ListTransactionsRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.TransactionID,
-			&i.Amount,
-			&i.Currency,
-			&i.PaymentMethod,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listUsers = `-- name: ListUsers :many
-SELECT
-    id,
-    full_name,
-    email,
-    role,
-    created_at
-FROM users
-ORDER BY created_at DESC
-LIMIT ? OFFSET ?
+const updateAgentStatus = `-- name: UpdateAgentStatus :exec
+INSERT INTO agent_settings (profile_id, status)
+VALUES (?, ?)
+ON DUPLICATE KEY UPDATE status = VALUES(status)
 `
 
-type ListUsersParams struct {
-	Limit  int32 `db:"limit"`
-	Offset int32 `db:"offset"`
+type UpdateAgentStatusParams struct {
+	ProfileID int32               `db:"profile_id"`
+	Status    AgentSettingsStatus `db:"status"`
 }
 
-type ListUsersRow struct {
-	ID        int64         `db:"id"`
-	FullName  string        `db:"full_name"`
-	Email     string        `db:"email"`
-	Role      NullUsersRole `db:"role"`
-	CreatedAt sql.NullTime  `db:"created_at"`
+func (q *Queries) UpdateAgentStatus(ctx context.Context, arg UpdateAgentStatusParams) error {
+	_, err := q.exec(ctx, q.updateAgentStatusStmt, updateAgentStatus, arg.ProfileID, arg.Status)
+	return err
 }
 
-func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
-	rows, err := q.query(ctx, q.listUsersStmt, listUsers, arg.Limit, arg.Offset)
+const updateCustomer = `-- name: UpdateCustomer :exec
+UPDATE customers
+SET full_name = ?, email = ?, phone_number = ?, segment = ?
+WHERE id = ?
+`
+
+type UpdateCustomerParams struct {
+	FullName    string `db:"full_name"`
+	Email       string `db:"email"`
+	PhoneNumber string `db:"phone_number"`
+	Segment     string `db:"segment"`
+	ID          int64  `db:"id"`
+}
+
+func (q *Queries) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) error {
+	_, err := q.exec(ctx, q.updateCustomerStmt, updateCustomer,
+		arg.FullName,
+		arg.Email,
+		arg.PhoneNumber,
+		arg.Segment,
+		arg.ID,
+	)
+	return err
+}
+
+const updateCustomerPhone = `-- name: UpdateCustomerPhone :exec
+UPDATE customers SET phone_number = ? WHERE id = ?
+`
+
+type UpdateCustomerPhoneParams struct {
+	PhoneNumber string `db:"phone_number"`
+	ID          int64  `db:"id"`
+}
+
+func (q *Queries) UpdateCustomerPhone(ctx context.Context, arg UpdateCustomerPhoneParams) error {
+	_, err := q.exec(ctx, q.updateCustomerPhoneStmt, updateCustomerPhone, arg.PhoneNumber, arg.ID)
+	return err
+}
+
+const updateImportJobProgress = `-- name: UpdateImportJobProgress :exec
+UPDATE import_jobs
+SET rows_processed = ?, updated_at = NOW()
+WHERE id = ?
+`
+
+type UpdateImportJobProgressParams struct {
+	RowsProcessed int32 `db:"rows_processed"`
+	ID            int64 `db:"id"`
+}
+
+func (q *Queries) UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) error {
+	_, err := q.exec(ctx, q.updateImportJobProgressStmt, updateImportJobProgress, arg.RowsProcessed, arg.ID)
+	return err
+}
+
+const updateProfilePassword = `-- name: UpdateProfilePassword :exec
+UPDATE profiles
+SET password_hash = ?, token_version = token_version + 1
+WHERE id = ?
+`
+
+type UpdateProfilePasswordParams struct {
+	PasswordHash string `db:"password_hash"`
+	ID           int32  `db:"id"`
+}
+
+// bumping token_version revokes every JWT issued before the change
+func (q *Queries) UpdateProfilePassword(ctx context.Context, arg UpdateProfilePasswordParams) error {
+	_, err := q.exec(ctx, q.updateProfilePasswordStmt, updateProfilePassword, arg.PasswordHash, arg.ID)
+	return err
+}
+
+const updateProfilePhone = `-- name: UpdateProfilePhone :exec
+UPDATE profiles SET phone = ? WHERE id = ?
+`
+
+type UpdateProfilePhoneParams struct {
+	Phone sql.NullString `db:"phone"`
+	ID    int32          `db:"id"`
+}
+
+func (q *Queries) UpdateProfilePhone(ctx context.Context, arg UpdateProfilePhoneParams) error {
+	_, err := q.exec(ctx, q.updateProfilePhoneStmt, updateProfilePhone, arg.Phone, arg.ID)
+	return err
+}
+
+const updateRoutingRule = `-- name: UpdateRoutingRule :exec
+UPDATE routing_rules
+SET name = ?, priority = ?, team_id = ?, ticket_priority = ?, ticket_type = ?, customer_segment = ?, keywords = ?, active = ?
+WHERE id = ?
+`
+
+type UpdateRoutingRuleParams struct {
+	Name            string                     `db:"name"`
+	Priority        int32                      `db:"priority"`
+	TeamID          int64                      `db:"team_id"`
+	TicketPriority  sql.NullString             `db:"ticket_priority"`
+	TicketType      NullRoutingRulesTicketType `db:"ticket_type"`
+	CustomerSegment sql.NullString             `db:"customer_segment"`
+	Keywords        string                     `db:"keywords"`
+	Active          bool                       `db:"active"`
+	ID              int64                      `db:"id"`
+}
+
+func (q *Queries) UpdateRoutingRule(ctx context.Context, arg UpdateRoutingRuleParams) error {
+	_, err := q.exec(ctx, q.updateRoutingRuleStmt, updateRoutingRule,
+		arg.Name,
+		arg.Priority,
+		arg.TeamID,
+		arg.TicketPriority,
+		arg.TicketType,
+		arg.CustomerSegment,
+		arg.Keywords,
+		arg.Active,
+		arg.ID,
+	)
+	return err
+}
+
+const updateTOTPLastUsedStep = `-- name: UpdateTOTPLastUsedStep :execrows
+UPDATE totp_secrets
+SET last_used_step = ?
+WHERE profile_id = ? AND last_used_step < ?
+`
+
+type UpdateTOTPLastUsedStepParams struct {
+	Step      int64 `db:"step"`
+	ProfileID int32 `db:"profile_id"`
+}
+
+// only moves forward, so a code can't be replayed within its window
+func (q *Queries) UpdateTOTPLastUsedStep(ctx context.Context, arg UpdateTOTPLastUsedStepParams) (int64, error) {
+	result, err := q.exec(ctx, q.updateTOTPLastUsedStepStmt, updateTOTPLastUsedStep, arg.Step, arg.ProfileID, arg.Step)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []ListUsersRow{}
-	for rows.Next() {
-		var i ListUsersRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.FullName,
-			&i.Email,
-			&i.Role,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const markOTPVerified = `-- name: MarkOTPVerified :exec
-UPDATE otp_codes
-SET verified = TRUE
+const updateTeam = `-- name: UpdateTeam :exec
+UPDATE teams SET name = ?, description = ?, assignment_strategy = ?
 WHERE id = ?
 `
 
-func (q *Queries) MarkOTPVerified(ctx context.Context, id int32) error {
-	_, err := q.exec(ctx, q.markOTPVerifiedStmt, markOTPVerified, id)
+type UpdateTeamParams struct {
+	Name               string `db:"name"`
+	Description        string `db:"description"`
+	AssignmentStrategy string `db:"assignment_strategy"`
+	ID                 int64  `db:"id"`
+}
+
+func (q *Queries) UpdateTeam(ctx context.Context, arg UpdateTeamParams) error {
+	_, err := q.exec(ctx, q.updateTeamStmt, updateTeam,
+		arg.Name,
+		arg.Description,
+		arg.AssignmentStrategy,
+		arg.ID,
+	)
 	return err
 }
 
@@ -582,28 +4978,151 @@ func (q *Queries) UpdateTicketStatus(ctx context.Context, arg UpdateTicketStatus
 	return err
 }
 
+const updateTicketTeam = `-- name: UpdateTicketTeam :execrows
+UPDATE tickets SET team_id = ?
+WHERE id = ?
+`
+
+type UpdateTicketTeamParams struct {
+	TeamID sql.NullInt64 `db:"team_id"`
+	ID     int64         `db:"id"`
+}
+
+func (q *Queries) UpdateTicketTeam(ctx context.Context, arg UpdateTicketTeamParams) (int64, error) {
+	result, err := q.exec(ctx, q.updateTicketTeamStmt, updateTicketTeam, arg.TeamID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :exec
-UPDATE users
+UPDATE profiles
 SET
-  full_name = ?,
-  email     = ?,
-  role      = ?
+  full_name = COALESCE(?, full_name),
+  email     = COALESCE(?, email),
+  phone     = COALESCE(?, phone),
+  role      = COALESCE(?, role)
 WHERE id = ?
 `
 
 type UpdateUserParams struct {
-	FullName string        `db:"full_name"`
-	Email    string        `db:"email"`
-	Role     NullUsersRole `db:"role"`
-	ID       int64         `db:"id"`
+	FullName sql.NullString   `db:"full_name"`
+	Email    sql.NullString   `db:"email"`
+	Phone    sql.NullString   `db:"phone"`
+	Role     NullProfilesRole `db:"role"`
+	ID       int32            `db:"id"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	_, err := q.exec(ctx, q.updateUserStmt, updateUser,
 		arg.FullName,
 		arg.Email,
+		arg.Phone,
 		arg.Role,
 		arg.ID,
 	)
 	return err
 }
+
+const updateWebhookSecret = `-- name: UpdateWebhookSecret :exec
+UPDATE webhook_subscriptions
+SET secret = ?
+WHERE id = ?
+`
+
+type UpdateWebhookSecretParams struct {
+	Secret string `db:"secret"`
+	ID     int64  `db:"id"`
+}
+
+func (q *Queries) UpdateWebhookSecret(ctx context.Context, arg UpdateWebhookSecretParams) error {
+	_, err := q.exec(ctx, q.updateWebhookSecretStmt, updateWebhookSecret, arg.Secret, arg.ID)
+	return err
+}
+
+const updateWebhookSubscription = `-- name: UpdateWebhookSubscription :exec
+UPDATE webhook_subscriptions
+SET url = ?, events = ?, active = ?, consecutive_failures = 0, disabled_at = NULL, disabled_reason = ''
+WHERE id = ?
+`
+
+type UpdateWebhookSubscriptionParams struct {
+	Url    string `db:"url"`
+	Events string `db:"events"`
+	Active bool   `db:"active"`
+	ID     int64  `db:"id"`
+}
+
+// an update also gives a disabled subscription a fresh start
+func (q *Queries) UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) error {
+	_, err := q.exec(ctx, q.updateWebhookSubscriptionStmt, updateWebhookSubscription,
+		arg.Url,
+		arg.Events,
+		arg.Active,
+		arg.ID,
+	)
+	return err
+}
+
+const upsertAgentSettings = `-- name: UpsertAgentSettings :exec
+INSERT INTO agent_settings (profile_id, status, max_concurrent, skills)
+VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE status = VALUES(status), max_concurrent = VALUES(max_concurrent), skills = VALUES(skills)
+`
+
+type UpsertAgentSettingsParams struct {
+	ProfileID     int32               `db:"profile_id"`
+	Status        AgentSettingsStatus `db:"status"`
+	MaxConcurrent int32               `db:"max_concurrent"`
+	Skills        string              `db:"skills"`
+}
+
+func (q *Queries) UpsertAgentSettings(ctx context.Context, arg UpsertAgentSettingsParams) error {
+	_, err := q.exec(ctx, q.upsertAgentSettingsStmt, upsertAgentSettings,
+		arg.ProfileID,
+		arg.Status,
+		arg.MaxConcurrent,
+		arg.Skills,
+	)
+	return err
+}
+
+const upsertTOTPSecret = `-- name: UpsertTOTPSecret :exec
+INSERT INTO totp_secrets (profile_id, secret_enc)
+VALUES (?, ?)
+ON DUPLICATE KEY UPDATE
+  secret_enc = VALUES(secret_enc),
+  confirmed_at = NULL,
+  last_used_step = 0
+`
+
+type UpsertTOTPSecretParams struct {
+	ProfileID int32  `db:"profile_id"`
+	SecretEnc string `db:"secret_enc"`
+}
+
+// re-enrolling replaces an unconfirmed secret and resets its state
+func (q *Queries) UpsertTOTPSecret(ctx context.Context, arg UpsertTOTPSecretParams) error {
+	_, err := q.exec(ctx, q.upsertTOTPSecretStmt, upsertTOTPSecret, arg.ProfileID, arg.SecretEnc)
+	return err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE profile_id = ? AND code_hash = ? AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	ProfileID int32  `db:"profile_id"`
+	CodeHash  string `db:"code_hash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.exec(ctx, q.useRecoveryCodeStmt, useRecoveryCode, arg.ProfileID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
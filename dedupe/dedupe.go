@@ -0,0 +1,200 @@
+// Package dedupe finds customers that are probably the same person entered
+// more than once: the same phone number written differently, the same email
+// in another case, or a slightly different spelling of the name.
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	db "tickets/db/sqlc"
+	"tickets/phone"
+)
+
+// Reason is why customers were grouped together.
+type Reason string
+
+const (
+	ReasonPhone Reason = "phone"
+	ReasonEmail Reason = "email"
+	ReasonName  Reason = "name"
+)
+
+// DefaultNameSimilarity is the name similarity above which two customers
+// are reported as likely duplicates.
+const DefaultNameSimilarity = 0.85
+
+// Pair is two customers that are likely one person, A the older. Similarity
+// is their name similarity, 0 when it is below the threshold the pair was
+// found with and only phone or email linked them.
+type Pair struct {
+	A, B       db.Customer
+	Reasons    []Reason
+	Similarity float64
+}
+
+// PhoneKey normalises a phone number for comparison: its E.164 form, so
+// 0712 345678, +254 712 345 678 and 254712345678 are the same number. A
+// number that does not parse falls back to its last nine digits.
+func PhoneKey(number string) string {
+	if phone.Valid(number) {
+		return phone.NormalizeOrTrim(number)
+	}
+	var b strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if len(digits) > 9 {
+		digits = digits[len(digits)-9:]
+	}
+	return digits
+}
+
+// EmailKey normalises an email address for comparison.
+func EmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NameKey normalises a name for comparison: lower case, punctuation dropped
+// and the words sorted, so "Smith, John" and "john smith" are equal.
+func NameKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// NameSimilarity compares two name keys: 1 for equal, falling towards 0 with
+// the edit distance between them.
+func NameSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Pairs finds likely duplicates among customers. Phone and email keys must
+// match exactly; names match when their similarity is at least
+// minSimilarity. To keep this well short of comparing every pair, names are
+// only compared with names that start with the same letter. This is still
+// too slow for a request on a large customer base; the worker runs it.
+func Pairs(customers []db.Customer, minSimilarity float64) []Pair {
+	type edge struct {
+		reasons    map[Reason]bool
+		similarity float64
+	}
+	edges := map[[2]int]*edge{} // keyed by the lower index first
+	link := func(i, j int, r Reason, s float64) {
+		if i > j {
+			i, j = j, i
+		}
+		e := edges[[2]int{i, j}]
+		if e == nil {
+			e = &edge{reasons: map[Reason]bool{}}
+			edges[[2]int{i, j}] = e
+		}
+		e.reasons[r] = true
+		e.similarity = max(e.similarity, s)
+	}
+
+	byPhone := map[string]int{}
+	byEmail := map[string]int{}
+	byInitial := map[rune][]int{}
+	names := make([]string, len(customers))
+	for i, cu := range customers {
+		if k := PhoneKey(cu.PhoneNumber); k != "" {
+			if j, ok := byPhone[k]; ok {
+				link(i, j, ReasonPhone, 0)
+			} else {
+				byPhone[k] = i
+			}
+		}
+		if k := EmailKey(cu.Email); k != "" {
+			if j, ok := byEmail[k]; ok {
+				link(i, j, ReasonEmail, 0)
+			} else {
+				byEmail[k] = i
+			}
+		}
+		names[i] = NameKey(cu.FullName)
+		if names[i] != "" {
+			initial := []rune(names[i])[0]
+			byInitial[initial] = append(byInitial[initial], i)
+		}
+	}
+
+	for _, block := range byInitial {
+		for x, i := range block {
+			li := len([]rune(names[i]))
+			for _, j := range block[x+1:] {
+				lj := len([]rune(names[j]))
+				// the length difference alone bounds the similarity
+				if 1-float64(abs(li-lj))/float64(max(li, lj)) < minSimilarity {
+					continue
+				}
+				if s := NameSimilarity(names[i], names[j]); s >= minSimilarity {
+					link(i, j, ReasonName, s)
+				}
+			}
+		}
+	}
+
+	out := make([]Pair, 0, len(edges))
+	for k, e := range edges {
+		p := Pair{A: customers[k[0]], B: customers[k[1]], Similarity: e.similarity}
+		if p.A.ID > p.B.ID {
+			p.A, p.B = p.B, p.A
+		}
+		for _, r := range []Reason{ReasonPhone, ReasonEmail, ReasonName} {
+			if e.reasons[r] {
+				p.Reasons = append(p.Reasons, r)
+			}
+		}
+		out = append(out, p)
+	}
+	// oldest customers first, so the report is stable between runs
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].A.ID != out[b].A.ID {
+			return out[a].A.ID < out[b].A.ID
+		}
+		return out[a].B.ID < out[b].B.ID
+	})
+	return out
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
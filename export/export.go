@@ -0,0 +1,61 @@
+// Package export writes tabular data as CSV or XLSX one row at a time, so
+// large exports can be streamed straight to an HTTP response.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// Formats accepted by New.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Writer writes rows. Close must be called to finish the file; it does not
+// close the underlying io.Writer.
+type Writer interface {
+	Write(row []string) error
+	// Flush pushes buffered rows to the underlying writer.
+	Flush() error
+	Close() error
+}
+
+// New returns a Writer for format. numeric lists the columns written as
+// numbers in XLSX; CSV ignores it.
+func New(format string, w io.Writer, numeric ...int) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return NewXLSX(w, "Sheet1", numeric...)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ContentType returns the MIME type for format.
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(row []string) error {
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// An XLSX file is a zip of XML parts. Only the worksheet grows with the
+// data; it is written as a single zip entry while rows arrive, using inline
+// strings so no shared string table has to be held in memory.
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const rootRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const workbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const sheetHeader = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const sheetFooter = `</sheetData></worksheet>`
+
+type xlsxWriter struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	numeric map[int]bool
+	row     int
+}
+
+// NewXLSX starts a single-sheet workbook on w. Cells in the numeric columns
+// that parse as numbers are written as numbers, everything else as text.
+func NewXLSX(w io.Writer, sheetName string, numeric ...int) (Writer, error) {
+	zw := zip.NewWriter(w)
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+	parts := []struct{ path, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", strings.Replace(workbookXML, "%s", name.String(), 1)},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zw: zw, sheet: bufio.NewWriter(f), numeric: map[int]bool{}}
+	for _, i := range numeric {
+		x.numeric[i] = true
+	}
+	if _, err := x.sheet.WriteString(sheetHeader); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(row []string) error {
+	x.row++
+	r := strconv.Itoa(x.row)
+	x.sheet.WriteString(`<row r="` + r + `">`)
+	for i, v := range row {
+		if v == "" {
+			continue
+		}
+		ref := column(i) + r
+		if x.numeric[i] && isNumber(v) {
+			x.sheet.WriteString(`<c r="` + ref + `"><v>` + v + `</v></c>`)
+			continue
+		}
+		x.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(x.sheet, []byte(v))
+		x.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Flush pushes buffered rows through the compressor. The zip writer keeps
+// its own small buffer, so some bytes may still trail until Close.
+func (x *xlsxWriter) Flush() error {
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Flush()
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(sheetFooter); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// column turns a 0-based index into a spreadsheet column name: A, B, ... Z, AA.
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// isNumber accepts plain decimals only; ParseFloat alone would also let
+// through "NaN", "Inf" and hex, which spreadsheets reject.
+func isNumber(s string) bool {
+	if strings.Trim(s, "-.0123456789") != "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -12,41 +12,69 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/password"
+	"tickets/phone"
 	"tickets/sms"
 	"tickets/utils"
 )
 
 type AuthHandler struct {
+	db          *sql.DB
 	queries     *db.Queries
 	smsProvider sms.SMSProvider
 	otpTTL      time.Duration
 	maxAttempts int
+	cooldown    time.Duration
+	passwords   *password.Policy
+	totpIssuer  string
+	lockout     lockoutPolicy
 }
 
-func NewAuthHandler(q *db.Queries, p sms.SMSProvider) *AuthHandler {
-	ttlMin := 5
-	if v := os.Getenv("OTP_TTL_MINUTES"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			ttlMin = n
-		}
-	}
-	maxA := 5
-	if v := os.Getenv("OTP_MAX_ATTEMPTS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			maxA = n
-		}
+// otp_codes.purpose values; a code issued for one flow can't be used in another
+const (
+	otpPurposeLogin         = "login"
+	otpPurposePasswordReset = "password_reset"
+	// a login_totp row is never sent; it records that the password step passed
+	otpPurposeTOTPLogin = "login_totp"
+)
+
+func NewAuthHandler(conn *sql.DB, q *db.Queries, p sms.SMSProvider) *AuthHandler {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Tickets"
 	}
 	return &AuthHandler{
+		db:          conn,
 		queries:     q,
 		smsProvider: p,
-		otpTTL:      time.Duration(ttlMin) * time.Minute,
-		maxAttempts: maxA,
+		otpTTL:      time.Duration(envInt("OTP_TTL_MINUTES", 5)) * time.Minute,
+		maxAttempts: envInt("OTP_MAX_ATTEMPTS", 5),
+		cooldown:    time.Duration(envInt("OTP_RESEND_COOLDOWN_SECONDS", 60)) * time.Second,
+		passwords:   password.LoadPolicy(),
+		totpIssuer:  issuer,
+		lockout: lockoutPolicy{
+			throttleAfter: envInt("LOGIN_THROTTLE_AFTER", 3),
+			maxFailures:   envInt("LOGIN_MAX_FAILURES", 10),
+			lockFor:       time.Duration(envInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+		},
 	}
 }
 
+// envInt reads an integer setting, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
 // helper generate 6-digit secure otp
 func generateOTP() (string, error) {
 	var b [3]byte
@@ -58,37 +86,113 @@ func generateOTP() (string, error) {
 	return fmt.Sprintf("%06d", code), nil
 }
 
+// loginReq identifies the profile by phone or email.
 type loginReq struct {
-	Phone    string `json:"phone" binding:"required"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email"`
 	Password string `json:"password" binding:"required"`
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req loginReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "phone and password required"})
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Phone == "" && req.Email == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone or email, and password required"})
 		slog.Error("invalid login request", "error", err)
 		return
 	}
+	req.Phone = phone.NormalizeOrTrim(req.Phone)
+
+	identifier := req.Phone
+	if identifier == "" {
+		identifier = req.Email
+	}
 
-	profile, err := h.queries.GetProfileByPhone(c, req.Phone)
+	profile, err := h.findProfile(c, req.Phone, req.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
-			slog.Error("invalid login credentials", "phone", req.Phone)
+			slog.Error("invalid login credentials", "phone", req.Phone, "email", req.Email)
+			h.recordLoginEvent(c, 0, identifier, false, loginReasonUnknownAccount)
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
-		slog.Error("failed to get profile by phone", "phone", req.Phone, "error", err)
+		slog.Error("failed to get profile", "phone", req.Phone, "email", req.Email, "error", err)
+		return
+	}
+
+	if !h.checkLockout(c, profile, identifier) {
 		return
 	}
 
 	// compare password
 	if err := bcrypt.CompareHashAndPassword([]byte(profile.PasswordHash), []byte(req.Password)); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		h.recordFailure(c, profile.ID)
+		h.recordLoginEvent(c, profile.ID, identifier, false, loginReasonBadPassword)
+		return
+	}
+	h.clearFailures(c, profile.ID)
+
+	// profiles enrolled in TOTP use their authenticator app instead of SMS
+	_, enrolled, ok := h.confirmedTOTP(c, profile.ID)
+	if !ok {
+		return
+	}
+	if enrolled {
+		if !h.startTOTPLogin(c, profile.ID) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "enter the code from your authenticator app", "method": "totp"})
+		return
+	}
+
+	if !h.issueOTP(c, profile, otpPurposeLogin) {
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "OTP sent", "method": "sms"})
+}
+
+// findProfile looks a profile up by phone, falling back to email.
+func (h *AuthHandler) findProfile(c *gin.Context, phone, email string) (db.Profile, error) {
+	if phone != "" {
+		return h.queries.GetProfileByPhone(c, utils.NullString(phone))
+	}
+	return h.queries.GetProfileByEmail(c, utils.NullString(strings.ToLower(email)))
+}
+
+// otpCooldown is how long until another OTP for purpose may be sent to the
+// profile. The issue time is derived from expires_at so both sides of the
+// comparison come from the app clock.
+func (h *AuthHandler) otpCooldown(c *gin.Context, profileID int32, purpose string) time.Duration {
+	last, err := h.queries.GetLatestOTPByProfileID(c, db.GetLatestOTPByProfileIDParams{
+		ProfileID: profileID,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("failed to get latest otp", "profile_id", profileID, "error", err)
+		}
+		return 0
+	}
+	return h.cooldown - time.Since(last.ExpiresAt.Add(-h.otpTTL))
+}
+
+// issueOTP enforces the resend cooldown, stores a fresh code for the given
+// purpose and sends it by SMS. It writes the error response itself and
+// returns false if the caller should stop.
+func (h *AuthHandler) issueOTP(c *gin.Context, profile db.Profile, purpose string) bool {
+	if !profile.Phone.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no phone number on file; add one or enroll an authenticator app"})
+		return false
+	}
+
+	if wait := h.otpCooldown(c, profile.ID, purpose); wait > 0 {
+		middleware.TooManyRequests(c, wait)
+		slog.Warn("otp resend cooldown", "profile_id", profile.ID)
+		return false
+	}
+
 	// delete expired OTPs (cleanup)
 	if err := h.queries.DeleteExpiredOTPs(c); err != nil {
 		log.Println("cleanup expired otp:", err)
@@ -97,7 +201,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	otp, err := generateOTP()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate otp"})
-		return
+		return false
 	}
 
 	expires := time.Now().Add(h.otpTTL)
@@ -106,81 +210,154 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	if _, err := h.queries.CreateOTP(c, db.CreateOTPParams{
 		ProfileID: profile.ID,
 		OtpCode:   otp,
+		Purpose:   purpose,
 		ExpiresAt: expires,
 	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save otp"})
-		return
+		return false
 	}
 
 	// send via SMS (async)
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		msg := fmt.Sprintf("Your login OTP is %s. It expires in %d minutes.", otp, int(h.otpTTL.Minutes()))
-		if err := h.smsProvider.SendSMS(ctx, profile.Phone, msg); err != nil {
+		label := "login"
+		if purpose == otpPurposePasswordReset {
+			label = "password reset"
+		}
+		msg := fmt.Sprintf("Your %s OTP is %s. It expires in %d minutes.", label, otp, int(h.otpTTL.Minutes()))
+		if err := h.smsProvider.SendSMS(ctx, profile.Phone.String, msg); err != nil {
 			log.Println("failed to send otp sms:", err)
 		}
 	}()
 
-	c.JSON(http.StatusOK, gin.H{"message": "OTP sent"})
+	return true
 }
 
 type verifyReq struct {
-	Phone string `json:"phone" binding:"required"`
+	Phone string `json:"phone"`
+	Email string `json:"email"`
 	OTP   string `json:"otp" binding:"required"`
 }
 
 func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	var req verifyReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "phone and otp required"})
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Phone == "" && req.Email == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone or email, and otp required"})
 		return
 	}
+	req.Phone = phone.NormalizeOrTrim(req.Phone)
 
-	profile, err := h.queries.GetProfileByPhone(c, req.Phone)
+	profile, err := h.findProfile(c, req.Phone, req.Email)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid otp or phone"})
 		return
 	}
 
-	otpRec, err := h.queries.GetLatestOTPByProfileID(c, profile.ID)
+	identifier := req.Phone
+	if identifier == "" {
+		identifier = req.Email
+	}
+
+	secret, enrolled, ok := h.confirmedTOTP(c, profile.ID)
+	if !ok {
+		return
+	}
+	if enrolled {
+		ok = h.verifyTOTPLogin(c, profile.ID, secret, req.OTP)
+	} else {
+		_, ok = h.checkOTP(c, profile.ID, otpPurposeLogin, req.OTP)
+	}
+	if !ok {
+		h.recordLoginEvent(c, profile.ID, identifier, false, loginReasonBadOTP)
+		return
+	}
+
+	h.alertNewSource(c, profile)
+	h.recordLoginEvent(c, profile.ID, identifier, true, loginReasonOK)
+
+	// issue JWT for profile
+	token, err := utils.GenerateJWT(int64(profile.ID), profile.TokenVersion)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid otp"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "login successful", "token": token})
+}
+
+// checkOTP validates code against the latest OTP issued for the purpose and
+// marks it used. It writes the error response itself and returns false if
+// the caller should stop.
+func (h *AuthHandler) checkOTP(c *gin.Context, profileID int32, purpose, code string) (db.OtpCode, bool) {
+	return h.useOTP(c, profileID, purpose, func(otpRec db.OtpCode) bool {
+		return otpRec.OtpCode == code
+	})
+}
+
+// useOTP applies the single-use, attempt and expiry checks to the latest OTP
+// for the purpose, then asks match whether the submitted code is correct.
+func (h *AuthHandler) useOTP(c *gin.Context, profileID int32, purpose string, match func(db.OtpCode) bool) (db.OtpCode, bool) {
+	otpRec, err := h.queries.GetLatestOTPByProfileID(c, db.GetLatestOTPByProfileIDParams{
+		ProfileID: profileID,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid otp"})
+		return otpRec, false
+	}
+
+	// a code can only be used once
+	if otpRec.Verified {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "otp already used"})
+		return otpRec, false
+	}
+
 	// check attempts
 	if otpRec.Attempts >= int32(h.maxAttempts) {
 		c.JSON(http.StatusTooManyRequests, gin.H{"error": "max OTP attempts exceeded"})
-		return
+		return otpRec, false
 	}
 
 	// check expiry
 	if time.Now().After(otpRec.ExpiresAt) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "otp expired"})
-		return
+		return otpRec, false
+	}
+
+	// claim an attempt before comparing, so concurrent guesses cannot go
+	// past the limit between the read above and this write
+	claimed, err := h.queries.IncrementOTPAttempts(c, db.IncrementOTPAttemptsParams{
+		ID:          otpRec.ID,
+		MaxAttempts: int32(h.maxAttempts),
+	})
+	if err != nil {
+		log.Println("failed increment attempts:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify otp"})
+		return otpRec, false
+	}
+	if claimed == 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "max OTP attempts exceeded"})
+		return otpRec, false
 	}
 
 	// compare
-	if otpRec.OtpCode != req.OTP {
-		if err := h.queries.IncrementOTPAttempts(c, otpRec.ID); err != nil {
-			log.Println("failed increment attempts:", err)
-		}
+	if !match(otpRec) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid otp"})
-		return
+		return otpRec, false
 	}
 
-	// mark verified
-	if err := h.queries.MarkOTPVerified(c, otpRec.ID); err != nil {
+	// mark verified; only one concurrent request can flip the flag
+	marked, err := h.queries.MarkOTPVerified(c, otpRec.ID)
+	if err != nil {
 		log.Println("failed mark verified:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify otp"})
+		return otpRec, false
 	}
-
-	// issue JWT for profile
-	token, err := utils.GenerateJWT(int64(otpRec.ProfileID))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
-		return
+	if marked == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "otp already used"})
+		return otpRec, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "login successful", "token": token})
+	return otpRec, true
 }
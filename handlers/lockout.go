@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+)
+
+// login_events.reason values
+const (
+	loginReasonOK             = "ok"
+	loginReasonUnknownAccount = "unknown_account"
+	loginReasonBadPassword    = "bad_password"
+	loginReasonBadOTP         = "bad_otp"
+	loginReasonLocked         = "locked"
+	loginReasonThrottled      = "throttled"
+)
+
+// lockoutPolicy slows down and then blocks repeated wrong passwords.
+// After throttleAfter failures each attempt has to wait 2^(n-throttleAfter)
+// seconds (capped at a minute); at maxFailures the profile is locked for lockFor.
+// The count starts over when a lock expires or no attempt has failed for lockFor.
+type lockoutPolicy struct {
+	throttleAfter int
+	maxFailures   int
+	lockFor       time.Duration
+}
+
+func (p lockoutPolicy) delay(failures int32) time.Duration {
+	over := int(failures) - p.throttleAfter
+	if over < 0 {
+		return 0
+	}
+	secs := math.Min(math.Pow(2, float64(over)), 60)
+	return time.Duration(secs) * time.Second
+}
+
+// checkLockout rejects the attempt if the profile is locked or still inside
+// its progressive delay. It writes the response and returns false in that case.
+// An expired lock or stale failures are cleared, so the next wrong password
+// does not lock the profile again straight away.
+func (h *AuthHandler) checkLockout(c *gin.Context, profile db.Profile, identifier string) bool {
+	lock, err := h.queries.GetProfileLockout(c, profile.ID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("failed to get lockout", "profile_id", profile.ID, "error", err)
+		}
+		return true
+	}
+
+	now := time.Now()
+	if lock.LockedUntil.Valid && now.Before(lock.LockedUntil.Time) {
+		wait := lock.LockedUntil.Time.Sub(now)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusLocked, gin.H{"error": "account temporarily locked"})
+		h.recordLoginEvent(c, profile.ID, identifier, false, loginReasonLocked)
+		return false
+	}
+	if lock.LockedUntil.Valid || now.Sub(lock.LastFailedAt) >= h.lockout.lockFor {
+		h.clearFailures(c, profile.ID)
+		return true
+	}
+	if wait := lock.LastFailedAt.Add(h.lockout.delay(lock.FailedAttempts)).Sub(now); wait > 0 {
+		middleware.TooManyRequests(c, wait)
+		h.recordLoginEvent(c, profile.ID, identifier, false, loginReasonThrottled)
+		return false
+	}
+	return true
+}
+
+// recordFailure counts a wrong password and locks the profile once it hits maxFailures.
+func (h *AuthHandler) recordFailure(c *gin.Context, profileID int32) {
+	now := time.Now()
+	if err := h.queries.RecordLoginFailure(c, db.RecordLoginFailureParams{
+		ProfileID:    profileID,
+		LastFailedAt: now,
+	}); err != nil {
+		slog.Error("failed to record login failure", "profile_id", profileID, "error", err)
+		return
+	}
+
+	lock, err := h.queries.GetProfileLockout(c, profileID)
+	if err != nil {
+		slog.Error("failed to get lockout", "profile_id", profileID, "error", err)
+		return
+	}
+	if int(lock.FailedAttempts) >= h.lockout.maxFailures {
+		if err := h.queries.LockProfile(c, db.LockProfileParams{
+			LockedUntil: sql.NullTime{Time: now.Add(h.lockout.lockFor), Valid: true},
+			ProfileID:   profileID,
+		}); err != nil {
+			slog.Error("failed to lock profile", "profile_id", profileID, "error", err)
+			return
+		}
+		slog.Warn("profile locked after repeated failures", "profile_id", profileID, "failures", lock.FailedAttempts)
+	}
+}
+
+func (h *AuthHandler) clearFailures(c *gin.Context, profileID int32) {
+	if err := h.queries.ClearLoginFailures(c, profileID); err != nil {
+		slog.Error("failed to clear login failures", "profile_id", profileID, "error", err)
+	}
+}
+
+// recordLoginEvent writes an audit row. profileID is 0 when the account is unknown.
+func (h *AuthHandler) recordLoginEvent(c *gin.Context, profileID int32, identifier string, success bool, reason string) {
+	if err := h.queries.CreateLoginEvent(c, db.CreateLoginEventParams{
+		ProfileID:  sql.NullInt32{Int32: profileID, Valid: profileID != 0},
+		Identifier: identifier,
+		Ip:         c.ClientIP(),
+		UserAgent:  truncate(c.Request.UserAgent(), 255),
+		Success:    success,
+		Reason:     reason,
+	}); err != nil {
+		slog.Error("failed to record login event", "profile_id", profileID, "error", err)
+	}
+}
+
+// alertNewSource texts the profile when a login succeeds from an IP or device
+// it has not logged in from before. The very first login never alerts.
+// Call it before recording the successful login event.
+func (h *AuthHandler) alertNewSource(c *gin.Context, profile db.Profile) {
+	if !profile.Phone.Valid {
+		return
+	}
+	ip, ua := c.ClientIP(), truncate(c.Request.UserAgent(), 255)
+	seen, err := h.queries.CountLoginSources(c, db.CountLoginSourcesParams{
+		Ip:        ip,
+		UserAgent: ua,
+		ProfileID: sql.NullInt32{Int32: profile.ID, Valid: true},
+	})
+	if err != nil {
+		slog.Error("failed to count login sources", "profile_id", profile.ID, "error", err)
+		return
+	}
+	if seen.Total == 0 || (seen.FromIp > 0 && seen.FromDevice > 0) {
+		return
+	}
+
+	slog.Warn("login from new source", "profile_id", profile.ID, "ip", ip, "user_agent", ua)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		msg := fmt.Sprintf("New sign-in to your account from %s at %s. If this wasn't you, reset your password now.",
+			ip, time.Now().Format("2006-01-02 15:04"))
+		if err := h.smsProvider.SendSMS(ctx, profile.Phone.String, msg); err != nil {
+			slog.Error("failed to send login alert sms", "profile_id", profile.ID, "error", err)
+		}
+	}()
+}
+
+// UnlockAccount lets an admin clear a lockout before it expires.
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid profile ID"})
+		return
+	}
+
+	if _, err := h.queries.GetProfileByID(c, int32(id)); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get profile", "profile_id", id, "error", err)
+		return
+	}
+
+	if err := h.queries.ClearLoginFailures(c, int32(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock account"})
+		slog.Error("failed to unlock profile", "profile_id", id, "error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account unlocked"})
+	slog.Info("profile unlocked", "profile_id", id, "unlocked_by", middleware.ProfileID(c))
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/phone"
+	"tickets/utils"
+)
+
+type forgotPasswordReq struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// ForgotPassword sends a password reset OTP. The response is the same whether
+// or not the phone is registered so it can't be used to enumerate accounts:
+// a registered phone still in its resend cooldown gets the usual answer
+// without a new code rather than a 429, and unregistered phones are held to
+// the same per-phone budget by the route's rate limiter.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone required"})
+		return
+	}
+	req.Phone = phone.NormalizeOrTrim(req.Phone)
+
+	const sent = "if the phone is registered, a reset code has been sent"
+
+	profile, err := h.queries.GetProfileByPhone(c, utils.NullString(req.Phone))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			slog.Warn("password reset for unknown phone", "phone", req.Phone)
+			c.JSON(http.StatusOK, gin.H{"message": sent})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get profile by phone", "phone", req.Phone, "error", err)
+		return
+	}
+
+	if h.otpCooldown(c, profile.ID, otpPurposePasswordReset) > 0 {
+		slog.Warn("password reset otp cooldown", "profile_id", profile.ID)
+		c.JSON(http.StatusOK, gin.H{"message": sent})
+		return
+	}
+	if !h.issueOTP(c, profile, otpPurposePasswordReset) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": sent})
+	slog.Info("password reset otp sent", "profile_id", profile.ID)
+}
+
+type resetPasswordReq struct {
+	Phone       string `json:"phone" binding:"required"`
+	OTP         string `json:"otp" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ResetPassword verifies a reset OTP and sets a new password, revoking all sessions.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone, otp and new_password required"})
+		return
+	}
+	req.Phone = phone.NormalizeOrTrim(req.Phone)
+
+	profile, err := h.queries.GetProfileByPhone(c, utils.NullString(req.Phone))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid otp or phone"})
+		return
+	}
+
+	// check the policy before spending the code so a weak password can be retried
+	if err := h.passwords.Validate(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := h.checkOTP(c, profile.ID, otpPurposePasswordReset, req.OTP); !ok {
+		return
+	}
+
+	if !h.setPassword(c, profile.ID, req.NewPassword) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset, please log in again"})
+	slog.Info("password reset", "profile_id", profile.ID)
+}
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword requires the current password. Other sessions are revoked and
+// a fresh token is returned for the caller.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req changePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "current_password and new_password required"})
+		return
+	}
+
+	profileID := int32(middleware.ProfileID(c))
+	profile, err := h.queries.GetProfileByID(c, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get profile", "profile_id", profileID, "error", err)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(profile.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		return
+	}
+	if req.CurrentPassword == req.NewPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new password must differ from the current one"})
+		return
+	}
+	if err := h.passwords.Validate(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.setPassword(c, profile.ID, req.NewPassword) {
+		return
+	}
+
+	token, err := utils.GenerateJWT(int64(profile.ID), profile.TokenVersion+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed", "token": token})
+	slog.Info("password changed", "profile_id", profile.ID)
+}
+
+// setPassword hashes and stores the new password. The update bumps
+// token_version, which invalidates every JWT issued so far.
+func (h *AuthHandler) setPassword(c *gin.Context, profileID int32, newPassword string) bool {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		slog.Error("failed to hash password", "error", err)
+		return false
+	}
+
+	if err := h.queries.UpdateProfilePassword(c, db.UpdateProfilePasswordParams{
+		PasswordHash: string(hashed),
+		ID:           profileID,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
+		slog.Error("failed to update password", "profile_id", profileID, "error", err)
+		return false
+	}
+	return true
+}
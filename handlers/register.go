@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 
 	db "tickets/db/sqlc"
+	"tickets/phone"
+	"tickets/utils"
 )
 
+// registerReq needs a phone: login codes are sent by SMS, so a profile
+// without one could never finish logging in. Email is optional and can be
+// used to log in alongside the phone.
 type registerReq struct {
 	FullName string `json:"full_name" binding:"required"`
 	Phone    string `json:"phone" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
 	Password string `json:"password" binding:"required"`
 }
 
@@ -24,17 +32,25 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		slog.Error("invalid register request", "error", err)
 		return
 	}
+	req.Email = strings.ToLower(req.Email)
+	p, err := phone.Normalize(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone number"})
+		return
+	}
+	req.Phone = p
 
 	// ✅ Check if profile already exists
-	_, err := h.queries.GetProfileByPhone(c, req.Phone)
-	if err != nil && err != sql.ErrNoRows {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
-		slog.Error("failed to check existing profile", "phone", req.Phone, "error", err)
+	if !h.profileAvailable(c, h.queries.GetProfileByPhone, req.Phone) {
 		return
 	}
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "profile already exists"})
-		slog.Warn("profile already exists", "phone", req.Phone)
+	if req.Email != "" && !h.profileAvailable(c, h.queries.GetProfileByEmail, req.Email) {
+		return
+	}
+
+	// ✅ Enforce password policy
+	if err := h.passwords.Validate(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -49,12 +65,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// ✅ Insert into DB
 	profile, err := h.queries.CreateProfile(c, db.CreateProfileParams{
 		FullName:     sql.NullString{String: req.FullName, Valid: req.FullName != ""},
-		Phone:        req.Phone,
+		Phone:        utils.NullString(req.Phone),
+		Email:        utils.NullString(req.Email),
 		PasswordHash: string(hashed),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create profile"})
-		slog.Error("failed to create profile", "phone", req.Phone, "error", err)
+		slog.Error("failed to create profile", "phone", req.Phone, "email", req.Email, "error", err)
 		return
 	}
 
@@ -63,3 +80,20 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		"profile": profile,
 	})
 }
+
+// profileAvailable writes a 409 (or 500) and returns false if lookup finds an
+// existing profile for value.
+func (h *AuthHandler) profileAvailable(c *gin.Context, lookup func(context.Context, sql.NullString) (db.Profile, error), value string) bool {
+	_, err := lookup(c, utils.NullString(value))
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to check existing profile", "value", value, "error", err)
+		return false
+	}
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "profile already exists"})
+		slog.Warn("profile already exists", "value", value)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/utils"
+)
+
+// CreateStreamTicket issues a ticket for opening /ws or /events/stream,
+// which browsers cannot send an Authorization header to. The ticket is
+// passed as ?ticket= and must be used within utils.StreamTicketTTL. It stays
+// valid for utils.StreamTicketTTL after each connection made with it opens or
+// closes, so an EventSource reconnects, with Last-Event-ID, on the same URL;
+// once it lapses the client fetches a new one. It only ever authenticates
+// stream connections of the same profile, and stops working when the
+// profile's sessions are revoked.
+func (h *AuthHandler) CreateStreamTicket(c *gin.Context) {
+	profileID := int32(middleware.ProfileID(c))
+	profile, err := h.queries.GetProfileByID(c, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get profile", "profile_id", profileID, "error", err)
+		return
+	}
+
+	if err := h.queries.DeleteExpiredStreamTickets(c, time.Now()); err != nil {
+		slog.Error("failed to delete expired stream tickets", "error", err)
+	}
+
+	ticket, err := utils.GenerateStreamTicket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ticket"})
+		return
+	}
+	if err := h.queries.CreateStreamTicket(c, db.CreateStreamTicketParams{
+		TicketHash:   utils.HashStreamTicket(ticket),
+		ProfileID:    profile.ID,
+		TokenVersion: profile.TokenVersion,
+		ExpiresAt:    time.Now().Add(utils.StreamTicketTTL),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ticket"})
+		slog.Error("failed to create stream ticket", "profile_id", profile.ID, "error", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ticket":     ticket,
+		"expires_in": int(utils.StreamTicketTTL.Seconds()),
+	})
+}
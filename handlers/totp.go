@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tickets/db/sqlc"
+	"tickets/middleware"
+	"tickets/totp"
+	"tickets/utils"
+)
+
+const recoveryCodeCount = 10
+
+// EnrollTOTP creates (or replaces an unconfirmed) authenticator secret and
+// returns what the client needs to render a QR code.
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	profileID := int32(middleware.ProfileID(c))
+	profile, err := h.queries.GetProfileByID(c, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get profile", "profile_id", profileID, "error", err)
+		return
+	}
+
+	existing, err := h.queries.GetTOTPSecret(c, profileID)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get totp secret", "profile_id", profileID, "error", err)
+		return
+	}
+	if err == nil && existing.ConfirmedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "authenticator already enrolled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+	enc, err := utils.Encrypt(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt secret"})
+		slog.Error("failed to encrypt totp secret", "error", err)
+		return
+	}
+
+	if err := h.queries.UpsertTOTPSecret(c, db.UpsertTOTPSecretParams{
+		ProfileID: profileID,
+		SecretEnc: enc,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save secret"})
+		slog.Error("failed to save totp secret", "profile_id", profileID, "error", err)
+		return
+	}
+
+	account := profile.Phone.String
+	if account == "" {
+		account = profile.Email.String
+	}
+	uri := totp.ProvisioningURI(h.totpIssuer, account, secret)
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+		"qr_payload":       uri,
+		"message":          "scan the code and confirm with a code from your app",
+	})
+	slog.Info("totp enrollment started", "profile_id", profileID)
+}
+
+type confirmTOTPReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ConfirmTOTP activates a pending secret once the user proves their app
+// produces valid codes, and returns one-time recovery codes.
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	var req confirmTOTPReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code required"})
+		return
+	}
+
+	profileID := int32(middleware.ProfileID(c))
+	rec, err := h.queries.GetTOTPSecret(c, profileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no pending enrollment"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get totp secret", "profile_id", profileID, "error", err)
+		return
+	}
+	if rec.ConfirmedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "authenticator already enrolled"})
+		return
+	}
+
+	secret, err := utils.Decrypt(rec.SecretEnc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to decrypt totp secret", "profile_id", profileID, "error", err)
+		return
+	}
+	step, ok := totp.Validate(secret, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate recovery codes"})
+		return
+	}
+
+	tx, err := h.db.BeginTx(c, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to begin tx", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	if _, err := qtx.UpdateTOTPLastUsedStep(c, db.UpdateTOTPLastUsedStepParams{Step: step, ProfileID: profileID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm authenticator"})
+		slog.Error("failed to update totp step", "profile_id", profileID, "error", err)
+		return
+	}
+	if err := qtx.ConfirmTOTPSecret(c, profileID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm authenticator"})
+		slog.Error("failed to confirm totp", "profile_id", profileID, "error", err)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(c, profileID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save recovery codes"})
+		slog.Error("failed to delete recovery codes", "profile_id", profileID, "error", err)
+		return
+	}
+	for _, code := range codes {
+		if err := qtx.CreateRecoveryCode(c, db.CreateRecoveryCodeParams{
+			ProfileID: profileID,
+			CodeHash:  hashRecoveryCode(code),
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save recovery codes"})
+			slog.Error("failed to create recovery code", "profile_id", profileID, "error", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm authenticator"})
+		slog.Error("failed to commit totp confirmation", "profile_id", profileID, "error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "authenticator enabled; store these recovery codes somewhere safe, they are only shown once",
+		"recovery_codes": codes,
+	})
+	slog.Info("totp enrollment confirmed", "profile_id", profileID)
+}
+
+// confirmedTOTP returns the decrypted secret and true if the profile has a
+// confirmed authenticator. A failure to read or decrypt the secret must not
+// quietly fall back to SMS, so it writes a 500 and returns ok false.
+func (h *AuthHandler) confirmedTOTP(c *gin.Context, profileID int32) (secret string, enrolled, ok bool) {
+	rec, err := h.queries.GetTOTPSecret(c, profileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, true
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to get totp secret", "profile_id", profileID, "error", err)
+		return "", false, false
+	}
+	if !rec.ConfirmedAt.Valid {
+		return "", false, true
+	}
+	secret, err = utils.Decrypt(rec.SecretEnc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		slog.Error("failed to decrypt totp secret", "profile_id", profileID, "error", err)
+		return "", false, false
+	}
+	return secret, true, true
+}
+
+// startTOTPLogin records a login challenge after a correct password, so that
+// /verify_otp can't be used with just a phone and an authenticator code.
+func (h *AuthHandler) startTOTPLogin(c *gin.Context, profileID int32) bool {
+	nonce, err := generateOTP()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start login"})
+		return false
+	}
+	if _, err := h.queries.CreateOTP(c, db.CreateOTPParams{
+		ProfileID: profileID,
+		OtpCode:   nonce,
+		Purpose:   otpPurposeTOTPLogin,
+		ExpiresAt: time.Now().Add(h.otpTTL),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start login"})
+		slog.Error("failed to create totp login challenge", "profile_id", profileID, "error", err)
+		return false
+	}
+	return true
+}
+
+// verifyTOTPLogin accepts either a current authenticator code or an unused
+// recovery code against the pending login challenge.
+func (h *AuthHandler) verifyTOTPLogin(c *gin.Context, profileID int32, secret, code string) bool {
+	code = strings.TrimSpace(code)
+	_, ok := h.useOTP(c, profileID, otpPurposeTOTPLogin, func(db.OtpCode) bool {
+		if step, ok := totp.Validate(secret, code, time.Now()); ok {
+			n, err := h.queries.UpdateTOTPLastUsedStep(c, db.UpdateTOTPLastUsedStepParams{Step: step, ProfileID: profileID})
+			if err != nil {
+				log.Println("failed update totp step:", err)
+				return false
+			}
+			// zero rows means this step was already used
+			return n == 1
+		}
+
+		n, err := h.queries.UseRecoveryCode(c, db.UseRecoveryCodeParams{
+			ProfileID: profileID,
+			CodeHash:  hashRecoveryCode(code),
+		})
+		if err != nil {
+			log.Println("failed use recovery code:", err)
+			return false
+		}
+		if n == 1 {
+			slog.Warn("recovery code used", "profile_id", profileID)
+		}
+		return n == 1
+	})
+	return ok
+}
+
+// generateRecoveryCodes returns n codes formatted as XXXXX-XXXXX.
+func generateRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		var b [10]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		for j, v := range b {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(alphabet[int(v)%len(alphabet)])
+		}
+		codes = append(codes, sb.String())
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes case and separators before hashing. Codes have
+// enough entropy that a plain SHA-256 is sufficient.
+func hashRecoveryCode(code string) string {
+	norm := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:])
+}
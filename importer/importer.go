@@ -0,0 +1,275 @@
+// Package importer bulk-loads customers and users from CSV. Rows are read
+// one at a time, validated with the same rules as the create endpoints and
+// upserted by email in batches, each batch in its own DB transaction. Rows
+// that fail are reported and skipped; they do not stop the import.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Kind string
+
+const (
+	KindCustomers Kind = "customers"
+	KindUsers     Kind = "users"
+)
+
+const (
+	DefaultBatchSize = 100
+	// maxReportedErrors caps the row errors kept in a report.
+	maxReportedErrors = 1000
+	// batchAttempts is how often a batch is tried when it fails with a
+	// transient error.
+	batchAttempts = 3
+)
+
+// rowErrors are the MySQL errors caused by a row's data, which fail that
+// row only: a NULL, a duplicate key, a number out of range, an invalid or a
+// too long value.
+var rowErrors = map[uint16]bool{1048: true, 1062: true, 1264: true, 1366: true, 1406: true}
+
+// transientErrors are the MySQL errors after which the batch is tried
+// again: a deadlock, which rolls the whole transaction back, and a lock
+// wait timeout.
+var transientErrors = map[uint16]bool{1205: true, 1213: true}
+
+// Options configure Run. In a dry run every batch is written and then rolled
+// back, so the report shows exactly what the import would do.
+type Options struct {
+	Kind      Kind
+	DryRun    bool
+	BatchSize int
+	// Progress, if set, is called after each batch with the rows read so far.
+	Progress func(rows int)
+}
+
+// RowError is why one row was not imported. Row is the line in the file.
+type RowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+type Report struct {
+	Kind            Kind       `json:"kind"`
+	DryRun          bool       `json:"dry_run"`
+	Rows            int        `json:"rows"`
+	Created         int        `json:"created"`
+	Updated         int        `json:"updated"`
+	Failed          int        `json:"failed"`
+	Errors          []RowError `json:"errors"`
+	ErrorsTruncated bool       `json:"errors_truncated,omitempty"`
+}
+
+func (r *Report) fail(e RowError) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, e)
+	} else {
+		r.ErrorsTruncated = true
+	}
+}
+
+// ErrBadFile is wrapped by errors about the file as a whole, such as a
+// missing column, as opposed to failures of the database.
+var ErrBadFile = errors.New("invalid import file")
+
+// Valid reports whether k is a known kind of import.
+func Valid(k Kind) bool {
+	_, ok := specs[k]
+	return ok
+}
+
+type pending struct {
+	line int
+	row  any
+}
+
+// Run imports the CSV in r. The first line is a header naming the columns:
+// full_name, email and phone_number for customers; full_name, email and
+// optionally phone and role for users. Other columns are ignored. An error
+// is returned only when the import could not go on; rows imported by
+// earlier batches stay imported.
+func Run(ctx context.Context, conn *sql.DB, q *db.Queries, r io.Reader, opts Options) (*Report, error) {
+	sp, ok := specs[opts.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrBadFile, opts.Kind)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrBadFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadFile, err)
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, col := range sp.columns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrBadFile, col)
+		}
+	}
+
+	columns := append(append([]string{}, sp.columns...), sp.optional...)
+	report := &Report{Kind: opts.Kind, DryRun: opts.DryRun, Errors: []RowError{}}
+	seen := map[string]int{} // "column=value" -> line it first appeared on
+	var batch []pending
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return report, err
+			}
+			report.Rows++
+			report.fail(RowError{Row: pe.Line, Error: pe.Err.Error()})
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // blank line
+		}
+		report.Rows++
+
+		fields := map[string]string{}
+		for _, col := range columns {
+			if i, ok := index[col]; ok && i < len(record) {
+				fields[col] = record[i]
+			}
+		}
+		row, rerr := sp.parse(fields)
+		if rerr != nil {
+			rerr.Row = line
+			report.fail(*rerr)
+			continue
+		}
+		if dup := duplicateOf(seen, sp.keys(row), line); dup != nil {
+			report.fail(*dup)
+			continue
+		}
+
+		batch = append(batch, pending{line: line, row: row})
+		if len(batch) == opts.BatchSize {
+			if err := writeBatch(ctx, conn, q, sp, batch, report, opts.DryRun); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+			if opts.Progress != nil {
+				opts.Progress(report.Rows)
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := writeBatch(ctx, conn, q, sp, batch, report, opts.DryRun); err != nil {
+			return report, err
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(report.Rows)
+	}
+	return report, nil
+}
+
+// duplicateOf records the row's unique values and returns an error if one
+// of them appeared on an earlier line.
+func duplicateOf(seen map[string]int, keys map[string]string, line int) *RowError {
+	for col, v := range keys {
+		if first, ok := seen[col+"="+v]; ok {
+			return &RowError{Row: line, Field: col, Error: fmt.Sprintf("duplicate of line %d", first)}
+		}
+	}
+	for col, v := range keys {
+		seen[col+"="+v] = line
+	}
+	return nil
+}
+
+// writeBatch writes a batch in one transaction, trying it again from the
+// start after a transient error.
+func writeBatch(ctx context.Context, conn *sql.DB, q *db.Queries, sp spec, batch []pending, report *Report, dryRun bool) error {
+	for attempt := 1; ; attempt++ {
+		err := tryBatch(ctx, conn, q, sp, batch, report, dryRun)
+		var me *mysql.MySQLError
+		if err == nil || attempt == batchAttempts || !errors.As(err, &me) || !transientErrors[me.Number] {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+		}
+	}
+}
+
+// tryBatch adds to report only once the batch went in.
+func tryBatch(ctx context.Context, conn *sql.DB, q *db.Queries, sp spec, batch []pending, report *Report, dryRun bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+
+	var created, updated int
+	var failed []RowError
+	for _, p := range batch {
+		ok, rerr, err := sp.upsert(ctx, qtx, p.row)
+		if err != nil {
+			// MySQL rejecting the row's data (a unique key, a value too
+			// long) is the row's problem; a failed statement does not
+			// abort the transaction, so the rest of the batch can still
+			// go in. Any other error stops the batch.
+			var me *mysql.MySQLError
+			if !errors.As(err, &me) || !rowErrors[me.Number] {
+				return err
+			}
+			rerr = &RowError{Error: me.Message}
+		}
+		if rerr != nil {
+			rerr.Row = p.line
+			failed = append(failed, *rerr)
+			continue
+		}
+		if ok {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	for _, e := range failed {
+		report.fail(e)
+	}
+	report.Created += created
+	report.Updated += updated
+	return nil
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	db "tickets/db/sqlc"
+	"tickets/phone"
+	"tickets/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// "phone" accepts what phone.Normalize does; Normalize methods then
+	// store the number in E.164 form
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+			return phone.Valid(fl.Field().String())
+		})
+	}
+}
+
+// CustomerRow is a customer as created through the API or imported.
+// POST /customers binds the same struct, so both apply the same rules.
+type CustomerRow struct {
+	FullName    string `json:"full_name" binding:"required,max=255"`
+	Email       string `json:"email" binding:"required,email,max=255"`
+	PhoneNumber string `json:"phone_number" binding:"required,max=20,phone"`
+	// Segment groups customers for ticket routing: enterprise, sme, ...
+	Segment string `json:"segment" binding:"max=50"`
+}
+
+func (r *CustomerRow) Normalize() {
+	r.FullName = strings.TrimSpace(r.FullName)
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	r.PhoneNumber = phone.NormalizeOrTrim(r.PhoneNumber)
+	r.Segment = strings.ToLower(strings.TrimSpace(r.Segment))
+}
+
+// UserRow is an imported user, validated like CreateUserRequest. Role only
+// applies to new users; an import never changes an existing user's role.
+type UserRow struct {
+	FullName string `json:"full_name" binding:"required,max=100"`
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Phone    string `json:"phone" binding:"omitempty,max=40,phone"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin agent customer"`
+}
+
+func (r *UserRow) Normalize() {
+	r.FullName = strings.TrimSpace(r.FullName)
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	r.Phone = phone.NormalizeOrTrim(r.Phone)
+	r.Role = strings.ToLower(strings.TrimSpace(r.Role))
+}
+
+// spec is what Run needs to know about one kind of import.
+type spec struct {
+	columns  []string // required CSV columns, in the row struct's json names
+	optional []string
+	// parse builds and validates a row from the named columns
+	parse func(fields map[string]string) (any, *RowError)
+	// keys are the values that must be unique across the file
+	keys func(row any) map[string]string
+	// upsert writes one row and reports whether it was created
+	upsert func(ctx context.Context, q *db.Queries, row any) (created bool, rerr *RowError, err error)
+}
+
+var specs = map[Kind]spec{
+	KindCustomers: {
+		columns:  []string{"full_name", "email", "phone_number"},
+		optional: []string{"segment"},
+		parse: func(f map[string]string) (any, *RowError) {
+			row := CustomerRow{FullName: f["full_name"], Email: f["email"], PhoneNumber: f["phone_number"], Segment: f["segment"]}
+			row.Normalize()
+			return row, validate(&row)
+		},
+		keys: func(row any) map[string]string {
+			r := row.(CustomerRow)
+			return map[string]string{"email": r.Email, "phone_number": r.PhoneNumber}
+		},
+		upsert: upsertCustomer,
+	},
+	KindUsers: {
+		columns:  []string{"full_name", "email"},
+		optional: []string{"phone", "role"},
+		parse: func(f map[string]string) (any, *RowError) {
+			row := UserRow{FullName: f["full_name"], Email: f["email"], Phone: f["phone"], Role: f["role"]}
+			row.Normalize()
+			return row, validate(&row)
+		},
+		keys: func(row any) map[string]string {
+			r := row.(UserRow)
+			keys := map[string]string{"email": r.Email}
+			if r.Phone != "" {
+				keys["phone"] = r.Phone
+			}
+			return keys
+		},
+		upsert: upsertUser,
+	},
+}
+
+// upsertCustomer updates the customer with the row's email, or creates one.
+// An empty segment keeps an existing customer's segment.
+func upsertCustomer(ctx context.Context, q *db.Queries, row any) (bool, *RowError, error) {
+	r := row.(CustomerRow)
+	existing, err := q.GetCustomerByEmail(ctx, r.Email)
+	if err != nil && err != sql.ErrNoRows {
+		return false, nil, err
+	}
+	found := err == nil
+
+	other, err := q.GetCustomerByPhone(ctx, r.PhoneNumber)
+	if err == nil && (!found || other.ID != existing.ID) {
+		return false, &RowError{Field: "phone_number", Error: fmt.Sprintf("already used by customer %d", other.ID)}, nil
+	} else if err != nil && err != sql.ErrNoRows {
+		return false, nil, err
+	}
+
+	if found {
+		segment := existing.Segment
+		if r.Segment != "" {
+			segment = r.Segment
+		}
+		return false, nil, q.UpdateCustomer(ctx, db.UpdateCustomerParams{
+			FullName:    r.FullName,
+			Email:       r.Email,
+			PhoneNumber: r.PhoneNumber,
+			Segment:     segment,
+			ID:          existing.ID,
+		})
+	}
+	_, err = q.CreateCustomer(ctx, db.CreateCustomerParams{
+		FullName:    r.FullName,
+		Email:       r.Email,
+		PhoneNumber: r.PhoneNumber,
+		Segment:     r.Segment,
+	})
+	return true, nil, err
+}
+
+// upsertUser updates the name and phone of the profile with the row's
+// email, or creates one with the row's role (default customer).
+func upsertUser(ctx context.Context, q *db.Queries, row any) (bool, *RowError, error) {
+	r := row.(UserRow)
+	existing, err := q.GetProfileByEmail(ctx, utils.NullString(r.Email))
+	if err != nil && err != sql.ErrNoRows {
+		return false, nil, err
+	}
+	found := err == nil
+
+	if r.Phone != "" {
+		other, err := q.GetProfileByPhone(ctx, utils.NullString(r.Phone))
+		if err == nil && (!found || other.ID != existing.ID) {
+			return false, &RowError{Field: "phone", Error: fmt.Sprintf("already used by user %d", other.ID)}, nil
+		} else if err != nil && err != sql.ErrNoRows {
+			return false, nil, err
+		}
+	}
+
+	if found {
+		// empty columns and the role keep their current value
+		return false, nil, q.UpdateUser(ctx, db.UpdateUserParams{
+			FullName: utils.NullString(r.FullName),
+			Email:    utils.NullString(r.Email),
+			Phone:    utils.NullString(r.Phone),
+			ID:       existing.ID,
+		})
+	}
+	role := db.ProfilesRoleCustomer
+	if r.Role != "" {
+		role = db.ProfilesRole(r.Role)
+	}
+	_, err = q.CreateUser(ctx, db.CreateUserParams{
+		FullName: utils.NullString(r.FullName),
+		Email:    utils.NullString(r.Email),
+		Phone:    utils.NullString(r.Phone),
+		Role:     role,
+	})
+	return true, nil, err
+}
+
+// validate applies the struct's binding rules, as gin does for requests, and
+// describes the first failure.
+func validate(row any) *RowError {
+	err := binding.Validator.ValidateStruct(row)
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) == 0 {
+		return &RowError{Error: err.Error()}
+	}
+	fe := errs[0]
+	field := fe.Field()
+	if sf, ok := reflect.TypeOf(row).Elem().FieldByName(fe.StructField()); ok {
+		field = sf.Tag.Get("json")
+	}
+	var msg string
+	switch fe.Tag() {
+	case "required":
+		msg = "is required"
+	case "email":
+		msg = "must be a valid email address"
+	case "min":
+		msg = "must be at least " + fe.Param() + " characters"
+	case "max":
+		msg = "must be at most " + fe.Param() + " characters"
+	case "oneof":
+		msg = "must be one of " + fe.Param()
+	case "phone":
+		msg = "must be a valid phone number"
+	default:
+		msg = "is invalid (" + fe.Tag() + ")"
+	}
+	return &RowError{Field: field, Error: msg}
+}
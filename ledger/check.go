@@ -0,0 +1,107 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/payments"
+)
+
+// Check looks for broken ledger invariants and returns one line per problem.
+// An empty result means the ledger is consistent.
+func Check(ctx context.Context, q *db.Queries) ([]string, error) {
+	var problems []string
+
+	unbalanced, err := q.ListUnbalancedEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range unbalanced {
+		problems = append(problems, fmt.Sprintf("entry %d: %s postings sum to %d, not 0", e.EntryID, e.Currency, e.Total))
+	}
+
+	short, err := q.ListEntriesWithTooFewPostings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range short {
+		problems = append(problems, fmt.Sprintf("entry %d: has %d postings, needs at least 2", e.ID, e.Postings))
+	}
+
+	mismatched, err := q.ListPostingCurrencyMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range mismatched {
+		problems = append(problems, fmt.Sprintf("posting %d (entry %d): %s posted to a %s account",
+			p.ID, p.EntryID, p.Currency, p.AccountCurrency))
+	}
+
+	unposted, err := q.ListUnpostedTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range unposted {
+		problems = append(problems, fmt.Sprintf("transaction %d (%s): %s %s has no journal entry",
+			t.ID, t.TransactionID, payments.StatusName(t.Status), t.Kind))
+	}
+
+	return problems, nil
+}
+
+// Backfill posts journal entries for settled transactions that have none,
+// such as those created before the ledger existed. Each transaction is
+// posted in its own database transaction. It returns how many were posted.
+func Backfill(ctx context.Context, conn *sql.DB, q *db.Queries) (int, error) {
+	unposted, err := q.ListUnpostedTransactions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, u := range unposted {
+		if err := backfillOne(ctx, conn, q, u.ID); err != nil {
+			return n, fmt.Errorf("transaction %d: %w", u.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func backfillOne(ctx context.Context, conn *sql.DB, q *db.Queries, id int32) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+
+	t, err := qtx.GetTransactionForUpdate(ctx, id)
+	if err != nil {
+		return err
+	}
+	at := time.Now()
+	if t.CreatedAt.Valid {
+		at = t.CreatedAt.Time
+	}
+
+	if t.Kind == db.TransactionsKindRefund {
+		err = RecordRefund(ctx, qtx, t, at)
+	} else {
+		err = RecordPayment(ctx, qtx, t, at)
+		if err == nil && t.Status == payments.StatusReversed {
+			reversedAt := at
+			if t.UpdatedAt.Valid {
+				reversedAt = t.UpdatedAt.Time
+			}
+			err = RecordReversal(ctx, qtx, t, reversedAt)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
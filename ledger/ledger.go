@@ -0,0 +1,188 @@
+// Package ledger keeps a double-entry record of every settled transaction.
+//
+// Each journal entry has postings whose amounts sum to zero per currency.
+// Debits are positive and credits negative, in minor units. Money received
+// from a customer is debited to the clearing account of the payment method
+// (an asset) and credited to the customer's account (a liability: what we
+// hold for them); refunds and reversals post the opposite.
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/money"
+	"tickets/payments"
+)
+
+var ErrUnbalanced = errors.New("journal entry does not balance")
+
+// Account identifies a ledger account; it is created on first use.
+type Account struct {
+	Code      string
+	Name      string
+	Type      db.LedgerAccountsType
+	ProfileID int32 // 0 when the account does not belong to a profile
+}
+
+// Posting debits (positive) or credits (negative) Amount to Account.
+type Posting struct {
+	Account Account
+	Amount  money.Money
+}
+
+type Entry struct {
+	TransactionID int32 // 0 for entries not tied to a transaction
+	Description   string
+	OccurredAt    time.Time
+	Postings      []Posting
+}
+
+// ClearingAccount holds money received through a payment method until it is settled.
+func ClearingAccount(method, currency string) Account {
+	if method == "" {
+		method = "unknown"
+	}
+	return Account{
+		Code: fmt.Sprintf("clearing:%s:%s", method, currency),
+		Name: fmt.Sprintf("%s clearing (%s)", method, currency),
+		Type: db.LedgerAccountsTypeAsset,
+	}
+}
+
+// CustomerAccount is what we hold on behalf of a profile.
+func CustomerAccount(profileID int32, currency string) Account {
+	return Account{
+		Code:      fmt.Sprintf("customer:%d:%s", profileID, currency),
+		Name:      fmt.Sprintf("Customer %d (%s)", profileID, currency),
+		Type:      db.LedgerAccountsTypeLiability,
+		ProfileID: profileID,
+	}
+}
+
+// Post validates and writes an entry. q must be bound to the same database
+// transaction as the change being recorded so that both commit or neither does.
+func Post(ctx context.Context, q *db.Queries, e Entry) (int64, error) {
+	if len(e.Postings) < 2 {
+		return 0, fmt.Errorf("%w: %d postings", ErrUnbalanced, len(e.Postings))
+	}
+	totals := map[string]int64{}
+	for _, p := range e.Postings {
+		sum, err := money.Money{Amount: totals[p.Amount.Currency], Currency: p.Amount.Currency}.Add(p.Amount)
+		if err != nil {
+			return 0, err
+		}
+		totals[p.Amount.Currency] = sum.Amount
+	}
+	for cur, total := range totals {
+		if total != 0 {
+			return 0, fmt.Errorf("%w: %s off by %d minor units", ErrUnbalanced, cur, total)
+		}
+	}
+
+	result, err := q.CreateJournalEntry(ctx, db.CreateJournalEntryParams{
+		TransactionID: sql.NullInt32{Int32: e.TransactionID, Valid: e.TransactionID != 0},
+		Description:   e.Description,
+		OccurredAt:    e.OccurredAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	entryID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range e.Postings {
+		accountID, err := ensureAccount(ctx, q, p.Account, p.Amount.Currency)
+		if err != nil {
+			return 0, err
+		}
+		if err := q.CreatePosting(ctx, db.CreatePostingParams{
+			EntryID:     entryID,
+			AccountID:   accountID,
+			AmountMinor: p.Amount.Amount,
+			Currency:    p.Amount.Currency,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return entryID, nil
+}
+
+func ensureAccount(ctx context.Context, q *db.Queries, a Account, currency string) (int64, error) {
+	if err := q.EnsureLedgerAccount(ctx, db.EnsureLedgerAccountParams{
+		Code:      a.Code,
+		Name:      a.Name,
+		Type:      a.Type,
+		Currency:  currency,
+		ProfileID: sql.NullInt32{Int32: a.ProfileID, Valid: a.ProfileID != 0},
+	}); err != nil {
+		return 0, err
+	}
+	acc, err := q.GetLedgerAccountByCode(ctx, a.Code)
+	if err != nil {
+		return 0, err
+	}
+	if acc.Currency != currency {
+		return 0, fmt.Errorf("account %s is in %s, not %s", a.Code, acc.Currency, currency)
+	}
+	return acc.ID, nil
+}
+
+// RecordPayment posts a completed payment.
+func RecordPayment(ctx context.Context, q *db.Queries, t db.Transaction, at time.Time) error {
+	amount := t.Money()
+	_, err := Post(ctx, q, Entry{
+		TransactionID: t.ID,
+		Description:   "payment " + t.TransactionID,
+		OccurredAt:    at,
+		Postings: []Posting{
+			{Account: ClearingAccount(t.PaymentMethod.String, amount.Currency), Amount: amount},
+			{Account: CustomerAccount(t.UserID, amount.Currency), Amount: amount.Neg()},
+		},
+	})
+	return err
+}
+
+// RecordRefund posts a refund row, which carries the parent payment's
+// method and user.
+func RecordRefund(ctx context.Context, q *db.Queries, refund db.Transaction, at time.Time) error {
+	return postReturn(ctx, q, refund, "refund "+refund.TransactionID, at)
+}
+
+// RecordReversal posts the full reversal of a completed payment.
+func RecordReversal(ctx context.Context, q *db.Queries, t db.Transaction, at time.Time) error {
+	return postReturn(ctx, q, t, "reversal "+t.TransactionID, at)
+}
+
+func postReturn(ctx context.Context, q *db.Queries, t db.Transaction, desc string, at time.Time) error {
+	amount := t.Money()
+	_, err := Post(ctx, q, Entry{
+		TransactionID: t.ID,
+		Description:   desc,
+		OccurredAt:    at,
+		Postings: []Posting{
+			{Account: CustomerAccount(t.UserID, amount.Currency), Amount: amount},
+			{Account: ClearingAccount(t.PaymentMethod.String, amount.Currency), Amount: amount.Neg()},
+		},
+	})
+	return err
+}
+
+// RecordStatusChange posts whatever a payment moving to status requires:
+// completed posts the payment and reversed undoes it. Refund states are
+// posted by RecordRefund against the refund row instead.
+func RecordStatusChange(ctx context.Context, q *db.Queries, t db.Transaction, status int16, at time.Time) error {
+	switch status {
+	case payments.StatusCompleted:
+		return RecordPayment(ctx, q, t, at)
+	case payments.StatusReversed:
+		return RecordReversal(ctx, q, t, at)
+	}
+	return nil
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
 	"os"
+	"strings"
+	"time"
 
 	"io"
 	"tickets/config"
 	"tickets/controllers"
 	db "tickets/db/sqlc"
+	"tickets/payments"
 	"tickets/publish"
+	"tickets/realtime"
 	"tickets/sms"
 
 	"tickets/handlers"
+	"tickets/middleware"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
@@ -68,27 +74,146 @@ func main() {
 	uc := &controllers.UserController{Queries: queries, DB: dbConn}
 	ct := &controllers.TransactionsController{Queries: queries, DB: dbConn}
 	custc := &controllers.CustomerController{Queries: queries, DB: dbConn}
+	akc := &controllers.APIKeyController{Queries: queries, DB: dbConn}
+	lc := &controllers.LedgerController{Queries: queries, DB: dbConn}
+	rcc := &controllers.ReconciliationController{Queries: queries, DB: dbConn}
+	imc := &controllers.ImportController{Queries: queries, DB: dbConn}
+	go imc.FailStaleImportJobs(5 * time.Minute)
+	prc := &controllers.PrivacyController{Queries: queries, DB: dbConn}
+	wc := &controllers.WebhookController{Queries: queries, DB: dbConn}
+	teamc := &controllers.TeamController{Queries: queries, DB: dbConn}
+	agc := &controllers.AgentController{Queries: queries, DB: dbConn}
+	// every replica follows the event streams itself, so WebSocket and SSE
+	// clients get the same updates whichever replica they are connected to
+	hub := realtime.NewHub()
+	go hub.Run(context.Background(), "ticket_events")
+	go hub.Run(context.Background(), "transaction_events")
+	rtc := &controllers.RealtimeController{Queries: queries, Hub: hub}
+	pc := &controllers.PaymentsController{Queries: queries, DB: dbConn, Mpesa: payments.NewMpesaClient()}
+	if os.Getenv("MPESA_CALLBACK_TOKEN") == "" {
+		slog.Warn("MPESA_CALLBACK_TOKEN is not set; M-Pesa callbacks will be refused")
+	}
 	tw := sms.NewTwilioProvider()
-	auth := handlers.NewAuthHandler(queries, tw)
+	auth := handlers.NewAuthHandler(dbConn, queries, tw)
+
+	// Rate limits for the SMS-sending and credential endpoints
+	limits := middleware.NewMemoryStore()
+	sendOTPLimit := middleware.NewRateLimiter(limits, "send_otp",
+		middleware.Per(200, time.Minute), middleware.Per(10, 10*time.Minute), middleware.Per(5, time.Hour))
+	verifyOTPLimit := middleware.NewRateLimiter(limits, "verify_otp",
+		middleware.Per(500, time.Minute), middleware.Per(30, 10*time.Minute), middleware.Per(10, 10*time.Minute))
+	registerLimit := middleware.NewRateLimiter(limits, "register",
+		middleware.Per(100, time.Minute), middleware.Per(5, time.Hour), middleware.Per(3, time.Hour))
+	forgotLimit := middleware.NewRateLimiter(limits, "password_forgot",
+		middleware.Per(100, time.Minute), middleware.Per(10, time.Hour), middleware.Per(3, time.Hour))
+	resetLimit := middleware.NewRateLimiter(limits, "password_reset",
+		middleware.Per(200, time.Minute), middleware.Per(30, 10*time.Minute), middleware.Per(10, 10*time.Minute))
+	requireAuth := middleware.RequireAuth(queries)
 
 	// Setup Gin
 	r := gin.Default()
 
+	// ClientIP feeds the rate limits and the login history, so forwarding
+	// headers are only honoured from the proxies listed in TRUSTED_PROXIES
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		slog.Error("invalid TRUSTED_PROXIES, trusting no proxies", "error", err)
+		r.SetTrustedProxies(nil)
+	}
+
+	// Resource routes act on behalf of the profile in the JWT, or of the
+	// admin who created the API key
+	api := r.Group("/", middleware.Authenticate(queries))
+	adminOnly := middleware.RequireRole(db.ProfilesRoleAdmin)
+	staff := []db.ProfilesRole{db.ProfilesRoleAdmin, db.ProfilesRoleAgent}
+	idempotent := middleware.Idempotency(queries)
+
 	// Ticket routes
-	r.POST("/tickets", tc.CreateTicket)
-	r.GET("/tickets", tc.ListTickets)
-	r.GET("/tickets/:id", tc.GetTicket)
-	r.PUT("/tickets/:id/status", tc.UpdateTicketStatus)
-	r.POST("/users", uc.CreateUser)
-	r.GET("/users", uc.ListUsers)
-	r.POST("/updateuser/:id", uc.UpdateUser)
-	r.GET("/transactions", ct.ListTransactions)
-	r.GET("/transaction/:id", ct.GetByID)
-	r.POST("/transactions", ct.CreateTransactions)
-	r.POST("/customer", custc.CreateCustomer)
-	r.GET("/customers", custc.GetCustomers)
-	r.POST("/send_otp", auth.Login)
-	r.POST("/verify_otp", auth.VerifyOTP)
-	r.POST("/register", auth.Register)
+	api.POST("/tickets", middleware.Guard("tickets:write"), idempotent, tc.CreateTicket)
+	api.GET("/tickets", middleware.Guard("tickets:read"), tc.ListTickets)
+	api.GET("/tickets/:id", middleware.Guard("tickets:read"), tc.GetTicket)
+	api.PUT("/tickets/:id/status", middleware.Guard("tickets:write", staff...), tc.UpdateTicketStatus)
+	api.PUT("/tickets/:id/team", middleware.Guard("tickets:write", staff...), tc.UpdateTicketTeam)
+	api.GET("/tickets/:id/assignments", middleware.Guard("tickets:read", staff...), agc.ListTicketAssignments)
+	api.GET("/agents", middleware.RequireRole(staff...), agc.ListAgents)
+	api.PUT("/agents/me/status", middleware.RequireRole(staff...), agc.UpdateMyStatus)
+	api.GET("/teams", middleware.RequireRole(staff...), teamc.ListTeams)
+	api.GET("/teams/:id", middleware.RequireRole(staff...), teamc.GetTeam)
+	api.GET("/teams/:id/tickets", middleware.Guard("tickets:read", staff...), teamc.ListTeamTickets)
+	api.POST("/users", adminOnly, uc.CreateUser)
+	api.GET("/users", middleware.RequireRole(staff...), uc.ListUsers)
+	api.POST("/updateuser/:id", adminOnly, uc.UpdateUser)
+	api.POST("/admin/profiles/:id/unlock", adminOnly, auth.UnlockAccount)
+	api.POST("/admin/api-keys", adminOnly, akc.CreateAPIKey)
+	api.GET("/admin/api-keys", adminOnly, akc.ListAPIKeys)
+	api.DELETE("/admin/api-keys/:id", adminOnly, akc.RevokeAPIKey)
+	api.POST("/admin/privacy/export", adminOnly, prc.ExportSubject)
+	api.POST("/admin/privacy/erasure", adminOnly, prc.EraseSubject)
+	api.GET("/admin/privacy/requests", adminOnly, prc.ListDataSubjectRequests)
+	api.POST("/admin/teams", adminOnly, teamc.CreateTeam)
+	api.PUT("/admin/teams/:id", adminOnly, teamc.UpdateTeam)
+	api.DELETE("/admin/teams/:id", adminOnly, teamc.DeleteTeam)
+	api.POST("/admin/teams/:id/members", adminOnly, teamc.AddTeamMember)
+	api.DELETE("/admin/teams/:id/members/:profile_id", adminOnly, teamc.RemoveTeamMember)
+	api.PUT("/admin/agents/:id", adminOnly, agc.UpdateAgent)
+	api.POST("/admin/routing-rules", adminOnly, teamc.CreateRoutingRule)
+	api.GET("/admin/routing-rules", adminOnly, teamc.ListRoutingRules)
+	api.GET("/admin/routing-rules/:id", adminOnly, teamc.GetRoutingRule)
+	api.PUT("/admin/routing-rules/:id", adminOnly, teamc.UpdateRoutingRule)
+	api.DELETE("/admin/routing-rules/:id", adminOnly, teamc.DeleteRoutingRule)
+	api.GET("/transactions", middleware.Guard("transactions:read"), ct.ListTransactions)
+	api.GET("/transactions/export", middleware.Guard("transactions:read"), ct.ExportTransactions)
+	api.GET("/transactions/:id/tickets", middleware.Guard("tickets:read"), tc.ListTransactionTickets)
+	api.GET("/transaction/:id", middleware.Guard("transactions:read"), ct.GetByID)
+	api.POST("/transactions", middleware.Guard("transactions:write"), idempotent, ct.CreateTransactions)
+	api.PUT("/transactions/:id/status", middleware.Guard("transactions:write", staff...), ct.UpdateTransactionStatus)
+	api.POST("/transactions/:id/refunds", middleware.Guard("transactions:write", staff...), idempotent, ct.CreateRefund)
+	api.GET("/transactions/:id/refunds", middleware.Guard("transactions:read"), ct.ListRefunds)
+	api.GET("/ledger/accounts", middleware.Guard("transactions:read", staff...), lc.ListAccounts)
+	api.GET("/ledger/accounts/:id/balance", middleware.Guard("transactions:read", staff...), lc.GetBalance)
+	api.POST("/reconciliations", middleware.Guard("transactions:write", staff...), rcc.CreateReconciliation)
+	api.GET("/reconciliations", middleware.Guard("transactions:read", staff...), rcc.ListReconciliations)
+	api.GET("/reconciliations/:id", middleware.Guard("transactions:read", staff...), rcc.GetReconciliation)
+	api.POST("/payments/mpesa/stk-push", middleware.Guard("transactions:write"), idempotent, pc.InitiateSTKPush)
+	api.POST("/customer", middleware.Guard("customers:write", staff...), custc.CreateCustomer)
+	api.POST("/customers", middleware.Guard("customers:write", staff...), custc.CreateCustomer)
+	api.GET("/customers", middleware.Guard("customers:read", staff...), custc.GetCustomers)
+	api.GET("/customers/duplicates", middleware.Guard("customers:read", staff...), custc.ListDuplicates)
+	api.GET("/customers/:id", middleware.Guard("customers:read", staff...), custc.GetCustomer)
+	api.PUT("/customers/:id", middleware.Guard("customers:write", staff...), custc.UpdateCustomer)
+	api.DELETE("/customers/:id", middleware.Guard("customers:write", staff...), custc.DeleteCustomer)
+	api.POST("/customers/:id/merge", middleware.Guard("customers:write", staff...), custc.MergeCustomer)
+	api.GET("/customers/:id/merges", middleware.Guard("customers:read", staff...), custc.ListCustomerMerges)
+	api.GET("/customers/:id/timeline", middleware.Guard("customers:read", staff...), custc.GetCustomerTimeline)
+	api.POST("/imports/customers", middleware.Guard("customers:write", staff...), imc.ImportCustomers)
+	api.POST("/imports/users", adminOnly, imc.ImportUsers)
+	api.GET("/imports/:id", middleware.Guard("customers:read", staff...), imc.GetImportJob)
+	api.GET("/customers/:id/tickets", middleware.Guard("tickets:read", staff...), tc.ListCustomerTickets)
+	api.POST("/webhooks", middleware.Guard("webhooks:write", staff...), wc.CreateWebhook)
+	api.GET("/webhooks", middleware.Guard("webhooks:read", staff...), wc.ListWebhooks)
+	api.GET("/webhooks/:id", middleware.Guard("webhooks:read", staff...), wc.GetWebhook)
+	api.PUT("/webhooks/:id", middleware.Guard("webhooks:write", staff...), wc.UpdateWebhook)
+	api.DELETE("/webhooks/:id", middleware.Guard("webhooks:write", staff...), wc.DeleteWebhook)
+	api.POST("/webhooks/:id/rotate-secret", middleware.Guard("webhooks:write", staff...), wc.RotateWebhookSecret)
+	api.GET("/webhooks/:id/deliveries", middleware.Guard("webhooks:read", staff...), wc.ListWebhookDeliveries)
+	api.GET("/webhooks/:id/deliveries/:delivery_id", middleware.Guard("webhooks:read", staff...), wc.GetWebhookDelivery)
+	api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", middleware.Guard("webhooks:write", staff...), wc.RedeliverWebhook)
+	r.POST("/payments/mpesa/callback", pc.MpesaCallback)
+	r.POST("/send_otp", sendOTPLimit.Handler(), auth.Login)
+	r.POST("/verify_otp", verifyOTPLimit.Handler(), auth.VerifyOTP)
+	r.POST("/register", registerLimit.Handler(), auth.Register)
+	r.POST("/auth/password/forgot", forgotLimit.Handler(), auth.ForgotPassword)
+	r.POST("/auth/password/reset", resetLimit.Handler(), auth.ResetPassword)
+	r.POST("/auth/password/change", requireAuth, auth.ChangePassword)
+	r.POST("/auth/totp/enroll", requireAuth, auth.EnrollTOTP)
+	r.POST("/auth/totp/confirm", requireAuth, auth.ConfirmTOTP)
+	r.POST("/auth/stream-ticket", requireAuth, auth.CreateStreamTicket)
+	r.GET("/ws", middleware.RequireStreamAuth(queries), rtc.Tickets)
+	r.GET("/events/stream", middleware.RequireStreamAuth(queries), rtc.StreamEvents)
 	r.Run(":8082")
 }
@@ -0,0 +1,300 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tickets/db/sqlc"
+	"tickets/utils"
+)
+
+const (
+	profileIDKey = "profile_id"
+	roleKey      = "role"
+	apiKeyIDKey  = "api_key_id"
+	scopesKey    = "scopes"
+)
+
+// last_used_at is only written when it is older than this, to avoid an
+// UPDATE on every request from a busy integration.
+const apiKeyTouchInterval = time.Minute
+
+// RequireAuth accepts an "Authorization: Bearer <jwt>" header, rejects tokens
+// revoked by a token_version bump and stores the profile id on the context.
+func RequireAuth(q *db.Queries) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || tokenStr == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		if !authenticateJWT(c, q, tokenStr) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireStreamAuth is RequireAuth for streaming endpoints. Browsers cannot
+// set headers on WebSocket or EventSource requests, so they pass a ticket
+// from POST /auth/stream-ticket as ?ticket= instead. The JWT itself is never
+// accepted in the URL, where access logs and proxies would record it. The
+// ticket is renewed when the connection closes, so the client can reconnect
+// with it for another utils.StreamTicketTTL.
+func RequireStreamAuth(q *db.Queries) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenStr, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && tokenStr != "" {
+			if !authenticateJWT(c, q, tokenStr) {
+				return
+			}
+			c.Next()
+			return
+		}
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token or stream ticket"})
+			return
+		}
+		rec, ok := authenticateStreamTicket(c, q, ticket)
+		if !ok {
+			return
+		}
+		c.Next()
+
+		// the request context is done once the client has gone
+		if err := renewStreamTicket(context.WithoutCancel(c.Request.Context()), q, rec); err != nil {
+			slog.Error("failed to renew stream ticket", "profile_id", rec.ProfileID, "error", err)
+		}
+	}
+}
+
+// Authenticate accepts either a JWT ("Authorization: Bearer <jwt>") or an API
+// key ("Authorization: ApiKey <key>"). API key callers act as the admin who
+// created the key and are limited to the key's scopes; see Guard. A key is
+// refused once its creator is no longer an admin.
+func Authenticate(q *db.Queries) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if tokenStr, ok := strings.CutPrefix(header, "Bearer "); ok && tokenStr != "" {
+			if !authenticateJWT(c, q, tokenStr) {
+				return
+			}
+			c.Next()
+			return
+		}
+		if key, ok := strings.CutPrefix(header, "ApiKey "); ok && key != "" {
+			if !authenticateAPIKey(c, q, strings.TrimSpace(key)) {
+				return
+			}
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	}
+}
+
+func authenticateJWT(c *gin.Context, q *db.Queries, tokenStr string) bool {
+	claims, err := utils.ParseJWT(tokenStr)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return false
+	}
+
+	profile, err := q.GetProfileByID(c, int32(claims.ProfileID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return false
+		}
+		slog.Error("failed to load profile for token", "profile_id", claims.ProfileID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return false
+	}
+	if profile.TokenVersion != claims.TokenVersion {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+		return false
+	}
+
+	c.Set(profileIDKey, claims.ProfileID)
+	c.Set(roleKey, profile.Role)
+	return true
+}
+
+// authenticateStreamTicket accepts a stream ticket that has not expired and
+// renews it, so the same client can open further connections with it.
+func authenticateStreamTicket(c *gin.Context, q *db.Queries, ticket string) (db.StreamTicket, bool) {
+	rec, err := q.GetStreamTicket(c, utils.HashStreamTicket(ticket))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid stream ticket"})
+			return rec, false
+		}
+		slog.Error("failed to load stream ticket", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return rec, false
+	}
+	if time.Now().After(rec.ExpiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid stream ticket"})
+		return rec, false
+	}
+
+	profile, err := q.GetProfileByID(c, rec.ProfileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid stream ticket"})
+			return rec, false
+		}
+		slog.Error("failed to load profile for stream ticket", "profile_id", rec.ProfileID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return rec, false
+	}
+	if profile.TokenVersion != rec.TokenVersion {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+		return rec, false
+	}
+	if err := renewStreamTicket(c, q, rec); err != nil {
+		slog.Error("failed to renew stream ticket", "profile_id", rec.ProfileID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return rec, false
+	}
+
+	c.Set(profileIDKey, int64(profile.ID))
+	c.Set(roleKey, profile.Role)
+	return rec, true
+}
+
+// renewStreamTicket makes the ticket valid for another utils.StreamTicketTTL.
+func renewStreamTicket(ctx context.Context, q *db.Queries, rec db.StreamTicket) error {
+	return q.RenewStreamTicket(ctx, db.RenewStreamTicketParams{
+		TicketHash:   rec.TicketHash,
+		ProfileID:    rec.ProfileID,
+		TokenVersion: rec.TokenVersion,
+		ExpiresAt:    time.Now().Add(utils.StreamTicketTTL),
+	})
+}
+
+func authenticateAPIKey(c *gin.Context, q *db.Queries, key string) bool {
+	prefix, ok := utils.APIKeyPrefix(key)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return false
+	}
+
+	row, err := q.GetAPIKeyByPrefix(c, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			return false
+		}
+		slog.Error("failed to load api key", "prefix", prefix, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return false
+	}
+	rec := row.ApiKey
+	if subtle.ConstantTimeCompare([]byte(rec.KeyHash), []byte(utils.HashAPIKey(key))) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return false
+	}
+
+	now := time.Now()
+	if rec.RevokedAt.Valid {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key revoked"})
+		return false
+	}
+	if rec.ExpiresAt.Valid && now.After(rec.ExpiresAt.Time) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key expired"})
+		return false
+	}
+	// a key acts as its creator, so it stops working once they are no
+	// longer an admin
+	if row.CreatorRole != db.ProfilesRoleAdmin {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key owner is no longer an admin"})
+		return false
+	}
+
+	if !rec.LastUsedAt.Valid || now.Sub(rec.LastUsedAt.Time) > apiKeyTouchInterval {
+		if err := q.TouchAPIKey(c, db.TouchAPIKeyParams{
+			LastUsedAt: sql.NullTime{Time: now, Valid: true},
+			ID:         rec.ID,
+		}); err != nil {
+			slog.Error("failed to update api key last used", "prefix", prefix, "error", err)
+		}
+	}
+
+	c.Set(profileIDKey, int64(rec.CreatedBy))
+	c.Set(apiKeyIDKey, rec.ID)
+	c.Set(scopesKey, strings.Split(rec.Scopes, ","))
+	return true
+}
+
+// RequireRole must run after RequireAuth and rejects callers whose role is not
+// listed. API key callers have no role and are always rejected.
+func RequireRole(roles ...db.ProfilesRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := Role(c)
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	}
+}
+
+// Guard must run after Authenticate. API key callers need scope; JWT callers
+// need one of roles, or nothing if no roles are given.
+func Guard(scope string, roles ...db.ProfilesRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := c.Get(apiKeyIDKey); isKey {
+			if !HasScope(c, scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key lacks scope " + scope})
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(roles) == 0 {
+			c.Next()
+			return
+		}
+		role := Role(c)
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	}
+}
+
+// ProfileID returns the authenticated profile id set by RequireAuth. This is
+// the one way controllers should resolve the acting user.
+func ProfileID(c *gin.Context) int64 {
+	return c.GetInt64(profileIDKey)
+}
+
+// Role returns the authenticated profile's role set by RequireAuth.
+func Role(c *gin.Context) db.ProfilesRole {
+	role, _ := c.Get(roleKey)
+	r, _ := role.(db.ProfilesRole)
+	return r
+}
+
+// HasScope reports whether the request was made with an API key holding scope.
+func HasScope(c *gin.Context, scope string) bool {
+	for _, s := range c.GetStringSlice(scopesKey) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tickets/db/sqlc"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	defaultIdemTTL    = 24 * time.Hour
+	maxIdemKeyLength  = 255
+)
+
+// Idempotency makes a POST safe to retry. When the request carries an
+// Idempotency-Key header, the first response for that key is stored and
+// replayed for identical retries; reusing the key with a different method,
+// path or body gets a 422. Keys are scoped to the caller and expire after
+// IDEMPOTENCY_KEY_TTL (a Go duration, default 24h). Requests without the header
+// pass straight through. Must run after Authenticate.
+func Idempotency(q *db.Queries) gin.HandlerFunc {
+	ttl := defaultIdemTTL
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Error("invalid IDEMPOTENCY_KEY_TTL, using default", "value", v, "default", ttl)
+		} else {
+			ttl = d
+		}
+	}
+	go cleanupIdempotencyKeys(q, time.Hour)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdemKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(c)
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		rec, claimed, err := claimIdempotencyKey(c, q, db.CreateIdempotencyKeyParams{
+			Scope:          scope,
+			IdempotencyKey: key,
+			RequestPath:    c.Request.Method + " " + c.Request.URL.Path,
+			Fingerprint:    fingerprint,
+			ExpiresAt:      time.Now().Add(ttl),
+		})
+		if err != nil {
+			slog.Error("failed to claim idempotency key", "scope", scope, "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+
+		if !claimed {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+					"error": "Idempotency-Key was already used for a different request",
+				})
+			case !rec.StatusCode.Valid:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+					"error": "a request with this Idempotency-Key is still in progress",
+				})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(int(rec.StatusCode.Int32), rec.ContentType.String, []byte(rec.ResponseBody.String))
+				c.Abort()
+			}
+			return
+		}
+
+		w := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		// a panicking handler must not leave the key in progress forever;
+		// release it and let gin's recovery answer the request
+		defer func() {
+			if r := recover(); r != nil {
+				if err := q.DeleteIdempotencyKey(c, rec.ID); err != nil {
+					slog.Error("failed to release idempotency key", "id", rec.ID, "error", err)
+				}
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		// server errors are not cached so that the client can retry them
+		status := w.Status()
+		if status >= http.StatusInternalServerError {
+			if err := q.DeleteIdempotencyKey(c, rec.ID); err != nil {
+				slog.Error("failed to release idempotency key", "id", rec.ID, "error", err)
+			}
+			return
+		}
+		if err := q.SaveIdempotentResponse(c, db.SaveIdempotentResponseParams{
+			StatusCode:   sql.NullInt32{Int32: int32(status), Valid: true},
+			ContentType:  sql.NullString{String: w.Header().Get("Content-Type"), Valid: true},
+			ResponseBody: sql.NullString{String: w.body.String(), Valid: true},
+			ID:           rec.ID,
+		}); err != nil {
+			slog.Error("failed to save idempotent response", "id", rec.ID, "error", err)
+		}
+	}
+}
+
+// claimIdempotencyKey inserts the key, or returns the existing record and
+// false if another request got there first. Expired records are replaced.
+func claimIdempotencyKey(ctx context.Context, q *db.Queries, arg db.CreateIdempotencyKeyParams) (db.IdempotencyKey, bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		n, err := q.CreateIdempotencyKey(ctx, arg)
+		if err != nil {
+			return db.IdempotencyKey{}, false, err
+		}
+		rec, err := q.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+			Scope:          arg.Scope,
+			IdempotencyKey: arg.IdempotencyKey,
+		})
+		if err != nil {
+			return db.IdempotencyKey{}, false, err
+		}
+		if n == 1 {
+			return rec, true, nil
+		}
+		if time.Now().Before(rec.ExpiresAt) {
+			return rec, false, nil
+		}
+		if err := q.DeleteIdempotencyKey(ctx, rec.ID); err != nil {
+			return db.IdempotencyKey{}, false, err
+		}
+	}
+	return db.IdempotencyKey{}, false, fmt.Errorf("idempotency key %q could not be claimed", arg.IdempotencyKey)
+}
+
+// idempotencyScope keeps keys from different callers apart.
+func idempotencyScope(c *gin.Context) string {
+	if id, ok := c.Get(apiKeyIDKey); ok {
+		return fmt.Sprintf("api_key:%d", id)
+	}
+	return fmt.Sprintf("profile:%d", ProfileID(c))
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func cleanupIdempotencyKeys(q *db.Queries, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := q.DeleteExpiredIdempotencyKeys(context.Background(), time.Now()); err != nil {
+			slog.Error("failed to delete expired idempotency keys", "error", err)
+		}
+	}
+}
+
+// capturingWriter keeps a copy of the response body so it can be stored.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
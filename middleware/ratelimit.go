@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets/phone"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limit describes a token bucket: Burst tokens that refill at Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Per builds a limit allowing n requests every d, with a burst of n.
+func Per(n int, d time.Duration) Limit {
+	return Limit{Rate: float64(n) / d.Seconds(), Burst: n}
+}
+
+// ParseLimit parses values like "5/1m" or "100/1h". An empty or "off" value disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <count>/<duration>", s)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit count %q", parts[0])
+	}
+	d, err := time.ParseDuration(parts[1])
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit duration %q", parts[1])
+	}
+	return Per(n, d), nil
+}
+
+func (l Limit) enabled() bool {
+	return l.Burst > 0 && l.Rate > 0
+}
+
+// Store keeps token buckets. Take consumes one token from the bucket at key and
+// reports whether it was allowed, and if not, how long until a token is available.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore is a process-local Store. Use a RedisStore when running several replicas.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	go s.cleanup(10 * time.Minute)
+	return s
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	// refill
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// cleanup drops buckets that have been idle long enough to be full again.
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		for k, b := range s.buckets {
+			if s.now().Sub(b.last) > interval {
+				delete(s.buckets, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// RedisScripter is the subset of a Redis client the RedisStore needs. Clients such
+// as go-redis can be adapted with a one-line wrapper around Eval.
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// tokenBucketScript refills and takes from a bucket stored as a hash.
+// Returns {allowed, wait_ms}.
+const tokenBucketScript = `
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local data = redis.call("HMGET", key, "tokens", "last")
+local tokens = tonumber(data[1])
+local last = tonumber(data[2])
+if tokens == nil then
+  tokens = burst
+  last = now
+end
+
+tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HSET", key, "tokens", tokens, "last", now)
+redis.call("PEXPIRE", key, math.ceil(burst / rate * 1000))
+return {allowed, wait}
+`
+
+// RedisStore shares buckets between API replicas through Redis.
+type RedisStore struct {
+	client RedisScripter
+	prefix string
+}
+
+func NewRedisStore(client RedisScripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	res, err := s.client.Eval(ctx, tokenBucketScript, []string{s.prefix + key},
+		limit.Rate, limit.Burst, time.Now().UnixMilli())
+	if err != nil {
+		return false, 0, fmt.Errorf("rate limit eval: %w", err)
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	allowed, _ := vals[0].(int64)
+	waitMs, _ := vals[1].(int64)
+	return allowed == 1, time.Duration(waitMs) * time.Millisecond, nil
+}
+
+// RateLimiter enforces global, per-IP and per-phone budgets for one route.
+type RateLimiter struct {
+	store    Store
+	name     string
+	global   Limit
+	perIP    Limit
+	perPhone Limit
+}
+
+// NewRateLimiter builds a limiter for the named route. The defaults can be
+// overridden with RATE_LIMIT_<NAME>_GLOBAL, RATE_LIMIT_<NAME>_PER_IP and
+// RATE_LIMIT_<NAME>_PER_PHONE, e.g. RATE_LIMIT_SEND_OTP_PER_PHONE=3/10m.
+func NewRateLimiter(store Store, name string, global, perIP, perPhone Limit) *RateLimiter {
+	env := "RATE_LIMIT_" + strings.ToUpper(name)
+	return &RateLimiter{
+		store:    store,
+		name:     name,
+		global:   limitFromEnv(env+"_GLOBAL", global),
+		perIP:    limitFromEnv(env+"_PER_IP", perIP),
+		perPhone: limitFromEnv(env+"_PER_PHONE", perPhone),
+	}
+}
+
+func limitFromEnv(key string, def Limit) Limit {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	l, err := ParseLimit(v)
+	if err != nil {
+		slog.Error("invalid rate limit, using default", "key", key, "error", err)
+		return def
+	}
+	return l
+}
+
+// Handler returns the gin middleware. It responds 429 with a Retry-After header
+// as soon as any budget is exhausted. The global budget is checked last, so
+// requests already refused for one IP or phone do not use it up for everyone
+// else.
+func (rl *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type check struct {
+			key   string
+			limit Limit
+		}
+		checks := []check{{rl.name + ":ip:" + c.ClientIP(), rl.perIP}}
+		if rl.perPhone.enabled() {
+			if phone := phoneFromBody(c); phone != "" {
+				checks = append(checks, check{rl.name + ":phone:" + phone, rl.perPhone})
+			}
+		}
+		checks = append(checks, check{rl.name + ":global", rl.global})
+
+		for _, chk := range checks {
+			if !chk.limit.enabled() {
+				continue
+			}
+			allowed, wait, err := rl.store.Take(c.Request.Context(), chk.key, chk.limit)
+			if err != nil {
+				// fail open, a broken limiter store should not take down login
+				slog.Error("rate limit store error", "key", chk.key, "error", err)
+				continue
+			}
+			if !allowed {
+				TooManyRequests(c, wait)
+				slog.Warn("rate limit exceeded", "key", chk.key, "ip", c.ClientIP())
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests aborts with 429 and a Retry-After header rounded up to whole seconds.
+func TooManyRequests(c *gin.Context, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       "too many requests",
+		"retry_after": secs,
+	})
+}
+
+// peekedBody is a request body whose first bytes have already been read.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// phoneFromBody peeks at the JSON body for a phone field and restores the body
+// so the handler can still bind it. Only the first MiB is read; anything after
+// it is left in the body for the handler. The phone is normalized, so writing
+// one number in different ways does not buy a fresh budget.
+func phoneFromBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body := c.Request.Body
+	raw, err := io.ReadAll(io.LimitReader(body, 1<<20))
+	c.Request.Body = peekedBody{io.MultiReader(bytes.NewReader(raw), body), body}
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Phone string `json:"phone"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return ""
+	}
+	return phone.NormalizeOrTrim(req.Phone)
+}
@@ -0,0 +1,176 @@
+// Package money represents amounts exactly, as an integer number of minor
+// units (cents, fils, yen) of an ISO 4217 currency. Never use float64 for money.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown ISO 4217 currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooPrecise       = errors.New("amount has more decimal places than the currency allows")
+	ErrOverflow         = errors.New("amount out of range")
+)
+
+// Money is an amount in minor units of Currency. The zero value is not a
+// valid Money; build one with New or Parse.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns minor units of currency, e.g. New(1050, "KES") is KES 10.50.
+func New(minor int64, currency string) (Money, error) {
+	cur := strings.ToUpper(strings.TrimSpace(currency))
+	if _, ok := Exponent(cur); !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Amount: minor, Currency: cur}, nil
+}
+
+// Parse reads a decimal string such as "10.5" or "-3.125" exactly. It fails
+// rather than rounds when the amount has more decimals than the currency's exponent.
+func Parse(amount, currency string) (Money, error) {
+	cur := strings.ToUpper(strings.TrimSpace(currency))
+	exp, ok := Exponent(cur)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+
+	s := strings.TrimSpace(amount)
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digits(whole) || !digits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	// trailing zeros never lose precision, so "10.500" is fine for KES
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q for %s", ErrTooPrecise, amount, cur)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrOverflow, amount)
+	}
+	if neg {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: cur}, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount with exactly the currency's decimals, e.g. "10.50".
+func (m Money) String() string {
+	exp, _ := Exponent(m.Currency)
+	neg := m.Amount < 0
+	u := uint64(m.Amount)
+	if neg {
+		u = -u
+	}
+	s := strconv.FormatUint(u, 10)
+	if exp > 0 {
+		if len(s) <= exp {
+			s = strings.Repeat("0", exp-len(s)+1) + s
+		}
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// Add returns m+o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m-o. Both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or 1. Both must be in the same currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+type jsonMoney struct {
+	Value    json.Number `json:"value"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes {"value":"10.50","currency":"KES"}. The value is a
+// string so that clients never parse it into a float by accident.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value    string `json:"value"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+// UnmarshalJSON accepts the value as a string or a JSON number; either way
+// it is read from the literal text, never through a float.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var j jsonMoney
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	v, err := Parse(j.Value.String(), j.Currency)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
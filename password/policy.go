@@ -0,0 +1,81 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrTooLong  = errors.New("password is too long")
+	ErrBreached = errors.New("password appears in a list of breached passwords")
+)
+
+// bcrypt ignores everything past 72 bytes, so longer passwords give a false sense of strength.
+const maxLength = 72
+
+// Policy decides whether a new password is acceptable.
+type Policy struct {
+	MinLength int
+	breached  map[string]struct{}
+}
+
+// LoadPolicy reads PASSWORD_MIN_LENGTH (default 8) and PASSWORD_BREACHED_LIST,
+// a path to a newline separated file of known breached passwords.
+func LoadPolicy() *Policy {
+	p := &Policy{MinLength: 8, breached: map[string]struct{}{}}
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			p.MinLength = n
+		}
+	}
+	if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+		if err := p.LoadBreachedList(path); err != nil {
+			slog.Error("failed to load breached password list", "path", path, "error", err)
+		}
+	}
+	return p
+}
+
+// LoadBreachedList adds every non-empty line of the file to the breached set.
+// Matching is case-insensitive.
+func (p *Policy) LoadBreachedList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open breached list: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read breached list: %w", err)
+	}
+	slog.Info("loaded breached password list", "count", len(p.breached))
+	return nil
+}
+
+// Validate returns nil if pw satisfies the policy.
+func (p *Policy) Validate(pw string) error {
+	if len([]rune(pw)) < p.MinLength {
+		return fmt.Errorf("%w: minimum is %d characters", ErrTooShort, p.MinLength)
+	}
+	if len(pw) > maxLength {
+		return fmt.Errorf("%w: maximum is %d bytes", ErrTooLong, maxLength)
+	}
+	if _, ok := p.breached[strings.ToLower(pw)]; ok {
+		return ErrBreached
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package payments talks to payment providers. MpesaClient covers the parts
+// of the Safaricom Daraja API we use: OAuth tokens and Lipa Na M-Pesa STK Push.
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets/phone"
+)
+
+const defaultDarajaURL = "https://sandbox.safaricom.co.ke"
+
+// MpesaClient is safe for concurrent use; the OAuth token is cached until shortly before it expires.
+type MpesaClient struct {
+	baseURL        string
+	consumerKey    string
+	consumerSecret string
+	shortcode      string
+	passkey        string
+	callbackURL    string
+	httpClient     *http.Client
+
+	mu       sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+// NewMpesaClient reads the MPESA_* variables from the environment.
+// MPESA_BASE_URL points the client at the Daraja sandbox by default and can
+// be set to a local stub server for testing.
+func NewMpesaClient() *MpesaClient {
+	base := os.Getenv("MPESA_BASE_URL")
+	if base == "" {
+		base = defaultDarajaURL
+	}
+	return &MpesaClient{
+		baseURL:        strings.TrimRight(base, "/"),
+		consumerKey:    os.Getenv("MPESA_CONSUMER_KEY"),
+		consumerSecret: os.Getenv("MPESA_CONSUMER_SECRET"),
+		shortcode:      os.Getenv("MPESA_SHORTCODE"),
+		passkey:        os.Getenv("MPESA_PASSKEY"),
+		callbackURL:    os.Getenv("MPESA_CALLBACK_URL"),
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// accessToken returns a cached OAuth token, fetching a new one when needed.
+func (m *MpesaClient) accessToken(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token != "" && time.Now().Before(m.tokenExp) {
+		return m.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		m.baseURL+"/oauth/v1/generate?grant_type=client_credentials", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(m.consumerKey, m.consumerSecret)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("daraja oauth: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("daraja oauth: status %d: %s", resp.StatusCode, body)
+	}
+
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("daraja oauth: decode: %w", err)
+	}
+	if out.AccessToken == "" {
+		return "", errors.New("daraja oauth: empty access token")
+	}
+
+	ttl, err := strconv.Atoi(out.ExpiresIn)
+	if err != nil || ttl <= 0 {
+		ttl = 3599
+	}
+	// refresh a minute early so a token never expires mid-request
+	m.token = out.AccessToken
+	m.tokenExp = time.Now().Add(time.Duration(ttl)*time.Second - time.Minute)
+	return m.token, nil
+}
+
+// STKPushRequest is what we need from the caller to start a payment.
+type STKPushRequest struct {
+	Phone            string // MSISDN in 2547XXXXXXXX form
+	Amount           int64  // whole shillings; Daraja rejects decimals
+	AccountReference string
+	Description      string
+}
+
+// STKPushResponse is Daraja's acknowledgement. The payment result arrives later on the callback.
+type STKPushResponse struct {
+	MerchantRequestID   string `json:"MerchantRequestID"`
+	CheckoutRequestID   string `json:"CheckoutRequestID"`
+	ResponseCode        string `json:"ResponseCode"`
+	ResponseDescription string `json:"ResponseDescription"`
+	CustomerMessage     string `json:"CustomerMessage"`
+}
+
+// STKPush asks Safaricom to prompt the customer's phone for payment.
+func (m *MpesaClient) STKPush(ctx context.Context, r STKPushRequest) (STKPushResponse, error) {
+	token, err := m.accessToken(ctx)
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+
+	ts := time.Now().Format("20060102150405")
+	password := base64.StdEncoding.EncodeToString([]byte(m.shortcode + m.passkey + ts))
+	payload := map[string]interface{}{
+		"BusinessShortCode": m.shortcode,
+		"Password":          password,
+		"Timestamp":         ts,
+		"TransactionType":   "CustomerPayBillOnline",
+		"Amount":            r.Amount,
+		"PartyA":            r.Phone,
+		"PartyB":            m.shortcode,
+		"PhoneNumber":       r.Phone,
+		"CallBackURL":       m.callbackURL,
+		"AccountReference":  r.AccountReference,
+		"TransactionDesc":   r.Description,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		m.baseURL+"/mpesa/stkpush/v1/processrequest", bytes.NewReader(body))
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return STKPushResponse{}, fmt.Errorf("daraja stk push: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return STKPushResponse{}, fmt.Errorf("daraja stk push: status %d: %s", resp.StatusCode, raw)
+	}
+
+	var out STKPushResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return STKPushResponse{}, fmt.Errorf("daraja stk push: decode: %w", err)
+	}
+	if out.ResponseCode != "0" {
+		return out, fmt.Errorf("daraja stk push rejected: %s", out.ResponseDescription)
+	}
+	return out, nil
+}
+
+// STKCallback is the body Daraja posts to MPESA_CALLBACK_URL.
+type STKCallback struct {
+	Body struct {
+		StkCallback struct {
+			MerchantRequestID string `json:"MerchantRequestID"`
+			CheckoutRequestID string `json:"CheckoutRequestID"`
+			ResultCode        int    `json:"ResultCode"`
+			ResultDesc        string `json:"ResultDesc"`
+			CallbackMetadata  struct {
+				Item []struct {
+					Name  string          `json:"Name"`
+					Value json.RawMessage `json:"Value"`
+				} `json:"Item"`
+			} `json:"CallbackMetadata"`
+		} `json:"stkCallback"`
+	} `json:"Body"`
+}
+
+// Metadata returns a callback metadata item as a string, e.g. "MpesaReceiptNumber".
+func (cb STKCallback) Metadata(name string) string {
+	for _, it := range cb.Body.StkCallback.CallbackMetadata.Item {
+		if it.Name != name {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(it.Value, &s); err == nil {
+			return s
+		}
+		return strings.Trim(string(it.Value), `"`)
+	}
+	return ""
+}
+
+// MSISDN converts local Kenyan formats (07.., 01.., +254..) to the 254XXXXXXXXX form Daraja expects.
+func MSISDN(number string) (string, error) {
+	p, err := phone.Parse(number, "KE")
+	if err != nil || !strings.HasPrefix(p, "+254") {
+		return "", fmt.Errorf("invalid M-Pesa phone number %q", number)
+	}
+	return strings.TrimPrefix(p, "+"), nil
+}
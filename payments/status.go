@@ -0,0 +1,73 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+)
+
+// transactions.status values. Rows default to pending until the provider
+// confirms the payment.
+const (
+	StatusPending           int16 = 1
+	StatusCompleted         int16 = 2
+	StatusFailed            int16 = 3
+	StatusRefunded          int16 = 4
+	StatusPartiallyRefunded int16 = 5
+	StatusReversed          int16 = 6
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+var statusNames = map[int16]string{
+	StatusPending:           "pending",
+	StatusCompleted:         "completed",
+	StatusFailed:            "failed",
+	StatusRefunded:          "refunded",
+	StatusPartiallyRefunded: "partially_refunded",
+	StatusReversed:          "reversed",
+}
+
+// transitions lists where each state may go next. failed, refunded and
+// reversed are final. The refund states are only set by the refunds
+// endpoint, which also checks the refunded total.
+var transitions = map[int16][]int16{
+	StatusPending:           {StatusCompleted, StatusFailed},
+	StatusCompleted:         {StatusPartiallyRefunded, StatusRefunded, StatusReversed},
+	StatusPartiallyRefunded: {StatusPartiallyRefunded, StatusRefunded},
+}
+
+// StatusName returns the API name of a status, e.g. "partially_refunded".
+func StatusName(s int16) string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", s)
+}
+
+// ParseStatus is the inverse of StatusName.
+func ParseStatus(name string) (int16, bool) {
+	for s, n := range statusNames {
+		if n == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// CanTransition reports whether a transaction may move from one status to another.
+func CanTransition(from, to int16) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition is CanTransition as an error wrapping ErrInvalidTransition.
+func CheckTransition(from, to int16) error {
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, StatusName(from), StatusName(to))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package phone parses phone numbers into E.164 form (+254712345678), so a
+// number written as 0712 345 678, +254 712 345678 or 254712345678 is stored,
+// compared and looked up as one value.
+//
+// Numbers without a country code are read in a default region, KE unless
+// PHONE_DEFAULT_REGION names another supported region.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultRegion is the region used when PHONE_DEFAULT_REGION is unset.
+const DefaultRegion = "KE"
+
+// ErrInvalid is returned for input that is not a phone number.
+var ErrInvalid = errors.New("invalid phone number")
+
+type region struct {
+	code     string // country calling code
+	national int    // digits in a national significant number
+	trunk    string // prefix dialled before national numbers, "" if none
+}
+
+// regions are the regions numbers may be read in without a country code.
+// Numbers written with a country code are accepted for any country; those
+// of these regions must also have the right length.
+var regions = map[string]region{
+	"KE": {code: "254", national: 9, trunk: "0"},
+	"UG": {code: "256", national: 9, trunk: "0"},
+	"TZ": {code: "255", national: 9, trunk: "0"},
+	"RW": {code: "250", national: 9, trunk: "0"},
+	"NG": {code: "234", national: 10, trunk: "0"},
+	"ZA": {code: "27", national: 9, trunk: "0"},
+	"GB": {code: "44", national: 10, trunk: "0"},
+	"US": {code: "1", national: 10},
+}
+
+// Region returns the configured default region. PHONE_DEFAULT_REGION is
+// read on the first call only.
+var Region = sync.OnceValue(func() string {
+	if v := strings.ToUpper(strings.TrimSpace(os.Getenv("PHONE_DEFAULT_REGION"))); v != "" {
+		if _, ok := regions[v]; ok {
+			return v
+		}
+		slog.Error("unsupported PHONE_DEFAULT_REGION, using default", "value", v)
+	}
+	return DefaultRegion
+})
+
+// Normalize parses number in the configured default region and returns it
+// in E.164 form.
+func Normalize(number string) (string, error) {
+	return Parse(number, Region())
+}
+
+// NormalizeOrTrim is Normalize for lookups and validation messages: input
+// that is not a phone number is returned trimmed rather than rejected, so
+// it matches nothing stored in E.164 form and can still be reported as
+// given.
+func NormalizeOrTrim(number string) string {
+	if p, err := Normalize(number); err == nil {
+		return p
+	}
+	return strings.TrimSpace(number)
+}
+
+// Valid reports whether Normalize accepts number.
+func Valid(number string) bool {
+	_, err := Normalize(number)
+	return err == nil
+}
+
+// Parse returns number in E.164 form. Spaces, dashes, dots and brackets are
+// ignored. A number starting with + or 00 carries its country code; any
+// other is read in regionCode: with the trunk prefix (0712345678), with the
+// country code but no + (254712345678) or as the bare national number
+// (712345678).
+func Parse(number, regionCode string) (string, error) {
+	s := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "\t", "").Replace(number)
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		s, international = s[1:], true
+	case strings.HasPrefix(s, "00"):
+		s, international = s[2:], true
+	}
+	if s == "" || !digits(s) {
+		return "", ErrInvalid
+	}
+	if international {
+		return checkE164(s)
+	}
+
+	r, ok := regions[strings.ToUpper(regionCode)]
+	if !ok {
+		return "", fmt.Errorf("unsupported region %q", regionCode)
+	}
+	switch {
+	case r.trunk != "" && strings.HasPrefix(s, r.trunk) && len(s) == len(r.trunk)+r.national:
+		return "+" + r.code + s[len(r.trunk):], nil
+	case strings.HasPrefix(s, r.code) && len(s) == len(r.code)+r.national:
+		return "+" + s, nil
+	case len(s) == r.national:
+		return "+" + r.code + s, nil
+	}
+	return "", ErrInvalid
+}
+
+// checkE164 validates the digits of an international number: at most 15,
+// no leading zero, and the right length for the regions we know.
+func checkE164(s string) (string, error) {
+	if len(s) < 7 || len(s) > 15 || s[0] == '0' {
+		return "", ErrInvalid
+	}
+	for _, r := range regions {
+		if strings.HasPrefix(s, r.code) && len(s) != len(r.code)+r.national {
+			return "", ErrInvalid
+		}
+	}
+	return "+" + s, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
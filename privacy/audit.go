@@ -0,0 +1,46 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+
+	db "tickets/db/sqlc"
+)
+
+// Options describe who asked for a request and why, for the audit trail.
+type Options struct {
+	RequestedBy int32
+	Reason      string
+	// DryRun applies to Erase only.
+	DryRun bool
+}
+
+// RecordExport adds an export of the archive to the audit trail and returns
+// the audit row's id.
+func RecordExport(ctx context.Context, q *db.Queries, s *Subject, a *Archive, opts Options) (int64, error) {
+	return record(ctx, q, db.DataSubjectRequestsKindExport, s, a.Counts(), opts)
+}
+
+func record(ctx context.Context, q *db.Queries, kind db.DataSubjectRequestsKind, s *Subject, summary any, opts Options) (int64, error) {
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return 0, err
+	}
+	hash, err := s.Hash()
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.CreateDataSubjectRequest(ctx, db.CreateDataSubjectRequestParams{
+		Kind:        kind,
+		SubjectHash: hash,
+		ProfileIds:  s.ProfileIDs(),
+		CustomerIds: s.CustomerIDs(),
+		Summary:     string(b),
+		Reason:      opts.Reason,
+		RequestedBy: opts.RequestedBy,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
@@ -0,0 +1,235 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	db "tickets/db/sqlc"
+)
+
+// Placeholders written over erased personal data. Emails and phones are
+// unique, so erased ones are made unique with the record's id.
+const (
+	erasedName        = "Erased"
+	erasedDescription = "[erased]"
+	erasedIdentifier  = "erased"
+	// unusablePassword is not a bcrypt hash, so no password matches it.
+	unusablePassword = "!"
+	// erasedPhonePrefix starts the placeholder of an erased customer's
+	// phone, which the column requires
+	erasedPhonePrefix = "erased-"
+)
+
+// ErasedPhone reports whether a customer phone is an erasure placeholder
+// rather than a number.
+func ErasedPhone(number string) bool {
+	return strings.HasPrefix(number, erasedPhonePrefix)
+}
+
+// ErrProtected is returned for a subject with an admin profile: erasing it
+// could lock every admin out. Demote the profile first.
+var ErrProtected = errors.New("admin profiles cannot be erased")
+
+// ErasureSummary counts what an erasure changed and what it kept, by table.
+type ErasureSummary struct {
+	Anonymized map[string]int `json:"anonymized"`
+	Deleted    map[string]int `json:"deleted"`
+	// Retained are financial records kept as the law requires. They still
+	// reference the anonymized profile by id.
+	Retained map[string]int `json:"retained"`
+}
+
+// Erase anonymizes the subject's personal data in one DB transaction:
+//
+//   - profiles lose their name, email and phone, cannot log in any more and
+//     their sessions end; OTP, TOTP, recovery codes and lockouts are deleted
+//   - customers, and copies of them kept by customer merges, are anonymized
+//   - the subject's tickets lose their title and description, and the
+//     ticket events that repeated them are deleted from the event log and
+//     the webhook delivery log, as are the profiles' user events
+//   - webhook subscriptions of the profiles are deleted with their deliveries
+//   - login history loses the identifier, IP address and user agent
+//
+// Transactions and M-Pesa payment requests are retained. The erasure is
+// recorded in the audit trail in the same transaction and the audit row's id
+// returned. In a dry run the changes are rolled back, nothing is recorded and
+// only the summary is returned.
+func Erase(ctx context.Context, conn *sql.DB, q *db.Queries, s *Subject, opts Options) (*ErasureSummary, int64, error) {
+	for _, p := range s.Profiles {
+		if p.Role == db.ProfilesRoleAdmin {
+			return nil, 0, ErrProtected
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+
+	sum := &ErasureSummary{Anonymized: map[string]int{}, Deleted: map[string]int{}, Retained: map[string]int{}}
+	tickets := map[int64]bool{}
+	transactions := map[int32]bool{}
+
+	for _, p := range s.Profiles {
+		if err := qtx.AnonymizeProfile(ctx, db.AnonymizeProfileParams{
+			FullName:     sql.NullString{String: erasedName, Valid: true},
+			Email:        sql.NullString{String: fmt.Sprintf("erased-profile-%d@erased.invalid", p.ID), Valid: true},
+			PasswordHash: unusablePassword,
+			ID:           p.ID,
+		}); err != nil {
+			return nil, 0, err
+		}
+		sum.Anonymized["profiles"]++
+		for _, del := range []func(context.Context, int32) error{
+			qtx.DeleteOTPsByProfile,
+			qtx.DeleteTOTPSecret,
+			qtx.DeleteRecoveryCodes,
+			qtx.ClearLoginFailures,
+		} {
+			if err := del(ctx, p.ID); err != nil {
+				return nil, 0, err
+			}
+		}
+		n, err := qtx.DeleteWebhookSubscriptionsByProfile(ctx, p.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		sum.Deleted["webhook_subscriptions"] += int(n)
+		n, err = qtx.DeleteWebhookDeliveriesBySubject(ctx, db.DeleteWebhookDeliveriesBySubjectParams{
+			EventType: "user.%",
+			SubjectID: int64(p.ID),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		sum.Deleted["webhook_deliveries"] += int(n)
+
+		ts, err := qtx.ListTicketsByCreator(ctx, int64(p.ID))
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, t := range ts {
+			tickets[t.ID] = true
+		}
+		txns, err := qtx.ListTransactionsByUser(ctx, p.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, t := range txns {
+			transactions[t.ID] = true
+		}
+		reqs, err := qtx.ListMpesaStkRequestsByUser(ctx, p.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		sum.Retained["mpesa_stk_requests"] += len(reqs)
+	}
+
+	merges := map[int64]bool{}
+	for _, cu := range s.Customers {
+		if err := qtx.AnonymizeCustomer(ctx, db.AnonymizeCustomerParams{
+			FullName:    erasedName,
+			Email:       fmt.Sprintf("erased-customer-%d@erased.invalid", cu.ID),
+			PhoneNumber: fmt.Sprintf("%s%d", erasedPhonePrefix, cu.ID),
+			ID:          cu.ID,
+		}); err != nil {
+			return nil, 0, err
+		}
+		sum.Anonymized["customers"]++
+
+		links, err := qtx.ListCustomerTicketLinks(ctx, sql.NullInt64{Int64: cu.ID, Valid: true})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, l := range links {
+			tickets[l.ID] = true
+			if l.TransactionID.Valid {
+				transactions[l.TransactionID.Int32] = true
+			}
+		}
+		ms, err := qtx.ListCustomerMerges(ctx, db.ListCustomerMergesParams{CustomerID: cu.ID})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, m := range ms {
+			merges[m.ID] = true
+		}
+	}
+	if s.Email != "" || s.Phone != "" {
+		ms, err := qtx.ListCustomerMergesByContact(ctx, db.ListCustomerMergesByContactParams{Email: s.Email, Phone: s.Phone})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, m := range ms {
+			merges[m.ID] = true
+		}
+	}
+	for id := range merges {
+		if err := qtx.AnonymizeCustomerMerge(ctx, db.AnonymizeCustomerMergeParams{
+			MergedFullName:    erasedName,
+			MergedEmail:       fmt.Sprintf("erased-merge-%d@erased.invalid", id),
+			MergedPhoneNumber: fmt.Sprintf("erased-m%d", id),
+			ID:                id,
+		}); err != nil {
+			return nil, 0, err
+		}
+		sum.Anonymized["customer_merges"]++
+	}
+
+	for id := range tickets {
+		if err := qtx.AnonymizeTicket(ctx, db.AnonymizeTicketParams{
+			Title:       fmt.Sprintf("[erased] ticket %d", id),
+			Description: erasedDescription,
+			ID:          id,
+		}); err != nil {
+			return nil, 0, err
+		}
+		sum.Anonymized["tickets"]++
+		n, err := qtx.DeleteEventsBySubject(ctx, db.DeleteEventsBySubjectParams{
+			Stream:    "ticket_events",
+			SubjectID: sql.NullInt64{Int64: id, Valid: true},
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		sum.Deleted["event_log"] += int(n)
+		n, err = qtx.DeleteWebhookDeliveriesBySubject(ctx, db.DeleteWebhookDeliveriesBySubjectParams{
+			EventType: "ticket.%",
+			SubjectID: id,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		sum.Deleted["webhook_deliveries"] += int(n)
+	}
+	sum.Retained["transactions"] = len(transactions)
+
+	for _, params := range loginLookups(s) {
+		n, err := qtx.AnonymizeLoginEvents(ctx, db.AnonymizeLoginEventsParams{
+			Replacement: erasedIdentifier,
+			ProfileID:   params.ProfileID,
+			Identifier:  params.Identifier,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		sum.Anonymized["login_events"] += int(n)
+	}
+
+	if opts.DryRun {
+		return sum, 0, nil
+	}
+	id, err := record(ctx, qtx, db.DataSubjectRequestsKindErasure, s, sum, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+	return sum, id, nil
+}
@@ -0,0 +1,273 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+
+	db "tickets/db/sqlc"
+)
+
+// ticketPage is how many of a customer's tickets are read per query.
+const ticketPage = 500
+
+// Record is one row as exported: its columns by name, NULLs as nil.
+type Record map[string]any
+
+// Section is the records of one kind, such as "tickets".
+type Section struct {
+	Name    string   `json:"name"`
+	Records []Record `json:"records"`
+}
+
+// Archive is everything held about a subject.
+type Archive struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Sections    []Section `json:"sections"`
+}
+
+// Counts is the number of records in each section.
+func (a *Archive) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, sec := range a.Sections {
+		counts[sec.Name] = len(sec.Records)
+	}
+	return counts
+}
+
+// Export collects the subject's profiles, customers, customer merges,
+// tickets, transactions, M-Pesa payment requests, OTP history and login
+// history. Secrets are left out: password hashes, OTP codes and TOTP and
+// recovery codes are not the subject's data but credentials.
+func Export(ctx context.Context, q *db.Queries, s *Subject) (*Archive, error) {
+	profiles := newSection("profiles")
+	for _, p := range s.Profiles {
+		profiles.add(p.ID, p, "password_hash", "token_version")
+	}
+	customers := newSection("customers")
+	for _, cu := range s.Customers {
+		customers.add(cu.ID, cu)
+	}
+
+	merges := newSection("customer_merges")
+	for _, cu := range s.Customers {
+		rows, err := q.ListCustomerMerges(ctx, db.ListCustomerMergesParams{CustomerID: cu.ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range rows {
+			merges.add(m.ID, m)
+		}
+	}
+	if s.Email != "" || s.Phone != "" {
+		rows, err := q.ListCustomerMergesByContact(ctx, db.ListCustomerMergesByContactParams{Email: s.Email, Phone: s.Phone})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range rows {
+			merges.add(m.ID, m)
+		}
+	}
+
+	tickets := newSection("tickets")
+	transactions := newSection("transactions")
+	mpesa := newSection("mpesa_stk_requests")
+	otps := newSection("otp_history")
+	logins := newSection("login_events")
+	var linked []int32 // transactions referenced by the subject's tickets
+	addTickets := func(rows []db.Ticket) {
+		for _, t := range rows {
+			tickets.add(t.ID, t)
+			if t.TransactionID.Valid {
+				linked = append(linked, t.TransactionID.Int32)
+			}
+		}
+	}
+
+	for _, p := range s.Profiles {
+		ts, err := q.ListTicketsByCreator(ctx, int64(p.ID))
+		if err != nil {
+			return nil, err
+		}
+		addTickets(ts)
+
+		txns, err := q.ListTransactionsByUser(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range txns {
+			transactions.add(t.ID, t)
+		}
+		reqs, err := q.ListMpesaStkRequestsByUser(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range reqs {
+			mpesa.add(r.ID, r)
+		}
+		codes, err := q.ListOTPsByProfile(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range codes {
+			otps.add(o.ID, o, "otp_code")
+		}
+	}
+	for _, cu := range s.Customers {
+		params := db.ListTicketsByCustomerParams{
+			CustomerID: sql.NullInt64{Int64: cu.ID, Valid: true},
+			Limit:      ticketPage,
+		}
+		for {
+			ts, err := q.ListTicketsByCustomer(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			addTickets(ts)
+			if len(ts) < ticketPage {
+				break
+			}
+			params.Offset += ticketPage
+		}
+	}
+	for _, id := range linked {
+		if transactions.has(id) {
+			continue
+		}
+		t, err := q.GetTransanctionByID(ctx, id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		transactions.add(t.ID, t)
+	}
+
+	for _, params := range loginLookups(s) {
+		events, err := q.ListLoginEventsForSubject(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			logins.add(e.ID, e)
+		}
+	}
+
+	return &Archive{
+		GeneratedAt: time.Now().UTC(),
+		Sections: []Section{
+			profiles.Section, customers.Section, merges.Section, tickets.Section,
+			transactions.Section, mpesa.Section, otps.Section, logins.Section,
+		},
+	}, nil
+}
+
+// WriteZip writes the archive as a ZIP with one JSON file per section and
+// manifest.json, which holds manifest as given plus the record counts.
+func WriteZip(w io.Writer, a *Archive, manifest map[string]any) error {
+	zw := zip.NewWriter(w)
+	m := map[string]any{"generated_at": a.GeneratedAt, "counts": a.Counts()}
+	for k, v := range manifest {
+		m[k] = v
+	}
+	if err := writeJSON(zw, "manifest.json", m, a.GeneratedAt); err != nil {
+		return err
+	}
+	for _, sec := range a.Sections {
+		if err := writeJSON(zw, sec.Name+".json", sec.Records, a.GeneratedAt); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeJSON(zw *zip.Writer, name string, v any, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// loginLookups are the login_events queries that cover the subject: by
+// profile, and by the identifiers typed at login, which failed attempts
+// record without a profile.
+func loginLookups(s *Subject) []db.ListLoginEventsForSubjectParams {
+	var out []db.ListLoginEventsForSubjectParams
+	for _, p := range s.Profiles {
+		out = append(out, db.ListLoginEventsForSubjectParams{
+			ProfileID: sql.NullInt32{Int32: p.ID, Valid: true},
+		})
+	}
+	for _, id := range s.identifiers() {
+		out = append(out, db.ListLoginEventsForSubjectParams{Identifier: id})
+	}
+	return out
+}
+
+// section builds a Section, keeping the first of records seen more than once.
+type section struct {
+	Section
+	seen map[int64]bool
+}
+
+func newSection(name string) *section {
+	return &section{Section: Section{Name: name, Records: []Record{}}, seen: map[int64]bool{}}
+}
+
+func (s *section) has(id int32) bool {
+	return s.seen[int64(id)]
+}
+
+func (s *section) add(id any, row any, omit ...string) {
+	key := reflect.ValueOf(id).Int()
+	if s.seen[key] {
+		return
+	}
+	s.seen[key] = true
+	s.Records = append(s.Records, toRecord(row, omit...))
+}
+
+// toRecord turns a sqlc row into a Record keyed by its db tags, so every
+// column is exported, including ones added after this was written.
+func toRecord(row any, omit ...string) Record {
+	r := Record{}
+	v := reflect.ValueOf(row)
+	t := v.Type()
+fields:
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("db")
+		for _, o := range omit {
+			if name == o {
+				continue fields
+			}
+		}
+		r[name] = columnValue(v.Field(i).Interface())
+	}
+	return r
+}
+
+// columnValue unwraps sql.Null* values. An empty login or an unset link
+// then exports as null rather than as {"Valid": false}.
+func columnValue(v any) any {
+	if _, ok := v.(time.Time); ok {
+		return v
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			if b, ok := dv.([]byte); ok {
+				return string(b)
+			}
+			return dv
+		}
+	}
+	return v
+}
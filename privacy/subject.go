@@ -0,0 +1,155 @@
+// Package privacy handles data subject requests under the GDPR and the Kenya
+// Data Protection Act: exporting everything held about a person and erasing
+// their personal data. A person is found by email and phone across profiles
+// (users, agents and admins) and customers, which are not linked to each
+// other.
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	db "tickets/db/sqlc"
+	"tickets/phone"
+	"tickets/utils"
+)
+
+// Subject is a person and the records their email and phone lead to.
+type Subject struct {
+	Email     string
+	Phone     string
+	Profiles  []db.Profile
+	Customers []db.Customer
+}
+
+// Find looks up the profiles and customers with the given email or phone.
+// Either may be empty, not both. Phones are stored in E.164 form, so the
+// phone is normalized first; one that does not parse is looked up as given.
+func Find(ctx context.Context, q *db.Queries, email, number string) (*Subject, error) {
+	s := &Subject{
+		Email: strings.ToLower(strings.TrimSpace(email)),
+		Phone: phone.NormalizeOrTrim(number),
+	}
+	for _, lookup := range []struct {
+		value string
+		get   func(context.Context, sql.NullString) (db.Profile, error)
+	}{
+		{s.Email, q.GetProfileByEmail},
+		{s.Phone, q.GetProfileByPhone},
+	} {
+		if lookup.value == "" {
+			continue
+		}
+		p, err := lookup.get(ctx, utils.NullString(lookup.value))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !s.hasProfile(p.ID) {
+			s.Profiles = append(s.Profiles, p)
+		}
+	}
+	for _, lookup := range []struct {
+		value string
+		get   func(context.Context, string) (db.Customer, error)
+	}{
+		{s.Email, q.GetCustomerByEmail},
+		{s.Phone, q.GetCustomerByPhone},
+	} {
+		if lookup.value == "" {
+			continue
+		}
+		cu, err := lookup.get(ctx, lookup.value)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !s.hasCustomer(cu.ID) {
+			s.Customers = append(s.Customers, cu)
+		}
+	}
+	return s, nil
+}
+
+// Empty reports whether nothing is held under the subject's email or phone.
+func (s *Subject) Empty() bool {
+	return len(s.Profiles) == 0 && len(s.Customers) == 0
+}
+
+// Hash identifies the subject in the audit trail without keeping the email
+// or phone themselves.
+func (s *Subject) Hash() (string, error) {
+	return HashIdentifiers(s.Email, s.Phone)
+}
+
+// HashIdentifiers is Subject.Hash for an email and phone, normalised as by
+// Find, so a request can be looked up in the audit trail. It is an
+// HMAC-SHA256 keyed with PRIVACY_HASH_KEY: phone numbers are few enough
+// that a plain hash of one could be reversed by trying them all.
+func HashIdentifiers(email, number string) (string, error) {
+	key := os.Getenv("PRIVACY_HASH_KEY")
+	if key == "" {
+		return "", errors.New("PRIVACY_HASH_KEY is not set")
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email)) + "\n" + phone.NormalizeOrTrim(number)))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ProfileIDs is the comma separated ids of the subject's profiles.
+func (s *Subject) ProfileIDs() string {
+	ids := make([]string, 0, len(s.Profiles))
+	for _, p := range s.Profiles {
+		ids = append(ids, strconv.Itoa(int(p.ID)))
+	}
+	return strings.Join(ids, ",")
+}
+
+// CustomerIDs is the comma separated ids of the subject's customers.
+func (s *Subject) CustomerIDs() string {
+	ids := make([]string, 0, len(s.Customers))
+	for _, cu := range s.Customers {
+		ids = append(ids, strconv.FormatInt(cu.ID, 10))
+	}
+	return strings.Join(ids, ",")
+}
+
+// identifiers are the values login_events.identifier may hold for the subject.
+func (s *Subject) identifiers() []string {
+	var ids []string
+	for _, v := range []string{s.Email, s.Phone} {
+		if v != "" {
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
+
+func (s *Subject) hasProfile(id int32) bool {
+	for _, p := range s.Profiles {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subject) hasCustomer(id int64) bool {
+	for _, cu := range s.Customers {
+		if cu.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -25,9 +25,25 @@ func InitRabbitMQ(url string) error {
 	return nil
 }
 
-func Publish(queueName string, body []byte) error {
+// declare sets up an event stream: a fanout exchange, and a durable queue of
+// the same name bound to it for the worker. API replicas bind their own
+// throwaway queues to the exchange as well, see Subscribe, so every replica
+// sees every event.
+func declare(name string) error {
+	if err := ch.ExchangeDeclare(
+		name,
+		"fanout",
+		true,  // durable
+		false, // auto-delete
+		false, // internal
+		false, // no-wait
+		nil,
+	); err != nil {
+		slog.Error("Failed to declare exchange", "error", err)
+		return err
+	}
 	_, err := ch.QueueDeclare(
-		queueName,
+		name,
 		true,  // durable
 		false, // auto-delete
 		false, // exclusive
@@ -38,10 +54,17 @@ func Publish(queueName string, body []byte) error {
 		slog.Error("Failed to declare queue", "error", err)
 		return err
 	}
+	return ch.QueueBind(name, "", name, false, nil)
+}
 
-	err = ch.Publish(
-		"",        // exchange
-		queueName, // routing key (queue name)
+func Publish(queueName string, body []byte) error {
+	if err := declare(queueName); err != nil {
+		return err
+	}
+
+	err := ch.Publish(
+		queueName, // exchange
+		"",        // routing key, ignored by fanout
 		false,     // mandatory
 		false,     // immediate
 		amqp091.Publishing{
@@ -49,29 +72,65 @@ func Publish(queueName string, body []byte) error {
 			Body:        body,
 		},
 	)
-	slog.Info("Published message to queue", "queue", queueName, "body", string(body))
+	// bodies carry personal data such as emails, so only their size is logged
+	slog.Info("Published message to queue", "queue", queueName, "bytes", len(body))
 
 	return err
 }
 
+// Consume reads the durable queue of a stream. Consumers of the same queue
+// share its messages. Messages are not acknowledged automatically: callers
+// Ack each once it is handled, or Nack it to have it redelivered.
 func Consume(queueName string) (<-chan amqp091.Delivery, error) {
-	_, err := ch.QueueDeclare(
+	if err := declare(queueName); err != nil {
+		return nil, err
+	}
+
+	msgs, err := ch.Consume(
 		queueName,
-		true,  // durable
-		false, // auto-delete
+		"",    // consumer
+		false, // auto-ack
 		false, // exclusive
+		false, // no-local
 		false, // no-wait
 		nil,
 	)
 	if err != nil {
+		slog.Error("Failed to register consumer", "error", err)
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+// ConsumeAs reads a durable queue of its own bound to a stream, so a job
+// receives every event of the stream rather than sharing the stream's queue
+// with the webhook fanout. Consumers of the same queue share its messages.
+// As with Consume, callers Ack or Nack each message.
+func ConsumeAs(stream, queueName string) (<-chan amqp091.Delivery, error) {
+	if err := declare(stream); err != nil {
+		return nil, err
+	}
+	if _, err := ch.QueueDeclare(
+		queueName,
+		true,  // durable
+		false, // auto-delete
+		false, // exclusive
+		false, // no-wait
+		nil,
+	); err != nil {
 		slog.Error("Failed to declare queue", "error", err)
 		return nil, err
 	}
+	if err := ch.QueueBind(queueName, "", stream, false, nil); err != nil {
+		slog.Error("Failed to bind queue", "stream", stream, "error", err)
+		return nil, err
+	}
 
 	msgs, err := ch.Consume(
 		queueName,
 		"",    // consumer
-		true,  // auto-ack
+		false, // auto-ack
 		false, // exclusive
 		false, // no-local
 		false, // no-wait
@@ -81,6 +140,44 @@ func Consume(queueName string) (<-chan amqp091.Delivery, error) {
 		slog.Error("Failed to register consumer", "error", err)
 		return nil, err
 	}
+	return msgs, nil
+}
+
+// Subscribe receives every message published to a stream from now on,
+// through an exclusive queue that is deleted when the connection closes.
+func Subscribe(stream string) (<-chan amqp091.Delivery, error) {
+	if err := declare(stream); err != nil {
+		return nil, err
+	}
+	q, err := ch.QueueDeclare(
+		"",    // server-named
+		false, // durable
+		true,  // auto-delete
+		true,  // exclusive
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		slog.Error("Failed to declare queue", "error", err)
+		return nil, err
+	}
+	if err := ch.QueueBind(q.Name, "", stream, false, nil); err != nil {
+		slog.Error("Failed to bind queue", "stream", stream, "error", err)
+		return nil, err
+	}
 
+	msgs, err := ch.Consume(
+		q.Name,
+		"",    // consumer
+		true,  // auto-ack
+		true,  // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		slog.Error("Failed to register consumer", "error", err)
+		return nil, err
+	}
 	return msgs, nil
 }
@@ -0,0 +1,138 @@
+// Package realtime pushes events to the clients connected to this API
+// replica. Every replica subscribes to the RabbitMQ event streams on its own,
+// so a client sees the same events whichever replica it is connected to.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tickets/publish"
+)
+
+// resubscribeDelay is how long Run waits before subscribing again after the
+// stream was lost.
+const resubscribeDelay = 5 * time.Second
+
+var errClosed = errors.New("subscription channel closed")
+
+// Event is a {"id", "type", "payload"} message from one of the event streams.
+// ID is the event's event_log id; it is 0 for events that were not recorded.
+type Event struct {
+	Stream  string          `json:"-"`
+	ID      int64           `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Subscriber receives the events its filter accepts. A subscriber that falls
+// more than its buffer behind is dropped rather than allowed to hold up the
+// others: Lagged is closed and no more events are sent.
+type Subscriber struct {
+	events chan Event
+	lagged chan struct{}
+	filter func(Event) bool
+}
+
+func (s *Subscriber) Events() <-chan Event    { return s.events }
+func (s *Subscriber) Lagged() <-chan struct{} { return s.lagged }
+
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscriber]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[*Subscriber]struct{}{}}
+}
+
+// Subscribe registers a subscriber with room for buffer pending events. filter
+// is called from the hub's goroutine and must not block.
+func (h *Hub) Subscribe(buffer int, filter func(Event) bool) *Subscriber {
+	s := &Subscriber{
+		events: make(chan Event, buffer),
+		lagged: make(chan struct{}),
+		filter: filter,
+	}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.mu.Unlock()
+}
+
+// Broadcast hands ev to every subscriber that wants it.
+func (h *Hub) Broadcast(ev Event) {
+	var slow []*Subscriber
+	h.mu.RLock()
+	for s := range h.subs {
+		if s.filter != nil && !s.filter(ev) {
+			continue
+		}
+		select {
+		case s.events <- ev:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+	h.mu.Lock()
+	for _, s := range slow {
+		if _, ok := h.subs[s]; ok {
+			delete(h.subs, s)
+			close(s.lagged)
+		}
+	}
+	h.mu.Unlock()
+}
+
+// Run broadcasts the events published to stream until ctx is done,
+// subscribing again whenever the subscription is lost.
+func (h *Hub) Run(ctx context.Context, stream string) {
+	for {
+		if err := h.consume(ctx, stream); err != nil {
+			slog.Error("Realtime stream lost", "stream", stream, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (h *Hub) consume(ctx context.Context, stream string) error {
+	msgs, err := publish.Subscribe(stream)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return errClosed
+			}
+			ev := Event{Stream: stream}
+			if err := json.Unmarshal(msg.Body, &ev); err != nil || ev.Type == "" {
+				slog.Warn("Skipping malformed event", "stream", stream, "error", err)
+				continue
+			}
+			h.Broadcast(ev)
+		}
+	}
+}
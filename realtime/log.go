@@ -0,0 +1,54 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	db "tickets/db/sqlc"
+	"tickets/publish"
+)
+
+// Publish records an event in event_log and publishes it on stream, so
+// change feeds can replay what a client missed. ownerID is the profile the
+// event is about (0 for none) and limits who may see it. The payload's "id",
+// the ticket or transaction the event is about, is recorded as the subject
+// so a record's history can be looked up. An event that cannot be recorded
+// is still published, without an id, and the error is returned.
+func Publish(ctx context.Context, q *db.Queries, stream, eventType string, ownerID int64, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var subject struct {
+		ID int64 `json:"id"`
+	}
+	json.Unmarshal(data, &subject) // payloads without an id have no subject
+
+	ev := Event{Type: eventType, Payload: data}
+	var logErr error
+	res, err := q.CreateEvent(ctx, db.CreateEventParams{
+		Stream:    stream,
+		Type:      eventType,
+		OwnerID:   sql.NullInt64{Int64: ownerID, Valid: ownerID != 0},
+		SubjectID: sql.NullInt64{Int64: subject.ID, Valid: subject.ID != 0},
+		Payload:   string(data),
+	})
+	if err == nil {
+		ev.ID, err = res.LastInsertId()
+	}
+	if err != nil {
+		logErr = fmt.Errorf("record event: %w", err)
+	}
+
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := publish.Publish(stream, body); err != nil {
+		return err
+	}
+	return logErr
+}
@@ -0,0 +1,216 @@
+// Package reconcile matches provider statements against our transactions.
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/money"
+	"tickets/payments"
+)
+
+// Status is the outcome for one statement line or transaction.
+type Status string
+
+const (
+	StatusMatched            Status = "matched"
+	StatusAmountMismatch     Status = "amount_mismatch"
+	StatusStatusMismatch     Status = "status_mismatch"     // on the statement, but not settled on our side
+	StatusDuplicate          Status = "duplicate"           // reference seen before in the statement, or transaction already matched
+	StatusMissingTransaction Status = "missing_transaction" // on the statement, not in our transactions
+	StatusMissingLine        Status = "missing_line"        // settled on our side, not on the statement
+)
+
+// DefaultWindow is how far apart a statement line and a transaction may be
+// when they are matched by amount and time.
+const DefaultWindow = 10 * time.Minute
+
+// Transaction is the part of a transactions row reconciliation needs.
+// Receipt is the provider's own reference, e.g. the M-Pesa receipt number.
+type Transaction struct {
+	ID            int32
+	TransactionID string
+	Receipt       string
+	Amount        money.Money
+	Kind          db.TransactionsKind
+	Status        int16
+	CreatedAt     time.Time
+}
+
+func (t Transaction) settled() bool {
+	if t.Kind == db.TransactionsKindRefund {
+		return true
+	}
+	switch t.Status {
+	case payments.StatusCompleted, payments.StatusRefunded, payments.StatusPartiallyRefunded, payments.StatusReversed:
+		return true
+	}
+	return false
+}
+
+// Item is one finding. Line is nil for StatusMissingLine and Transaction is
+// nil for StatusMissingTransaction and some duplicates.
+type Item struct {
+	Status      Status
+	Line        *Line
+	Transaction *Transaction
+	Note        string
+}
+
+type Report struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Window      time.Duration
+	Items       []Item
+}
+
+// Counts returns how many items there are per status.
+func (r Report) Counts() map[Status]int {
+	counts := map[Status]int{}
+	for _, it := range r.Items {
+		counts[it.Status]++
+	}
+	return counts
+}
+
+// Period returns the earliest and latest line times.
+func Period(lines []Line) (start, end time.Time) {
+	for i, l := range lines {
+		if i == 0 || l.OccurredAt.Before(start) {
+			start = l.OccurredAt
+		}
+		if i == 0 || l.OccurredAt.After(end) {
+			end = l.OccurredAt
+		}
+	}
+	return start, end
+}
+
+// Match reconciles statement lines against transactions. A line matches a
+// transaction by reference (our transaction_id or the provider receipt);
+// lines without a reference match fall back to the same amount within
+// window of the transaction's creation. Settled transactions created inside
+// the statement period that no line matched are reported missing.
+func Match(lines []Line, txns []Transaction, window time.Duration) Report {
+	start, end := Period(lines)
+	report := Report{PeriodStart: start, PeriodEnd: end, Window: window}
+
+	byRef := map[string]int{}
+	for i, t := range txns {
+		byRef[t.TransactionID] = i
+		if t.Receipt != "" {
+			byRef[t.Receipt] = i
+		}
+	}
+	used := make([]bool, len(txns))
+	seen := map[string]int{} // reference -> first line number
+
+	var unmatched []int
+	for i := range lines {
+		l := &lines[i]
+		if l.Reference != "" {
+			if first, dup := seen[l.Reference]; dup {
+				report.Items = append(report.Items, Item{
+					Status: StatusDuplicate,
+					Line:   l,
+					Note:   fmt.Sprintf("reference also on line %d", first),
+				})
+				continue
+			}
+			seen[l.Reference] = l.Number
+		}
+
+		ti, ok := byRef[l.Reference]
+		if l.Reference == "" || !ok {
+			unmatched = append(unmatched, i)
+			continue
+		}
+		t := &txns[ti]
+		if used[ti] {
+			report.Items = append(report.Items, Item{
+				Status:      StatusDuplicate,
+				Line:        l,
+				Transaction: t,
+				Note:        "transaction already matched to another line",
+			})
+			continue
+		}
+		used[ti] = true
+		report.Items = append(report.Items, compare(l, t, "matched by reference"))
+	}
+
+	// fall back to amount and time for lines the reference did not resolve
+	for _, i := range unmatched {
+		l := &lines[i]
+		best, bestGap := -1, time.Duration(0)
+		for ti, t := range txns {
+			if used[ti] || t.Amount.Currency != l.Amount.Currency || t.Amount.Amount != abs(l.Amount.Amount) {
+				continue
+			}
+			gap := l.OccurredAt.Sub(t.CreatedAt)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap <= window && (best < 0 || gap < bestGap) {
+				best, bestGap = ti, gap
+			}
+		}
+		if best < 0 {
+			report.Items = append(report.Items, Item{Status: StatusMissingTransaction, Line: l})
+			continue
+		}
+		used[best] = true
+		report.Items = append(report.Items, compare(l, &txns[best], "matched by amount and time"))
+	}
+
+	for ti := range txns {
+		t := &txns[ti]
+		if used[ti] || !t.settled() || t.CreatedAt.Before(start) || t.CreatedAt.After(end) {
+			continue
+		}
+		report.Items = append(report.Items, Item{Status: StatusMissingLine, Transaction: t})
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		return itemTime(report.Items[i]).Before(itemTime(report.Items[j]))
+	})
+	return report
+}
+
+// compare classifies a line that has been paired with a transaction.
+func compare(l *Line, t *Transaction, how string) Item {
+	if l.Amount.Currency != t.Amount.Currency || abs(l.Amount.Amount) != t.Amount.Amount {
+		return Item{
+			Status:      StatusAmountMismatch,
+			Line:        l,
+			Transaction: t,
+			Note: fmt.Sprintf("statement %s %s, transaction %s %s",
+				l.Amount.Currency, l.Amount.String(), t.Amount.Currency, t.Amount.String()),
+		}
+	}
+	if !t.settled() {
+		return Item{
+			Status:      StatusStatusMismatch,
+			Line:        l,
+			Transaction: t,
+			Note:        "transaction is " + payments.StatusName(t.Status),
+		}
+	}
+	return Item{Status: StatusMatched, Line: l, Transaction: t, Note: how}
+}
+
+func itemTime(it Item) time.Time {
+	if it.Line != nil {
+		return it.Line.OccurredAt
+	}
+	return it.Transaction.CreatedAt
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
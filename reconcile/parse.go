@@ -0,0 +1,243 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"tickets/money"
+)
+
+// Statement formats accepted by Parse.
+const (
+	FormatCSV   = "csv"
+	FormatMpesa = "mpesa"
+)
+
+// Line is one entry of a provider statement. Amount is negative for money
+// paid out (refunds, reversals).
+type Line struct {
+	Number     int // 1-based line in the file
+	Reference  string
+	Amount     money.Money
+	OccurredAt time.Time
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"02-01-2006 15:04:05",
+	"02/01/2006 15:04:05",
+	"02-01-2006 15:04",
+	"02/01/2006 15:04",
+	time.DateOnly,
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", s)
+}
+
+// parseAmount accepts "1,250.00" style amounts.
+func parseAmount(s, currency string) (money.Money, error) {
+	s = strings.NewReplacer(",", "", " ", "").Replace(s)
+	return money.Parse(s, currency)
+}
+
+// Parse reads a statement in the given format. defaultCurrency is used for
+// CSV files without a currency column; M-Pesa statements are always KES.
+func Parse(format string, r io.Reader, defaultCurrency string) ([]Line, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r, defaultCurrency)
+	case FormatMpesa:
+		return ParseMpesa(r)
+	}
+	return nil, fmt.Errorf("unknown statement format %q", format)
+}
+
+// header maps lower-cased column names to their index.
+type header map[string]int
+
+func newHeader(row []string) header {
+	h := header{}
+	for i, name := range row {
+		h[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return h
+}
+
+// find returns the index of the first of names present in the header.
+func (h header) find(names ...string) (int, bool) {
+	for _, n := range names {
+		if i, ok := h[n]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func field(row []string, i int) string {
+	if i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+// ParseCSV reads a generic statement with a header row. Recognised columns:
+// reference (transaction_id, reference, ref, receipt), amount, currency
+// (optional) and time (occurred_at, date, time, timestamp, completed_at).
+func ParseCSV(r io.Reader, defaultCurrency string) ([]Line, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	first, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	h := newHeader(first)
+	refCol, ok := h.find("transaction_id", "reference", "ref", "receipt", "receipt_no")
+	if !ok {
+		return nil, errors.New("no reference column (transaction_id, reference, ref, receipt)")
+	}
+	amountCol, ok := h.find("amount")
+	if !ok {
+		return nil, errors.New("no amount column")
+	}
+	timeCol, ok := h.find("occurred_at", "date", "time", "timestamp", "completed_at", "created_at")
+	if !ok {
+		return nil, errors.New("no time column (occurred_at, date, time, timestamp, completed_at)")
+	}
+	curCol, hasCur := h.find("currency")
+
+	var lines []Line
+	for n := 2; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		currency := defaultCurrency
+		if hasCur && field(row, curCol) != "" {
+			currency = field(row, curCol)
+		}
+		amount, err := parseAmount(field(row, amountCol), currency)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		at, err := parseTime(field(row, timeCol))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		lines = append(lines, Line{
+			Number:     n,
+			Reference:  field(row, refCol),
+			Amount:     amount,
+			OccurredAt: at,
+		})
+	}
+	return lines, nil
+}
+
+// ParseMpesa reads the CSV statement exported from the M-Pesa organisation
+// portal. Rows before the "Receipt No." header are skipped, as are rows
+// whose Transaction Status is not Completed. Paid In becomes a positive
+// amount and Withdrawn a negative one.
+func ParseMpesa(r io.Reader) ([]Line, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var h header
+	n := 0
+	for h == nil {
+		row, err := cr.Read()
+		n++
+		if err == io.EOF {
+			return nil, errors.New(`no "Receipt No." header found`)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if candidate := newHeader(row); hasKey(candidate, "receipt no.") {
+			h = candidate
+		}
+	}
+	receiptCol, _ := h.find("receipt no.")
+	timeCol, ok := h.find("completion time", "initiation time")
+	if !ok {
+		return nil, errors.New(`no "Completion Time" column`)
+	}
+	paidInCol, hasPaidIn := h.find("paid in")
+	withdrawnCol, hasWithdrawn := h.find("withdrawn")
+	if !hasPaidIn && !hasWithdrawn {
+		return nil, errors.New(`no "Paid In" or "Withdrawn" column`)
+	}
+	statusCol, hasStatus := h.find("transaction status")
+
+	var lines []Line
+	for {
+		row, err := cr.Read()
+		n++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if field(row, receiptCol) == "" {
+			continue
+		}
+		if hasStatus && !strings.EqualFold(field(row, statusCol), "completed") {
+			continue
+		}
+
+		var amount money.Money
+		switch {
+		case hasPaidIn && nonZero(field(row, paidInCol)):
+			amount, err = parseAmount(field(row, paidInCol), "KES")
+		case hasWithdrawn && nonZero(field(row, withdrawnCol)):
+			amount, err = parseAmount(strings.TrimPrefix(field(row, withdrawnCol), "-"), "KES")
+			amount = amount.Neg()
+		default:
+			err = errors.New("neither Paid In nor Withdrawn is set")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		at, err := parseTime(field(row, timeCol))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		lines = append(lines, Line{
+			Number:     n,
+			Reference:  field(row, receiptCol),
+			Amount:     amount,
+			OccurredAt: at,
+		})
+	}
+	return lines, nil
+}
+
+// nonZero is false for "", "0" and "0.00"; the portal fills the unused column either way.
+func nonZero(s string) bool {
+	return strings.Trim(s, "0.,- ") != ""
+}
+
+func hasKey(h header, key string) bool {
+	_, ok := h[key]
+	return ok
+}
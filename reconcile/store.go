@@ -0,0 +1,112 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/money"
+)
+
+// Load returns the transactions that could match lines between start and
+// end, widened by window on both sides.
+func Load(ctx context.Context, q *db.Queries, start, end time.Time, window time.Duration) ([]Transaction, error) {
+	rows, err := q.ListTransactionsForReconciliation(ctx, db.ListTransactionsForReconciliationParams{
+		FromTime: sql.NullTime{Time: start.Add(-window), Valid: true},
+		ToTime:   sql.NullTime{Time: end.Add(window), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	txns := make([]Transaction, 0, len(rows))
+	for _, r := range rows {
+		txns = append(txns, Transaction{
+			ID:            r.ID,
+			TransactionID: r.TransactionID,
+			Receipt:       r.MpesaReceipt.String,
+			Amount:        money.Money{Amount: r.AmountMinor, Currency: r.Currency},
+			Kind:          r.Kind,
+			Status:        r.Status,
+			CreatedAt:     r.CreatedAt.Time,
+		})
+	}
+	return txns, nil
+}
+
+// Run describes a statement import for Save.
+type Run struct {
+	Format    string
+	Filename  string
+	LineCount int
+	CreatedBy sql.NullInt32
+}
+
+// Save stores a report and its items in one database transaction and
+// returns the run id.
+func Save(ctx context.Context, conn *sql.DB, q *db.Queries, run Run, report Report) (int64, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+
+	result, err := qtx.CreateReconciliationRun(ctx, db.CreateReconciliationRunParams{
+		Format:        run.Format,
+		Filename:      run.Filename,
+		LineCount:     int32(run.LineCount),
+		PeriodStart:   nullTime(report.PeriodStart),
+		PeriodEnd:     nullTime(report.PeriodEnd),
+		WindowSeconds: int32(report.Window / time.Second),
+		CreatedBy:     run.CreatedBy,
+	})
+	if err != nil {
+		return 0, err
+	}
+	runID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, it := range report.Items {
+		params := db.CreateReconciliationItemParams{
+			RunID:  runID,
+			Status: db.ReconciliationItemsStatus(it.Status),
+			Note:   truncate(it.Note, 255),
+		}
+		if l := it.Line; l != nil {
+			params.LineNumber = sql.NullInt32{Int32: int32(l.Number), Valid: true}
+			params.Reference = sql.NullString{String: truncate(l.Reference, 255), Valid: l.Reference != ""}
+			params.AmountMinor = sql.NullInt64{Int64: l.Amount.Amount, Valid: true}
+			params.Currency = sql.NullString{String: l.Amount.Currency, Valid: true}
+			params.OccurredAt = nullTime(l.OccurredAt)
+		} else if t := it.Transaction; t != nil {
+			// missing lines carry the transaction's own figures
+			params.Reference = sql.NullString{String: t.TransactionID, Valid: true}
+			params.AmountMinor = sql.NullInt64{Int64: t.Amount.Amount, Valid: true}
+			params.Currency = sql.NullString{String: t.Amount.Currency, Valid: true}
+			params.OccurredAt = nullTime(t.CreatedAt)
+		}
+		if it.Transaction != nil {
+			params.TransactionID = sql.NullInt32{Int32: it.Transaction.ID, Valid: true}
+		}
+		if err := qtx.CreateReconciliationItem(ctx, params); err != nil {
+			return 0, err
+		}
+	}
+
+	return runID, tx.Commit()
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+// Package routing picks the team a new ticket goes to. Routing rules are
+// tried in priority order, lowest first, then by id; the first rule whose
+// conditions all hold wins. A rule's conditions are the ticket's priority,
+// its type, the segment of its customer and keywords in its title or
+// description. A condition left unset matches any ticket.
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	db "tickets/db/sqlc"
+)
+
+// Ticket is what rules are matched against.
+type Ticket struct {
+	Title       string
+	Description string
+	Priority    string
+	Type        db.TicketsType
+	// Segment is the customer's segment, "" when the ticket has no customer.
+	Segment string
+}
+
+// Match returns the first rule, in priority order, that matches t, and
+// false when none does. Inactive rules are skipped.
+func Match(rules []db.RoutingRule, t Ticket) (db.RoutingRule, bool) {
+	sorted := make([]db.RoutingRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	for _, r := range sorted {
+		if r.Active && Matches(r, t) {
+			return r, true
+		}
+	}
+	return db.RoutingRule{}, false
+}
+
+// Matches reports whether every condition of r holds for t.
+func Matches(r db.RoutingRule, t Ticket) bool {
+	if r.TicketPriority.Valid && !strings.EqualFold(r.TicketPriority.String, t.Priority) {
+		return false
+	}
+	if r.TicketType.Valid && string(r.TicketType.RoutingRulesTicketType) != string(t.Type) {
+		return false
+	}
+	if r.CustomerSegment.Valid && !strings.EqualFold(r.CustomerSegment.String, t.Segment) {
+		return false
+	}
+	keywords := SplitKeywords(r.Keywords)
+	if len(keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(t.Title + "\n" + t.Description)
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKeywords validates keywords and returns them lowercased, trimmed and
+// without duplicates. Keywords cannot contain commas, which separate them
+// in routing_rules.keywords.
+func ParseKeywords(keywords []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			return nil, errors.New("keywords cannot be empty")
+		}
+		if strings.Contains(k, ",") {
+			return nil, fmt.Errorf("keyword %q cannot contain a comma", k)
+		}
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+// JoinKeywords and SplitKeywords convert keywords to and from the comma
+// separated form stored in routing_rules.keywords.
+func JoinKeywords(keywords []string) string {
+	return strings.Join(keywords, ",")
+}
+
+func SplitKeywords(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
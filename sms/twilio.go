@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 
+	"tickets/phone"
+
 	twilio "github.com/twilio/twilio-go"
 	openapi "github.com/twilio/twilio-go/rest/api/v2010"
 )
@@ -29,9 +31,11 @@ func NewTwilioProvider() *TwilioProvider {
 	}
 }
 
+// SendSMS sends body to the number to. Twilio wants E.164, so numbers
+// stored before phones were normalized are converted first.
 func (t *TwilioProvider) SendSMS(ctx context.Context, to, body string) error {
 	params := &openapi.CreateMessageParams{}
-	params.SetTo(to)
+	params.SetTo(phone.NormalizeOrTrim(to))
 	params.SetFrom(t.from)
 	params.SetBody(body)
 
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+// API keys look like tk_<prefix>_<secret>. The prefix is stored in clear to
+// find the key; only the SHA-256 of the whole key is stored.
+const apiKeyTag = "tk"
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateAPIKey returns a new key and its prefix.
+func GenerateAPIKey() (key, prefix string, err error) {
+	var p [5]byte
+	var s [20]byte
+	if _, err := rand.Read(p[:]); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(s[:]); err != nil {
+		return "", "", err
+	}
+	prefix = strings.ToLower(apiKeyEncoding.EncodeToString(p[:]))
+	secret := strings.ToLower(apiKeyEncoding.EncodeToString(s[:]))
+	return apiKeyTag + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// APIKeyPrefix extracts the prefix from a key, or returns false if it is malformed.
+func APIKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// HashAPIKey returns the hex SHA-256 of the key. Keys are random enough that
+// a slow hash isn't needed.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,19 +1,54 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(userID int64) (string, error) {
+// Claims are the fields we read back out of a verified token.
+type Claims struct {
+	ProfileID    int64
+	TokenVersion int32
+}
+
+// GenerateJWT issues a token for the profile. version is the profile's current
+// token_version; bumping it in the database revokes all earlier tokens.
+func GenerateJWT(userID int64, version int32) (string, error) {
 	secret := os.Getenv("JWT_SECRET")
 	claims := jwt.MapClaims{
 		"profile_id": userID,
+		"ver":        version,
 		"exp":        time.Now().Add(24 * time.Hour).Unix(),
 		"iat":        time.Now().Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
+
+// ParseJWT verifies the signature and expiry of a token and returns its claims.
+func ParseJWT(tokenStr string) (Claims, error) {
+	secret := os.Getenv("JWT_SECRET")
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return Claims{}, fmt.Errorf("invalid token: %w", err)
+	}
+
+	mc, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Claims{}, errors.New("invalid token claims")
+	}
+	id, ok := mc["profile_id"].(float64)
+	if !ok {
+		return Claims{}, errors.New("token missing profile_id")
+	}
+	// tokens issued before versioning carry no ver claim and count as version 0
+	ver, _ := mc["ver"].(float64)
+
+	return Claims{ProfileID: int64(id), TokenVersion: int32(ver)}, nil
+}
@@ -0,0 +1,8 @@
+package utils
+
+import "database/sql"
+
+// NullString maps "" to NULL, for optional columns such as profiles.phone and profiles.email.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
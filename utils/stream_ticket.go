@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// StreamTicketTTL is how long a stream ticket may wait to be used, and how
+// long after its last connection opened or closed it can be used again.
+// Clients fetch one right before opening the connection.
+const StreamTicketTTL = 30 * time.Second
+
+// GenerateStreamTicket returns a new random stream ticket.
+func GenerateStreamTicket() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// HashStreamTicket returns the hex SHA-256 the ticket is stored under.
+func HashStreamTicket(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
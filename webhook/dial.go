@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is returned for webhook URLs that point, or resolve,
+// to an address that is not on the public internet.
+var ErrAddressNotAllowed = errors.New("webhook address is not allowed")
+
+// blockedPrefixes are the special purpose ranges net/netip has no predicate
+// for: this network, shared address space, IETF protocol assignments,
+// documentation, benchmarking, reserved, NAT64 and 6to4, which can embed
+// any IPv4 address.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// PublicAddr reports whether webhooks may be sent to addr. Loopback,
+// private, link-local (which includes cloud metadata endpoints such as
+// 169.254.169.254), multicast and other special purpose addresses are
+// refused.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL validates a subscription URL: absolute http or https, and not an
+// IP literal or localhost name that PublicAddr refuses. Host names are only
+// resolved when a delivery is sent, by the dialer of NewClient.
+func CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrAddressNotAllowed
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !PublicAddr(addr) {
+		return ErrAddressNotAllowed
+	}
+	return nil
+}
+
+// NewClient returns the HTTP client deliveries are sent with. It connects
+// only to public addresses, checked on the resolved address of every
+// connection so a host name cannot be re-pointed at an internal one after
+// the URL was accepted. It ignores proxy settings and does not follow
+// redirects: a 3xx answer is a failed delivery.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+			}
+			if !PublicAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrAddressNotAllowed, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil, // a proxy would make the connection on our behalf
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
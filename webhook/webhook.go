@@ -0,0 +1,147 @@
+// Package webhook holds what both the API and the worker need to know about
+// outbound webhooks: how requests are signed and how subscriptions select
+// events.
+//
+// Every delivery is a POST of a JSON event
+//
+//	{"id": "...", "type": "ticket.created", "created_at": "...", "payload": {...}}
+//
+// with the headers
+//
+//	Webhook-Id:        the event id; redeliveries reuse it, so receivers can dedupe
+//	Webhook-Timestamp: unix seconds when this attempt was signed
+//	Webhook-Signature: v1=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// Receivers should recompute the signature and reject timestamps more than
+// a few minutes old, which stops captured requests from being replayed.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+
+	signatureVersion = "v1"
+	secretPrefix     = "whsec_"
+)
+
+var (
+	ErrBadSignature = errors.New("webhook: signature mismatch")
+	ErrStale        = errors.New("webhook: timestamp outside tolerance")
+)
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Sign returns the Webhook-Signature header value for body sent at ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a received delivery.
+// tolerance is how old (or how far in the future) the timestamp may be.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("webhook: bad timestamp %q", timestamp)
+	}
+	ts := time.Unix(unix, 0)
+	if d := time.Since(ts); d > tolerance || d < -tolerance {
+		return ErrStale
+	}
+	want := Sign(secret, ts, body)
+	// the header may carry several signatures while a secret is rotated
+	for _, sig := range strings.Split(signature, " ") {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+var patternRe = regexp.MustCompile(`^(\*|[a-z_]+(\.[a-z_]+)*(\.\*)?)$`)
+
+// ParseEvents validates event patterns and returns them normalised. A
+// pattern is an event type (ticket.created), a prefix ending in .*
+// (transaction.*) or * for everything.
+func ParseEvents(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("at least one event pattern is required")
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if !patternRe.MatchString(p) {
+			return nil, fmt.Errorf("invalid event pattern %q", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// Matches reports whether eventType is selected by any of patterns.
+func Matches(patterns []string, eventType string) bool {
+	for _, p := range patterns {
+		switch {
+		case p == "*", p == eventType:
+			return true
+		case strings.HasSuffix(p, ".*") && strings.HasPrefix(eventType, p[:len(p)-1]):
+			return true
+		}
+	}
+	return false
+}
+
+// JoinEvents and SplitEvents convert patterns to and from the comma
+// separated form stored in webhook_subscriptions.events.
+func JoinEvents(patterns []string) string {
+	return strings.Join(patterns, ",")
+}
+
+func SplitEvents(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// Backoff returns how long to wait after the given failed attempt (1-based):
+// 30s doubling each time, capped at six hours.
+func Backoff(attempt int) time.Duration {
+	const base, ceiling = 30 * time.Second, 6 * time.Hour
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 20 {
+		return ceiling
+	}
+	return min(base<<(attempt-1), ceiling)
+}
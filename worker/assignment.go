@@ -0,0 +1,237 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tickets/assignment"
+	db "tickets/db/sqlc"
+	"tickets/publish"
+	"tickets/realtime"
+)
+
+// assignmentQueue is the worker's own queue on ticket_events, so the
+// assigner sees every ticket.created and ticket.team_changed rather than
+// sharing them with the webhook fanout.
+const assignmentQueue = "ticket_events.assignment"
+
+// Assigner assigns new and re-teamed tickets to an agent of their team with the team's
+// assignment strategy, and logs every decision in ticket_assignments.
+type Assigner struct {
+	Queries *db.Queries
+	DB      *sql.DB
+	// ClosedStatuses are the comma separated ticket statuses that do not
+	// count toward an agent's open tickets.
+	ClosedStatuses string
+}
+
+// NewAssigner reads ASSIGNMENT_CLOSED_STATUSES (default 3,4).
+func NewAssigner(conn *sql.DB, q *db.Queries) *Assigner {
+	return &Assigner{Queries: q, DB: conn, ClosedStatuses: assignment.ClosedStatuses()}
+}
+
+// Run assigns the tickets of ticket.created and ticket.team_changed events
+// until ctx is done or the queue channel closes. A message is acknowledged
+// once its ticket is handled; if the database fails it goes back on the
+// queue.
+func (a *Assigner) Run(ctx context.Context) error {
+	msgs, err := publish.ConsumeAs("ticket_events", assignmentQueue)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("%s: consumer channel closed", assignmentQueue)
+			}
+			err := a.handle(ctx, msg.Body)
+			switch {
+			case err == nil:
+				if err := msg.Ack(false); err != nil {
+					slog.Error("Failed to ack event", "queue", assignmentQueue, "error", err)
+				}
+			case errors.Is(err, errBadEvent):
+				// retrying cannot fix it
+				slog.Error("Dropping ticket event", "queue", assignmentQueue, "error", err)
+				if err := msg.Ack(false); err != nil {
+					slog.Error("Failed to ack event", "queue", assignmentQueue, "error", err)
+				}
+			default:
+				slog.Error("Failed to assign ticket", "queue", assignmentQueue, "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(webhookPollInterval):
+				}
+				if err := msg.Nack(false, true); err != nil {
+					slog.Error("Failed to requeue event", "queue", assignmentQueue, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// handle assigns the ticket of one event message. Events of other types are
+// ignored, and tickets deleted since the event are skipped.
+func (a *Assigner) handle(ctx context.Context, body []byte) error {
+	var ev realtime.Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return fmt.Errorf("%w: %v", errBadEvent, err)
+	}
+	if ev.Type != "ticket.created" && ev.Type != "ticket.team_changed" {
+		return nil
+	}
+	var ticket struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(ev.Payload, &ticket); err != nil || ticket.ID == 0 {
+		return fmt.Errorf("%w: %s event %d without a ticket id", errBadEvent, ev.Type, ev.ID)
+	}
+	err := a.Assign(ctx, ticket.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Info("Ticket or team is gone, not assigning", "ticket_id", ticket.ID)
+		return nil
+	}
+	return err
+}
+
+// Assign picks an agent for an unassigned ticket whose team has an
+// assignment strategy. Tickets without a team, already assigned or of a
+// manual team are left alone. When no agent is available the decision is
+// still logged and the ticket stays unassigned.
+func (a *Assigner) Assign(ctx context.Context, ticketID int64) error {
+	ticket, err := a.Queries.GetTicket(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	if ticket.AssignedTo.Valid || !ticket.TeamID.Valid {
+		return nil
+	}
+	team, err := a.Queries.GetTeam(ctx, ticket.TeamID.Int64)
+	if err != nil {
+		return err
+	}
+	strategy, ok := assignment.Lookup(team.AssignmentStrategy)
+	if !ok {
+		if team.AssignmentStrategy != assignment.Manual {
+			slog.Warn("Unknown assignment strategy", "team_id", team.ID, "strategy", team.AssignmentStrategy)
+		}
+		return nil
+	}
+
+	t := assignment.Ticket{
+		ID:          ticket.ID,
+		Title:       ticket.Title,
+		Description: ticket.Description,
+		Priority:    ticket.Priority,
+		Type:        ticket.Type,
+	}
+	if ticket.CustomerID.Valid {
+		customer, err := a.Queries.GetCustomer(ctx, ticket.CustomerID.Int64)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		t.Segment = customer.Segment
+	}
+
+	rows, err := a.Queries.ListTeamCandidates(ctx, db.ListTeamCandidatesParams{
+		ClosedStatuses: a.ClosedStatuses,
+		TeamID:         team.ID,
+	})
+	if err != nil {
+		return err
+	}
+	candidates := make([]assignment.Candidate, 0, len(rows))
+	for _, r := range rows {
+		c := assignment.Candidate{
+			ProfileID:     r.ProfileID,
+			Status:        db.AgentSettingsStatusOffline,
+			MaxConcurrent: r.MaxConcurrent.Int32,
+			OpenTickets:   r.OpenTickets,
+			Skills:        assignment.SplitSkills(r.Skills.String),
+		}
+		if r.Status.Valid {
+			c.Status = r.Status.AgentSettingsStatus
+		}
+		candidates = append(candidates, c)
+	}
+	last, err := a.Queries.GetLastTeamAssignee(ctx, ticket.TeamID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	d := assignment.Decide(strategy, t, candidates, last.Int32)
+	assigned, err := a.record(ctx, ticket, team, d)
+	if err != nil || !assigned {
+		return err
+	}
+
+	slog.Info("Ticket assigned", "ticket_id", ticket.ID, "team_id", team.ID, "assigned_to", d.ProfileID,
+		"strategy", team.AssignmentStrategy, "reason", d.Reason)
+	err = realtime.Publish(ctx, a.Queries, "ticket_events", "ticket.assigned", ticket.CreatedBy, map[string]any{
+		"id":          ticket.ID,
+		"created_by":  ticket.CreatedBy,
+		"team_id":     team.ID,
+		"assigned_to": d.ProfileID,
+		"strategy":    team.AssignmentStrategy,
+		"reason":      d.Reason,
+	})
+	if err != nil {
+		slog.Error("Failed to publish ticket event", "error", err)
+	}
+	return nil
+}
+
+// record assigns the ticket and logs the decision in one transaction. It
+// reports false, logging nothing, if the ticket was assigned in the
+// meantime, and false with the decision logged if no agent was available.
+func (a *Assigner) record(ctx context.Context, ticket db.Ticket, team db.Team, d assignment.Decision) (bool, error) {
+	candidates, err := json.Marshal(d.Candidates)
+	if err != nil {
+		return false, err
+	}
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := a.Queries.WithTx(tx)
+
+	if d.ProfileID != 0 {
+		n, err := qtx.ClaimUnassignedTicket(ctx, db.ClaimUnassignedTicketParams{
+			AssignedTo: sql.NullInt64{Int64: int64(d.ProfileID), Valid: true},
+			ID:         ticket.ID,
+		})
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			slog.Info("Ticket was assigned before the worker got to it", "ticket_id", ticket.ID)
+			return false, nil
+		}
+	}
+	if _, err := qtx.CreateTicketAssignment(ctx, db.CreateTicketAssignmentParams{
+		TicketID:   ticket.ID,
+		TeamID:     ticket.TeamID,
+		Strategy:   team.AssignmentStrategy,
+		AssignedTo: sql.NullInt32{Int32: d.ProfileID, Valid: d.ProfileID != 0},
+		Reason:     truncate(d.Reason, 255),
+		Candidates: string(candidates),
+	}); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if d.ProfileID == 0 {
+		slog.Warn("No agent available for ticket", "ticket_id", ticket.ID, "team_id", team.ID, "reason", d.Reason)
+	}
+	return d.ProfileID != 0, nil
+}
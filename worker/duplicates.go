@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/dedupe"
+)
+
+const (
+	defaultDuplicateScanInterval = time.Hour
+	// duplicateScanPage is how many customers the scan reads per query.
+	duplicateScanPage = 1000
+)
+
+// Duplicates scans all customers for likely duplicates and stores the
+// pairs in customer_duplicates for GET /customers/duplicates, so the
+// comparison does not run inside a request.
+type Duplicates struct {
+	Queries  *db.Queries
+	DB       *sql.DB
+	Interval time.Duration
+}
+
+// NewDuplicates reads DUPLICATE_SCAN_INTERVAL (a Go duration, default 1h).
+func NewDuplicates(conn *sql.DB, q *db.Queries) *Duplicates {
+	return &Duplicates{
+		Queries:  q,
+		DB:       conn,
+		Interval: envDuration("DUPLICATE_SCAN_INTERVAL", defaultDuplicateScanInterval),
+	}
+}
+
+// Run scans at start and then every Interval until ctx is done.
+func (d *Duplicates) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		if n, err := d.Scan(ctx); err != nil {
+			slog.Error("Customer duplicate scan failed", "error", err)
+		} else {
+			slog.Info("Customer duplicate scan", "pairs", n, "took", time.Since(start))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan replaces the stored pairs with those found among the current
+// customers and returns how many there are.
+func (d *Duplicates) Scan(ctx context.Context) (int, error) {
+	var customers []db.Customer
+	params := db.ListCustomersAfterParams{Limit: duplicateScanPage}
+	for {
+		page, err := d.Queries.ListCustomersAfter(ctx, params)
+		if err != nil {
+			return 0, err
+		}
+		customers = append(customers, page...)
+		if len(page) < duplicateScanPage {
+			break
+		}
+		params.ID = page[len(page)-1].ID
+	}
+	pairs := dedupe.Pairs(customers, dedupe.DefaultNameSimilarity)
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := d.Queries.WithTx(tx)
+	if err := qtx.DeleteCustomerDuplicates(ctx); err != nil {
+		return 0, err
+	}
+	for _, p := range pairs {
+		reasons := make([]string, len(p.Reasons))
+		for i, r := range p.Reasons {
+			reasons[i] = string(r)
+		}
+		if err := qtx.CreateCustomerDuplicate(ctx, db.CreateCustomerDuplicateParams{
+			CustomerID:     p.A.ID,
+			DuplicateID:    p.B.ID,
+			Reasons:        strings.Join(reasons, ","),
+			NameSimilarity: p.Similarity,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(pairs), nil
+}
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "tickets/db/sqlc"
+)
+
+const (
+	defaultEventLogRetention = 90 * 24 * time.Hour
+	eventLogPruneInterval    = time.Hour
+	eventLogPruneBatch       = 5000
+)
+
+// EventLogPruner deletes event_log rows older than Retention. The change
+// feed can only replay as far back as the log goes. Ticket and transaction
+// events other than *.created are kept, as customer timelines show them as
+// status changes.
+type EventLogPruner struct {
+	Queries   *db.Queries
+	Retention time.Duration
+}
+
+// NewEventLogPruner reads EVENT_LOG_RETENTION (a Go duration, default 2160h,
+// that is 90 days).
+func NewEventLogPruner(q *db.Queries) *EventLogPruner {
+	return &EventLogPruner{
+		Queries:   q,
+		Retention: envDuration("EVENT_LOG_RETENTION", defaultEventLogRetention),
+	}
+}
+
+// Run prunes at start and then every hour until ctx is done.
+func (p *EventLogPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(eventLogPruneInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := p.Prune(ctx); err != nil {
+			slog.Error("Failed to prune event log", "error", err)
+		} else if n > 0 {
+			slog.Info("Pruned event log", "deleted", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes the expired rows in batches, so no single statement holds
+// locks on the log for long, and returns how many it deleted.
+func (p *EventLogPruner) Prune(ctx context.Context) (int64, error) {
+	before := time.Now().Add(-p.Retention)
+	var total int64
+	for {
+		n, err := p.Queries.DeleteEventsBefore(ctx, db.DeleteEventsBeforeParams{
+			CreatedAt: before,
+			Limit:     eventLogPruneBatch,
+		})
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < eventLogPruneBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	db "tickets/db/sqlc"
+	"tickets/publish"
+	"tickets/utils"
+	"tickets/webhook"
+)
+
+const (
+	defaultWebhookMaxAttempts  = 10
+	defaultWebhookDisableAfter = 25
+	defaultWebhookTimeout      = 10 * time.Second
+	webhookPollInterval        = 2 * time.Second
+	webhookBatchSize           = 50
+	webhookConcurrency         = 8
+)
+
+// Webhooks turns queued events into webhook deliveries and sends them.
+type Webhooks struct {
+	Queries *db.Queries
+	DB      *sql.DB
+	Client  *http.Client
+	// MaxAttempts is how often a delivery is tried before it is marked failed.
+	MaxAttempts int
+	// DisableAfter is how many attempts in a row may fail, across all of a
+	// subscription's deliveries, before the subscription is disabled.
+	DisableAfter int
+}
+
+// NewWebhooks reads WEBHOOK_MAX_ATTEMPTS (default 10), WEBHOOK_DISABLE_AFTER
+// (default 25) and WEBHOOK_TIMEOUT (a Go duration, default 10s).
+func NewWebhooks(conn *sql.DB, q *db.Queries) *Webhooks {
+	return &Webhooks{
+		Queries:      q,
+		DB:           conn,
+		Client:       webhook.NewClient(envDuration("WEBHOOK_TIMEOUT", defaultWebhookTimeout)),
+		MaxAttempts:  envInt("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+		DisableAfter: envInt("WEBHOOK_DISABLE_AFTER", defaultWebhookDisableAfter),
+	}
+}
+
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		slog.Error("invalid "+key+", using default", "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Error("invalid "+key+", using default", "value", v, "default", def)
+		return def
+	}
+	return d
+}
+
+// Event is the body of every webhook request.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Fanout consumes events from queue and queues a delivery for every active
+// subscription that wants them. A message is acknowledged once its
+// deliveries are saved; if saving fails it goes back on the queue. It
+// returns when ctx is done or the queue channel closes.
+func (w *Webhooks) Fanout(ctx context.Context, queue string) error {
+	msgs, err := publish.Consume(queue)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("%s: consumer channel closed", queue)
+			}
+			err := w.Enqueue(ctx, msg.Body)
+			switch {
+			case err == nil:
+				if err := msg.Ack(false); err != nil {
+					slog.Error("Failed to ack event", "queue", queue, "error", err)
+				}
+			case errors.Is(err, errBadEvent):
+				// retrying cannot fix it
+				slog.Error("Dropping undeliverable event", "queue", queue, "error", err)
+				if err := msg.Ack(false); err != nil {
+					slog.Error("Failed to ack event", "queue", queue, "error", err)
+				}
+			default:
+				slog.Error("Failed to queue webhook deliveries", "queue", queue, "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(webhookPollInterval):
+				}
+				if err := msg.Nack(false, true); err != nil {
+					slog.Error("Failed to requeue event", "queue", queue, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// errBadEvent wraps the errors of messages that can never be delivered.
+var errBadEvent = errors.New("bad event")
+
+// Enqueue creates the deliveries for one {"type", "payload"} event message,
+// all of them or none.
+func (w *Webhooks) Enqueue(ctx context.Context, body []byte) error {
+	var msg struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return fmt.Errorf("%w: decode: %v", errBadEvent, err)
+	}
+	if msg.Type == "" {
+		return fmt.Errorf("%w: no type", errBadEvent)
+	}
+
+	subs, err := w.Queries.ListActiveWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	var targets []db.WebhookSubscription
+	for _, s := range subs {
+		if !webhook.Matches(webhook.SplitEvents(s.Events), msg.Type) {
+			continue
+		}
+		if s.Scope == db.WebhookSubscriptionsScopeOwn && !ownedBy(msg.Payload, s.ProfileID) {
+			continue
+		}
+		targets = append(targets, s)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+	event, err := json.Marshal(Event{
+		ID:        id,
+		Type:      msg.Type,
+		CreatedAt: time.Now().UTC(),
+		Payload:   msg.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := w.Queries.WithTx(tx)
+	now := time.Now()
+	for _, s := range targets {
+		if _, err := qtx.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			SubscriptionID: s.ID,
+			EventID:        id,
+			EventType:      msg.Type,
+			Payload:        string(event),
+			NextAttemptAt:  now,
+		}); err != nil {
+			return fmt.Errorf("subscription %d: %w", s.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ownedBy reports whether an event payload is about profileID: tickets carry
+// created_by and transactions user_id.
+func ownedBy(payload json.RawMessage, profileID int32) bool {
+	var p struct {
+		CreatedBy *int64 `json:"created_by"`
+		UserID    *int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return false
+	}
+	switch {
+	case p.CreatedBy != nil:
+		return *p.CreatedBy == int64(profileID)
+	case p.UserID != nil:
+		return *p.UserID == int64(profileID)
+	}
+	return false
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Deliver sends due deliveries until ctx is done.
+func (w *Webhooks) Deliver(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		w.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Webhooks) deliverDue(ctx context.Context) {
+	now := time.Now()
+	due, err := w.Queries.ListDueWebhookDeliveries(ctx, db.ListDueWebhookDeliveriesParams{
+		NextAttemptAt: now,
+		Limit:         webhookBatchSize,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to list due webhook deliveries", "error", err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookConcurrency)
+	for _, d := range due {
+		// the lease outlives the request timeout, so a crashed worker's
+		// delivery is picked up again once it expires
+		n, err := w.Queries.ClaimWebhookDelivery(ctx, db.ClaimWebhookDeliveryParams{
+			LeaseUntil:    now.Add(2*w.Client.Timeout + time.Minute),
+			ID:            d.ID,
+			NextAttemptAt: d.NextAttemptAt,
+		})
+		if err != nil {
+			slog.Error("Failed to claim webhook delivery", "id", d.ID, "error", err)
+			continue
+		}
+		if n == 0 {
+			continue // another worker has it
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d db.ListDueWebhookDeliveriesRow) {
+			defer func() { <-sem; wg.Done() }()
+			w.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+}
+
+var errDecryptSecret = errors.New("decrypt secret")
+
+// attempt sends one delivery and records the outcome.
+func (w *Webhooks) attempt(ctx context.Context, d db.ListDueWebhookDeliveriesRow) {
+	var code int
+	secret, err := utils.Decrypt(d.Secret)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", errDecryptSecret, err)
+	} else {
+		code, err = w.post(ctx, d.Url, secret, d.EventID, []byte(d.Payload))
+	}
+
+	// record even if ctx was cancelled mid-request
+	rctx := context.WithoutCancel(ctx)
+	now := time.Now()
+	attempts := int(d.Attempts) + 1
+	responseStatus := sql.NullInt32{Int32: int32(code), Valid: code != 0}
+
+	if err == nil {
+		if err := w.Queries.RecordWebhookAttempt(rctx, db.RecordWebhookAttemptParams{
+			Status:         db.WebhookDeliveriesStatusSucceeded,
+			NextAttemptAt:  now,
+			LastAttemptAt:  sql.NullTime{Time: now, Valid: true},
+			ResponseStatus: responseStatus,
+			ID:             d.ID,
+		}); err != nil {
+			slog.Error("Failed to record webhook delivery", "id", d.ID, "error", err)
+		}
+		if err := w.Queries.ResetWebhookFailures(rctx, d.SubscriptionID); err != nil {
+			slog.Error("Failed to reset webhook failures", "subscription_id", d.SubscriptionID, "error", err)
+		}
+		slog.Info("Webhook delivered", "id", d.ID, "subscription_id", d.SubscriptionID, "event", d.EventType, "status", code)
+		return
+	}
+
+	status := db.WebhookDeliveriesStatusPending
+	if attempts >= w.MaxAttempts {
+		status = db.WebhookDeliveriesStatusFailed
+	}
+	if rerr := w.Queries.RecordWebhookAttempt(rctx, db.RecordWebhookAttemptParams{
+		Status:         status,
+		NextAttemptAt:  now.Add(webhook.Backoff(attempts)),
+		LastAttemptAt:  sql.NullTime{Time: now, Valid: true},
+		ResponseStatus: responseStatus,
+		LastError:      deliveryError(err),
+		ID:             d.ID,
+	}); rerr != nil {
+		slog.Error("Failed to record webhook delivery", "id", d.ID, "error", rerr)
+	}
+	slog.Warn("Webhook delivery failed", "id", d.ID, "subscription_id", d.SubscriptionID,
+		"event", d.EventType, "attempt", attempts, "final", status == db.WebhookDeliveriesStatusFailed, "error", err)
+
+	if err := w.Queries.IncrementWebhookFailures(rctx, d.SubscriptionID); err != nil {
+		slog.Error("Failed to count webhook failure", "subscription_id", d.SubscriptionID, "error", err)
+		return
+	}
+	n, err := w.Queries.DisableWebhookSubscription(rctx, db.DisableWebhookSubscriptionParams{
+		DisabledAt:          sql.NullTime{Time: now, Valid: true},
+		DisabledReason:      fmt.Sprintf("%d consecutive failed attempts", w.DisableAfter),
+		ID:                  d.SubscriptionID,
+		ConsecutiveFailures: int32(w.DisableAfter),
+	})
+	if err != nil {
+		slog.Error("Failed to disable webhook subscription", "subscription_id", d.SubscriptionID, "error", err)
+	} else if n > 0 {
+		slog.Warn("Webhook subscription disabled", "subscription_id", d.SubscriptionID, "failures", w.DisableAfter)
+	}
+}
+
+// post sends a signed event and returns the response status. Anything but
+// a 2xx is an error.
+func (w *Webhooks) post(ctx context.Context, url, secret, eventID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tickets-webhooks/1")
+	req.Header.Set(webhook.HeaderID, eventID)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, now, body))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%w %d", errUnexpectedStatus, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+var errUnexpectedStatus = errors.New("unexpected response status")
+
+// deliveryError is what the delivery log shows for a failed attempt. The
+// log is visible through the API, so it names the kind of failure only;
+// the full error, which can quote the receiver, is logged by the worker.
+func deliveryError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errUnexpectedStatus):
+		return errUnexpectedStatus.Error()
+	case errors.Is(err, webhook.ErrAddressNotAllowed):
+		return "address not allowed"
+	case errors.As(err, &dnsErr):
+		return "host not found"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timed out"
+	case errors.Is(err, errDecryptSecret):
+		return "internal error"
+	}
+	return "connection failed"
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}